@@ -1,13 +1,27 @@
 package main
 
+import "patterns/registry"
+
 type adidas struct {
+	size int
+}
+
+func init() {
+	sportsFactories.MustRegister(registry.Metadata{Name: "adidas", Version: "1.0.0"},
+		func(o registry.Options) (iSportsFactory, error) {
+			opts, err := decodeBrandOptions(o)
+			if err != nil {
+				return nil, err
+			}
+			return &adidas{size: opts.Size}, nil
+		})
 }
 
 func (a *adidas) makeShoe() iShoe {
 	return &adidasShoe{
 		shoe: shoe{
 			logo: "adidas",
-			size: 14,
+			size: a.size,
 		},
 	}
 }
@@ -16,7 +30,7 @@ func (a *adidas) makeShirt() iShirt {
 	return &adidasShirt{
 		shirt: shirt{
 			logo: "adidas",
-			size: 14,
+			size: a.size,
 		},
 	}
 }
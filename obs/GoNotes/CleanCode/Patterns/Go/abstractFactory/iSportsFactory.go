@@ -1,20 +1,27 @@
 package main
 
-import "fmt"
+import "patterns/registry"
 
 type iSportsFactory interface {
 	makeShoe() iShoe
 	makeShirt() iShirt
 }
 
-func getSportsFactory(brand string) (iSportsFactory, error) {
-	if brand == "adidas" {
-		return &adidas{}, nil
-	}
+// sportsFactories holds every brand; each one registers itself from an init
+// function in its own file.
+var sportsFactories = registry.New[iSportsFactory]("sports factory")
 
-	if brand == "nike" {
-		return &nike{}, nil
-	}
+// brandOptions are the constructor options every brand accepts.
+type brandOptions struct {
+	Size int `json:"size"`
+}
 
-	return nil, fmt.Errorf("Wrong brand type passed")
+func decodeBrandOptions(o registry.Options) (brandOptions, error) {
+	opts := brandOptions{Size: 14}
+	err := o.Decode(&opts)
+	return opts, err
+}
+
+func getSportsFactory(brand string) (iSportsFactory, error) {
+	return sportsFactories.New(brand, nil)
 }
@@ -1,13 +1,27 @@
 package main
 
+import "patterns/registry"
+
 type nike struct {
+	size int
+}
+
+func init() {
+	sportsFactories.MustRegister(registry.Metadata{Name: "nike", Version: "1.0.0"},
+		func(o registry.Options) (iSportsFactory, error) {
+			opts, err := decodeBrandOptions(o)
+			if err != nil {
+				return nil, err
+			}
+			return &nike{size: opts.Size}, nil
+		})
 }
 
 func (n *nike) makeShoe() iShoe {
 	return &nikeShoe{
 		shoe: shoe{
 			logo: "nike",
-			size: 14,
+			size: n.size,
 		},
 	}
 }
@@ -16,7 +30,7 @@ func (n *nike) makeShirt() iShirt {
 	return &nikeShirt{
 		shirt: shirt{
 			logo: "nike",
-			size: 14,
+			size: n.size,
 		},
 	}
 }
@@ -0,0 +1,35 @@
+package main
+
+import "fmt"
+
+type usbCPort interface {
+	insertIntoUSBCPort()
+}
+
+// linux is a thin laptop with nothing but USB-C.
+type linux struct{}
+
+func (l *linux) insertIntoUSBCPort() {
+	fmt.Println("USB-C connector is plugged into linux machine.")
+}
+
+// usbCDongle gives a USB-C port a USB socket.
+type usbCDongle struct {
+	port usbCPort
+}
+
+func (d *usbCDongle) insertIntoUSBPort() {
+	fmt.Println("Dongle converts USB signal to USB-C.")
+	d.port.insertIntoUSBCPort()
+}
+
+type serialPort interface {
+	insertIntoSerialPort()
+}
+
+// terminal only has a serial port, and nobody makes an adapter for it.
+type terminal struct{}
+
+func (t *terminal) insertIntoSerialPort() {
+	fmt.Println("Serial connector is plugged into terminal.")
+}
@@ -1,5 +1,11 @@
 package main
 
+import (
+	"fmt"
+
+	"patterns/adapters"
+)
+
 func main() {
 
 	client := &client{}
@@ -13,4 +19,16 @@ func main() {
 	}
 
 	client.insertLightningConnectorIntoComputer(windowsMachineAdapter)
+
+	// The same, with the adapter chain found for us.
+	for _, machine := range []any{mac, windowsMachine, &linux{}, &terminal{}} {
+		fmt.Println()
+		com, path, err := adapters.Adapt[computer](ports, machine)
+		if err != nil {
+			fmt.Println("Client gives up:", err)
+			continue
+		}
+		fmt.Printf("Adapters for %T: %v\n", machine, path)
+		client.insertLightningConnectorIntoComputer(com)
+	}
 }
@@ -0,0 +1,16 @@
+package main
+
+import "patterns/adapters"
+
+// ports knows every adapter in the drawer. The client asks it for a
+// Lightning socket and gets whatever chain of adapters fits.
+var ports = adapters.New()
+
+func init() {
+	adapters.MustRegister(ports, "lightning-to-usb", 1, func(p usbPort) (computer, error) {
+		return &windowsAdapter{windowMachine: p}, nil
+	})
+	adapters.MustRegister(ports, "usb-to-usb-c", 1, func(p usbCPort) (usbPort, error) {
+		return &usbCDongle{port: p}, nil
+	})
+}
@@ -2,6 +2,10 @@ package main
 
 import "fmt"
 
+type usbPort interface {
+	insertIntoUSBPort()
+}
+
 type windows struct{}
 
 func (w *windows) insertIntoUSBPort() {
@@ -2,8 +2,9 @@ package main
 
 import "fmt"
 
+// windowsAdapter gives any USB port a Lightning socket.
 type windowsAdapter struct {
-	windowMachine *windows
+	windowMachine usbPort
 }
 
 func (w *windowsAdapter) insertIntoLightningPort() {
@@ -0,0 +1,292 @@
+// Package adapters finds and applies chains of adapters. Each adapter
+// converts a value of one type, usually an interface such as a port or a
+// protocol, into another; the registry treats them as weighted edges and
+// picks the cheapest chain with Dijkstra's algorithm, wrapping the value
+// one hop at a time.
+package adapters
+
+import (
+	"container/heap"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Hop is one adapter in a chain.
+type Hop struct {
+	Name     string
+	From, To reflect.Type
+	Cost     int
+
+	// In is the type of the value the hop was given: From itself or,
+	// more often, a concrete type assignable to it.
+	In reflect.Type
+}
+
+// Path is a chain of adapters, applied first to last.
+type Path []Hop
+
+// Cost is the total cost of the chain.
+func (p Path) Cost() int {
+	n := 0
+	for _, h := range p {
+		n += h.Cost
+	}
+	return n
+}
+
+func (p Path) String() string {
+	if len(p) == 0 {
+		return "(direct)"
+	}
+	in := p[0].In
+	if in == nil {
+		in = p[0].From
+	}
+	parts := []string{in.String()}
+	for _, h := range p {
+		parts = append(parts, fmt.Sprintf("[%s] %v", h.Name, h.To))
+	}
+	return strings.Join(parts, " -> ")
+}
+
+type edge struct {
+	Hop
+	order int // registration order, to break ties deterministically
+	wrap  func(any) (any, error)
+}
+
+// Registry holds adapters. The zero value is not usable; call New.
+type Registry struct {
+	mu    sync.RWMutex
+	edges []*edge
+	cache map[[2]reflect.Type]cached
+}
+
+type cached struct {
+	path []*edge
+	err  error
+}
+
+// New returns an empty registry.
+func New() *Registry {
+	return &Registry{cache: make(map[[2]reflect.Type]cached)}
+}
+
+// Register adds an adapter from From to To with the given cost, which
+// must be positive; give lossy or slow conversions a higher one. Names
+// must be unique.
+func Register[From, To any](r *Registry, name string, cost int, wrap func(From) (To, error)) error {
+	from, to := reflect.TypeFor[From](), reflect.TypeFor[To]()
+	if cost < 1 {
+		return fmt.Errorf("adapters: %s: cost %d, want at least 1", name, cost)
+	}
+	if from == to {
+		return fmt.Errorf("adapters: %s: adapts %v to itself", name, from)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.edges {
+		if e.Name == name {
+			return fmt.Errorf("adapters: %s registered twice", name)
+		}
+	}
+	r.edges = append(r.edges, &edge{
+		Hop:   Hop{Name: name, From: from, To: to, Cost: cost},
+		order: len(r.edges),
+		wrap: func(v any) (any, error) {
+			return wrap(v.(From))
+		},
+	})
+	clear(r.cache)
+	return nil
+}
+
+// MustRegister is Register for package initialisation.
+func MustRegister[From, To any](r *Registry, name string, cost int, wrap func(From) (To, error)) {
+	if err := Register(r, name, cost, wrap); err != nil {
+		panic(err)
+	}
+}
+
+// NoPathError reports that no chain of adapters leads from a value's type
+// to the requested one.
+type NoPathError struct {
+	From, To  reflect.Type
+	Reachable []reflect.Type // what From can be adapted to, sorted by name
+}
+
+func (e *NoPathError) Error() string {
+	msg := fmt.Sprintf("adapters: no adapter chain from %v to %v", e.From, e.To)
+	if len(e.Reachable) > 0 {
+		names := make([]string, len(e.Reachable))
+		for i, t := range e.Reachable {
+			names[i] = t.String()
+		}
+		msg += " (reachable: " + strings.Join(names, ", ") + ")"
+	}
+	return msg
+}
+
+// HopError reports an adapter that failed while a chain was applied.
+type HopError struct {
+	Hop Hop
+	Err error
+}
+
+func (e *HopError) Error() string {
+	return fmt.Sprintf("adapters: %s (%v -> %v): %v", e.Hop.Name, e.Hop.From, e.Hop.To, e.Err)
+}
+
+func (e *HopError) Unwrap() error { return e.Err }
+
+// Adapt converts v to To through the cheapest adapter chain. A v that
+// already is a To is returned as is with an empty path.
+func Adapt[To any](r *Registry, v any) (To, Path, error) {
+	var zero To
+	if t, ok := v.(To); ok {
+		return t, nil, nil
+	}
+	if v == nil {
+		return zero, nil, fmt.Errorf("adapters: cannot adapt nil to %v", reflect.TypeFor[To]())
+	}
+	edges, err := r.find(reflect.TypeOf(v), reflect.TypeFor[To]())
+	if err != nil {
+		return zero, nil, err
+	}
+	path := make(Path, len(edges))
+	for i, e := range edges {
+		path[i] = e.Hop
+		path[i].In = reflect.TypeOf(v)
+		if v, err = e.wrap(v); err == nil && v == nil {
+			err = fmt.Errorf("adapter returned nil")
+		}
+		if err != nil {
+			return zero, path[:i+1], &HopError{Hop: e.Hop, Err: err}
+		}
+	}
+	return v.(To), path, nil
+}
+
+// Find returns the cheapest chain from from to to without applying it.
+func (r *Registry) Find(from, to reflect.Type) (Path, error) {
+	if from.AssignableTo(to) {
+		return nil, nil
+	}
+	edges, err := r.find(from, to)
+	if err != nil {
+		return nil, err
+	}
+	path := make(Path, len(edges))
+	in := from
+	for i, e := range edges {
+		path[i] = e.Hop
+		path[i].In = in
+		in = e.To
+	}
+	return path, nil
+}
+
+func (r *Registry) find(from, to reflect.Type) ([]*edge, error) {
+	key := [2]reflect.Type{from, to}
+	r.mu.RLock()
+	c, ok := r.cache[key]
+	r.mu.RUnlock()
+	if ok {
+		return c.path, c.err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	path, err := r.dijkstra(from, to)
+	r.cache[key] = cached{path, err}
+	return path, err
+}
+
+// dijkstra searches over types. A value of type t can take any adapter
+// whose source t is assignable to, so a concrete type reaches the adapters
+// of every interface it implements. The caller holds r.mu.
+func (r *Registry) dijkstra(from, to reflect.Type) ([]*edge, error) {
+	type visit struct {
+		cost int
+		hops int
+		via  *edge
+		prev reflect.Type
+		done bool
+	}
+	best := map[reflect.Type]*visit{from: {}}
+	q := &typeQueue{{t: from}}
+	for q.Len() > 0 {
+		item := heap.Pop(q).(queued)
+		cur := best[item.t]
+		if cur.done || item.cost != cur.cost || item.hops != cur.hops {
+			continue // stale entry
+		}
+		cur.done = true
+		if item.t.AssignableTo(to) {
+			var path []*edge
+			for t := item.t; best[t].via != nil; t = best[t].prev {
+				path = append(path, best[t].via)
+			}
+			slices.Reverse(path)
+			return path, nil
+		}
+		for _, e := range r.edges {
+			if !item.t.AssignableTo(e.From) {
+				continue
+			}
+			next := visit{cost: cur.cost + e.Cost, hops: cur.hops + 1, via: e, prev: item.t}
+			old, seen := best[e.To]
+			if seen && (old.done || !better(next.cost, next.hops, e.order, old.cost, old.hops, old.via.order)) {
+				continue
+			}
+			best[e.To] = &next
+			heap.Push(q, queued{t: e.To, cost: next.cost, hops: next.hops, order: e.order})
+		}
+	}
+
+	var reachable []reflect.Type
+	for t, v := range best {
+		if t != from && v.done {
+			reachable = append(reachable, t)
+		}
+	}
+	slices.SortFunc(reachable, func(a, b reflect.Type) int { return strings.Compare(a.String(), b.String()) })
+	return nil, &NoPathError{From: from, To: to, Reachable: reachable}
+}
+
+// better orders candidate routes: cheaper first, then fewer hops, then the
+// adapter registered first.
+func better(cost, hops, order, oldCost, oldHops, oldOrder int) bool {
+	if cost != oldCost {
+		return cost < oldCost
+	}
+	if hops != oldHops {
+		return hops < oldHops
+	}
+	return order < oldOrder
+}
+
+type queued struct {
+	t     reflect.Type
+	cost  int
+	hops  int
+	order int
+}
+
+type typeQueue []queued
+
+func (q typeQueue) Len() int { return len(q) }
+func (q typeQueue) Less(i, j int) bool {
+	return better(q[i].cost, q[i].hops, q[i].order, q[j].cost, q[j].hops, q[j].order)
+}
+func (q typeQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *typeQueue) Push(x any)   { *q = append(*q, x.(queued)) }
+func (q *typeQueue) Pop() any {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,179 @@
+package adapters
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// A protocol stack: raw bytes, lines of text, JSON records.
+
+type lineSource interface{ Lines() []string }
+type recordSource interface{ Records() []map[string]any }
+type csvSource interface{ Rows() [][]string }
+
+type lines []string
+
+func (l lines) Lines() []string { return l }
+
+type records []map[string]any
+
+func (r records) Records() []map[string]any { return r }
+
+type rows [][]string
+
+func (r rows) Rows() [][]string { return r }
+
+func protocols(t *testing.T) *Registry {
+	t.Helper()
+	r := New()
+	MustRegister(r, "split-lines", 1, func(rd io.Reader) (lineSource, error) {
+		var out lines
+		sc := bufio.NewScanner(rd)
+		for sc.Scan() {
+			out = append(out, sc.Text())
+		}
+		return out, sc.Err()
+	})
+	MustRegister(r, "ndjson", 1, func(src lineSource) (recordSource, error) {
+		var out records
+		for i, l := range src.Lines() {
+			var rec map[string]any
+			if err := json.Unmarshal([]byte(l), &rec); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			out = append(out, rec)
+		}
+		return out, nil
+	})
+	MustRegister(r, "csv-naive", 1, func(src lineSource) (csvSource, error) {
+		var out rows
+		for _, l := range src.Lines() {
+			out = append(out, strings.Split(l, ","))
+		}
+		return out, nil
+	})
+	return r
+}
+
+func TestMultiHop(t *testing.T) {
+	r := protocols(t)
+	got, path, err := Adapt[recordSource](r, strings.NewReader("{\"a\":1}\n{\"a\":2}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Records()) != 2 || got.Records()[1]["a"] != 2.0 {
+		t.Errorf("records %v", got.Records())
+	}
+	if len(path) != 2 || path[0].Name != "split-lines" || path[1].Name != "ndjson" || path.Cost() != 2 {
+		t.Errorf("path %v", path)
+	}
+	if want := "*strings.Reader -> [split-lines] adapters.lineSource -> [ndjson] adapters.recordSource"; path.String() != want {
+		t.Errorf("path string %q", path)
+	}
+	found, err := r.Find(reflect.TypeFor[*bytes.Buffer](), reflect.TypeFor[recordSource]())
+	if want := "*bytes.Buffer -> [split-lines] adapters.lineSource -> [ndjson] adapters.recordSource"; err != nil || found.String() != want {
+		t.Errorf("found %q, %v", found, err)
+	}
+}
+
+func TestDirect(t *testing.T) {
+	r := protocols(t)
+	l := lines{"x"}
+	got, path, err := Adapt[lineSource](r, l)
+	if err != nil || len(path) != 0 || !reflect.DeepEqual(got, lineSource(l)) {
+		t.Errorf("got %v %v %v", got, path, err)
+	}
+}
+
+func TestCheapestWins(t *testing.T) {
+	r := protocols(t)
+	// A direct but expensive conversion loses to the two-hop route.
+	MustRegister(r, "slurp-json", 5, func(rd io.Reader) (recordSource, error) {
+		return records{{"slurped": true}}, nil
+	})
+	_, path, _ := Adapt[recordSource](r, bytes.NewBufferString("{}"))
+	if len(path) != 2 {
+		t.Errorf("path %v", path)
+	}
+	// Equal cost prefers fewer hops.
+	MustRegister(r, "fast-json", 2, func(rd io.Reader) (recordSource, error) {
+		return records{{"fast": true}}, nil
+	})
+	got, path, _ := Adapt[recordSource](r, bytes.NewBufferString("{}"))
+	if len(path) != 1 || path[0].Name != "fast-json" || got.Records()[0]["fast"] != true {
+		t.Errorf("path %v", path)
+	}
+}
+
+func TestNoPath(t *testing.T) {
+	r := protocols(t)
+	_, _, err := Adapt[recordSource](r, 42)
+	var np *NoPathError
+	if !errors.As(err, &np) || np.From != reflect.TypeFor[int]() {
+		t.Fatalf("err = %v", err)
+	}
+
+	// From a reader, CSV is reachable but nothing leads back to a reader.
+	_, err = r.Find(reflect.TypeFor[*strings.Reader](), reflect.TypeFor[io.WriterTo]())
+	if err != nil {
+		t.Errorf("strings.Reader already is an io.WriterTo: %v", err)
+	}
+	_, err = r.Find(reflect.TypeFor[lines](), reflect.TypeFor[io.Reader]())
+	if !errors.As(err, &np) || len(np.Reachable) != 2 {
+		t.Errorf("err = %v", err)
+	}
+	if !strings.Contains(err.Error(), "reachable: adapters.csvSource, adapters.recordSource") {
+		t.Errorf("message %q", err)
+	}
+}
+
+func TestHopError(t *testing.T) {
+	r := protocols(t)
+	_, path, err := Adapt[recordSource](r, strings.NewReader("{}\nnot json\n"))
+	var he *HopError
+	if !errors.As(err, &he) || he.Hop.Name != "ndjson" || len(path) != 2 {
+		t.Errorf("err = %v, path %v", err, path)
+	}
+	var syntax *json.SyntaxError
+	if !errors.As(err, &syntax) {
+		t.Error("cause not wrapped")
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	r := New()
+	ok := func(lines) (lineSource, error) { return nil, nil }
+	if err := Register(r, "x", 0, ok); err == nil {
+		t.Error("zero cost accepted")
+	}
+	if err := Register(r, "x", 1, ok); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(r, "x", 1, ok); err == nil {
+		t.Error("duplicate name accepted")
+	}
+	if err := Register(r, "self", 1, func(l lineSource) (lineSource, error) { return l, nil }); err == nil {
+		t.Error("self adapter accepted")
+	}
+	if _, _, err := Adapt[recordSource](r, nil); err == nil {
+		t.Error("nil adapted")
+	}
+}
+
+func TestCacheInvalidated(t *testing.T) {
+	r := New()
+	if _, err := r.Find(reflect.TypeFor[lines](), reflect.TypeFor[csvSource]()); err == nil {
+		t.Fatal("found a path in an empty registry")
+	}
+	MustRegister(r, "csv", 1, func(l lineSource) (csvSource, error) { return rows{}, nil })
+	if _, err := r.Find(reflect.TypeFor[lines](), reflect.TypeFor[csvSource]()); err != nil {
+		t.Errorf("stale cache: %v", err)
+	}
+}
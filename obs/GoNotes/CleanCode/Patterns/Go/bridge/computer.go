@@ -1,6 +1,13 @@
 package main
 
+import "errors"
+
+var errNoPrinter = errors.New("no printer selected")
+
+// computer is the abstraction side of the bridge: it prepares documents
+// the way its platform stores them and hands them to whatever printer is
+// selected, without knowing how that printer renders.
 type computer interface {
-	print()
+	print(doc document, prio priority) (*printJob, error)
 	setPrinter(printer)
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// format is a page description language a driver can emit.
+type format int
+
+const (
+	formatText format = iota
+	formatPostScript
+	formatPDF
+)
+
+func (f format) String() string {
+	switch f {
+	case formatText:
+		return "text"
+	case formatPostScript:
+		return "postscript"
+	case formatPDF:
+		return "pdf"
+	}
+	return fmt.Sprintf("format(%d)", int(f))
+}
+
+// ext is the file extension used for spooled output in this format.
+func (f format) ext() string {
+	switch f {
+	case formatPostScript:
+		return ".ps"
+	case formatPDF:
+		return ".pdf"
+	}
+	return ".txt"
+}
+
+var errEmptyDocument = errors.New("document has no content")
+
+// document is what a computer hands to the spooler.
+type document struct {
+	title  string
+	body   string
+	copies int // 1 if zero
+}
+
+func (d document) lines() []string {
+	return strings.Split(strings.TrimRight(d.body, "\n"), "\n")
+}
+
+func (d document) validate() error {
+	if strings.TrimSpace(d.body) == "" {
+		return errEmptyDocument
+	}
+	if d.copies < 0 {
+		return fmt.Errorf("document %q: %d copies", d.title, d.copies)
+	}
+	return nil
+}
+
+func (d document) copyCount() int {
+	if d.copies == 0 {
+		return 1
+	}
+	return d.copies
+}
@@ -1,10 +1,18 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"io"
+)
 
+// epson drives a PostScript laser printer.
 type epson struct {
+	model string
 }
 
-func (p *epson) printFile() {
-	fmt.Println("Printing by a EPSON Printer")
+func (p *epson) name() string   { return "epson-" + p.model }
+func (p *epson) format() format { return formatPostScript }
+
+func (p *epson) printFile(ctx context.Context, doc document, w io.Writer) error {
+	return renderPostScript(ctx, doc, w)
 }
@@ -1,10 +1,18 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"io"
+)
 
+// hp drives a printer that accepts PDF directly.
 type hp struct {
+	model string
 }
 
-func (p *hp) printFile() {
-	fmt.Println("Printing by a HP Printer")
+func (p *hp) name() string   { return "hp-" + p.model }
+func (p *hp) format() format { return formatPDF }
+
+func (p *hp) printFile(ctx context.Context, doc document, w io.Writer) error {
+	return renderPDF(ctx, doc, w)
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// maxDocumentSize caps a submitted document body.
+const maxDocumentSize = 1 << 20
+
+// ippHandler exposes the spooler over HTTP in the spirit of IPP:
+//
+//	GET    /printers                 installed printers and queue lengths
+//	POST   /printers/{name}/jobs     submit the request body as a document
+//	GET    /printers/{name}/jobs     the printer's queue
+//	POST   /printers/{name}/pause    stop starting new jobs
+//	POST   /printers/{name}/resume   start again
+//	GET    /jobs/{id}                job status
+//	DELETE /jobs/{id}                cancel a job
+//	GET    /jobs/{id}/document       the rendered output of a completed job
+//
+// Submissions take title, copies, priority and requesting-user-name from
+// the query string.
+func ippHandler(s *spooler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /printers", func(w http.ResponseWriter, r *http.Request) {
+		type printerInfo struct {
+			Name   string `json:"name"`
+			Format string `json:"format"`
+			Queued int    `json:"queued"`
+		}
+		var out []printerInfo
+		for _, name := range s.printers() {
+			p, _ := s.driver(name)
+			jobs, _ := s.queue(name)
+			out = append(out, printerInfo{Name: name, Format: p.format().String(), Queued: len(jobs)})
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+	mux.HandleFunc("POST /printers/{name}/jobs", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		prio, err := parsePriority(q.Get("priority"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		doc := document{title: q.Get("title")}
+		if doc.title == "" {
+			doc.title = "untitled"
+		}
+		if c := q.Get("copies"); c != "" {
+			if doc.copies, err = strconv.Atoi(c); err != nil || doc.copies < 1 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("bad copies %q", c))
+				return
+			}
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDocumentSize))
+		if err != nil {
+			status := http.StatusBadRequest
+			var tooBig *http.MaxBytesError
+			if errors.As(err, &tooBig) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			writeError(w, status, err)
+			return
+		}
+		doc.body = string(body)
+		j, err := s.submit(r.PathValue("name"), q.Get("requesting-user-name"), doc, prio)
+		if err != nil {
+			writeError(w, errorStatus(err), err)
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/jobs/%d", j.id))
+		writeJSON(w, http.StatusCreated, s.status(j))
+	})
+	mux.HandleFunc("GET /printers/{name}/jobs", func(w http.ResponseWriter, r *http.Request) {
+		jobs, err := s.queue(r.PathValue("name"))
+		if err != nil {
+			writeError(w, errorStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, jobs)
+	})
+	for action, f := range map[string]func(string) error{"pause": s.pause, "resume": s.resume} {
+		mux.HandleFunc("POST /printers/{name}/"+action, func(w http.ResponseWriter, r *http.Request) {
+			if err := f(r.PathValue("name")); err != nil {
+				writeError(w, errorStatus(err), err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	mux.HandleFunc("GET /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		j, err := lookupJob(s, r)
+		if err != nil {
+			writeError(w, errorStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.status(j))
+	})
+	mux.HandleFunc("DELETE /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		j, err := lookupJob(s, r)
+		if err == nil {
+			err = s.cancel(j.id)
+		}
+		if err != nil {
+			writeError(w, errorStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, s.status(j))
+	})
+	mux.HandleFunc("GET /jobs/{id}/document", func(w http.ResponseWriter, r *http.Request) {
+		j, err := lookupJob(s, r)
+		if err != nil {
+			writeError(w, errorStatus(err), err)
+			return
+		}
+		st := s.status(j)
+		if st.Output == "" {
+			writeError(w, http.StatusConflict, fmt.Errorf("job %d is %s", j.id, st.State))
+			return
+		}
+		http.ServeFile(w, r, st.Output)
+	})
+	return mux
+}
+
+func lookupJob(s *spooler, r *http.Request) (*printJob, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", errUnknownJob, r.PathValue("id"))
+	}
+	return s.job(id)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errUnknownPrinter), errors.Is(err, errUnknownJob):
+		return http.StatusNotFound
+	case errors.Is(err, errJobFinished):
+		return http.StatusConflict
+	case errors.Is(err, errSpoolerClosed):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
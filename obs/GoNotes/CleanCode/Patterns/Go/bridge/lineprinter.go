@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+	"io"
+)
+
+// linePrinter drives a dot-matrix or receipt printer fed plain text.
+type linePrinter struct {
+	device string
+}
+
+func (p *linePrinter) name() string   { return "lp-" + p.device }
+func (p *linePrinter) format() format { return formatText }
+
+func (p *linePrinter) printFile(ctx context.Context, doc document, w io.Writer) error {
+	return renderText(ctx, doc, w)
+}
@@ -1,14 +1,20 @@
 package main
 
-import "fmt"
+import "strings"
 
 type mac struct {
+	spool   *spooler
+	user    string
 	printer printer
 }
 
-func (m *mac) print() {
-	fmt.Println("Print request for mac")
-	m.printer.printFile()
+// print submits doc, converting classic Mac line endings on the way.
+func (m *mac) print(doc document, prio priority) (*printJob, error) {
+	if m.printer == nil {
+		return nil, errNoPrinter
+	}
+	doc.body = strings.ReplaceAll(doc.body, "\r", "\n")
+	return m.spool.submit(m.printer.name(), m.user+"@mac", doc, prio)
 }
 
 func (m *mac) setPrinter(p printer) {
@@ -1,29 +1,127 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const memo = "Quarterly report\nRevenue: up\nCosts: (mostly) down\n"
 
 func main() {
+	dir, err := os.MkdirTemp("", "spool-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	spool, err := newSpooler(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer spool.close()
 
-	hpPrinter := &hp{}
-	epsonPrinter := &epson{}
+	hpPrinter := &hp{model: "laserjet"}
+	epsonPrinter := &epson{model: "workforce"}
+	lp := &linePrinter{device: "lpt1"}
+	for _, p := range []printer{hpPrinter, epsonPrinter, lp} {
+		if err := spool.addPrinter(p); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	macComputer := &mac{}
+	macComputer := &mac{spool: spool, user: "alice"}
+	winComputer := &windows{spool: spool, user: "bob"}
 
-	macComputer.setPrinter(hpPrinter)
-	macComputer.print()
-	fmt.Println()
+	if _, err := macComputer.print(document{title: "memo", body: memo}, normalPriority); err != nil {
+		fmt.Println("Print request for mac:", err)
+	}
 
-	macComputer.setPrinter(epsonPrinter)
-	macComputer.print()
+	ctx := context.Background()
+	for _, c := range []struct {
+		name     string
+		computer computer
+		printer  printer
+		doc      document
+	}{
+		{"mac", macComputer, hpPrinter, document{title: "memo", body: memo}},
+		{"mac", macComputer, epsonPrinter, document{title: "memo", body: strings.ReplaceAll(memo, "\n", "\r"), copies: 2}},
+		{"windows", winComputer, hpPrinter, document{title: "invoice", body: "Total: 42\r\n"}},
+		{"windows", winComputer, lp, document{title: "receipt", body: "1 x coffee\r\n1 x bagel\r\n"}},
+	} {
+		c.computer.setPrinter(c.printer)
+		j, err := c.computer.print(c.doc, normalPriority)
+		if err != nil {
+			log.Fatal(err)
+		}
+		st, err := spool.wait(ctx, j)
+		if err != nil {
+			log.Fatal(err)
+		}
+		out, err := os.ReadFile(st.Output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Print request for %s: job %d on %s by %s is %s -> %s (%s)\n",
+			c.name, st.ID, st.Printer, st.Owner, st.State, filepath.Base(st.Output), firstLine(out))
+	}
 	fmt.Println()
 
-	winComputer := &windows{}
+	// The same spooler over HTTP. The line printer is paused so the queue
+	// can be inspected and one job cancelled before anything prints.
+	srv := httptest.NewServer(ippHandler(spool))
+	defer srv.Close()
+	call := func(method, path, body string) {
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var v any
+		json.NewDecoder(resp.Body).Decode(&v)
+		fmt.Printf("%s %s -> %d %s\n", method, path, resp.StatusCode, summarize(v))
+	}
+	call("POST", "/printers/lp-lpt1/pause", "")
+	call("POST", "/printers/lp-lpt1/jobs?title=draft&requesting-user-name=carol&priority=low", "draft\n")
+	call("POST", "/printers/lp-lpt1/jobs?title=urgent&requesting-user-name=carol&priority=high", "urgent\n")
+	call("GET", "/printers/lp-lpt1/jobs", "")
+	call("DELETE", "/jobs/5", "")
+	call("POST", "/printers/lp-lpt1/resume", "")
+	if j, err := spool.job(6); err == nil {
+		spool.wait(ctx, j)
+	}
+	call("GET", "/jobs/6", "")
+	call("DELETE", "/jobs/6", "")
+	call("POST", "/printers/nope/jobs", "hello")
+	call("POST", "/printers/hp-laserjet/jobs?priority=urgent", "hello")
+	call("POST", "/printers/hp-laserjet/jobs", "   ")
+}
 
-	winComputer.setPrinter(hpPrinter)
-	winComputer.print()
-	fmt.Println()
+func firstLine(b []byte) string {
+	line, _, _ := strings.Cut(string(b), "\n")
+	return line
+}
 
-	winComputer.setPrinter(epsonPrinter)
-	winComputer.print()
-	fmt.Println()
+// summarize keeps the demo output stable by dropping timestamps and paths.
+func summarize(v any) string {
+	switch v := v.(type) {
+	case map[string]any:
+		if _, ok := v["id"]; !ok {
+			return fmt.Sprint("error: ", v["error"])
+		}
+		return fmt.Sprintf("job %v %q %v %v", v["id"], v["title"], v["priority"], v["state"])
+	case []any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = summarize(e)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	return ""
 }
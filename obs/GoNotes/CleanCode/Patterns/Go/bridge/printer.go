@@ -1,5 +1,14 @@
 package main
 
+import (
+	"context"
+	"io"
+)
+
+// printer is the implementation side of the bridge: a device driver that
+// turns a document into the page description language its hardware speaks.
 type printer interface {
-	printFile()
+	name() string
+	format() format
+	printFile(ctx context.Context, doc document, w io.Writer) error
 }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page geometry shared by the PostScript and PDF renderers, in points.
+const (
+	pageWidth    = 612 // US Letter
+	pageHeight   = 792
+	margin       = 72
+	fontSize     = 11
+	leading      = 14
+	linesPerPage = (pageHeight - 2*margin) / leading
+)
+
+// paginate splits the document into pages of at most linesPerPage lines,
+// repeating the whole run once per copy.
+func paginate(doc document) [][]string {
+	lines := doc.lines()
+	var pages [][]string
+	for range doc.copyCount() {
+		for start := 0; start < len(lines); start += linesPerPage {
+			pages = append(pages, lines[start:min(start+linesPerPage, len(lines))])
+		}
+	}
+	return pages
+}
+
+// renderText writes pages separated by form feeds, with the title on top.
+func renderText(ctx context.Context, doc document, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for i, page := range paginate(doc) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if i > 0 {
+			bw.WriteString("\f")
+		}
+		fmt.Fprintf(bw, "%s — page %d\n\n", doc.title, i+1)
+		for _, line := range page {
+			bw.WriteString(line)
+			bw.WriteString("\n")
+		}
+	}
+	return bw.Flush()
+}
+
+// psEscape quotes a string for use inside a PostScript (...) literal. PDF
+// string literals use the same rules.
+func psEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(s)
+}
+
+// renderPostScript writes a DSC-conforming PostScript program.
+func renderPostScript(ctx context.Context, doc document, w io.Writer) error {
+	pages := paginate(doc)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%%!PS-Adobe-3.0\n%%%%Title: %s\n%%%%Pages: %d\n%%%%BoundingBox: 0 0 %d %d\n%%%%EndComments\n",
+		doc.title, len(pages), pageWidth, pageHeight)
+	fmt.Fprintf(bw, "/Courier findfont %d scalefont setfont\n", fontSize)
+	for i, page := range pages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "%%%%Page: %d %d\n", i+1, i+1)
+		y := pageHeight - margin
+		for _, line := range page {
+			fmt.Fprintf(bw, "%d %d moveto (%s) show\n", margin, y, psEscape(line))
+			y -= leading
+		}
+		bw.WriteString("showpage\n")
+	}
+	bw.WriteString("%%EOF\n")
+	return bw.Flush()
+}
+
+// renderPDF writes a minimal PDF 1.4 file: a catalog, a page tree, one
+// content stream per page and a shared Courier font, with a correct xref
+// table so readers need not repair it.
+func renderPDF(ctx context.Context, doc document, w io.Writer) error {
+	pages := paginate(doc)
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	var offsets []int64 // offsets[i] is where object i+1 starts
+
+	object := func(body string) {
+		offsets = append(offsets, cw.n)
+		fmt.Fprintf(cw, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1-3 are fixed; each page then takes two: the page and its
+	// content stream.
+	fmt.Fprint(cw, "%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>")
+	for i, page := range pages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 5+2*i))
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", fontSize, leading, margin, pageHeight-margin)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) '\n", psEscape(line))
+		}
+		content.WriteString("ET")
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xref := cw.n
+	fmt.Fprintf(cw, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(cw, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(cw, "trailer\n<< /Size %d /Root 1 0 R /Info << /Title (%s) >> >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, psEscape(doc.title), xref)
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.w.Flush()
+}
+
+// countingWriter tracks the byte offset for the PDF xref table and keeps
+// the first write error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	errUnknownPrinter = errors.New("spooler: unknown printer")
+	errUnknownJob     = errors.New("spooler: unknown job")
+	errJobFinished    = errors.New("spooler: job already finished")
+	errSpoolerClosed  = errors.New("spooler: closed")
+)
+
+type priority int
+
+const (
+	lowPriority priority = iota
+	normalPriority
+	highPriority
+)
+
+func (p priority) String() string {
+	switch p {
+	case lowPriority:
+		return "low"
+	case normalPriority:
+		return "normal"
+	case highPriority:
+		return "high"
+	}
+	return fmt.Sprintf("priority(%d)", int(p))
+}
+
+func parsePriority(s string) (priority, error) {
+	for p := lowPriority; p <= highPriority; p++ {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	if s == "" {
+		return normalPriority, nil
+	}
+	return 0, fmt.Errorf("unknown priority %q", s)
+}
+
+type jobState int
+
+const (
+	jobPending jobState = iota
+	jobPrinting
+	jobCompleted
+	jobCancelled
+	jobFailed
+)
+
+func (s jobState) String() string {
+	return [...]string{"pending", "printing", "completed", "cancelled", "failed"}[s]
+}
+
+func (s jobState) finished() bool { return s >= jobCompleted }
+
+// printJob is one document queued on one printer. Everything but done is
+// guarded by the spooler's mutex; use status for a consistent snapshot.
+type printJob struct {
+	id        int
+	printer   string
+	owner     string
+	doc       document
+	priority  priority
+	submitted time.Time
+
+	state    jobState
+	err      error
+	path     string // spooled output, set once completed
+	finished time.Time
+	index    int // position in the queue, -1 once dequeued
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// jobStatus is a point-in-time view of a job, shaped for JSON.
+type jobStatus struct {
+	ID        int       `json:"id"`
+	Printer   string    `json:"printer"`
+	Owner     string    `json:"owner,omitempty"`
+	Title     string    `json:"title"`
+	Priority  string    `json:"priority"`
+	State     string    `json:"state"`
+	Error     string    `json:"error,omitempty"`
+	Output    string    `json:"output,omitempty"`
+	Submitted time.Time `json:"submitted"`
+	Finished  time.Time `json:"finished,omitzero"`
+}
+
+type jobHeap []*printJob
+
+func (h jobHeap) Len() int { return len(h) }
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].id < h[j].id
+}
+func (h jobHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *jobHeap) Push(x any) {
+	j := x.(*printJob)
+	j.index = len(*h)
+	*h = append(*h, j)
+}
+func (h *jobHeap) Pop() any {
+	old := *h
+	j := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	j.index = -1
+	return j
+}
+
+// printQueue feeds one printer, one job at a time.
+type printQueue struct {
+	driver  printer
+	pending jobHeap
+	ready   *sync.Cond
+	current *printJob
+	paused  bool
+}
+
+// maxFinishedJobs is how many finished jobs the spooler remembers; older
+// ones are forgotten, though their output stays in the spool directory.
+const maxFinishedJobs = 1000
+
+// spooler owns a queue and a worker per printer and renders finished jobs
+// into dir as <id>-<printer>.<ext>.
+type spooler struct {
+	dir string
+
+	mu       sync.Mutex
+	queues   map[string]*printQueue
+	jobs     map[int]*printJob
+	finished []int // ids of finished jobs still in jobs, oldest first
+	keep     int   // the most finished jobs to remember
+	nextID   int
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+func newSpooler(dir string) (*spooler, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("spooler: %w", err)
+	}
+	return &spooler{
+		dir:    dir,
+		queues: make(map[string]*printQueue),
+		jobs:   make(map[int]*printJob),
+		keep:   maxFinishedJobs,
+	}, nil
+}
+
+// addPrinter installs a driver and starts its queue.
+func (s *spooler) addPrinter(p printer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errSpoolerClosed
+	}
+	if _, ok := s.queues[p.name()]; ok {
+		return fmt.Errorf("spooler: printer %q already installed", p.name())
+	}
+	q := &printQueue{driver: p, ready: sync.NewCond(&s.mu)}
+	s.queues[p.name()] = q
+	s.wg.Add(1)
+	go s.work(q)
+	return nil
+}
+
+// printers returns the installed printer names in order.
+func (s *spooler) printers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.queues))
+	for name := range s.queues {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// driver returns the named printer.
+func (s *spooler) driver(name string) (printer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queues[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errUnknownPrinter, name)
+	}
+	return q.driver, nil
+}
+
+// pause stops a printer from starting new jobs; the one printing finishes.
+func (s *spooler) pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+// resume lets a paused printer pick up its queue again.
+func (s *spooler) resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+func (s *spooler) setPaused(name string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queues[name]
+	if !ok {
+		return fmt.Errorf("%w %q", errUnknownPrinter, name)
+	}
+	q.paused = paused
+	q.ready.Signal()
+	return nil
+}
+
+// submit queues doc on the named printer.
+func (s *spooler) submit(printerName, owner string, doc document, prio priority) (*printJob, error) {
+	if err := doc.validate(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errSpoolerClosed
+	}
+	q, ok := s.queues[printerName]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errUnknownPrinter, printerName)
+	}
+	s.nextID++
+	j := &printJob{
+		id:        s.nextID,
+		printer:   printerName,
+		owner:     owner,
+		doc:       doc,
+		priority:  prio,
+		submitted: time.Now(),
+		done:      make(chan struct{}),
+	}
+	s.jobs[j.id] = j
+	heap.Push(&q.pending, j)
+	q.ready.Signal()
+	return j, nil
+}
+
+// cancel drops a pending job or interrupts one that is printing.
+func (s *spooler) cancel(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return fmt.Errorf("%w %d", errUnknownJob, id)
+	}
+	switch {
+	case j.state.finished():
+		return fmt.Errorf("%w: job %d is %s", errJobFinished, id, j.state)
+	case j.state == jobPrinting:
+		j.cancel() // the worker records the outcome
+	default:
+		heap.Remove(&s.queues[j.printer].pending, j.index)
+		s.finish(j, jobCancelled, context.Canceled)
+	}
+	return nil
+}
+
+// wait blocks until the job finishes or ctx is done.
+func (s *spooler) wait(ctx context.Context, j *printJob) (jobStatus, error) {
+	select {
+	case <-j.done:
+		return s.status(j), nil
+	case <-ctx.Done():
+		return s.status(j), ctx.Err()
+	}
+}
+
+// job looks a job up by id.
+func (s *spooler) job(id int) (*printJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %d", errUnknownJob, id)
+	}
+	return j, nil
+}
+
+func (s *spooler) status(j *printJob) jobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return j.statusLocked()
+}
+
+func (j *printJob) statusLocked() jobStatus {
+	st := jobStatus{
+		ID:        j.id,
+		Printer:   j.printer,
+		Owner:     j.owner,
+		Title:     j.doc.title,
+		Priority:  j.priority.String(),
+		State:     j.state.String(),
+		Output:    j.path,
+		Submitted: j.submitted,
+		Finished:  j.finished,
+	}
+	if j.err != nil {
+		st.Error = j.err.Error()
+	}
+	return st
+}
+
+// queue lists a printer's jobs: the one printing first, then pending jobs
+// in the order they will print.
+func (s *spooler) queue(printerName string) ([]jobStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queues[printerName]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errUnknownPrinter, printerName)
+	}
+	var out []jobStatus
+	if q.current != nil {
+		out = append(out, q.current.statusLocked())
+	}
+	pending := slices.Clone(q.pending)
+	slices.SortFunc(pending, func(a, b *printJob) int {
+		if jobHeap([]*printJob{a, b}).Less(0, 1) {
+			return -1
+		}
+		return 1
+	})
+	for _, j := range pending {
+		out = append(out, j.statusLocked())
+	}
+	return out, nil
+}
+
+// close cancels everything still pending, waits for jobs in progress and
+// stops the workers.
+func (s *spooler) close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	for _, q := range s.queues {
+		for _, j := range q.pending {
+			s.finish(j, jobCancelled, errSpoolerClosed)
+		}
+		q.pending = nil
+		q.ready.Broadcast()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// finish records a final state, and forgets the oldest finished job if
+// there are too many. The caller holds s.mu.
+func (s *spooler) finish(j *printJob, state jobState, err error) {
+	j.state, j.err, j.finished = state, err, time.Now()
+	close(j.done)
+	s.finished = append(s.finished, j.id)
+	for len(s.finished) > s.keep {
+		delete(s.jobs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+func (s *spooler) work(q *printQueue) {
+	defer s.wg.Done()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		for (len(q.pending) == 0 || q.paused) && !s.closed {
+			q.ready.Wait()
+		}
+		if s.closed {
+			return
+		}
+		j := heap.Pop(&q.pending).(*printJob)
+		ctx, cancel := context.WithCancel(context.Background())
+		j.state, j.cancel, q.current = jobPrinting, cancel, j
+		s.mu.Unlock()
+
+		path, err := s.render(ctx, q.driver, j)
+		cancel()
+
+		s.mu.Lock()
+		q.current = nil
+		switch {
+		case errors.Is(err, context.Canceled):
+			s.finish(j, jobCancelled, err)
+		case err != nil:
+			s.finish(j, jobFailed, err)
+		default:
+			j.path = path
+			s.finish(j, jobCompleted, nil)
+		}
+	}
+}
+
+// render drives the printer into a temporary file and renames it into
+// place, so a spooled file is always complete.
+func (s *spooler) render(ctx context.Context, p printer, j *printJob) (string, error) {
+	tmp, err := os.CreateTemp(s.dir, ".job-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+	err = p.printFile(ctx, j.doc, tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", p.name(), err)
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("%d-%s%s", j.id, p.name(), p.format().ext()))
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// fakePrinter records the order documents reach it and can block until
+// told to continue or cancelled.
+type fakePrinter struct {
+	mu      sync.Mutex
+	printed []string
+	started chan string   // receives a title as each job starts, if set
+	release chan struct{} // printing waits on this, if set
+}
+
+func (p *fakePrinter) name() string   { return "fake" }
+func (p *fakePrinter) format() format { return formatText }
+
+func (p *fakePrinter) printFile(ctx context.Context, doc document, w io.Writer) error {
+	if p.started != nil {
+		p.started <- doc.title
+	}
+	if p.release != nil {
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	p.mu.Lock()
+	p.printed = append(p.printed, doc.title)
+	p.mu.Unlock()
+	return renderText(ctx, doc, w)
+}
+
+func newTestSpooler(t *testing.T, printers ...printer) *spooler {
+	t.Helper()
+	s, err := newSpooler(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.close)
+	for _, p := range printers {
+		if err := s.addPrinter(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func waitAll(t *testing.T, s *spooler, jobs ...*printJob) []jobStatus {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var out []jobStatus
+	for _, j := range jobs {
+		st, err := s.wait(ctx, j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+func TestPriorityOrder(t *testing.T) {
+	p := &fakePrinter{}
+	s := newTestSpooler(t, p)
+	s.pause("fake")
+	var jobs []*printJob
+	for _, sub := range []struct {
+		title string
+		prio  priority
+	}{{"low", lowPriority}, {"normal1", normalPriority}, {"high", highPriority}, {"normal2", normalPriority}} {
+		j, err := s.submit("fake", "", document{title: sub.title, body: "x"}, sub.prio)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, j)
+	}
+	s.resume("fake")
+	waitAll(t, s, jobs...)
+	want := []string{"high", "normal1", "normal2", "low"}
+	if fmt.Sprint(p.printed) != fmt.Sprint(want) {
+		t.Errorf("printed %v, want %v", p.printed, want)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	p := &fakePrinter{started: make(chan string, 1), release: make(chan struct{})}
+	s := newTestSpooler(t, p)
+	running, _ := s.submit("fake", "", document{title: "running", body: "x"}, normalPriority)
+	<-p.started
+	queued, _ := s.submit("fake", "", document{title: "queued", body: "x"}, normalPriority)
+
+	if err := s.cancel(queued.id); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.cancel(running.id); err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range waitAll(t, s, running, queued) {
+		if st.State != "cancelled" || st.Output != "" {
+			t.Errorf("job %d: %s, output %q", st.ID, st.State, st.Output)
+		}
+	}
+	if len(p.printed) != 0 {
+		t.Errorf("printed %v after cancel", p.printed)
+	}
+	if err := s.cancel(running.id); !errors.Is(err, errJobFinished) {
+		t.Errorf("second cancel = %v", err)
+	}
+	if err := s.cancel(99); !errors.Is(err, errUnknownJob) {
+		t.Errorf("cancel unknown = %v", err)
+	}
+	// Nothing but finished output is left in the spool directory.
+	entries, _ := os.ReadDir(s.dir)
+	if len(entries) != 0 {
+		t.Errorf("spool dir has %d entries", len(entries))
+	}
+}
+
+func TestCloseCancelsPending(t *testing.T) {
+	p := &fakePrinter{}
+	s := newTestSpooler(t, p)
+	s.pause("fake")
+	j, _ := s.submit("fake", "", document{title: "t", body: "x"}, normalPriority)
+	s.close()
+	if st := waitAll(t, s, j)[0]; st.State != "cancelled" {
+		t.Errorf("pending job %s after close", st.State)
+	}
+	if _, err := s.submit("fake", "", document{title: "t", body: "x"}, normalPriority); !errors.Is(err, errSpoolerClosed) {
+		t.Errorf("submit after close = %v", err)
+	}
+}
+
+func TestSubmitErrors(t *testing.T) {
+	s := newTestSpooler(t, &fakePrinter{})
+	if _, err := s.submit("nope", "", document{body: "x"}, normalPriority); !errors.Is(err, errUnknownPrinter) {
+		t.Errorf("unknown printer: %v", err)
+	}
+	if _, err := s.submit("fake", "", document{body: "\n"}, normalPriority); !errors.Is(err, errEmptyDocument) {
+		t.Errorf("empty document: %v", err)
+	}
+	if err := s.addPrinter(&fakePrinter{}); err == nil {
+		t.Error("duplicate printer accepted")
+	}
+	if _, err := (&mac{spool: s}).print(document{body: "x"}, normalPriority); !errors.Is(err, errNoPrinter) {
+		t.Errorf("mac without printer: %v", err)
+	}
+}
+
+func TestDrivers(t *testing.T) {
+	var body strings.Builder
+	for i := range linesPerPage + 5 {
+		fmt.Fprintf(&body, "line %d (with parens) \\ backslash\n", i)
+	}
+	doc := document{title: "report", body: body.String(), copies: 2}
+	ctx := context.Background()
+
+	var txt bytes.Buffer
+	if err := (&linePrinter{}).printFile(ctx, doc, &txt); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(txt.String(), "\f"); n != 3 {
+		t.Errorf("text has %d form feeds, want 3", n)
+	}
+
+	var ps bytes.Buffer
+	if err := (&epson{}).printFile(ctx, doc, &ps); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(ps.String(), "%!PS-Adobe-3.0\n") || !strings.Contains(ps.String(), "%%Pages: 4\n") ||
+		strings.Count(ps.String(), "showpage") != 4 || !strings.Contains(ps.String(), `(line 0 \(with parens\) \\ backslash) show`) {
+		t.Errorf("bad PostScript:\n%.400s", ps.String())
+	}
+
+	var pdf bytes.Buffer
+	if err := (&hp{}).printFile(ctx, doc, &pdf); err != nil {
+		t.Fatal(err)
+	}
+	checkPDF(t, pdf.Bytes(), 4)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := (&hp{}).printFile(cancelled, doc, io.Discard); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled render = %v", err)
+	}
+}
+
+// checkPDF verifies that every xref entry points at its object and that
+// startxref points at the table.
+func checkPDF(t *testing.T, b []byte, pages int) {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(b)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(b[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(b[xref:], -1)
+	if want := 3 + 2*pages; len(entries) != want {
+		t.Fatalf("%d xref entries, want %d", len(entries), want)
+	}
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(b[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, b[off:off+10])
+		}
+	}
+	if !bytes.Contains(b, []byte(fmt.Sprintf("/Count %d", pages))) {
+		t.Error("page count missing")
+	}
+	// Content stream lengths must match.
+	for _, sm := range regexp.MustCompile(`(?s)/Length (\d+) >>\nstream\n(.*?)\nendstream`).FindAllSubmatch(b, -1) {
+		if n, _ := strconv.Atoi(string(sm[1])); n != len(sm[2]) {
+			t.Errorf("stream /Length %d, actual %d", n, len(sm[2]))
+		}
+	}
+}
+
+func TestIPP(t *testing.T) {
+	s := newTestSpooler(t, &hp{model: "t"})
+	srv := httptest.NewServer(ippHandler(s))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/printers/hp-t/jobs?title=hello&copies=2", "text/plain", strings.NewReader("hello\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Location") != "/jobs/1" {
+		t.Fatalf("submit: %s, Location %q", resp.Status, resp.Header.Get("Location"))
+	}
+	j, _ := s.job(1)
+	waitAll(t, s, j)
+
+	resp, err = http.Get(srv.URL + "/jobs/1/document")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "application/pdf" {
+		t.Errorf("Content-Type %q", resp.Header.Get("Content-Type"))
+	}
+	checkPDF(t, out, 2)
+
+	for _, c := range []struct {
+		method, path string
+		status       int
+	}{
+		{"GET", "/printers", 200},
+		{"GET", "/printers/hp-t/jobs", 200},
+		{"GET", "/printers/nope/jobs", 404},
+		{"GET", "/jobs/1", 200},
+		{"GET", "/jobs/x", 404},
+		{"DELETE", "/jobs/1", 409},
+		{"POST", "/printers/hp-t/jobs?copies=0", 400},
+		{"POST", "/printers/nope/pause", 404},
+		{"POST", "/printers/hp-t/jobs", 413}, // body set below
+	} {
+		body := "x"
+		if c.status == http.StatusRequestEntityTooLarge {
+			body = strings.Repeat("x", maxDocumentSize+1)
+		}
+		req, _ := http.NewRequest(c.method, srv.URL+c.path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Errorf("%s %s = %d, want %d", c.method, c.path, resp.StatusCode, c.status)
+		}
+	}
+
+	// A body that cannot be read is the client's fault, not too large.
+	w := httptest.NewRecorder()
+	ippHandler(s).ServeHTTP(w, httptest.NewRequest("POST", "/printers/hp-t/jobs", iotest.ErrReader(errors.New("reset"))))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unreadable body: %d, want 400", w.Code)
+	}
+}
+
+func TestForgetFinishedJobs(t *testing.T) {
+	s := newTestSpooler(t, &fakePrinter{})
+	s.keep = 2
+	var jobs []*printJob
+	for i := 0; i < 3; i++ {
+		j, err := s.submit("fake", "", document{title: strconv.Itoa(i), body: "x"}, normalPriority)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, j)
+	}
+	waitAll(t, s, jobs...)
+	if _, err := s.job(jobs[0].id); !errors.Is(err, errUnknownJob) {
+		t.Errorf("oldest job still known: %v", err)
+	}
+	for _, j := range jobs[1:] {
+		if _, err := s.job(j.id); err != nil {
+			t.Errorf("job %d: %v", j.id, err)
+		}
+	}
+}
@@ -1,14 +1,20 @@
 package main
 
-import "fmt"
+import "strings"
 
 type windows struct {
+	spool   *spooler
+	user    string
 	printer printer
 }
 
-func (w *windows) print() {
-	fmt.Println("Print request for windows")
-	w.printer.printFile()
+// print submits doc with CRLF line endings folded to LF.
+func (w *windows) print(doc document, prio priority) (*printJob, error) {
+	if w.printer == nil {
+		return nil, errNoPrinter
+	}
+	doc.body = strings.ReplaceAll(doc.body, "\r\n", "\n")
+	return w.spool.submit(w.printer.name(), `CORP\`+w.user, doc, prio)
 }
 
 func (w *windows) setPrinter(p printer) {
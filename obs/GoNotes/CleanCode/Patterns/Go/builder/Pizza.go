@@ -1,126 +1,42 @@
 package main
 
-import (
-	"fmt"
-)
-
-// Pizza представляет продукт, который мы будем строить.
+// Pizza представляет продукт, который мы будем строить. Строитель
+// PizzaBuilder генерируется из тегов полей: go generate.
+//
+//builder:generate
 type Pizza struct {
-	size      string
-	cheese    bool
+	size      string `builder:"default=Medium"`
+	dough     string `builder:"required"`
+	cheese    bool   `builder:"default=true"`
 	pepperoni bool
 	veggies   bool
+	extras    []string
 }
 
-// PizzaBuilder интерфейс для строителей пиццы.
-type PizzaBuilder interface {
-	SetSize(size string) PizzaBuilder
-	AddCheese() PizzaBuilder
-	AddPepperoni() PizzaBuilder
-	AddVeggies() PizzaBuilder
-	Build() Pizza
-}
-
-// MargheritaPizzaBuilder конкретный строитель для пиццы Маргарита.
-type MargheritaPizzaBuilder struct {
-	pizza Pizza
-}
-
-func NewMargheritaPizzaBuilder() *MargheritaPizzaBuilder {
-	return &MargheritaPizzaBuilder{}
-}
-
-func (b *MargheritaPizzaBuilder) SetSize(size string) PizzaBuilder {
-	b.pizza.size = size
-	return b
-}
-
-func (b *MargheritaPizzaBuilder) AddCheese() PizzaBuilder {
-	b.pizza.cheese = true
-	return b
-}
-
-func (b *MargheritaPizzaBuilder) AddPepperoni() PizzaBuilder {
-	// Пицца Маргарита не содержит пепперони
-	return b
-}
-
-func (b *MargheritaPizzaBuilder) AddVeggies() PizzaBuilder {
-	b.pizza.veggies = true
-	return b
-}
-
-func (b *MargheritaPizzaBuilder) Build() Pizza {
-	return b.pizza
-}
-
-// PepperoniPizzaBuilder конкретный строитель для пиццы Пепперони.
-type PepperoniPizzaBuilder struct {
-	pizza Pizza
-}
-
-func NewPepperoniPizzaBuilder() *PepperoniPizzaBuilder {
-	return &PepperoniPizzaBuilder{}
-}
-
-func (b *PepperoniPizzaBuilder) SetSize(size string) PizzaBuilder {
-	b.pizza.size = size
-	return b
-}
-
-func (b *PepperoniPizzaBuilder) AddCheese() PizzaBuilder {
-	b.pizza.cheese = true
-	return b
-}
-
-func (b *PepperoniPizzaBuilder) AddPepperoni() PizzaBuilder {
-	b.pizza.pepperoni = true
-	return b
-}
-
-func (b *PepperoniPizzaBuilder) AddVeggies() PizzaBuilder {
-	b.pizza.veggies = true
-	return b
-}
-
-func (b *PepperoniPizzaBuilder) Build() Pizza {
-	return b.pizza
-}
+// Рецепты — наборы опций поверх сгенерированного строителя вместо
+// отдельного строителя на каждую пиццу.
+var (
+	Margherita = []PizzaOption{PizzaWithDough("thin"), PizzaWithVeggies(true)}
+	Pepperoni  = []PizzaOption{PizzaWithDough("classic"), PizzaWithPepperoni(true)}
+)
 
-// Director управляет процессом строительства.
+// Director управляет процессом строительства. Каждую пиццу он строит
+// новым строителем, чтобы начинка одной не попала в следующую.
 type Director struct {
-	builder PizzaBuilder
+	newBuilder func() *PizzaBuilder
 }
 
-// NewDirector создает нового директора с заданным строителем.
-func NewDirector(builder PizzaBuilder) *Director {
-	return &Director{builder: builder}
+// NewDirector создает нового директора, берущего строителей у newBuilder.
+func NewDirector(newBuilder func() *PizzaBuilder) *Director {
+	return &Director{newBuilder: newBuilder}
 }
 
-// ConstructPizza строит пиццу с заданными параметрами.
-func (d *Director) ConstructPizza() {
-	d.builder.SetSize("Large").AddCheese().AddVeggies()
-	if _, ok := d.builder.(*PepperoniPizzaBuilder); ok {
-		d.builder.AddPepperoni()
+// ConstructPizza строит большую пиццу по рецепту.
+func (d *Director) ConstructPizza(recipe []PizzaOption) (Pizza, error) {
+	b := d.newBuilder()
+	b.Size("Large")
+	for _, opt := range recipe {
+		opt(b)
 	}
-}
-
-func main() {
-	// Строим пиццу Маргарита
-	margheritaBuilder := NewMargheritaPizzaBuilder()
-	director := NewDirector(margheritaBuilder)
-	director.ConstructPizza()
-	margheritaPizza := margheritaBuilder.Build()
-	fmt.Println("Margherita Pizza:")
-	fmt.Printf("Size: %s, Cheese: %t, Pepperoni: %t, Veggies: %t\n",
-		margheritaPizza.size, margheritaPizza.cheese, margheritaPizza.pepperoni, margheritaPizza.veggies)
-
-	// Строим пиццу Пепперони
-	pepperoniBuilder := NewPepperoniPizzaBuilder()
-	director = NewDirector(pepperoniBuilder)
-	director.ConstructPizza()
-	pepperoniPizza := pepperoniBuilder.Build()
-	fmt.Println("Pepperoni Pizza:")
-	fmt.Printf("Size: %s, Cheese: %t, Pepperoni: %t, Veggies: %t\n",
-		pepperoniPizza.size, pepperoniPizza.cheese, pepperoniPizza.pepperoni, pepperoniPizza.veggies)
+	return b.Build()
 }
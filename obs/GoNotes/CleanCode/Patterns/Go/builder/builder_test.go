@@ -0,0 +1,73 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPizzaBuilder(t *testing.T) {
+	if _, err := NewPizzaBuilder().Build(); err == nil || !strings.Contains(err.Error(), "dough") {
+		t.Errorf("missing dough: %v", err)
+	}
+
+	p, err := NewPizzaBuilder().Dough("thin").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.size != "Medium" || !p.cheese || p.pepperoni {
+		t.Errorf("defaults not applied: %+v", p)
+	}
+
+	p, err = NewPizza(PizzaWithDough("thin"), PizzaWithCheese(false), PizzaWithSize("Small"))
+	if err != nil || p.cheese || p.size != "Small" {
+		t.Errorf("options: %+v, %v", p, err)
+	}
+}
+
+func TestCopyOnBuild(t *testing.T) {
+	extras := []string{"olives"}
+	b := NewPizzaBuilder().Dough("thin").Extras(extras)
+	extras[0] = "anchovies" // the builder took a copy
+	first, _ := b.Build()
+	first.extras[0] = "pineapple" // so did Build
+	second, _ := b.Build()
+	if !slices.Equal(second.extras, []string{"olives"}) {
+		t.Errorf("builder state leaked: %v", second.extras)
+	}
+	b.Size("Small")
+	if first.size != "Medium" {
+		t.Error("built pizza changed with the builder")
+	}
+}
+
+func TestDirectorBuildsEachPizzaAfresh(t *testing.T) {
+	d := NewDirector(NewPizzaBuilder)
+	withExtras := append([]PizzaOption{PizzaWithExtras([]string{"olives"})}, Pepperoni...)
+	if _, err := d.ConstructPizza(withExtras); err != nil {
+		t.Fatal(err)
+	}
+	p, err := d.ConstructPizza(Margherita)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.pepperoni || len(p.extras) > 0 || p.dough != "thin" || !p.veggies || p.size != "Large" {
+		t.Errorf("second pizza = %+v", p)
+	}
+}
+
+func TestHouseBuilder(t *testing.T) {
+	_, err := newHouse()
+	if err == nil || !strings.Contains(err.Error(), "windowType, doorType") {
+		t.Errorf("missing fields: %v", err)
+	}
+	h, err := newHouse(houseWithDoorType("d"), houseWithWindowType("w"))
+	if err != nil || h.floor != 1 {
+		t.Errorf("house %+v, %v", h, err)
+	}
+	for _, kind := range []string{"normal", "igloo"} {
+		if _, err := newDirector(getBuilder(kind)).buildHouse(); err != nil {
+			t.Errorf("%s: %v", kind, err)
+		}
+	}
+}
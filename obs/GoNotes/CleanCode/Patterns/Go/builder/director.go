@@ -14,7 +14,7 @@ func (d *director) setBuilder(b iBuilder) {
 	d.builder = b
 }
 
-func (d *director) buildHouse() house {
+func (d *director) buildHouse() (house, error) {
 	d.builder.setDoorType()
 	d.builder.setWindowType()
 	d.builder.setNumFloor()
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"go/token"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// outputName is the file gen writes for a struct: Pizza → pizzaBuilder_gen.go.
+func outputName(typeName string) string {
+	return lowerFirst(typeName) + "Builder_gen.go"
+}
+
+// generate renders and gofmts the builder for s.
+func generate(pkg string, s structSpec) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := builderTemplate.Execute(&buf, view{Package: pkg, structSpec: s}); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// view is what the template sees; its methods pick names matching the
+// struct's visibility.
+type view struct {
+	Package string
+	structSpec
+}
+
+func (v view) exported() bool { return token.IsExported(v.Name) }
+
+func (v view) ident(name string) string {
+	if v.exported() {
+		return upperFirst(name)
+	}
+	return lowerFirst(name)
+}
+
+func (v view) Builder() string      { return v.ident(v.Name + "Builder") }
+func (v view) NewBuilder() string   { return v.ident("new" + upperFirst(v.Name) + "Builder") }
+func (v view) New() string          { return v.ident("new" + upperFirst(v.Name)) }
+func (v view) Option() string       { return v.ident(v.Name + "Option") }
+func (v view) With(f string) string { return v.ident(v.Name + "With" + upperFirst(f)) }
+
+func (v view) Required() []fieldSpec {
+	var out []fieldSpec
+	for _, f := range v.Fields {
+		if f.Required {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func (v view) Defaults() []fieldSpec {
+	var out []fieldSpec
+	for _, f := range v.Fields {
+		if f.Default != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Clone is the expression copying a field value so the builder and what
+// it built never share backing storage, or "" if plain assignment is
+// already a copy.
+func (f fieldSpec) Clone(expr string) string {
+	switch f.Kind {
+	case kindSlice:
+		return "slices.Clone(" + expr + ")"
+	case kindMap:
+		return "maps.Clone(" + expr + ")"
+	}
+	return ""
+}
+
+func (v view) Imports() []string {
+	var imports []string
+	has := func(k kind) bool {
+		for _, f := range v.Fields {
+			if f.Kind == k {
+				return true
+			}
+		}
+		return false
+	}
+	if len(v.Required()) > 0 {
+		imports = append(imports, "fmt", "strings")
+	}
+	if has(kindMap) {
+		imports = append(imports, "maps")
+	}
+	if has(kindSlice) {
+		imports = append(imports, "slices")
+	}
+	return imports
+}
+
+func upperFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
+
+func lowerFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[n:]
+}
+
+var builderTemplate = template.Must(template.New("builder").Funcs(template.FuncMap{
+	"upper": upperFirst,
+	"join":  strings.Join,
+}).Parse(`// Code generated by gen; DO NOT EDIT.
+
+package {{.Package}}
+{{with .Imports}}
+import (
+{{- range .}}
+	"{{.}}"
+{{- end}}
+)
+{{end}}
+// {{.Builder}} assembles a {{.Name}} field by field. Build copies the
+// result out, so a builder can be reused and values it returned never
+// change afterwards.
+type {{.Builder}} struct {
+	v {{.Name}}
+{{- range .Required}}
+	{{.Name}}Set bool
+{{- end}}
+}
+
+// {{.NewBuilder}} returns a builder preset with the {{.Name}} defaults.
+func {{.NewBuilder}}() *{{.Builder}} {
+	b := &{{.Builder}}{}
+{{- range .Defaults}}
+	b.v.{{.Name}} = {{.Default}}
+{{- end}}
+	return b
+}
+{{range .Fields}}
+// {{upper .Name}} sets {{.Name}}{{if .Required}} (required){{end}}.
+func (b *{{$.Builder}}) {{upper .Name}}(v {{.Type}}) *{{$.Builder}} {
+	b.v.{{.Name}} = {{with .Clone "v"}}{{.}}{{else}}v{{end}}
+{{- if .Required}}
+	b.{{.Name}}Set = true
+{{- end}}
+	return b
+}
+{{end}}
+// Build returns the {{.Name}}{{if .Required}}, or an error naming every
+// required field that was never set{{end}}.
+func (b *{{.Builder}}) Build() ({{.Name}}, error) {
+{{- with .Required}}
+	var missing []string
+{{- range .}}
+	if !b.{{.Name}}Set {
+		missing = append(missing, "{{.Name}}")
+	}
+{{- end}}
+	if len(missing) > 0 {
+		return {{$.Name}}{}, fmt.Errorf("build {{$.Name}}: missing required %s", strings.Join(missing, ", "))
+	}
+{{- end}}
+	v := b.v
+{{- range $f := .Fields}}{{with $f.Clone (print "b.v." $f.Name)}}
+	v.{{$f.Name}} = {{.}}{{end}}{{end}}
+	return v, nil
+}
+
+// {{.Option}} configures a {{.Name}} built by {{.New}}.
+type {{.Option}} func(*{{.Builder}})
+{{range .Fields}}
+// {{$.With .Name}} sets {{.Name}}.
+func {{$.With .Name}}(v {{.Type}}) {{$.Option}} {
+	return func(b *{{$.Builder}}) { b.{{upper .Name}}(v) }
+}
+{{end}}
+// {{.New}} builds a {{.Name}} from its defaults and opts.
+func {{.New}}(opts ...{{.Option}}) ({{.Name}}, error) {
+	b := {{.NewBuilder}}()
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b.Build()
+}
+`))
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.golden")
+
+// TestGolden generates the builders for the structs in the parent
+// directory and compares them with testdata/<type>.golden and with the
+// files checked in next to the structs.
+func TestGolden(t *testing.T) {
+	pkg, specs, err := parseDir("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for _, s := range specs {
+		got[s.Name] = true
+		src, err := generate(pkg, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden := filepath.Join("testdata", s.Name+".golden")
+		if *update {
+			if err := os.WriteFile(golden, src, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(src, want) {
+			t.Errorf("%s: output differs from %s (run go test -update)\n%s", s.Name, golden, src)
+		}
+		checked, err := os.ReadFile(filepath.Join("..", outputName(s.Name)))
+		if err != nil || !bytes.Equal(checked, want) {
+			t.Errorf("%s is stale, run go generate", outputName(s.Name))
+		}
+	}
+	for _, name := range []string{"Pizza", "house"} {
+		if !got[name] {
+			t.Errorf("%s not found", name)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	for _, test := range []struct {
+		field string
+		err   string // substring, empty for success
+		check func(fieldSpec) bool
+	}{
+		{"n int `builder:\"default=0x10\"`", "", func(f fieldSpec) bool { return f.Default == "16" }},
+		{"b bool `builder:\"default=T\"`", "", func(f fieldSpec) bool { return f.Default == "true" }},
+		{"b bool `builder:\"default=0\"`", "", func(f fieldSpec) bool { return f.Default == "false" }},
+		{"x float64 `builder:\"default=.5e1\"`", "", func(f fieldSpec) bool { return f.Default == "5" }},
+		{"x float32 `builder:\"default=0.1\"`", "", func(f fieldSpec) bool { return f.Default == "0.1" }},
+		{"u uint8 `builder:\"default=255\"`", "", func(f fieldSpec) bool { return f.Default == "255" }},
+		{"x float64 `builder:\"default=inf\"`", "not a finite number", nil},
+		{"x float64 `builder:\"default=NaN\"`", "not a finite number", nil},
+		{"u uint `builder:\"default=-1\"`", "invalid syntax", nil},
+		{"u uint8 `builder:\"default=256\"`", "out of range", nil},
+		{"n int8 `builder:\"default=-129\"`", "out of range", nil},
+		{"s string `builder:\"default=a,b\"`", "", func(f fieldSpec) bool { return f.Default == `"a,b"` }},
+		{"s string `builder:\"required\"`", "", func(f fieldSpec) bool { return f.Required }},
+		{"m map[string]int", "", func(f fieldSpec) bool { return f.Kind == kindMap && f.Type == "map[string]int" }},
+		{"n int `builder:\"default=ten\"`", "default \"ten\"", nil},
+		{"s string `builder:\"required,default=x\"`", "both required and defaulted", nil},
+		{"s []string `builder:\"default=x\"`", "not supported", nil},
+		{"s string `builder:\"optional\"`", "unknown builder option", nil},
+	} {
+		src := "package p\n\n//builder:generate\ntype T struct {\n\t" + test.field + "\n}\n"
+		_, specs, err := parseFile(token.NewFileSet(), "t.go", []byte(src))
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: err = %v, want %q", test.field, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.field, err)
+			continue
+		}
+		if f := specs[0].Fields[0]; !test.check(f) {
+			t.Errorf("%s: parsed %+v", test.field, f)
+		}
+	}
+}
+
+func TestUnmarkedAndSkipped(t *testing.T) {
+	src := "package p\n\ntype A struct{ x int }\n\n//builder:generate\ntype B struct {\n\tx int\n\ty int `builder:\"-\"`\n}\n"
+	_, specs, err := parseFile(token.NewFileSet(), "t.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 1 || specs[0].Name != "B" || len(specs[0].Fields) != 1 {
+		t.Errorf("specs = %+v", specs)
+	}
+}
@@ -0,0 +1,53 @@
+// Command gen writes fluent builders for structs marked with a
+// //builder:generate comment. Run it through go generate:
+//
+//	//go:generate go run gen/main.go gen/parse.go gen/emit.go
+//
+// Field tags control the output:
+//
+//	size  string   `builder:"required"`      // Build fails until it is set
+//	floor int      `builder:"default=1"`     // preset by the constructor
+//	tags  []string `builder:"-"`             // no setter
+//
+// For each struct T, gen writes <t>Builder_gen.go next to it with a T
+// builder (setters, Build returning an error for missing required fields,
+// copy-on-build for slices and maps), a T option type with one option per
+// field, and a constructor that applies options. Names follow T's
+// visibility: Pizza gets NewPizzaBuilder, house gets newHouseBuilder.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "package `directory` to scan")
+	flag.Parse()
+	if err := run(*dir); err != nil {
+		fmt.Fprintln(os.Stderr, "gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string) error {
+	pkg, specs, err := parseDir(dir)
+	if err != nil {
+		return err
+	}
+	if len(specs) == 0 {
+		return fmt.Errorf("no //builder:generate structs in %s", dir)
+	}
+	for _, s := range specs {
+		src, err := generate(pkg, s)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, outputName(s.Name)), src, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const marker = "//builder:generate"
+
+// structSpec describes one annotated struct.
+type structSpec struct {
+	Name   string
+	Fields []fieldSpec
+}
+
+// fieldSpec describes one field the builder can set.
+type fieldSpec struct {
+	Name     string
+	Type     string // as written in the source
+	Kind     kind
+	Required bool
+	Default  string // a Go expression, empty for none
+}
+
+type kind int
+
+const (
+	kindOther kind = iota
+	kindString
+	kindBool
+	kindInt
+	kindFloat
+	kindSlice
+	kindMap
+)
+
+// parseDir reads the non-test, non-generated Go files in dir and returns
+// the package name and the annotated structs in source order.
+func parseDir(dir string) (string, []structSpec, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", nil, err
+	}
+	slices.Sort(names)
+	fset := token.NewFileSet()
+	var pkg string
+	var specs []structSpec
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") || strings.HasSuffix(name, "_gen.go") {
+			continue
+		}
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return "", nil, err
+		}
+		p, s, err := parseFile(fset, name, src)
+		if err != nil {
+			return "", nil, err
+		}
+		if pkg == "" {
+			pkg = p
+		}
+		specs = append(specs, s...)
+	}
+	return pkg, specs, nil
+}
+
+// parseFile returns the package name and annotated structs of one file.
+func parseFile(fset *token.FileSet, name string, src []byte) (string, []structSpec, error) {
+	f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	if err != nil {
+		return "", nil, err
+	}
+	var specs []structSpec
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			doc := ts.Doc
+			if doc == nil && len(gen.Specs) == 1 {
+				doc = gen.Doc
+			}
+			if !marked(doc) {
+				continue
+			}
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok || ts.TypeParams != nil {
+				return "", nil, fmt.Errorf("%s: %s: %s needs a non-generic struct", fset.Position(ts.Pos()), marker, ts.Name.Name)
+			}
+			s, err := parseStruct(fset, ts.Name.Name, st, src)
+			if err != nil {
+				return "", nil, err
+			}
+			specs = append(specs, s)
+		}
+	}
+	return f.Name.Name, specs, nil
+}
+
+func marked(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == marker {
+			return true
+		}
+	}
+	return false
+}
+
+func parseStruct(fset *token.FileSet, name string, st *ast.StructType, src []byte) (structSpec, error) {
+	s := structSpec{Name: name}
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			return s, fmt.Errorf("%s: %s: embedded fields are not supported", fset.Position(field.Pos()), name)
+		}
+		var tag string
+		if field.Tag != nil {
+			raw, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(raw).Get("builder")
+		}
+		if tag == "-" {
+			continue
+		}
+		typ := string(src[fset.Position(field.Type.Pos()).Offset:fset.Position(field.Type.End()).Offset])
+		for _, id := range field.Names {
+			f := fieldSpec{Name: id.Name, Type: typ, Kind: kindOf(field.Type)}
+			if err := f.parseTag(tag); err != nil {
+				return s, fmt.Errorf("%s: %s.%s: %v", fset.Position(id.Pos()), name, id.Name, err)
+			}
+			s.Fields = append(s.Fields, f)
+		}
+	}
+	return s, nil
+}
+
+func kindOf(expr ast.Expr) kind {
+	switch t := expr.(type) {
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return kindSlice
+		}
+	case *ast.MapType:
+		return kindMap
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return kindString
+		case "bool":
+			return kindBool
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "byte", "rune":
+			return kindInt
+		case "float32", "float64":
+			return kindFloat
+		}
+	}
+	return kindOther
+}
+
+// parseTag reads a comma-separated list of "required" and "default=v".
+// A default is the last option, so it may itself contain commas.
+func (f *fieldSpec) parseTag(tag string) error {
+	for tag != "" {
+		opt, rest, _ := strings.Cut(tag, ",")
+		switch {
+		case opt == "required":
+			f.Required = true
+		case strings.HasPrefix(opt, "default="):
+			def, err := f.defaultExpr(strings.TrimPrefix(tag, "default="))
+			if err != nil {
+				return err
+			}
+			f.Default = def
+			rest = ""
+		default:
+			return fmt.Errorf("unknown builder option %q", opt)
+		}
+		tag = rest
+	}
+	if f.Required && f.Default != "" {
+		return fmt.Errorf("a field cannot be both required and defaulted")
+	}
+	return nil
+}
+
+// defaultExpr turns a tag default into a Go expression of the field type.
+// The expression is written from the parsed value, not the tag text, so
+// spellings strconv accepts but Go does not, such as T or NaN, never
+// reach the output.
+func (f *fieldSpec) defaultExpr(v string) (string, error) {
+	var expr string
+	var err error
+	switch f.Kind {
+	case kindString:
+		return strconv.Quote(v), nil
+	case kindBool:
+		var b bool
+		b, err = strconv.ParseBool(v)
+		expr = strconv.FormatBool(b)
+	case kindInt:
+		bits := intBits[f.Type]
+		if strings.HasPrefix(f.Type, "u") || f.Type == "byte" {
+			var n uint64
+			n, err = strconv.ParseUint(v, 0, bits)
+			expr = strconv.FormatUint(n, 10)
+		} else {
+			var n int64
+			n, err = strconv.ParseInt(v, 0, bits)
+			expr = strconv.FormatInt(n, 10)
+		}
+	case kindFloat:
+		bits := 64
+		if f.Type == "float32" {
+			bits = 32
+		}
+		var x float64
+		x, err = strconv.ParseFloat(v, bits)
+		if err == nil && (math.IsInf(x, 0) || math.IsNaN(x)) {
+			err = fmt.Errorf("not a finite number")
+		}
+		expr = strconv.FormatFloat(x, 'g', -1, bits)
+	default:
+		return "", fmt.Errorf("defaults are not supported for %s", f.Type)
+	}
+	if err != nil {
+		return "", fmt.Errorf("default %q: %v", v, err)
+	}
+	return expr, nil
+}
+
+// intBits gives the size of each integer type kindOf recognizes.
+var intBits = map[string]int{
+	"int": strconv.IntSize, "int8": 8, "int16": 16, "int32": 32, "int64": 64,
+	"uint": strconv.IntSize, "uint8": 8, "uint16": 16, "uint32": 32, "uint64": 64,
+	"byte": 8, "rune": 32,
+}
@@ -1,7 +1,8 @@
 package main
 
+//builder:generate
 type house struct {
-	windowType string
-	doorType   string
-	floor      int
+	windowType string `builder:"required"`
+	doorType   string `builder:"required"`
+	floor      int    `builder:"default=1"`
 }
@@ -0,0 +1,88 @@
+// Code generated by gen; DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// houseBuilder assembles a house field by field. Build copies the
+// result out, so a builder can be reused and values it returned never
+// change afterwards.
+type houseBuilder struct {
+	v             house
+	windowTypeSet bool
+	doorTypeSet   bool
+}
+
+// newHouseBuilder returns a builder preset with the house defaults.
+func newHouseBuilder() *houseBuilder {
+	b := &houseBuilder{}
+	b.v.floor = 1
+	return b
+}
+
+// WindowType sets windowType (required).
+func (b *houseBuilder) WindowType(v string) *houseBuilder {
+	b.v.windowType = v
+	b.windowTypeSet = true
+	return b
+}
+
+// DoorType sets doorType (required).
+func (b *houseBuilder) DoorType(v string) *houseBuilder {
+	b.v.doorType = v
+	b.doorTypeSet = true
+	return b
+}
+
+// Floor sets floor.
+func (b *houseBuilder) Floor(v int) *houseBuilder {
+	b.v.floor = v
+	return b
+}
+
+// Build returns the house, or an error naming every
+// required field that was never set.
+func (b *houseBuilder) Build() (house, error) {
+	var missing []string
+	if !b.windowTypeSet {
+		missing = append(missing, "windowType")
+	}
+	if !b.doorTypeSet {
+		missing = append(missing, "doorType")
+	}
+	if len(missing) > 0 {
+		return house{}, fmt.Errorf("build house: missing required %s", strings.Join(missing, ", "))
+	}
+	v := b.v
+	return v, nil
+}
+
+// houseOption configures a house built by newHouse.
+type houseOption func(*houseBuilder)
+
+// houseWithWindowType sets windowType.
+func houseWithWindowType(v string) houseOption {
+	return func(b *houseBuilder) { b.WindowType(v) }
+}
+
+// houseWithDoorType sets doorType.
+func houseWithDoorType(v string) houseOption {
+	return func(b *houseBuilder) { b.DoorType(v) }
+}
+
+// houseWithFloor sets floor.
+func houseWithFloor(v int) houseOption {
+	return func(b *houseBuilder) { b.Floor(v) }
+}
+
+// newHouse builds a house from its defaults and opts.
+func newHouse(opts ...houseOption) (house, error) {
+	b := newHouseBuilder()
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b.Build()
+}
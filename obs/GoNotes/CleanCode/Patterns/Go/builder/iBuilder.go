@@ -4,16 +4,16 @@ type iBuilder interface {
 	setWindowType()
 	setDoorType()
 	setNumFloor()
-	getHouse() house
+	getHouse() (house, error)
 }
 
 func getBuilder(builderType string) iBuilder {
 	if builderType == "normal" {
-		return &normalBuilder{}
+		return newNormalBuilder()
 	}
 
 	if builderType == "igloo" {
-		return &iglooBuilder{}
+		return newIglooBuilder()
 	}
 	return nil
 }
@@ -1,31 +1,25 @@
 package main
 
 type iglooBuilder struct {
-	windowType string
-	doorType   string
-	floor      int
+	house *houseBuilder
 }
 
 func newIglooBuilder() *iglooBuilder {
-	return &iglooBuilder{}
+	return &iglooBuilder{house: newHouseBuilder()}
 }
 
 func (b *iglooBuilder) setWindowType() {
-	b.windowType = "Snow Window"
+	b.house.WindowType("Snow Window")
 }
 
 func (b *iglooBuilder) setDoorType() {
-	b.doorType = "Snow Door"
+	b.house.DoorType("Snow Door")
 }
 
 func (b *iglooBuilder) setNumFloor() {
-	b.floor = 1
+	// An igloo keeps the single-floor default.
 }
 
-func (b *iglooBuilder) getHouse() house {
-	return house{
-		doorType:   b.doorType,
-		windowType: b.windowType,
-		floor:      b.floor,
-	}
+func (b *iglooBuilder) getHouse() (house, error) {
+	return b.house.Build()
 }
@@ -1,23 +1,60 @@
 package main
 
-import "fmt"
+//go:generate go run gen/main.go gen/parse.go gen/emit.go
+
+import (
+	"fmt"
+	"log"
+)
 
 func main() {
 	normalBuilder := getBuilder("normal")
 	iglooBuilder := getBuilder("igloo")
 
 	director := newDirector(normalBuilder)
-	normalHouse := director.buildHouse()
+	normalHouse, err := director.buildHouse()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	fmt.Printf("Normal House Door Type: %s\n", normalHouse.doorType)
 	fmt.Printf("Normal House Window Type: %s\n", normalHouse.windowType)
 	fmt.Printf("Normal House Num Floor: %d\n", normalHouse.floor)
 
 	director.setBuilder(iglooBuilder)
-	iglooHouse := director.buildHouse()
+	iglooHouse, err := director.buildHouse()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	fmt.Printf("\nIgloo House Door Type: %s\n", iglooHouse.doorType)
 	fmt.Printf("Igloo House Window Type: %s\n", iglooHouse.windowType)
 	fmt.Printf("Igloo House Num Floor: %d\n", iglooHouse.floor)
 
+	_, err = newHouse(houseWithFloor(3))
+	fmt.Printf("\nHouse without walls: %v\n", err)
+
+	// Строим пиццы по рецептам одним директором
+	pizzaDirector := NewDirector(NewPizzaBuilder)
+	for _, recipe := range []struct {
+		name    string
+		options []PizzaOption
+	}{{"Margherita", Margherita}, {"Pepperoni", Pepperoni}} {
+		pizza, err := pizzaDirector.ConstructPizza(recipe.options)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("\n%s Pizza:\n", recipe.name)
+		fmt.Printf("Size: %s, Dough: %s, Cheese: %t, Pepperoni: %t, Veggies: %t\n",
+			pizza.size, pizza.dough, pizza.cheese, pizza.pepperoni, pizza.veggies)
+	}
+
+	// Один строитель — несколько пицц: Build копирует результат.
+	b := NewPizzaBuilder().Dough("thin").Extras([]string{"olives"})
+	first, _ := b.Build()
+	second, _ := b.Extras([]string{"olives", "basil"}).Size("Small").Build()
+	fmt.Printf("\nFirst: %s %v, second: %s %v\n", first.size, first.extras, second.size, second.extras)
+
+	_, err = NewPizza(PizzaWithSize("Large"))
+	fmt.Printf("Pizza without dough: %v\n", err)
 }
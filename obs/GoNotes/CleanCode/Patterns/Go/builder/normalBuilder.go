@@ -1,31 +1,25 @@
 package main
 
 type normalBuilder struct {
-	windowType string
-	doorType   string
-	floor      int
+	house *houseBuilder
 }
 
 func newNormalBuilder() *normalBuilder {
-	return &normalBuilder{}
+	return &normalBuilder{house: newHouseBuilder()}
 }
 
 func (b *normalBuilder) setWindowType() {
-	b.windowType = "Wooden Window"
+	b.house.WindowType("Wooden Window")
 }
 
 func (b *normalBuilder) setDoorType() {
-	b.doorType = "Wooden Door"
+	b.house.DoorType("Wooden Door")
 }
 
 func (b *normalBuilder) setNumFloor() {
-	b.floor = 2
+	b.house.Floor(2)
 }
 
-func (b *normalBuilder) getHouse() house {
-	return house{
-		doorType:   b.doorType,
-		windowType: b.windowType,
-		floor:      b.floor,
-	}
+func (b *normalBuilder) getHouse() (house, error) {
+	return b.house.Build()
 }
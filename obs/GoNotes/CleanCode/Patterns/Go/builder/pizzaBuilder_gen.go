@@ -0,0 +1,119 @@
+// Code generated by gen; DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// PizzaBuilder assembles a Pizza field by field. Build copies the
+// result out, so a builder can be reused and values it returned never
+// change afterwards.
+type PizzaBuilder struct {
+	v        Pizza
+	doughSet bool
+}
+
+// NewPizzaBuilder returns a builder preset with the Pizza defaults.
+func NewPizzaBuilder() *PizzaBuilder {
+	b := &PizzaBuilder{}
+	b.v.size = "Medium"
+	b.v.cheese = true
+	return b
+}
+
+// Size sets size.
+func (b *PizzaBuilder) Size(v string) *PizzaBuilder {
+	b.v.size = v
+	return b
+}
+
+// Dough sets dough (required).
+func (b *PizzaBuilder) Dough(v string) *PizzaBuilder {
+	b.v.dough = v
+	b.doughSet = true
+	return b
+}
+
+// Cheese sets cheese.
+func (b *PizzaBuilder) Cheese(v bool) *PizzaBuilder {
+	b.v.cheese = v
+	return b
+}
+
+// Pepperoni sets pepperoni.
+func (b *PizzaBuilder) Pepperoni(v bool) *PizzaBuilder {
+	b.v.pepperoni = v
+	return b
+}
+
+// Veggies sets veggies.
+func (b *PizzaBuilder) Veggies(v bool) *PizzaBuilder {
+	b.v.veggies = v
+	return b
+}
+
+// Extras sets extras.
+func (b *PizzaBuilder) Extras(v []string) *PizzaBuilder {
+	b.v.extras = slices.Clone(v)
+	return b
+}
+
+// Build returns the Pizza, or an error naming every
+// required field that was never set.
+func (b *PizzaBuilder) Build() (Pizza, error) {
+	var missing []string
+	if !b.doughSet {
+		missing = append(missing, "dough")
+	}
+	if len(missing) > 0 {
+		return Pizza{}, fmt.Errorf("build Pizza: missing required %s", strings.Join(missing, ", "))
+	}
+	v := b.v
+	v.extras = slices.Clone(b.v.extras)
+	return v, nil
+}
+
+// PizzaOption configures a Pizza built by NewPizza.
+type PizzaOption func(*PizzaBuilder)
+
+// PizzaWithSize sets size.
+func PizzaWithSize(v string) PizzaOption {
+	return func(b *PizzaBuilder) { b.Size(v) }
+}
+
+// PizzaWithDough sets dough.
+func PizzaWithDough(v string) PizzaOption {
+	return func(b *PizzaBuilder) { b.Dough(v) }
+}
+
+// PizzaWithCheese sets cheese.
+func PizzaWithCheese(v bool) PizzaOption {
+	return func(b *PizzaBuilder) { b.Cheese(v) }
+}
+
+// PizzaWithPepperoni sets pepperoni.
+func PizzaWithPepperoni(v bool) PizzaOption {
+	return func(b *PizzaBuilder) { b.Pepperoni(v) }
+}
+
+// PizzaWithVeggies sets veggies.
+func PizzaWithVeggies(v bool) PizzaOption {
+	return func(b *PizzaBuilder) { b.Veggies(v) }
+}
+
+// PizzaWithExtras sets extras.
+func PizzaWithExtras(v []string) PizzaOption {
+	return func(b *PizzaBuilder) { b.Extras(v) }
+}
+
+// NewPizza builds a Pizza from its defaults and opts.
+func NewPizza(opts ...PizzaOption) (Pizza, error) {
+	b := NewPizzaBuilder()
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b.Build()
+}
@@ -1,18 +1,19 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
-type cashier struct {
-	next department
-}
+type cashier struct{}
+
+func (c *cashier) Name() string { return "cashier" }
 
-func (c *cashier) execute(p *patient) {
+// Handle ends the visit: the cashier never passes the patient on.
+func (c *cashier) Handle(ctx context.Context, p *patient, next Next[*patient]) error {
 	if p.paymentDone {
 		fmt.Println("Payment Done")
 	}
 	fmt.Println("Cashier getting money from patient patient")
-}
-
-func (c *cashier) setNext(next department) {
-	c.next = next
+	return nil
 }
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func appendStage(name string) Handler[*[]string] {
+	return Stage(name, func(ctx context.Context, log *[]string, next Next[*[]string]) error {
+		*log = append(*log, name)
+		return next(ctx, log)
+	})
+}
+
+func TestChainOrderAndShortCircuit(t *testing.T) {
+	stop := Stage("stop", func(ctx context.Context, log *[]string, next Next[*[]string]) error {
+		*log = append(*log, "stop")
+		return nil
+	})
+	var log []string
+	ctx, trace := WithTrace(context.Background())
+	if err := Run(ctx, Chain(appendStage("a"), stop, appendStage("never")), &log); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(log, ","); got != "a,stop" {
+		t.Errorf("ran %s", got)
+	}
+	spans := trace.Spans()
+	if len(spans) != 2 || spans[0].Stage != "stop" || spans[0].Outcome != Handled || spans[1].Outcome != Passed {
+		t.Errorf("unexpected trace:\n%s", trace)
+	}
+}
+
+func TestRejectIsTraced(t *testing.T) {
+	ctx, trace := WithTrace(context.Background())
+	err := Run(ctx, Chain[*patient](&reception{}, &doctor{}), &patient{})
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || rejected.Stage != "reception" {
+		t.Fatalf("got %v, want rejection by reception", err)
+	}
+	spans := trace.Spans()
+	if len(spans) != 1 || spans[0].Outcome != Rejected {
+		t.Errorf("unexpected trace:\n%s", trace)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	slow := Stage("slow", func(ctx context.Context, log *[]string, next Next[*[]string]) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	var log []string
+	err := Run(context.Background(), Chain(Timeout(10*time.Millisecond, slow)), &log)
+	if !errors.Is(err, ErrStageTimeout) {
+		t.Fatalf("got %v, want ErrStageTimeout", err)
+	}
+
+	// The timeout must not cover later stages.
+	late := Stage("late", func(ctx context.Context, log *[]string, next Next[*[]string]) error {
+		time.Sleep(30 * time.Millisecond)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return next(ctx, log)
+	})
+	err = Run(context.Background(), Chain(Timeout(10*time.Millisecond, appendStage("fast")), late), &log)
+	if err != nil {
+		t.Fatalf("later stage affected by earlier timeout: %v", err)
+	}
+}
+
+type stageKey struct{}
+
+func TestTimeoutPassesContext(t *testing.T) {
+	tag := Stage("tag", func(ctx context.Context, log *[]string, next Next[*[]string]) error {
+		return next(context.WithValue(ctx, stageKey{}, "tagged"), log)
+	})
+	check := Stage("check", func(ctx context.Context, log *[]string, next Next[*[]string]) error {
+		if v, _ := ctx.Value(stageKey{}).(string); v != "tagged" {
+			return errors.New("context from the timed stage was dropped")
+		}
+		return nil
+	})
+	var log []string
+	if err := Run(context.Background(), Chain(Timeout(time.Second, tag), check), &log); err != nil {
+		t.Fatal(err)
+	}
+
+	// A stage that overruns but succeeds has not failed.
+	overrun := Stage("overrun", func(ctx context.Context, log *[]string, next Next[*[]string]) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	if err := Run(context.Background(), Chain(Timeout(time.Millisecond, overrun)), &log); err != nil {
+		t.Errorf("successful stage reported %v", err)
+	}
+}
+
+func TestRetryAndFallback(t *testing.T) {
+	calls := 0
+	flaky := Stage("flaky", func(ctx context.Context, log *[]string, next Next[*[]string]) error {
+		calls++
+		if calls < 3 {
+			return errors.New("temporary")
+		}
+		return next(ctx, log)
+	})
+	var log []string
+	if err := Run(context.Background(), Chain(Retry(3, 0, flaky), appendStage("after")), &log); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || len(log) != 1 {
+		t.Errorf("calls = %d, log = %v", calls, log)
+	}
+
+	// Downstream failures are not retried.
+	calls = 0
+	once := Stage("once", func(ctx context.Context, log *[]string, next Next[*[]string]) error {
+		calls++
+		return next(ctx, log)
+	})
+	failing := Stage("failing", func(context.Context, *[]string, Next[*[]string]) error {
+		return errors.New("downstream")
+	})
+	if err := Run(context.Background(), Chain(Retry(5, 0, once), failing), &log); err == nil || calls != 1 {
+		t.Errorf("err = %v, calls = %d", err, calls)
+	}
+
+	// A non-positive count still runs the stage once.
+	calls = 0
+	failOnce := Stage("failOnce", func(context.Context, *[]string, Next[*[]string]) error {
+		calls++
+		return errors.New("down")
+	})
+	if err := Run(context.Background(), Chain(Retry(0, 0, failOnce)), &log); err == nil || calls != 1 || !strings.Contains(err.Error(), "down") {
+		t.Errorf("Retry(0): err = %v, calls = %d", err, calls)
+	}
+
+	broken := Stage("broken", func(context.Context, *[]string, Next[*[]string]) error {
+		return errors.New("down")
+	})
+	log = nil
+	if err := Run(context.Background(), Chain(Fallback(broken, appendStage("backup")), appendStage("end")), &log); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(log, ","); got != "backup,end" {
+		t.Errorf("ran %s", got)
+	}
+}
+
+func TestBranch(t *testing.T) {
+	long := func(log *[]string) bool { return len(*log) > 0 }
+	h := Chain(Branch("route", long, appendStage("then"), appendStage("else")), appendStage("end"))
+
+	var log []string
+	Run(context.Background(), h, &log)
+	if got := strings.Join(log, ","); got != "else,end" {
+		t.Errorf("empty log ran %s", got)
+	}
+	log = []string{"x"}
+	Run(context.Background(), h, &log)
+	if got := strings.Join(log, ","); got != "x,then,end" {
+		t.Errorf("non-empty log ran %s", got)
+	}
+}
+
+func TestHTTPStage(t *testing.T) {
+	header := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Stage", "header")
+			next.ServeHTTP(w, r)
+		})
+	}
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				http.Error(w, "no auth", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	srv := HTTPHandler(Chain(HTTPStage("header", header), HTTPStage("auth", auth)), final)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("X-Stage") != "header" {
+		t.Errorf("unauthenticated: code %d, header %q", rec.Code, rec.Header().Get("X-Stage"))
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "yes")
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Errorf("authenticated: code %d, body %q", rec.Code, rec.Body)
+	}
+}
@@ -1,6 +1,13 @@
 package main
 
+// department is a stage of the hospital chain.
 type department interface {
-	execute(*patient)
-	setNext(department)
+	Handler[*patient]
 }
+
+var (
+	_ department = (*reception)(nil)
+	_ department = (*doctor)(nil)
+	_ department = (*medical)(nil)
+	_ department = (*cashier)(nil)
+)
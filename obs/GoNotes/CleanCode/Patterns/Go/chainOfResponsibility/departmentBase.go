@@ -1,5 +0,0 @@
-package main
-
-type departmentBase struct {
-	nextDepartment department
-}
@@ -1,22 +1,20 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
-type doctor struct {
-	next department
-}
+type doctor struct{}
+
+func (d *doctor) Name() string { return "doctor" }
 
-func (d *doctor) execute(p *patient) {
+func (d *doctor) Handle(ctx context.Context, p *patient, next Next[*patient]) error {
 	if p.doctorCheckUpDone {
 		fmt.Println("Doctor checkup already done")
-		d.next.execute(p)
-		return
+		return next(ctx, p)
 	}
 	fmt.Println("Doctor checking patient")
 	p.doctorCheckUpDone = true
-	d.next.execute(p)
-}
-
-func (d *doctor) setNext(next department) {
-	d.next = next
+	return next(ctx, p)
 }
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Next continues the chain with the (possibly modified) request.
+type Next[T any] func(ctx context.Context, req T) error
+
+// Handler is one stage of a chain. A stage does its work and either calls
+// next to pass the request on, or returns without calling it to
+// short-circuit the rest of the chain.
+type Handler[T any] interface {
+	Name() string
+	Handle(ctx context.Context, req T, next Next[T]) error
+}
+
+type stage[T any] struct {
+	name string
+	fn   func(ctx context.Context, req T, next Next[T]) error
+}
+
+func (s *stage[T]) Name() string { return s.name }
+
+func (s *stage[T]) Handle(ctx context.Context, req T, next Next[T]) error {
+	return s.fn(ctx, req, next)
+}
+
+// Stage turns a function into a named Handler.
+func Stage[T any](name string, fn func(ctx context.Context, req T, next Next[T]) error) Handler[T] {
+	return &stage[T]{name: name, fn: fn}
+}
+
+// RejectedError is returned by a stage that refuses a request.
+type RejectedError struct {
+	Stage  string
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("%s rejected request: %s", e.Stage, e.Reason)
+}
+
+// Reject builds the error a stage returns to stop the chain with a refusal.
+func Reject(stage, reason string) error {
+	return &RejectedError{Stage: stage, Reason: reason}
+}
+
+type chain[T any] struct {
+	handlers []Handler[T]
+}
+
+// Chain composes handlers into one Handler that runs them in order. The
+// composed handler is itself a stage, so chains nest.
+func Chain[T any](handlers ...Handler[T]) Handler[T] {
+	return &chain[T]{handlers: handlers}
+}
+
+func (c *chain[T]) Name() string { return "chain" }
+
+func (c *chain[T]) Handle(ctx context.Context, req T, next Next[T]) error {
+	return c.run(ctx, 0, req, next)
+}
+
+func (c *chain[T]) run(ctx context.Context, i int, req T, next Next[T]) error {
+	if i == len(c.handlers) {
+		return next(ctx, req)
+	}
+	h := c.handlers[i]
+	passed := false
+	start := time.Now()
+	err := h.Handle(ctx, req, func(ctx context.Context, req T) error {
+		passed = true
+		return c.run(ctx, i+1, req, next)
+	})
+	record(ctx, h.Name(), passed, err, time.Since(start))
+	return err
+}
+
+// Run sends req through h. The end of the chain is a no-op.
+func Run[T any](ctx context.Context, h Handler[T], req T) error {
+	return h.Handle(ctx, req, func(context.Context, T) error { return nil })
+}
+
+// passedError marks an error that came from further down the chain rather
+// than from the stage itself, so wrappers do not retry or replace it.
+type passedError struct{ err error }
+
+func (e *passedError) Error() string { return e.err.Error() }
+func (e *passedError) Unwrap() error { return e.err }
+
+// handleOwn calls h and reports whether it invoked next. Errors coming back
+// through next are returned unchanged.
+func handleOwn[T any](ctx context.Context, h Handler[T], req T, next Next[T]) (passed bool, err error) {
+	err = h.Handle(ctx, req, func(ctx context.Context, req T) error {
+		passed = true
+		if err := next(ctx, req); err != nil {
+			return &passedError{err}
+		}
+		return nil
+	})
+	var pe *passedError
+	if errors.As(err, &pe) && err == error(pe) {
+		err = pe.err
+	}
+	return passed, err
+}
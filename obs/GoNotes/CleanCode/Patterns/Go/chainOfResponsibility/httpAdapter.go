@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Exchange is the request type of chains built from net/http middleware.
+type Exchange struct {
+	W http.ResponseWriter
+	R *http.Request
+}
+
+// HTTPStage expresses standard func(http.Handler) http.Handler middleware as
+// a chain stage. If the middleware calls the wrapped handler the chain
+// continues with whatever writer and request it passed along.
+func HTTPStage(name string, mw func(http.Handler) http.Handler) Handler[*Exchange] {
+	return Stage(name, func(ctx context.Context, ex *Exchange, next Next[*Exchange]) error {
+		var err error
+		inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err = next(r.Context(), &Exchange{W: w, R: r})
+		})
+		mw(inner).ServeHTTP(ex.W, ex.R.WithContext(ctx))
+		return err
+	})
+}
+
+// HTTPHandler serves requests through h and then final. Errors that reach
+// the top are reported as 500 Internal Server Error, rejections as 403.
+func HTTPHandler(h Handler[*Exchange], final http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h.Handle(r.Context(), &Exchange{W: w, R: r}, func(_ context.Context, ex *Exchange) error {
+			final.ServeHTTP(ex.W, ex.R)
+			return nil
+		})
+		if err == nil {
+			return
+		}
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	})
+}
@@ -1,22 +1,23 @@
 package main
 
-func main() {
-
-	cashier := &cashier{}
-
-	//Set next for medical department
-	medical := &medical{}
-	medical.setNext(cashier)
+import (
+	"context"
+	"fmt"
+)
 
-	//Set next for doctor department
-	doctor := &doctor{}
-	doctor.setNext(medical)
-
-	//Set next for reception department
-	reception := &reception{}
-	reception.setNext(doctor)
+func main() {
+	hospital := Chain[*patient](
+		&reception{},
+		&doctor{},
+		&medical{},
+		&cashier{},
+	)
 
 	patient := &patient{name: "abc"}
 	//Patient visiting
-	reception.execute(patient)
+	ctx, trace := WithTrace(context.Background())
+	if err := Run(ctx, hospital, patient); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Print(trace)
 }
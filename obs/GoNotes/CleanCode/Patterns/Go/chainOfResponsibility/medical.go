@@ -1,22 +1,20 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
-type medical struct {
-	next department
-}
+type medical struct{}
+
+func (m *medical) Name() string { return "medical" }
 
-func (m *medical) execute(p *patient) {
+func (m *medical) Handle(ctx context.Context, p *patient, next Next[*patient]) error {
 	if p.medicineDone {
 		fmt.Println("Medicine already given to patient")
-		m.next.execute(p)
-		return
+		return next(ctx, p)
 	}
 	fmt.Println("Medical giving medicine to patient")
 	p.medicineDone = true
-	m.next.execute(p)
-}
-
-func (m *medical) setNext(next department) {
-	m.next = next
+	return next(ctx, p)
 }
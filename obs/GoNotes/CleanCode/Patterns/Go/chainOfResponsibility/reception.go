@@ -1,22 +1,23 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
-type reception struct {
-	next department
-}
+type reception struct{}
+
+func (r *reception) Name() string { return "reception" }
 
-func (r *reception) execute(p *patient) {
+func (r *reception) Handle(ctx context.Context, p *patient, next Next[*patient]) error {
+	if p.name == "" {
+		return Reject(r.Name(), "patient has no name")
+	}
 	if p.registrationDone {
 		fmt.Println("Patient registration already done")
-		r.next.execute(p)
-		return
+		return next(ctx, p)
 	}
 	fmt.Println("Reception registering patient")
 	p.registrationDone = true
-	r.next.execute(p)
-}
-
-func (r *reception) setNext(next department) {
-	r.next = next
+	return next(ctx, p)
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStageTimeout is the cause of a stage context cancelled by Timeout.
+var ErrStageTimeout = errors.New("stage timed out")
+
+// Timeout limits the time h may spend before it passes the request on or
+// returns. Only h's own work is bounded: once h calls next the clock stops,
+// and later stages see the context h passes on. A stage that finishes
+// without error after the deadline has not timed out.
+func Timeout[T any](d time.Duration, h Handler[T]) Handler[T] {
+	return Stage(h.Name(), func(ctx context.Context, req T, next Next[T]) error {
+		stageCtx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		timer := time.AfterFunc(d, func() { cancel(ErrStageTimeout) })
+		defer timer.Stop()
+
+		passed, err := handleOwn(stageCtx, h, req, func(ctx context.Context, req T) error {
+			timer.Stop()
+			return next(ctx, req)
+		})
+		if !passed && err != nil && errors.Is(context.Cause(stageCtx), ErrStageTimeout) {
+			return fmt.Errorf("%s: %w after %v", h.Name(), ErrStageTimeout, d)
+		}
+		return err
+	})
+}
+
+// Retry re-runs h up to attempts times while it fails before passing the
+// request on. Failures from later stages are never retried. h always runs
+// at least once.
+func Retry[T any](attempts int, backoff time.Duration, h Handler[T]) Handler[T] {
+	attempts = max(attempts, 1)
+	return Stage(h.Name(), func(ctx context.Context, req T, next Next[T]) error {
+		var err error
+		for i := 0; i < attempts; i++ {
+			if i > 0 && backoff > 0 {
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			var passed bool
+			passed, err = handleOwn(ctx, h, req, next)
+			if err == nil || passed {
+				return err
+			}
+			var rejected *RejectedError
+			if errors.As(err, &rejected) {
+				return err
+			}
+		}
+		return fmt.Errorf("%s: giving up after %d attempts: %w", h.Name(), attempts, err)
+	})
+}
+
+// Fallback runs secondary if primary fails before passing the request on.
+func Fallback[T any](primary, secondary Handler[T]) Handler[T] {
+	return Stage(primary.Name(), func(ctx context.Context, req T, next Next[T]) error {
+		passed, err := handleOwn(ctx, primary, req, next)
+		if err == nil || passed {
+			return err
+		}
+		return secondary.Handle(ctx, req, next)
+	})
+}
+
+// Branch sends the request through then if pred holds and through otherwise
+// if not; either branch continues with the rest of the chain. A nil branch
+// passes the request straight on.
+func Branch[T any](name string, pred func(T) bool, then, otherwise Handler[T]) Handler[T] {
+	return Stage(name, func(ctx context.Context, req T, next Next[T]) error {
+		h := otherwise
+		if pred(req) {
+			h = then
+		}
+		if h == nil {
+			return next(ctx, req)
+		}
+		return h.Handle(ctx, req, next)
+	})
+}
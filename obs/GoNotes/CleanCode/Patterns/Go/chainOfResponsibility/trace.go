@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcome says what a stage did with a request.
+type Outcome string
+
+const (
+	Passed   Outcome = "passed"   // called next
+	Handled  Outcome = "handled"  // finished the request without calling next
+	Rejected Outcome = "rejected" // returned a RejectedError
+	Failed   Outcome = "failed"   // returned any other error
+)
+
+// Span is the record of one stage run.
+type Span struct {
+	Stage    string
+	Outcome  Outcome
+	Err      error
+	Duration time.Duration
+}
+
+// Trace collects spans for a request. Spans are appended as stages return,
+// so the innermost stage comes first.
+type Trace struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+func (t *Trace) Spans() []Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Span(nil), t.spans...)
+}
+
+func (t *Trace) String() string {
+	var b strings.Builder
+	for _, s := range t.Spans() {
+		fmt.Fprintf(&b, "%s: %s", s.Stage, s.Outcome)
+		if s.Err != nil && s.Outcome != Passed {
+			fmt.Fprintf(&b, " (%v)", s.Err)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+type traceKey struct{}
+
+// WithTrace returns a context that makes chains record into the returned Trace.
+func WithTrace(ctx context.Context) (context.Context, *Trace) {
+	t := &Trace{}
+	return context.WithValue(ctx, traceKey{}, t), t
+}
+
+func record(ctx context.Context, stage string, passed bool, err error, d time.Duration) {
+	t, ok := ctx.Value(traceKey{}).(*Trace)
+	if !ok {
+		return
+	}
+	var rejected *RejectedError
+	outcome := Handled
+	switch {
+	case passed:
+		outcome = Passed
+	case errors.As(err, &rejected):
+		outcome = Rejected
+	case err != nil:
+		outcome = Failed
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, Span{Stage: stage, Outcome: outcome, Err: err, Duration: d})
+	t.mu.Unlock()
+}
@@ -1,9 +1,11 @@
 package main
 
+import "context"
+
 type button struct {
 	command command
 }
 
-func (b *button) press() {
-	b.command.execute()
+func (b *button) press() error {
+	return b.command.Execute(context.Background())
 }
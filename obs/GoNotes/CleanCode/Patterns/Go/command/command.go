@@ -1,5 +1,25 @@
 package main
 
+import (
+	"context"
+	"encoding/json"
+)
+
 type command interface {
-	execute()
+	Execute(ctx context.Context) error
+	Undo(ctx context.Context) error
+}
+
+// record describes a command in a form that can be written to the journal
+// and turned back into a command on replay.
+type record struct {
+	Op     string          `json:"op"`
+	Target string          `json:"target,omitempty"`
+	Args   json.RawMessage `json:"args,omitempty"`
+}
+
+// journaled is implemented by commands that can be written to the journal.
+type journaled interface {
+	command
+	record() (record, error)
 }
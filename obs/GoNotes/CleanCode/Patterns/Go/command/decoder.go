@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// decoder turns journal records back into commands bound to live devices.
+type decoder struct {
+	devices map[string]device
+}
+
+func (d *decoder) decode(r record) (command, error) {
+	switch r.Op {
+	case "on", "off":
+		dev, ok := d.devices[r.Target]
+		if !ok {
+			return nil, fmt.Errorf("unknown device %q", r.Target)
+		}
+		if r.Op == "on" {
+			return &onCommand{device: dev, deviceID: r.Target}, nil
+		}
+		return &offCommand{device: dev, deviceID: r.Target}, nil
+	case "macro":
+		var steps []record
+		if err := json.Unmarshal(r.Args, &steps); err != nil {
+			return nil, fmt.Errorf("macro args: %v", err)
+		}
+		m := &macroCommand{}
+		for _, s := range steps {
+			c, err := d.decode(s)
+			if err != nil {
+				return nil, err
+			}
+			m.commands = append(m.commands, c)
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unknown command %q", r.Op)
+}
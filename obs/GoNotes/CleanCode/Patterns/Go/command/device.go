@@ -3,4 +3,14 @@ package main
 type device interface {
 	on()
 	off()
+	running() bool
+}
+
+// restore puts d back in the state recorded before a command changed it.
+func restore(d device, wasOn bool) {
+	if wasOn {
+		d.on()
+	} else {
+		d.off()
+	}
 }
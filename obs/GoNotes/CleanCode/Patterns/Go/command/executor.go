@@ -0,0 +1,209 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+)
+
+type priority int
+
+const (
+	lowPriority priority = iota
+	normalPriority
+	highPriority
+)
+
+var errExecutorClosed = errors.New("executor closed")
+
+// job is a command submitted to the executor.
+type job struct {
+	cmd      command
+	priority priority
+	order    uint64 // submission order, FIFO within a priority
+	index    int    // position in the queue, -1 once dequeued
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   func() bool
+
+	done chan struct{}
+	err  error
+}
+
+// Wait blocks until the job has run or been cancelled and returns its error.
+func (j *job) Wait() error {
+	<-j.done
+	return j.err
+}
+
+// Cancel stops the job: a queued job never runs, a running one sees its
+// context cancelled.
+func (j *job) Cancel() {
+	j.cancel()
+}
+
+type jobQueue []*job
+
+func (q jobQueue) Len() int { return len(q) }
+func (q jobQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].order < q[j].order
+}
+func (q jobQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+func (q *jobQueue) Push(x any) {
+	j := x.(*job)
+	j.index = len(*q)
+	*q = append(*q, j)
+}
+func (q *jobQueue) Pop() any {
+	old := *q
+	j := old[len(old)-1]
+	old[len(old)-1] = nil
+	j.index = -1
+	*q = old[:len(old)-1]
+	return j
+}
+
+// executor runs commands on a pool of workers, highest priority first, and
+// records them in the history and, if set, in the write-ahead journal.
+type executor struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  jobQueue
+	order  uint64
+	closed bool
+	wg     sync.WaitGroup
+
+	journal *wal
+	history *history
+}
+
+func newExecutor(workers int, journal *wal) *executor {
+	e := &executor{journal: journal, history: &history{}}
+	e.cond = sync.NewCond(&e.mu)
+	for i := 0; i < workers; i++ {
+		e.wg.Add(1)
+		go e.work()
+	}
+	return e
+}
+
+func (e *executor) submit(ctx context.Context, cmd command, p priority) (*job, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil, errExecutorClosed
+	}
+	jctx, cancel := context.WithCancel(ctx)
+	e.order++
+	j := &job{cmd: cmd, priority: p, order: e.order, ctx: jctx, cancel: cancel, done: make(chan struct{})}
+	j.stop = context.AfterFunc(jctx, func() { e.dequeue(j) })
+	heap.Push(&e.queue, j)
+	e.cond.Signal()
+	return j, nil
+}
+
+// dequeue finishes a job cancelled while it was still waiting in the queue.
+func (e *executor) dequeue(j *job) {
+	e.mu.Lock()
+	if j.index < 0 {
+		e.mu.Unlock()
+		return
+	}
+	heap.Remove(&e.queue, j.index)
+	e.mu.Unlock()
+	j.err = j.ctx.Err()
+	close(j.done)
+}
+
+func (e *executor) work() {
+	defer e.wg.Done()
+	for {
+		e.mu.Lock()
+		for len(e.queue) == 0 && !e.closed {
+			e.cond.Wait()
+		}
+		if len(e.queue) == 0 {
+			e.mu.Unlock()
+			return
+		}
+		j := heap.Pop(&e.queue).(*job)
+		e.mu.Unlock()
+
+		j.err = e.run(j.ctx, j.cmd, false)
+		j.stop()
+		j.cancel()
+		close(j.done)
+	}
+}
+
+func (e *executor) run(ctx context.Context, cmd command, redo bool) error {
+	var seq uint64
+	if e.journal != nil {
+		jc, ok := cmd.(journaled)
+		if !ok {
+			return errors.New("command cannot be journaled")
+		}
+		r, err := jc.record()
+		if err != nil {
+			return err
+		}
+		if seq, err = e.journal.begin(r); err != nil {
+			return err
+		}
+	}
+	err := cmd.Execute(ctx)
+	if e.journal != nil {
+		state := walCommit
+		if err != nil {
+			state = walAbort
+		}
+		if jerr := e.journal.mark(seq, state); jerr != nil && err == nil {
+			err = jerr
+		}
+	}
+	if err == nil {
+		e.history.push(historyEntry{cmd: cmd, seq: seq}, redo)
+	}
+	return err
+}
+
+// undo reverts the most recently completed command.
+func (e *executor) undo(ctx context.Context) error {
+	entry, err := e.history.undo(ctx)
+	if err != nil {
+		return err
+	}
+	if e.journal != nil && entry.seq != 0 {
+		return e.journal.mark(entry.seq, walUndo)
+	}
+	return nil
+}
+
+// redo runs the most recently undone command again on the caller's
+// goroutine.
+func (e *executor) redo(ctx context.Context) error {
+	entry, err := e.history.popRedo()
+	if err != nil {
+		return err
+	}
+	return e.run(ctx, entry.cmd, true)
+}
+
+// close stops accepting jobs, runs what is already queued and waits for the
+// workers to exit.
+func (e *executor) close() {
+	e.mu.Lock()
+	e.closed = true
+	e.cond.Broadcast()
+	e.mu.Unlock()
+	e.wg.Wait()
+}
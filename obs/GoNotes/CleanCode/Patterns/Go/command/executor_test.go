@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// gate blocks its worker until released.
+type gate struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newGate() *gate {
+	return &gate{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (g *gate) Execute(ctx context.Context) error {
+	close(g.started)
+	<-g.release
+	return nil
+}
+
+func (g *gate) Undo(ctx context.Context) error { return nil }
+
+// recorder appends its name to a shared log when executed.
+type recorder struct {
+	mu   *sync.Mutex
+	log  *[]string
+	name string
+}
+
+func (r *recorder) Execute(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.log = append(*r.log, r.name)
+	return nil
+}
+
+func (r *recorder) Undo(ctx context.Context) error { return nil }
+
+type failing struct{}
+
+func (failing) Execute(ctx context.Context) error { return errors.New("broken") }
+func (failing) Undo(ctx context.Context) error    { return nil }
+
+func TestUndoRedoOnTV(t *testing.T) {
+	tv := &tv{}
+	e := newExecutor(2, nil)
+	defer e.close()
+	ctx := context.Background()
+
+	j, _ := e.submit(ctx, &onCommand{device: tv}, normalPriority)
+	if err := j.Wait(); err != nil || !tv.running() {
+		t.Fatalf("on: err %v, running %t", err, tv.running())
+	}
+	if err := e.undo(ctx); err != nil || tv.running() {
+		t.Fatalf("undo: err %v, running %t", err, tv.running())
+	}
+	if err := e.redo(ctx); err != nil || !tv.running() {
+		t.Fatalf("redo: err %v, running %t", err, tv.running())
+	}
+	e.undo(ctx)
+	if err := e.undo(ctx); err != errNothingToUndo {
+		t.Errorf("second undo: %v", err)
+	}
+}
+
+func TestUndoRestoresPriorState(t *testing.T) {
+	ctx := context.Background()
+	on, off := &tv{isRunning: true}, &tv{}
+	for _, c := range []struct {
+		cmd   command
+		tv    *tv
+		wasOn bool
+	}{
+		{&onCommand{device: on}, on, true},
+		{&offCommand{device: off}, off, false},
+	} {
+		if err := c.cmd.Execute(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.cmd.Undo(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if c.tv.running() != c.wasOn {
+			t.Errorf("%T: undo left running %t, want %t", c.cmd, c.tv.running(), c.wasOn)
+		}
+	}
+}
+
+func TestPriorityAndCancel(t *testing.T) {
+	e := newExecutor(1, nil)
+	ctx := context.Background()
+	g := newGate()
+	first, _ := e.submit(ctx, g, normalPriority)
+	<-g.started
+
+	var mu sync.Mutex
+	var log []string
+	submit := func(name string, p priority) *job {
+		j, err := e.submit(ctx, &recorder{mu: &mu, log: &log, name: name}, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return j
+	}
+	submit("low", lowPriority)
+	submit("normal", normalPriority)
+	cancelled := submit("cancelled", highPriority)
+	submit("high", highPriority)
+
+	cancelled.Cancel()
+	if err := cancelled.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled job: %v", err)
+	}
+	close(g.release)
+	first.Wait()
+	e.close()
+
+	want := []string{"high", "normal", "low"}
+	if len(log) != len(want) {
+		t.Fatalf("ran %v, want %v", log, want)
+	}
+	for i := range want {
+		if log[i] != want[i] {
+			t.Fatalf("ran %v, want %v", log, want)
+		}
+	}
+	if _, err := e.submit(ctx, g, normalPriority); err != errExecutorClosed {
+		t.Errorf("submit after close: %v", err)
+	}
+}
+
+func TestMacroRollback(t *testing.T) {
+	tv := &tv{}
+	m := &macroCommand{commands: []command{&onCommand{device: tv}, failing{}}}
+	if err := m.Execute(context.Background()); err == nil {
+		t.Fatal("macro with failing step succeeded")
+	}
+	if tv.running() {
+		t.Error("tv left on after rollback")
+	}
+}
+
+func TestJournalReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commands.wal")
+	journal, err := openWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := &tv{}
+	on := &onCommand{device: set, deviceID: "tv"}
+	off := &offCommand{device: set, deviceID: "tv"}
+	e := newExecutor(1, journal)
+	ctx := context.Background()
+	for _, c := range []command{on, &macroCommand{commands: []command{off, on}}, off} {
+		j, _ := e.submit(ctx, c, normalPriority)
+		if err := j.Wait(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.undo(ctx); err != nil { // undo the final off
+		t.Fatal(err)
+	}
+	e.close()
+
+	// Simulate a crash in the middle of a command and a torn last write.
+	seq, _ := journal.begin(record{Op: "off", Target: "tv"})
+	journal.f.WriteString(`{"seq":`)
+	journal.close()
+
+	replica := &tv{}
+	replayed, incomplete, err := replayWAL(ctx, path, &decoder{devices: map[string]device{"tv": replica}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed != 2 {
+		t.Errorf("replayed %d commands, want 2", replayed)
+	}
+	if len(incomplete) != 1 || incomplete[0].Op != "off" {
+		t.Errorf("incomplete = %v", incomplete)
+	}
+	if replica.running() != set.running() {
+		t.Errorf("replica running %t, original %t", replica.running(), set.running())
+	}
+
+	// Reopening continues the sequence.
+	reopened, err := openWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.close()
+	if reopened.seq != seq {
+		t.Errorf("reopened seq %d, want %d", reopened.seq, seq)
+	}
+}
+
+func TestJournalAppendAfterTornWrite(t *testing.T) {
+	ctx := context.Background()
+	for _, tail := range []string{`{"seq":`, `{"seq":9,"state":"abort"}`} {
+		path := filepath.Join(t.TempDir(), "commands.wal")
+		journal, err := openWAL(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seq, _ := journal.begin(record{Op: "on", Target: "tv"})
+		journal.mark(seq, walCommit)
+		journal.f.WriteString(tail) // torn, or intact but unterminated
+		journal.close()
+
+		reopened, err := openWAL(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seq, _ = reopened.begin(record{Op: "off", Target: "tv"})
+		reopened.mark(seq, walCommit)
+		reopened.close()
+
+		replica := &tv{}
+		replayed, incomplete, err := replayWAL(ctx, path, &decoder{devices: map[string]device{"tv": replica}})
+		if err != nil {
+			t.Fatalf("after %q: %v", tail, err)
+		}
+		if replayed != 2 || len(incomplete) != 0 || replica.running() {
+			t.Errorf("after %q: replayed %d, incomplete %v, running %t",
+				tail, replayed, incomplete, replica.running())
+		}
+	}
+}
+
+func TestDecodeUnknown(t *testing.T) {
+	d := &decoder{devices: map[string]device{}}
+	if _, err := d.decode(record{Op: "on", Target: "radio"}); err == nil {
+		t.Error("decoded command for unknown device")
+	}
+	if _, err := d.decode(record{Op: "explode"}); err == nil {
+		t.Error("decoded unknown op")
+	}
+}
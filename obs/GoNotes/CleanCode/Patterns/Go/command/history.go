@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var errNothingToUndo = errors.New("nothing to undo")
+var errNothingToRedo = errors.New("nothing to redo")
+
+type historyEntry struct {
+	cmd command
+	seq uint64 // journal sequence number, 0 if not journaled
+}
+
+// history keeps executed commands for undo and undone ones for redo.
+type history struct {
+	mu   sync.Mutex
+	done []historyEntry
+	redo []historyEntry
+}
+
+// push records an executed command. A fresh command invalidates the redo
+// stack; a redone one keeps the rest of it.
+func (h *history) push(e historyEntry, redo bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.done = append(h.done, e)
+	if !redo {
+		h.redo = nil
+	}
+}
+
+func (h *history) undo(ctx context.Context) (historyEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.done) == 0 {
+		return historyEntry{}, errNothingToUndo
+	}
+	e := h.done[len(h.done)-1]
+	if err := e.cmd.Undo(ctx); err != nil {
+		return e, err
+	}
+	h.done = h.done[:len(h.done)-1]
+	h.redo = append(h.redo, e)
+	return e, nil
+}
+
+func (h *history) popRedo() (historyEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.redo) == 0 {
+		return historyEntry{}, errNothingToRedo
+	}
+	e := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	return e, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// macroCommand runs its commands in order as one unit. If one of them fails
+// the ones that already ran are undone in reverse order.
+type macroCommand struct {
+	commands []command
+}
+
+func (m *macroCommand) Execute(ctx context.Context) error {
+	for i, c := range m.commands {
+		if err := c.Execute(ctx); err != nil {
+			if uerr := undoAll(context.WithoutCancel(ctx), m.commands[:i]); uerr != nil {
+				return fmt.Errorf("macro step %d: %v (rollback: %v)", i, err, uerr)
+			}
+			return fmt.Errorf("macro step %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (m *macroCommand) Undo(ctx context.Context) error {
+	return undoAll(ctx, m.commands)
+}
+
+func (m *macroCommand) record() (record, error) {
+	var steps []record
+	for _, c := range m.commands {
+		j, ok := c.(journaled)
+		if !ok {
+			return record{}, fmt.Errorf("macro step %T cannot be journaled", c)
+		}
+		r, err := j.record()
+		if err != nil {
+			return record{}, err
+		}
+		steps = append(steps, r)
+	}
+	args, err := json.Marshal(steps)
+	if err != nil {
+		return record{}, err
+	}
+	return record{Op: "macro", Args: args}, nil
+}
+
+func undoAll(ctx context.Context, commands []command) error {
+	for i := len(commands) - 1; i >= 0; i-- {
+		if err := commands[i].Undo(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,14 +1,24 @@
 package main
 
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
 func main() {
 	tv := &tv{}
 
 	onCommand := &onCommand{
-		device: tv,
+		device:   tv,
+		deviceID: "tv",
 	}
 
 	offCommand := &offCommand{
-		device: tv,
+		device:   tv,
+		deviceID: "tv",
 	}
 
 	onButton := &button{
@@ -20,4 +30,47 @@ func main() {
 		command: offCommand,
 	}
 	offButton.press()
+
+	// The same commands queued on an executor with a journal and undo.
+	dir, err := os.MkdirTemp("", "command")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "commands.wal")
+
+	journal, err := openWAL(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	executor := newExecutor(1, journal)
+	ctx := context.Background()
+	restart := &macroCommand{commands: []command{offCommand, onCommand}}
+	for _, c := range []command{onCommand, restart} {
+		j, err := executor.submit(ctx, c, normalPriority)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := j.Wait(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Println("Undo restart:")
+	if err := executor.undo(ctx); err != nil {
+		log.Fatal(err)
+	}
+	executor.close()
+	journal.close()
+
+	replay(ctx, path)
+}
+
+// replay rebuilds the state of a fresh tv from the journal, as after a crash.
+func replay(ctx context.Context, path string) {
+	fmt.Println("Replaying journal on a new tv:")
+	replayed, _, err := replayWAL(ctx, path, &decoder{devices: map[string]device{"tv": &tv{}}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Replayed %d commands\n", replayed)
 }
@@ -1,9 +1,27 @@
 package main
 
+import "context"
+
 type offCommand struct {
-	device device
+	device   device
+	deviceID string
+	wasOn    bool // the state before Execute, for Undo
 }
 
-func (c *offCommand) execute() {
+func (c *offCommand) Execute(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.wasOn = c.device.running()
 	c.device.off()
+	return nil
+}
+
+func (c *offCommand) Undo(ctx context.Context) error {
+	restore(c.device, c.wasOn)
+	return nil
+}
+
+func (c *offCommand) record() (record, error) {
+	return record{Op: "off", Target: c.deviceID}, nil
 }
@@ -1,9 +1,27 @@
 package main
 
+import "context"
+
 type onCommand struct {
-	device device
+	device   device
+	deviceID string
+	wasOn    bool // the state before Execute, for Undo
 }
 
-func (c *onCommand) execute() {
+func (c *onCommand) Execute(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.wasOn = c.device.running()
 	c.device.on()
+	return nil
+}
+
+func (c *onCommand) Undo(ctx context.Context) error {
+	restore(c.device, c.wasOn)
+	return nil
+}
+
+func (c *onCommand) record() (record, error) {
+	return record{Op: "on", Target: c.deviceID}, nil
 }
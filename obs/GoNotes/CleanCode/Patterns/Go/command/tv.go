@@ -1,17 +1,31 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
 type tv struct {
+	mu        sync.Mutex
 	isRunning bool
 }
 
 func (t *tv) on() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.isRunning = true
 	fmt.Println("Turning tv on")
 }
 
 func (t *tv) off() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.isRunning = false
 	fmt.Println("Turning tv off")
 }
+
+func (t *tv) running() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.isRunning
+}
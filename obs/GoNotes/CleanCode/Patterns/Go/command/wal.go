@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// Journal entry states. An entry is written as "begin" before the command
+// runs and followed by "commit" or "abort" once it finishes; "undo" marks a
+// committed command that was later undone.
+const (
+	walBegin  = "begin"
+	walCommit = "commit"
+	walAbort  = "abort"
+	walUndo   = "undo"
+)
+
+type walEntry struct {
+	Seq    uint64  `json:"seq"`
+	State  string  `json:"state"`
+	Record *record `json:"record,omitempty"`
+}
+
+// wal is an append-only, fsynced journal of executed commands.
+type wal struct {
+	mu  sync.Mutex
+	f   *os.File
+	seq uint64
+}
+
+func openWAL(path string) (*wal, error) {
+	entries, size, err := readWAL(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	// Drop any torn final write so the next entry starts on a line of
+	// its own, and end an intact final entry that lost its newline.
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if size > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, size-1); err != nil {
+			f.Close()
+			return nil, err
+		}
+		if last[0] != '\n' {
+			if _, err := f.Write([]byte{'\n'}); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+	}
+	w := &wal{f: f}
+	for _, e := range entries {
+		w.seq = max(w.seq, e.Seq)
+	}
+	return w, nil
+}
+
+func (w *wal) begin(r record) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.seq++
+	return w.seq, w.write(walEntry{Seq: w.seq, State: walBegin, Record: &r})
+}
+
+func (w *wal) mark(seq uint64, state string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.write(walEntry{Seq: seq, State: state})
+}
+
+func (w *wal) write(e walEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := w.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return w.f.Sync()
+}
+
+func (w *wal) close() error {
+	return w.f.Close()
+}
+
+// readWAL returns the entries of the journal at path and the length of
+// the intact part of the file, which excludes a torn final write.
+func readWAL(path string) ([]walEntry, int64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var entries []walEntry
+	var size int64
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		b, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		if len(b) == 0 {
+			break
+		}
+		var e walEntry
+		if jerr := json.Unmarshal(b, &e); jerr != nil {
+			// A torn final write from a crash is expected; anything
+			// else is corruption.
+			if _, perr := r.Peek(1); perr == io.EOF {
+				break
+			}
+			return nil, 0, fmt.Errorf("%s:%d: %v", path, line, jerr)
+		}
+		entries = append(entries, e)
+		size += int64(len(b))
+		if err == io.EOF {
+			break
+		}
+	}
+	return entries, size, nil
+}
+
+// replayWAL re-executes, in commit order, every command the journal at path
+// records as committed and not undone. Commands that began but never
+// finished are returned so the caller can decide what to do with them.
+func replayWAL(ctx context.Context, path string, d *decoder) (replayed int, incomplete []record, err error) {
+	entries, _, err := readWAL(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	begun := make(map[uint64]*record)
+	finished := make(map[uint64]bool)
+	undone := make(map[uint64]bool)
+	var committed []uint64
+	for _, e := range entries {
+		switch e.State {
+		case walBegin:
+			begun[e.Seq] = e.Record
+		case walCommit:
+			committed = append(committed, e.Seq)
+			finished[e.Seq] = true
+		case walAbort:
+			finished[e.Seq] = true
+		case walUndo:
+			undone[e.Seq] = true
+		}
+	}
+	for _, seq := range committed {
+		if undone[seq] || begun[seq] == nil {
+			continue
+		}
+		c, err := d.decode(*begun[seq])
+		if err != nil {
+			return replayed, nil, fmt.Errorf("replay #%d: %v", seq, err)
+		}
+		if err := c.Execute(ctx); err != nil {
+			return replayed, nil, fmt.Errorf("replay #%d: %w", seq, err)
+		}
+		replayed++
+	}
+	for _, e := range entries {
+		if e.State == walBegin && !finished[e.Seq] && e.Record != nil {
+			incomplete = append(incomplete, *e.Record)
+		}
+	}
+	return replayed, incomplete, nil
+}
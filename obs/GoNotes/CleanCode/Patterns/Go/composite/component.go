@@ -1,5 +1,26 @@
 package main
 
+import (
+	"io"
+	"time"
+)
+
+// component is a node of the virtual filesystem. Nodes are immutable once
+// they are part of a tree: every change builds new nodes along the path from
+// the root, so trees can share unchanged subtrees. That is what makes
+// clone free and snapshots copy-on-write.
 type component interface {
-	search(string)
+	getName() string
+	modTime() time.Time
+	// size is the length of a file, or the total of everything below a
+	// folder.
+	size() int64
+	// search appends the matches found in this subtree; dir is the path
+	// of the folder holding the node.
+	search(q *query, dir string, found *[]match)
+	print(w io.Writer, indentation string)
+	// clone returns a copy with the given name. The copy shares its
+	// contents with the original, which is safe because neither can be
+	// changed in place.
+	clone(name string) component
 }
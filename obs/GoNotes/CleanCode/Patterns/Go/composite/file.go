@@ -1,15 +1,35 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"time"
+)
 
 type file struct {
-	name string
-}
-
-func (f *file) search(keyword string) {
-	fmt.Printf("Searching for keyword %s in file %s\n", keyword, f.name)
+	name  string
+	data  []byte // never modified after the file is created
+	mtime time.Time
 }
 
 func (f *file) getName() string {
 	return f.name
 }
+
+func (f *file) modTime() time.Time { return f.mtime }
+
+func (f *file) size() int64 { return int64(len(f.data)) }
+
+func (f *file) search(q *query, dir string, found *[]match) {
+	q.searchFile(f, joinPath(dir, f.name), found)
+}
+
+func (f *file) print(w io.Writer, indentation string) {
+	fmt.Fprintf(w, "%s%s (%d bytes)\n", indentation, f.name, len(f.data))
+}
+
+func (f *file) clone(name string) component {
+	c := *f
+	c.name = name
+	return &c
+}
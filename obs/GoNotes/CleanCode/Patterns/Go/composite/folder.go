@@ -1,19 +1,85 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
 
 type folder struct {
-	components []component
+	components map[string]component // never modified once shared
 	name       string
+	mtime      time.Time
+	total      int64 // cached size of the subtree
 }
 
-func (f *folder) search(keyword string) {
-	fmt.Printf("Serching recursively for keyword %s in folder %s\n", keyword, f.name)
-	for _, composite := range f.components {
-		composite.search(keyword)
+func newFolder(name string, mtime time.Time) *folder {
+	return &folder{components: make(map[string]component), name: name, mtime: mtime}
+}
+
+func (f *folder) getName() string { return f.name }
+
+func (f *folder) modTime() time.Time { return f.mtime }
+
+func (f *folder) size() int64 { return f.total }
+
+func (f *folder) search(q *query, dir string, found *[]match) {
+	path := joinPath(dir, f.name)
+	for _, c := range f.sorted() {
+		q.searchName(c, path, found)
+		c.search(q, path, found)
+	}
+}
+
+func (f *folder) print(w io.Writer, indentation string) {
+	fmt.Fprintf(w, "%s%s/ (%d bytes)\n", indentation, f.name, f.total)
+	for _, c := range f.sorted() {
+		c.print(w, indentation+"  ")
+	}
+}
+
+func (f *folder) clone(name string) component {
+	c := *f
+	c.name = name
+	return &c
+}
+
+// with returns a copy of f in which c is stored under its name.
+func (f *folder) with(c component, mtime time.Time) *folder {
+	n := f.copy(mtime)
+	if old, ok := n.components[c.getName()]; ok {
+		n.total -= old.size()
+	}
+	n.components[c.getName()] = c
+	n.total += c.size()
+	return n
+}
+
+// without returns a copy of f with the named child removed.
+func (f *folder) without(name string, mtime time.Time) *folder {
+	n := f.copy(mtime)
+	if old, ok := n.components[name]; ok {
+		n.total -= old.size()
+		delete(n.components, name)
 	}
+	return n
 }
 
-func (f *folder) add(c component) {
-	f.components = append(f.components, c)
+func (f *folder) copy(mtime time.Time) *folder {
+	n := &folder{components: make(map[string]component, len(f.components)+1), name: f.name, mtime: mtime, total: f.total}
+	for k, v := range f.components {
+		n.components[k] = v
+	}
+	return n
+}
+
+// sorted returns the children ordered by name.
+func (f *folder) sorted() []component {
+	out := make([]component, 0, len(f.components))
+	for _, c := range f.components {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].getName() < out[j].getName() })
+	return out
 }
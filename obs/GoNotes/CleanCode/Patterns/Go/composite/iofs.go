@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// Open implements fs.FS, so a vfs works with fs.WalkDir, http.FS,
+// template.ParseFS and friends. Every open file sees the tree as it was
+// when it was opened.
+func (v *vfs) Open(name string) (fs.File, error) {
+	c, err := v.open("open", name)
+	if err != nil {
+		return nil, err
+	}
+	switch c := c.(type) {
+	case *file:
+		return &openFile{info: fileInfo{c}, r: bytes.NewReader(c.data)}, nil
+	case *folder:
+		return &openDir{info: fileInfo{c}, entries: c.sorted()}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (v *vfs) ReadFile(name string) ([]byte, error) {
+	c, err := v.open("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := c.(*file)
+	if !ok {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: errIsDir}
+	}
+	return append([]byte(nil), f.data...), nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (v *vfs) ReadDir(name string) ([]fs.DirEntry, error) {
+	c, err := v.open("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	dir, ok := c.(*folder)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+	var entries []fs.DirEntry
+	for _, c := range dir.sorted() {
+		entries = append(entries, fs.FileInfoToDirEntry(fileInfo{c}))
+	}
+	return entries, nil
+}
+
+// Stat implements fs.StatFS.
+func (v *vfs) Stat(name string) (fs.FileInfo, error) {
+	c, err := v.open("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{c}, nil
+}
+
+// open resolves an io/fs style name: unrooted, slash-separated, "." for
+// the root.
+func (v *vfs) open(op, name string) (component, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	v.mu.RLock()
+	root := v.root
+	v.mu.RUnlock()
+	var parts []string
+	if name != "." {
+		parts = splitPath(name)
+	}
+	c, err := lookup(root, parts)
+	if err != nil {
+		if err == errNotDir {
+			err = fs.ErrNotExist
+		}
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if c == component(root) {
+		c = root.clone(".")
+	}
+	return c, nil
+}
+
+type fileInfo struct{ c component }
+
+func (i fileInfo) Name() string       { return i.c.getName() }
+func (i fileInfo) ModTime() time.Time { return i.c.modTime() }
+func (i fileInfo) Sys() any           { return nil }
+
+func (i fileInfo) IsDir() bool {
+	_, ok := i.c.(*folder)
+	return ok
+}
+
+func (i fileInfo) Size() int64 {
+	if i.IsDir() {
+		return 0
+	}
+	return i.c.size()
+}
+
+func (i fileInfo) Mode() fs.FileMode {
+	if i.IsDir() {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// openFile is a read-only handle on a file. It also satisfies io.Seeker,
+// which http.FileServer needs for range requests.
+type openFile struct {
+	info fileInfo
+	r    *bytes.Reader
+}
+
+func (f *openFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *openFile) Read(p []byte) (int, error) { return f.r.Read(p) }
+func (f *openFile) Close() error               { return nil }
+
+func (f *openFile) Seek(offset int64, whence int) (int64, error) {
+	return f.r.Seek(offset, whence)
+}
+
+func (f *openFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.r.ReadAt(p, off)
+}
+
+type openDir struct {
+	info    fileInfo
+	entries []component
+	offset  int
+}
+
+func (d *openDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *openDir) Close() error               { return nil }
+
+func (d *openDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errIsDir}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *openDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n > 0 && len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < len(rest) {
+		rest = rest[:n]
+	}
+	d.offset += len(rest)
+	out := make([]fs.DirEntry, len(rest))
+	for i, c := range rest {
+		out[i] = fs.FileInfoToDirEntry(fileInfo{c})
+	}
+	return out, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"sort"
+)
+
+// jsonDir is the tree format of Ozon/dir.json: a folder lists its files by
+// name and nests its sub-folders. File contents are not part of it.
+type jsonDir struct {
+	Dir     string    `json:"dir"`
+	Files   []string  `json:"files,omitempty"`
+	Folders []jsonDir `json:"folders,omitempty"`
+}
+
+// loadJSON builds a filesystem from the tree in r. The top-level "dir"
+// becomes the root, so "root/a" in the file is "/a" in the filesystem.
+// Files are created empty.
+func loadJSON(r io.Reader) (*vfs, error) {
+	var top jsonDir
+	if err := json.NewDecoder(r).Decode(&top); err != nil {
+		return nil, err
+	}
+	v := newVFS()
+	if err := v.loadDir("/", top); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *vfs) loadDir(dir string, d jsonDir) error {
+	for _, name := range d.Files {
+		if err := v.writeFile(path.Join(dir, name), nil); err != nil {
+			return err
+		}
+	}
+	for _, sub := range d.Folders {
+		p := path.Join(dir, sub.Dir)
+		if err := v.mkdir(p, true); err != nil {
+			return err
+		}
+		if err := v.loadDir(p, sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveJSON writes the tree in the format loadJSON reads, naming the root
+// rootName. Names are sorted so the output is stable.
+func (v *vfs) saveJSON(w io.Writer, rootName string) error {
+	v.mu.RLock()
+	root := v.root
+	v.mu.RUnlock()
+	top := toJSON(root)
+	top.Dir = rootName
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(top)
+}
+
+func toJSON(f *folder) jsonDir {
+	d := jsonDir{Dir: f.name}
+	for _, c := range f.sorted() {
+		switch c := c.(type) {
+		case *file:
+			d.Files = append(d.Files, c.name)
+		case *folder:
+			d.Folders = append(d.Folders, toJSON(c))
+		}
+	}
+	sort.Strings(d.Files)
+	return d
+}
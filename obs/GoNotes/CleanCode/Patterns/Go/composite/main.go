@@ -1,22 +1,42 @@
 package main
 
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
 func main() {
-	file1 := &file{name: "File1"}
-	file2 := &file{name: "File2"}
-	file3 := &file{name: "File3"}
+	fsys := newVFS()
+	must(fsys.mkdir("/Folder2/Folder1", true))
+	must(fsys.writeFile("/Folder2/Folder1/File1", []byte("a rose\nby any other name\n")))
+	must(fsys.writeFile("/Folder2/File2", []byte("would smell as sweet\n")))
+	must(fsys.writeFile("/Folder2/File3", []byte("rose, rose, rose\n")))
 
-	folder1 := &folder{
-		name: "Folder1",
+	fmt.Println("Searching for keyword rose in /Folder2")
+	matches, err := fsys.search("/Folder2", "", "rose")
+	must(err)
+	for _, m := range matches {
+		fmt.Println(" ", m)
 	}
 
-	folder1.add(file1)
+	fmt.Println("\nSnapshot, then changes to the original")
+	snapshot := fsys.snapshot()
+	must(fsys.cp("/Folder2/Folder1", "/Folder3"))
+	must(fsys.mv("/Folder2/File3", "/Folder3/File3"))
+	must(fsys.rm("/Folder2/File2", false))
+	fsys.print(os.Stdout)
 
-	folder2 := &folder{
-		name: "Folder2",
-	}
-	folder2.add(file2)
-	folder2.add(file3)
-	folder2.add(folder1)
+	fmt.Println("\nSnapshot is unchanged")
+	snapshot.print(os.Stdout)
 
-	folder2.search("rose")
+	size, err := snapshot.du("/Folder2")
+	must(err)
+	fmt.Printf("\ndu /Folder2 in snapshot: %d bytes\n", size)
+}
+
+func must(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+)
+
+// match is a search hit. Name-only searches leave line at 0.
+type match struct {
+	path string
+	line int
+	text string
+}
+
+func (m match) String() string {
+	if m.line == 0 {
+		return m.path
+	}
+	return fmt.Sprintf("%s:%d: %s", m.path, m.line, m.text)
+}
+
+// query selects nodes by a glob on their names and, optionally, files by a
+// regular expression on their contents.
+type query struct {
+	glob    string // path.Match pattern on the base name; "" matches all
+	content *regexp.Regexp
+}
+
+func (q *query) nameMatches(name string) bool {
+	if q.glob == "" {
+		return true
+	}
+	ok, _ := path.Match(q.glob, name)
+	return ok
+}
+
+// searchName reports c itself for name-only queries.
+func (q *query) searchName(c component, dir string, found *[]match) {
+	if q.content == nil && q.nameMatches(c.getName()) {
+		*found = append(*found, match{path: joinPath(dir, c.getName())})
+	}
+}
+
+// searchFile reports every matching line of f for content queries.
+func (q *query) searchFile(f *file, p string, found *[]match) {
+	if q.content == nil || !q.nameMatches(f.name) {
+		return
+	}
+	for i, line := range bytes.Split(f.data, []byte("\n")) {
+		if q.content.Match(line) {
+			*found = append(*found, match{path: p, line: i + 1, text: string(line)})
+		}
+	}
+}
+
+// search looks below root for names matching glob and, if pattern is not
+// empty, for lines matching the regular expression pattern in those files.
+// Results come in path order.
+func (v *vfs) search(root, glob, pattern string) ([]match, error) {
+	q := &query{glob: glob}
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("search: bad glob %q: %v", glob, err)
+	}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("search: %v", err)
+		}
+		q.content = re
+	}
+	c, err := v.stat(root)
+	if err != nil {
+		return nil, err
+	}
+	var found []match
+	dir := path.Dir(path.Clean("/" + root))
+	if f, ok := c.(*file); ok {
+		q.searchName(f, dir, &found)
+	}
+	c.search(q, dir, &found)
+	return found, nil
+}
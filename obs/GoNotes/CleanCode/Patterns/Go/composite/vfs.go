@@ -0,0 +1,298 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errNotDir   = errors.New("not a directory")
+	errIsDir    = errors.New("is a directory")
+	errNotEmpty = errors.New("directory not empty")
+	errIntoSelf = errors.New("cannot move or copy a directory into itself")
+)
+
+// vfs is an in-memory filesystem built from folder and file components.
+// Paths are slash-separated; a leading "/" is optional.
+type vfs struct {
+	mu   sync.RWMutex
+	root *folder
+	now  func() time.Time
+}
+
+func newVFS() *vfs {
+	return &vfs{root: newFolder("", time.Now()), now: time.Now}
+}
+
+// snapshot returns an independent copy of the filesystem in O(1): both
+// share every node until one of them changes something, and changes only
+// ever build new nodes.
+func (v *vfs) snapshot() *vfs {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return &vfs{root: v.root, now: v.now}
+}
+
+// splitPath cleans p and returns its elements; the root has none.
+func splitPath(p string) []string {
+	p = path.Clean("/" + p)
+	if p == "/" {
+		return nil
+	}
+	return strings.Split(p[1:], "/")
+}
+
+func joinPath(dir, name string) string {
+	if name == "" {
+		return dir
+	}
+	if dir == "" || dir == "/" {
+		return "/" + name
+	}
+	return dir + "/" + name
+}
+
+func lookup(root *folder, parts []string) (component, error) {
+	var c component = root
+	for _, name := range parts {
+		dir, ok := c.(*folder)
+		if !ok {
+			return nil, errNotDir
+		}
+		if c, ok = dir.components[name]; !ok {
+			return nil, fs.ErrNotExist
+		}
+	}
+	return c, nil
+}
+
+// rebuild returns a new root in which the folder at dir has been replaced
+// by fn's result. Folders on the way are copied; everything else is shared.
+func rebuild(f *folder, dir []string, fn func(*folder) (*folder, error)) (*folder, error) {
+	if len(dir) == 0 {
+		return fn(f)
+	}
+	child, ok := f.components[dir[0]]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	sub, ok := child.(*folder)
+	if !ok {
+		return nil, errNotDir
+	}
+	nsub, err := rebuild(sub, dir[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	return f.with(nsub, f.mtime), nil
+}
+
+func (v *vfs) stat(p string) (component, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	c, err := lookup(v.root, splitPath(p))
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: err}
+	}
+	return c, nil
+}
+
+// mkdir creates the folder p. With parents it also creates missing
+// ancestors and does not mind if p already exists, like mkdir -p.
+func (v *vfs) mkdir(p string, parents bool) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	parts := splitPath(p)
+	if len(parts) == 0 {
+		if parents {
+			return nil
+		}
+		return &fs.PathError{Op: "mkdir", Path: p, Err: fs.ErrExist}
+	}
+	now := v.now()
+	root := v.root
+	for i := range parts {
+		if i < len(parts)-1 && !parents {
+			continue
+		}
+		existing, err := lookup(root, parts[:i+1])
+		if err == nil {
+			if _, ok := existing.(*folder); !ok {
+				return &fs.PathError{Op: "mkdir", Path: p, Err: errNotDir}
+			}
+			if i == len(parts)-1 && !parents {
+				return &fs.PathError{Op: "mkdir", Path: p, Err: fs.ErrExist}
+			}
+			continue
+		}
+		name := parts[i]
+		root, err = rebuild(root, parts[:i], func(dir *folder) (*folder, error) {
+			return dir.with(newFolder(name, now), now), nil
+		})
+		if err != nil {
+			return &fs.PathError{Op: "mkdir", Path: p, Err: err}
+		}
+	}
+	v.root = root
+	return nil
+}
+
+// writeFile creates or replaces the file p. Its folder must exist.
+func (v *vfs) writeFile(p string, data []byte) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	parts := splitPath(p)
+	if len(parts) == 0 {
+		return &fs.PathError{Op: "write", Path: p, Err: errIsDir}
+	}
+	now := v.now()
+	name := parts[len(parts)-1]
+	f := &file{name: name, data: append([]byte(nil), data...), mtime: now}
+	root, err := rebuild(v.root, parts[:len(parts)-1], func(dir *folder) (*folder, error) {
+		if _, ok := dir.components[name].(*folder); ok {
+			return nil, errIsDir
+		}
+		return dir.with(f, now), nil
+	})
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: p, Err: err}
+	}
+	v.root = root
+	return nil
+}
+
+func (v *vfs) readFile(p string) ([]byte, error) {
+	c, err := v.stat(p)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := c.(*file)
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: p, Err: errIsDir}
+	}
+	return append([]byte(nil), f.data...), nil
+}
+
+// rm removes p. A non-empty folder is only removed with recursive.
+func (v *vfs) rm(p string, recursive bool) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	parts := splitPath(p)
+	if len(parts) == 0 {
+		return &fs.PathError{Op: "rm", Path: p, Err: fs.ErrInvalid}
+	}
+	c, err := lookup(v.root, parts)
+	if err != nil {
+		return &fs.PathError{Op: "rm", Path: p, Err: err}
+	}
+	if dir, ok := c.(*folder); ok && len(dir.components) > 0 && !recursive {
+		return &fs.PathError{Op: "rm", Path: p, Err: errNotEmpty}
+	}
+	now := v.now()
+	root, err := rebuild(v.root, parts[:len(parts)-1], func(dir *folder) (*folder, error) {
+		return dir.without(parts[len(parts)-1], now), nil
+	})
+	if err != nil {
+		return &fs.PathError{Op: "rm", Path: p, Err: err}
+	}
+	v.root = root
+	return nil
+}
+
+// mv moves src to dst. If dst is an existing folder, src moves into it.
+func (v *vfs) mv(src, dst string) error {
+	return v.transfer("mv", src, dst, true)
+}
+
+// cp copies src to dst, recursively for folders. The copy costs nothing
+// until one side is changed.
+func (v *vfs) cp(src, dst string) error {
+	return v.transfer("cp", src, dst, false)
+}
+
+func (v *vfs) transfer(op, src, dst string, remove bool) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	sparts, dparts := splitPath(src), splitPath(dst)
+	if len(sparts) == 0 {
+		return &fs.PathError{Op: op, Path: src, Err: fs.ErrInvalid}
+	}
+	node, err := lookup(v.root, sparts)
+	if err != nil {
+		return &fs.PathError{Op: op, Path: src, Err: err}
+	}
+	if target, err := lookup(v.root, dparts); err == nil {
+		if _, ok := target.(*folder); ok {
+			dparts = append(dparts, node.getName())
+		} else if _, ok := node.(*folder); ok {
+			return &fs.PathError{Op: op, Path: dst, Err: errNotDir}
+		}
+	}
+	if len(dparts) == 0 {
+		return &fs.PathError{Op: op, Path: dst, Err: fs.ErrExist}
+	}
+	if _, ok := node.(*folder); ok && hasPrefix(dparts, sparts) {
+		return &fs.PathError{Op: op, Path: dst, Err: errIntoSelf}
+	}
+	if existing, err := lookup(v.root, dparts); err == nil {
+		if _, ok := existing.(*folder); ok {
+			return &fs.PathError{Op: op, Path: dst, Err: fs.ErrExist}
+		}
+	}
+
+	now := v.now()
+	root := v.root
+	if remove {
+		if hasPrefix(dparts, sparts) { // moving onto itself
+			return nil
+		}
+		root, err = rebuild(root, sparts[:len(sparts)-1], func(dir *folder) (*folder, error) {
+			return dir.without(sparts[len(sparts)-1], now), nil
+		})
+		if err != nil {
+			return &fs.PathError{Op: op, Path: src, Err: err}
+		}
+	}
+	moved := node.clone(dparts[len(dparts)-1])
+	root, err = rebuild(root, dparts[:len(dparts)-1], func(dir *folder) (*folder, error) {
+		return dir.with(moved, now), nil
+	})
+	if err != nil {
+		return &fs.PathError{Op: op, Path: dst, Err: err}
+	}
+	v.root = root
+	return nil
+}
+
+func hasPrefix(p, prefix []string) bool {
+	if len(p) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if p[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// du returns the total size of everything at or below p.
+func (v *vfs) du(p string) (int64, error) {
+	c, err := v.stat(p)
+	if err != nil {
+		return 0, err
+	}
+	return c.size(), nil
+}
+
+func (v *vfs) print(w io.Writer) {
+	v.mu.RLock()
+	root := v.root
+	v.mu.RUnlock()
+	root.print(w, "")
+}
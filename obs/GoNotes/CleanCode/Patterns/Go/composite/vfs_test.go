@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func sample(t *testing.T) *vfs {
+	v := newVFS()
+	for _, err := range []error{
+		v.mkdir("/docs/notes", true),
+		v.mkdir("/src", false),
+		v.writeFile("/docs/readme.md", []byte("# Roses\nroses are red\n")),
+		v.writeFile("/docs/notes/todo.txt", []byte("buy violets\nwater the rose\n")),
+		v.writeFile("/src/main.go", []byte("package main\n")),
+		v.writeFile("/page.html", []byte(`{{define "page"}}Hello, {{.}}{{end}}`)),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return v
+}
+
+func TestFSConformance(t *testing.T) {
+	if err := fstest.TestFS(sample(t), "docs/readme.md", "docs/notes/todo.txt", "src/main.go", "page.html"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPathOperations(t *testing.T) {
+	v := sample(t)
+	for _, test := range []struct {
+		err  error
+		want error
+	}{
+		{v.mkdir("/docs", false), fs.ErrExist},
+		{v.mkdir("/missing/dir", false), fs.ErrNotExist},
+		{v.mkdir("/docs/readme.md/x", true), errNotDir},
+		{v.writeFile("/docs", nil), errIsDir},
+		{v.writeFile("/nope/file", nil), fs.ErrNotExist},
+		{v.rm("/docs", false), errNotEmpty},
+		{v.rm("/", true), fs.ErrInvalid},
+		{v.mv("/docs", "/docs/notes"), errIntoSelf},
+		{v.cp("/docs", "/docs/notes/copy"), errIntoSelf},
+		{v.mv("/missing", "/x"), fs.ErrNotExist},
+	} {
+		if !errors.Is(test.err, test.want) {
+			t.Errorf("got %v, want %v", test.err, test.want)
+		}
+	}
+
+	if err := v.mv("/docs/readme.md", "/src"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := v.readFile("/src/readme.md"); err != nil || !bytes.HasPrefix(data, []byte("# Roses")) {
+		t.Errorf("moved file: %q, %v", data, err)
+	}
+	if _, err := v.stat("/docs/readme.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("source still present: %v", err)
+	}
+
+	if err := v.cp("/docs", "/backup"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.writeFile("/backup/notes/todo.txt", []byte("changed")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := v.readFile("/docs/notes/todo.txt"); !bytes.HasPrefix(data, []byte("buy violets")) {
+		t.Errorf("writing the copy changed the original: %q", data)
+	}
+
+	if err := v.rm("/docs", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.mkdir("/a/b/c", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.mkdir("/a/b", true); err != nil {
+		t.Errorf("mkdir -p on existing dir: %v", err)
+	}
+}
+
+func TestSnapshotIsolation(t *testing.T) {
+	v := sample(t)
+	snap := v.snapshot()
+	v.writeFile("/docs/readme.md", []byte("rewritten"))
+	v.rm("/src", true)
+	v.mkdir("/new", false)
+
+	if data, _ := snap.readFile("/docs/readme.md"); !bytes.HasPrefix(data, []byte("# Roses")) {
+		t.Errorf("snapshot saw write: %q", data)
+	}
+	if _, err := snap.stat("/src/main.go"); err != nil {
+		t.Errorf("snapshot saw rm: %v", err)
+	}
+	if _, err := snap.stat("/new"); err == nil {
+		t.Error("snapshot saw mkdir")
+	}
+	snap.writeFile("/only-in-snapshot", nil)
+	if _, err := v.stat("/only-in-snapshot"); err == nil {
+		t.Error("original saw snapshot write")
+	}
+}
+
+func TestSizeRollup(t *testing.T) {
+	v := sample(t)
+	total := 0
+	fs.WalkDir(v, ".", func(p string, d fs.DirEntry, err error) error {
+		if !d.IsDir() {
+			info, _ := d.Info()
+			total += int(info.Size())
+		}
+		return nil
+	})
+	if n, _ := v.du("/"); n != int64(total) {
+		t.Errorf("du / = %d, walk found %d", n, total)
+	}
+	if n, _ := v.du("/docs/notes"); n != int64(len("buy violets\nwater the rose\n")) {
+		t.Errorf("du /docs/notes = %d", n)
+	}
+	v.rm("/docs", true)
+	if n, _ := v.du("/"); n != int64(len("package main\n")+len(`{{define "page"}}Hello, {{.}}{{end}}`)) {
+		t.Errorf("du / after rm = %d", n)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	v := sample(t)
+	got := func(root, glob, pattern string) []string {
+		ms, err := v.search(root, glob, pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, m := range ms {
+			out = append(out, m.String())
+		}
+		return out
+	}
+	if g := got("/", "*.txt", ""); !reflect.DeepEqual(g, []string{"/docs/notes/todo.txt"}) {
+		t.Errorf("glob search = %v", g)
+	}
+	if g := got("/", "", `(?i)\broses?\b`); !reflect.DeepEqual(g, []string{
+		"/docs/notes/todo.txt:2: water the rose",
+		"/docs/readme.md:1: # Roses",
+		"/docs/readme.md:2: roses are red",
+	}) {
+		t.Errorf("regexp search = %v", g)
+	}
+	if g := got("/docs", "*.md", "red"); !reflect.DeepEqual(g, []string{"/docs/readme.md:2: roses are red"}) {
+		t.Errorf("glob+regexp search = %v", g)
+	}
+	if _, err := v.search("/", "[", ""); err == nil {
+		t.Error("bad glob accepted")
+	}
+}
+
+func TestHTTPAndTemplates(t *testing.T) {
+	v := sample(t)
+	srv := httptest.NewServer(http.FileServer(http.FS(v)))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/src/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "package main\n" {
+		t.Errorf("served %q", body)
+	}
+
+	tmpl, err := template.ParseFS(v, "*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, "page", "rose"); err != nil || b.String() != "Hello, rose" {
+		t.Errorf("template: %q, %v", b.String(), err)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	const src = `{"dir": "root", "files": ["a.hack", "b"], "folders": [
+		{"dir": "x", "files": ["c"]},
+		{"dir": "y", "folders": [{"dir": "z", "files": ["d.hack"]}]}]}`
+	v, err := loadJSON(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms, _ := v.search("/", "*.hack", "")
+	if len(ms) != 2 || ms[0].path != "/a.hack" || ms[1].path != "/y/z/d.hack" {
+		t.Errorf("hack files = %v", ms)
+	}
+
+	var out bytes.Buffer
+	if err := v.saveJSON(&out, "root"); err != nil {
+		t.Fatal(err)
+	}
+	again, err := loadJSON(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a, b bytes.Buffer
+	v.print(&a)
+	again.print(&b)
+	if a.String() != b.String() {
+		t.Errorf("round trip changed tree:\n%s\nvs\n%s", a.String(), b.String())
+	}
+}
+
+// The Ozon task ships a large tree in this format; make sure it loads.
+func TestLoadOzonTree(t *testing.T) {
+	f, err := os.Open("../../../../../../Ozon/dir.json")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer f.Close()
+	v, err := loadJSON(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(v, "xpvmug", "lvoon/pbkyytznwgzzjm"); err != nil {
+		t.Fatal(err)
+	}
+}
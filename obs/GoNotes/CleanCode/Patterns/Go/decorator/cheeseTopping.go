@@ -1,10 +1,5 @@
 package main
 
-type cheeseTopping struct {
-	pizza pizza
-}
-
-func (c *cheeseTopping) getPrice() int {
-	pizzaPrice := c.pizza.getPrice()
-	return pizzaPrice + 10
+func newCheeseTopping(p pizza) *topping {
+	return &topping{pizza: p, name: "cheese", price: newMoney(fromInt(10), currencies["USD"])}
 }
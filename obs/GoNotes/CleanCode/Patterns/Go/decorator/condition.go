@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// condition gates a rule. When it does not hold it says why, for the
+// breakdown.
+type condition interface {
+	holds(o order) (ok bool, reason string)
+}
+
+// always is the condition of an unconditional rule.
+type always struct{}
+
+func (always) holds(order) (bool, string) { return true, "" }
+
+// timeWindow holds from from to to (time of day, to exclusive) on the
+// listed weekdays, or every day if none are listed. A window whose end is
+// before its start runs past midnight.
+type timeWindow struct {
+	from, to time.Duration // since midnight
+	days     []time.Weekday
+	loc      *time.Location // time.UTC if nil
+}
+
+func (w timeWindow) holds(o order) (bool, string) {
+	loc := w.loc
+	if loc == nil {
+		loc = time.UTC
+	}
+	at := o.at.In(loc)
+	y, m, d := at.Date()
+	since := at.Sub(time.Date(y, m, d, 0, 0, 0, 0, loc))
+	day := at.Weekday()
+	var in bool
+	if w.from <= w.to {
+		in = since >= w.from && since < w.to
+	} else if since >= w.from {
+		in = true
+	} else if since < w.to {
+		in, day = true, (day+6)%7 // the window opened yesterday
+	}
+	if in && (len(w.days) == 0 || slices.Contains(w.days, day)) {
+		return true, ""
+	}
+	return false, "only " + w.String()
+}
+
+func (w timeWindow) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	s := clock(w.from) + "-" + clock(w.to)
+	if len(w.days) > 0 {
+		names := make([]string, len(w.days))
+		for i, d := range w.days {
+			names[i] = d.String()[:3]
+		}
+		s += " " + strings.Join(names, ",")
+	}
+	return s
+}
+
+// minQuantity holds for orders of at least n units.
+type minQuantity int
+
+func (n minQuantity) holds(o order) (bool, string) {
+	if o.quantity >= int(n) {
+		return true, ""
+	}
+	return false, fmt.Sprintf("needs %d or more", int(n))
+}
+
+// allOf holds when every condition does.
+type allOf []condition
+
+func (all allOf) holds(o order) (bool, string) {
+	for _, c := range all {
+		if ok, reason := c.holds(o); !ok {
+			return false, reason
+		}
+	}
+	return true, ""
+}
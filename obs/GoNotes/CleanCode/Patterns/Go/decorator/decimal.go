@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// decimal is a fixed-point number with scale fractional digits, stored as
+// an integer count of 10^-scale. Prices, rates and percentages all use it,
+// so no amount ever passes through a float.
+type decimal int64
+
+const (
+	scale    = 4
+	decOne   = decimal(10000) // 10^scale
+	decHalf  = decOne / 2
+	maxScale = 18
+)
+
+// parseDecimal reads "12", "-0.5" or "8.875". More than scale fractional
+// digits is an error rather than a silent rounding.
+func parseDecimal(s string) (decimal, error) {
+	orig := s
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || len(frac) > scale || strings.ContainsAny(whole+frac, "+-") {
+		return 0, fmt.Errorf("bad decimal %q", orig)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	frac += strings.Repeat("0", scale-len(frac))
+	if len(whole) > maxScale-scale {
+		return 0, fmt.Errorf("decimal %q out of range", orig)
+	}
+	n, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad decimal %q", orig)
+	}
+	if neg {
+		n = -n
+	}
+	return decimal(n), nil
+}
+
+func mustDecimal(s string) decimal {
+	d, err := parseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// fromInt returns n as a decimal.
+func fromInt(n int64) decimal { return decimal(n) * decOne }
+
+// String prints d with trailing fractional zeros trimmed.
+func (d decimal) String() string {
+	s := d.format(scale)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// format prints d rounded to places fractional digits.
+func (d decimal) format(places int) string {
+	d = d.round(places)
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	whole, frac := int64(d/decOne), int64(d%decOne)
+	if places == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	f := fmt.Sprintf("%0*d", scale, frac)[:places]
+	return fmt.Sprintf("%s%d.%s", sign, whole, f)
+}
+
+// mulDiv returns d*m/div rounded half to even, computed without overflow.
+func (d decimal) mulDiv(m, div decimal) decimal {
+	num := new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(int64(m)))
+	return decimal(roundQuo(num, big.NewInt(int64(div))))
+}
+
+// mul multiplies two decimals.
+func (d decimal) mul(m decimal) decimal { return d.mulDiv(m, decOne) }
+
+// percent returns p percent of d.
+func (d decimal) percent(p decimal) decimal { return d.mulDiv(p, fromInt(100)) }
+
+// div divides d by an integer.
+func (d decimal) div(n int64) decimal {
+	return decimal(roundQuo(big.NewInt(int64(d)), big.NewInt(n)))
+}
+
+// round rounds to places fractional digits, half to even.
+func (d decimal) round(places int) decimal {
+	if places >= scale {
+		return d
+	}
+	unit := int64(1)
+	for range scale - places {
+		unit *= 10
+	}
+	return decimal(roundQuo(big.NewInt(int64(d)), big.NewInt(unit)) * unit)
+}
+
+// roundQuo is num/den rounded half to even.
+func roundQuo(num, den *big.Int) int64 {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	twice := new(big.Int).Abs(r)
+	twice.Lsh(twice, 1)
+	switch c := twice.Cmp(new(big.Int).Abs(den)); {
+	case c > 0, c == 0 && q.Bit(0) == 1:
+		if (num.Sign() < 0) != (den.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q.Int64()
+}
+
+// MarshalJSON writes a decimal as a JSON string to keep every digit.
+func (d decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts a string ("8.875") or a bare number (8.875); the
+// number is parsed from its text, never through float64.
+func (d *decimal) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if unq, err := strconv.Unquote(s); err == nil {
+		s = unq
+	}
+	v, err := parseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
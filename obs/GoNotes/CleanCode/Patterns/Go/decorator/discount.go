@@ -0,0 +1,77 @@
+package main
+
+import "fmt"
+
+// percentOff takes a percentage off the price so far.
+type percentOff struct {
+	pizza   pizza
+	name    string
+	percent decimal
+	when    condition
+}
+
+func (d *percentOff) getPrice(o order) (quote, error) {
+	q, err := d.pizza.getPrice(o)
+	if err != nil {
+		return quote{}, err
+	}
+	label := fmt.Sprintf("- %s %s%%", d.name, d.percent)
+	if ok, reason := d.when.holds(o); !ok {
+		return q.skip(label, reason), nil
+	}
+	return q.adjust(label, newMoney(q.total.amount.percent(d.percent), q.total.cur).neg(), "")
+}
+
+// fixedOff takes a fixed amount off the price so far, never going below
+// zero.
+type fixedOff struct {
+	pizza  pizza
+	name   string
+	amount money
+	when   condition
+}
+
+func (d *fixedOff) getPrice(o order) (quote, error) {
+	q, err := d.pizza.getPrice(o)
+	if err != nil {
+		return quote{}, err
+	}
+	label := fmt.Sprintf("- %s", d.name)
+	if ok, reason := d.when.holds(o); !ok {
+		return q.skip(label, reason), nil
+	}
+	if d.amount.cur != q.total.cur {
+		return quote{}, fmt.Errorf("%s: discount in %s on a price in %s", d.name, d.amount.cur.code, q.total.cur.code)
+	}
+	off, note := d.amount, ""
+	if off.amount > q.total.amount {
+		off, note = q.total, "capped at the price"
+	}
+	return q.adjust(label, off.neg(), note)
+}
+
+// buyXGetY makes y of every x+y units free, valued at the average unit
+// price so far.
+type buyXGetY struct {
+	pizza pizza
+	buy   int
+	get   int
+	when  condition
+}
+
+func (d *buyXGetY) getPrice(o order) (quote, error) {
+	q, err := d.pizza.getPrice(o)
+	if err != nil {
+		return quote{}, err
+	}
+	label := fmt.Sprintf("- buy %d get %d free", d.buy, d.get)
+	if ok, reason := d.when.holds(o); !ok {
+		return q.skip(label, reason), nil
+	}
+	free := o.quantity / (d.buy + d.get) * d.get
+	if free == 0 {
+		return q.skip(label, fmt.Sprintf("needs %d or more", d.buy+d.get)), nil
+	}
+	unit := q.total.amount.div(int64(o.quantity))
+	return q.adjust(label, newMoney(unit*decimal(free), q.total.cur).neg(), fmt.Sprintf("%d free at %s", free, newMoney(unit, q.total.cur)))
+}
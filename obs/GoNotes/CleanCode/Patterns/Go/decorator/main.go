@@ -1,20 +1,46 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
 
 func main() {
 
-	pizza := &veggeMania{}
+	pizza := newVeggeMania()
 
 	//Add cheese topping
-	pizzaWithCheese := &cheeseTopping{
-		pizza: pizza,
-	}
+	pizzaWithCheese := newCheeseTopping(pizza)
 
 	//Add tomato topping
-	pizzaWithCheeseAndTomato := &tomatoTopping{
-		pizza: pizzaWithCheese,
+	pizzaWithCheeseAndTomato := newTomatoTopping(pizzaWithCheese)
+
+	q, err := pizzaWithCheeseAndTomato.getPrice(order{quantity: 1, at: time.Now()})
+	if err != nil {
+		log.Fatal(err)
 	}
+	fmt.Printf("Price of veggeMania with tomato and cheese topping is %s\n%s\n", q.total, q)
 
-	fmt.Printf("Price of veggeMania with tomato and cheese topping is %d\n", pizzaWithCheeseAndTomato.getPrice())
+	f, err := os.Open("rules.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	items, err := loadRules(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tuesdayNoon := time.Date(2024, 3, 12, 12, 30, 0, 0, time.UTC)
+	saturdayNight := time.Date(2024, 3, 16, 23, 15, 0, 0, time.UTC)
+	for _, item := range items {
+		for _, o := range []order{{quantity: 3, at: tuesdayNoon}, {quantity: 2, at: saturdayNight}} {
+			q, err := item.pizza.getPrice(o)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("%s, %d on %s:\n%s\n", item.name, o.quantity, o.at.Format("Mon 15:04"), q)
+		}
+	}
 }
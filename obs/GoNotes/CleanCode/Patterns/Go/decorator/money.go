@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// currency is an ISO 4217 code with the number of minor-unit digits
+// amounts in it are rounded to.
+type currency struct {
+	code  string
+	minor int
+}
+
+var currencies = map[string]currency{
+	"USD": {"USD", 2},
+	"EUR": {"EUR", 2},
+	"GBP": {"GBP", 2},
+	"JPY": {"JPY", 0},
+	"KWD": {"KWD", 3},
+}
+
+func lookupCurrency(code string) (currency, error) {
+	c, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return currency{}, fmt.Errorf("unknown currency %q", code)
+	}
+	return c, nil
+}
+
+// money is an amount in a currency, always rounded to its minor units.
+type money struct {
+	amount decimal
+	cur    currency
+}
+
+func newMoney(amount decimal, cur currency) money {
+	return money{amount: amount.round(cur.minor), cur: cur}
+}
+
+// parseMoney reads "12.50 USD".
+func parseMoney(s string) (money, error) {
+	amt, code, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok {
+		return money{}, fmt.Errorf("money %q: want \"<amount> <currency>\"", s)
+	}
+	d, err := parseDecimal(amt)
+	if err != nil {
+		return money{}, err
+	}
+	cur, err := lookupCurrency(code)
+	if err != nil {
+		return money{}, err
+	}
+	if d.round(cur.minor) != d {
+		return money{}, fmt.Errorf("money %q: more precision than %s allows", s, cur.code)
+	}
+	return newMoney(d, cur), nil
+}
+
+func (m money) add(o money) (money, error) {
+	if m.cur != o.cur {
+		return money{}, fmt.Errorf("cannot add %s to %s", o.cur.code, m.cur.code)
+	}
+	return money{amount: m.amount + o.amount, cur: m.cur}, nil
+}
+
+func (m money) neg() money { return money{amount: -m.amount, cur: m.cur} }
+
+func (m money) isZero() bool { return m.amount == 0 }
+
+func (m money) String() string {
+	return m.amount.format(m.cur.minor) + " " + m.cur.code
+}
+
+func (m money) MarshalJSON() ([]byte, error) { return json.Marshal(m.String()) }
+
+func (m *money) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := parseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
@@ -1,5 +1,77 @@
 package main
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// pizza is anything with a price: a base product or a decorator layered
+// on one. Each layer prices its inner pizza and then adds its own line.
 type pizza interface {
-	getPrice() int
+	getPrice(o order) (quote, error)
+}
+
+// order is the context a price depends on.
+type order struct {
+	quantity int
+	at       time.Time
+}
+
+// quote is a price with the lines that produced it. total is always the
+// sum of the line amounts, converted at any currency lines.
+type quote struct {
+	lines []line
+	total money
+}
+
+// line is one step of the breakdown. A rule that did not apply still gets
+// a line, with a zero amount and the reason in note.
+type line struct {
+	label  string
+	amount money
+	note   string
+	after  money // running total after this line
+	rate   bool  // a currency conversion: no amount, after is in the new currency
+}
+
+// adjust appends a line and moves the total by amount.
+func (q quote) adjust(label string, amount money, note string) (quote, error) {
+	total, err := q.total.add(amount)
+	if err != nil {
+		return quote{}, fmt.Errorf("%s: %w", label, err)
+	}
+	q.lines = append(q.lines[:len(q.lines):len(q.lines)], line{label: label, amount: amount, note: note, after: total})
+	q.total = total
+	return q, nil
+}
+
+// skip records a rule that did not apply.
+func (q quote) skip(label, reason string) quote {
+	q.lines = append(q.lines[:len(q.lines):len(q.lines)], line{label: label, amount: newMoney(0, q.total.cur), note: reason, after: q.total})
+	return q
+}
+
+func (q quote) String() string {
+	var b strings.Builder
+	for _, l := range q.lines {
+		amount := signed(l.amount)
+		if l.rate {
+			amount = ""
+		}
+		fmt.Fprintf(&b, "  %-28s %14s  = %14s", l.label, amount, l.after)
+		if l.note != "" {
+			fmt.Fprintf(&b, "  (%s)", l.note)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "  %-28s %14s  = %14s\n", "total", "", q.total)
+	return b.String()
+}
+
+func signed(m money) string {
+	if m.amount >= 0 {
+		return "+" + m.String()
+	}
+	return m.String()
 }
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	usd = currencies["USD"]
+	eur = currencies["EUR"]
+	jpy = currencies["JPY"]
+)
+
+func usdm(s string) money { return newMoney(mustDecimal(s), usd) }
+
+// Layer constructors so test tables read in the order layers are applied.
+type layer func(pizza) pizza
+
+func stack(base pizza, layers ...layer) pizza {
+	for _, l := range layers {
+		base = l(base)
+	}
+	return base
+}
+
+func pct(p string) layer {
+	return func(in pizza) pizza {
+		return &percentOff{pizza: in, name: "pct", percent: mustDecimal(p), when: always{}}
+	}
+}
+func off(a string) layer {
+	return func(in pizza) pizza { return &fixedOff{pizza: in, name: "off", amount: usdm(a), when: always{}} }
+}
+func add(a string) layer {
+	return func(in pizza) pizza { return &topping{pizza: in, name: "extra", price: usdm(a)} }
+}
+func taxed(p string) layer {
+	return func(in pizza) pizza { return &tax{pizza: in, name: "tax", percent: mustDecimal(p)} }
+}
+func bogo(buy, get int) layer {
+	return func(in pizza) pizza { return &buyXGetY{pizza: in, buy: buy, get: get, when: always{}} }
+}
+func to(c currency, rate string) layer {
+	return func(in pizza) pizza { return &exchange{pizza: in, to: c, rate: mustDecimal(rate)} }
+}
+
+func TestStackingOrder(t *testing.T) {
+	base := &product{name: "base", price: usdm("20.00")}
+	for _, test := range []struct {
+		name   string
+		qty    int
+		layers []layer
+		want   string
+	}{
+		{"percent then fixed", 1, []layer{pct("10"), off("5")}, "13.00 USD"},
+		{"fixed then percent", 1, []layer{off("5"), pct("10")}, "13.50 USD"},
+		{"discount then tax", 1, []layer{pct("25"), taxed("10")}, "16.50 USD"},
+		{"tax then discount", 1, []layer{taxed("10"), pct("25")}, "16.50 USD"},
+		{"fixed then tax", 1, []layer{off("5"), taxed("10")}, "16.50 USD"},
+		{"tax then fixed", 1, []layer{taxed("10"), off("5")}, "17.00 USD"},
+		{"topping then bogo", 3, []layer{add("1.00"), bogo(2, 1)}, "42.00 USD"},
+		{"bogo then topping", 3, []layer{bogo(2, 1), add("1.00")}, "43.00 USD"},
+		{"bogo needs a full set", 2, []layer{bogo(2, 1)}, "40.00 USD"},
+		{"bogo counts whole sets", 7, []layer{bogo(2, 1)}, "100.00 USD"},
+		{"fixed capped at zero", 1, []layer{off("50")}, "0.00 USD"},
+		{"exchange then round", 1, []layer{taxed("8.875"), to(jpy, "151.37")}, "3297 JPY"},
+		{"half to even down", 1, []layer{off("19.75"), pct("10")}, "0.23 USD"}, // 0.025 off rounds to 0.02
+		{"half to even up", 1, []layer{off("19.85"), pct("10")}, "0.13 USD"},   // 0.015 off rounds to 0.02
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := stack(base, test.layers...).getPrice(order{quantity: test.qty})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := q.total.String(); got != test.want {
+				t.Errorf("total %s, want %s\n%s", got, test.want, q)
+			}
+			// The breakdown always adds up.
+			sum := newMoney(0, usd)
+			for _, l := range q.lines {
+				if l.rate {
+					sum = l.after
+					continue
+				}
+				if sum, err = sum.add(l.amount); err != nil {
+					t.Fatal(err)
+				}
+				if sum != l.after {
+					t.Errorf("line %q: running total %s, recorded %s", l.label, sum, l.after)
+				}
+			}
+			if sum != q.total {
+				t.Errorf("lines sum to %s, total %s", sum, q.total)
+			}
+		})
+	}
+}
+
+func TestCurrencyMismatch(t *testing.T) {
+	p := stack(&product{name: "base", price: usdm("10.00")}, to(eur, "0.9"), off("1.00"))
+	if _, err := p.getPrice(order{quantity: 1}); err == nil || !strings.Contains(err.Error(), "USD on a price in EUR") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestConditions(t *testing.T) {
+	at := func(day, hh, mm int) order {
+		return order{quantity: 1, at: time.Date(2024, 3, day, hh, mm, 0, 0, time.UTC)} // 11th is a Monday
+	}
+	lunch := timeWindow{from: 11 * time.Hour, to: 14 * time.Hour, days: []time.Weekday{time.Monday}}
+	night := timeWindow{from: 22 * time.Hour, to: 2 * time.Hour, days: []time.Weekday{time.Friday}}
+	for _, test := range []struct {
+		name string
+		c    condition
+		o    order
+		want bool
+	}{
+		{"lunch start", lunch, at(11, 11, 0), true},
+		{"lunch end exclusive", lunch, at(11, 14, 0), false},
+		{"lunch wrong day", lunch, at(12, 12, 0), false},
+		{"night before midnight", night, at(15, 23, 0), true},
+		{"night after midnight belongs to friday", night, at(16, 1, 30), true},
+		{"night after midnight on friday is thursday's", night, at(15, 1, 30), false},
+		{"min quantity", minQuantity(2), at(11, 12, 0), false},
+		{"all of", allOf{lunch, minQuantity(1)}, at(11, 12, 0), true},
+	} {
+		if got, reason := test.c.holds(test.o); got != test.want {
+			t.Errorf("%s: holds = %v (%s)", test.name, got, reason)
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"12", "12"}, {"-0.5", "-0.5"}, {".25", "0.25"}, {"8.875", "8.875"}, {"1.0000", "1"},
+	} {
+		d, err := parseDecimal(test.in)
+		if err != nil || d.String() != test.want {
+			t.Errorf("parse %q = %v, %v", test.in, d, err)
+		}
+	}
+	for _, bad := range []string{"", "-", "1.23456", "1e3", "--1", "1.-2", "99999999999999999"} {
+		if _, err := parseDecimal(bad); err == nil {
+			t.Errorf("parse %q succeeded", bad)
+		}
+	}
+	if got := mustDecimal("-2.5").round(0).String(); got != "-2" {
+		t.Errorf("round(-2.5) = %s", got)
+	}
+	if got := mustDecimal("-3.5").round(0).String(); got != "-4" {
+		t.Errorf("round(-3.5) = %s", got)
+	}
+	if _, err := parseMoney("1.005 USD"); err == nil {
+		t.Error("sub-cent USD accepted")
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	items, err := loadRules(strings.NewReader(`[{"name": "x",
+		"base": {"product": "p", "price": "10.00 USD"},
+		"layers": [{"type": "percentOff", "name": "happy", "percent": 50,
+		            "when": {"from": "16:00", "to": "18:00", "minQuantity": 2}},
+		           {"type": "tax", "name": "t", "percent": "10"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := items[0].pizza.getPrice(order{quantity: 2, at: time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC)})
+	if err != nil || q.total.String() != "11.00 USD" {
+		t.Errorf("total %v, %v\n%s", q.total, err, q)
+	}
+
+	for _, bad := range []struct{ rules, err string }{
+		{`[{"name": "x", "base": {"product": "p", "price": "10 USD"}, "layers": [{"type": "magic"}]}]`, "unknown layer type"},
+		{`[{"name": "x", "base": {"product": "p", "price": "10 XXX"}}]`, "unknown currency"},
+		{`[{"name": "x", "base": {"product": "p", "price": "10 USD"}, "layers": [{"type": "percentOff", "percent": "150"}]}]`, "out of range"},
+		{`[{"name": "x", "base": {"product": "p", "price": "10 USD"}, "layers": [{"type": "tax", "percent": "5", "when": {"minQuantity": 2}}]}]`, "cannot be conditional"},
+		{`[{"name": "x", "base": {"product": "p", "price": "10 USD"}, "layers": [{"type": "fixedOff", "when": {"from": "25:00", "to": "01:00"}}]}]`, "HH:MM"},
+		{`[{"name": "x", "colour": "red"}]`, "unknown field"},
+	} {
+		if _, err := loadRules(strings.NewReader(bad.rules)); err == nil || !strings.Contains(err.Error(), bad.err) {
+			t.Errorf("%s: err = %v, want %q", bad.rules, err, bad.err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// menuItem is a named pizza built from JSON rules.
+type menuItem struct {
+	name  string
+	pizza pizza
+}
+
+// The JSON shape of a rules file:
+//
+//	[{"name": "lunch special",
+//	  "base": {"product": "veggeMania", "price": "15.00 USD"},
+//	  "layers": [
+//	    {"type": "topping", "name": "cheese", "price": "10.00 USD"},
+//	    {"type": "percentOff", "name": "lunch", "percent": "20",
+//	     "when": {"from": "11:00", "to": "14:00", "days": ["Mon", "Tue"]}},
+//	    {"type": "fixedOff", "name": "coupon", "amount": "5.00 USD"},
+//	    {"type": "buyXGetY", "buy": 2, "get": 1},
+//	    {"type": "tax", "name": "sales tax", "percent": "8.875"},
+//	    {"type": "exchange", "to": "EUR", "rate": "0.92"}]}]
+//
+// Layers wrap the base in order, so the first layer is applied first.
+type itemSpec struct {
+	Name string `json:"name"`
+	Base struct {
+		Product string `json:"product"`
+		Price   money  `json:"price"`
+	} `json:"base"`
+	Layers []layerSpec `json:"layers"`
+}
+
+type layerSpec struct {
+	Type    string    `json:"type"`
+	Name    string    `json:"name"`
+	Price   money     `json:"price"`
+	Amount  money     `json:"amount"`
+	Percent decimal   `json:"percent"`
+	Buy     int       `json:"buy"`
+	Get     int       `json:"get"`
+	To      string    `json:"to"`
+	Rate    decimal   `json:"rate"`
+	When    *whenSpec `json:"when"`
+}
+
+type whenSpec struct {
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	Days        []string `json:"days"`
+	Zone        string   `json:"zone"`
+	MinQuantity int      `json:"minQuantity"`
+}
+
+// loadRules reads a rules file and builds its decorator chains.
+func loadRules(r io.Reader) ([]menuItem, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var specs []itemSpec
+	if err := dec.Decode(&specs); err != nil {
+		return nil, fmt.Errorf("rules: %w", err)
+	}
+	items := make([]menuItem, 0, len(specs))
+	for _, spec := range specs {
+		p, err := spec.build()
+		if err != nil {
+			return nil, fmt.Errorf("rules: %s: %w", spec.Name, err)
+		}
+		items = append(items, menuItem{name: spec.Name, pizza: p})
+	}
+	return items, nil
+}
+
+func (spec itemSpec) build() (pizza, error) {
+	if spec.Base.Product == "" || spec.Base.Price.cur.code == "" {
+		return nil, fmt.Errorf("base needs a product and a price")
+	}
+	var p pizza = &product{name: spec.Base.Product, price: spec.Base.Price}
+	for i, l := range spec.Layers {
+		var err error
+		if p, err = l.wrap(p); err != nil {
+			return nil, fmt.Errorf("layer %d (%s): %w", i, l.Type, err)
+		}
+	}
+	return p, nil
+}
+
+func (l layerSpec) wrap(p pizza) (pizza, error) {
+	when, err := l.When.condition()
+	if err != nil {
+		return nil, err
+	}
+	if l.When != nil && (l.Type == "topping" || l.Type == "tax" || l.Type == "exchange") {
+		return nil, fmt.Errorf("%s layers cannot be conditional", l.Type)
+	}
+	switch l.Type {
+	case "topping":
+		return &topping{pizza: p, name: l.Name, price: l.Price}, nil
+	case "percentOff":
+		if l.Percent <= 0 || l.Percent > fromInt(100) {
+			return nil, fmt.Errorf("percent %s out of range", l.Percent)
+		}
+		return &percentOff{pizza: p, name: l.Name, percent: l.Percent, when: when}, nil
+	case "fixedOff":
+		return &fixedOff{pizza: p, name: l.Name, amount: l.Amount, when: when}, nil
+	case "buyXGetY":
+		if l.Buy < 1 || l.Get < 1 {
+			return nil, fmt.Errorf("buy %d get %d", l.Buy, l.Get)
+		}
+		return &buyXGetY{pizza: p, buy: l.Buy, get: l.Get, when: when}, nil
+	case "tax":
+		if l.Percent < 0 {
+			return nil, fmt.Errorf("negative tax %s", l.Percent)
+		}
+		return &tax{pizza: p, name: l.Name, percent: l.Percent}, nil
+	case "exchange":
+		cur, err := lookupCurrency(l.To)
+		if err != nil {
+			return nil, err
+		}
+		if l.Rate <= 0 {
+			return nil, fmt.Errorf("rate %s", l.Rate)
+		}
+		return &exchange{pizza: p, to: cur, rate: l.Rate}, nil
+	}
+	return nil, fmt.Errorf("unknown layer type %q", l.Type)
+}
+
+func (w *whenSpec) condition() (condition, error) {
+	if w == nil {
+		return always{}, nil
+	}
+	var all allOf
+	if w.From != "" || w.To != "" {
+		win := timeWindow{}
+		var err error
+		if win.from, err = parseClock(w.From); err != nil {
+			return nil, err
+		}
+		if win.to, err = parseClock(w.To); err != nil {
+			return nil, err
+		}
+		for _, d := range w.Days {
+			day, err := parseWeekday(d)
+			if err != nil {
+				return nil, err
+			}
+			win.days = append(win.days, day)
+		}
+		if w.Zone != "" {
+			if win.loc, err = time.LoadLocation(w.Zone); err != nil {
+				return nil, err
+			}
+		}
+		all = append(all, win)
+	} else if len(w.Days) > 0 || w.Zone != "" {
+		return nil, fmt.Errorf("days and zone need from and to")
+	}
+	if w.MinQuantity > 0 {
+		all = append(all, minQuantity(w.MinQuantity))
+	}
+	if len(all) == 1 {
+		return all[0], nil
+	}
+	return all, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("time of day %q: want HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()[:3]) || strings.EqualFold(s, d.String()) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", s)
+}
@@ -0,0 +1,43 @@
+package main
+
+import "fmt"
+
+// tax adds a percentage of the price so far.
+type tax struct {
+	pizza   pizza
+	name    string
+	percent decimal
+}
+
+func (t *tax) getPrice(o order) (quote, error) {
+	q, err := t.pizza.getPrice(o)
+	if err != nil {
+		return quote{}, err
+	}
+	return q.adjust(fmt.Sprintf("+ %s %s%%", t.name, t.percent), newMoney(q.total.amount.percent(t.percent), q.total.cur), "")
+}
+
+// exchange converts the price so far into another currency. Lines after
+// it are in the new currency.
+type exchange struct {
+	pizza pizza
+	to    currency
+	rate  decimal // units of to per unit of the source currency
+}
+
+func (e *exchange) getPrice(o order) (quote, error) {
+	q, err := e.pizza.getPrice(o)
+	if err != nil {
+		return quote{}, err
+	}
+	from := q.total
+	q.total = newMoney(from.amount.mul(e.rate), e.to)
+	q.lines = append(q.lines[:len(q.lines):len(q.lines)], line{
+		label:  fmt.Sprintf("= in %s", e.to.code),
+		amount: newMoney(0, e.to),
+		note:   fmt.Sprintf("%s at %s", from, e.rate),
+		after:  q.total,
+		rate:   true,
+	})
+	return q, nil
+}
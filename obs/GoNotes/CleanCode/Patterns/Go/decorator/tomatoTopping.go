@@ -1,10 +1,5 @@
 package main
 
-type tomatoTopping struct {
-	pizza pizza
-}
-
-func (c *tomatoTopping) getPrice() int {
-	pizzaPrice := c.pizza.getPrice()
-	return pizzaPrice + 7
+func newTomatoTopping(p pizza) *topping {
+	return &topping{pizza: p, name: "tomato", price: newMoney(fromInt(7), currencies["USD"])}
 }
@@ -0,0 +1,18 @@
+package main
+
+import "fmt"
+
+// topping adds a fixed per-unit price to the pizza it wraps.
+type topping struct {
+	pizza pizza
+	name  string
+	price money
+}
+
+func (t *topping) getPrice(o order) (quote, error) {
+	q, err := t.pizza.getPrice(o)
+	if err != nil {
+		return quote{}, err
+	}
+	return q.adjust(fmt.Sprintf("+ %s x%d", t.name, o.quantity), newMoney(t.price.amount*decimal(o.quantity), t.price.cur), "")
+}
@@ -1,8 +1,21 @@
 package main
 
-type veggeMania struct {
+import "fmt"
+
+// product is a base pizza priced per unit.
+type product struct {
+	name  string
+	price money
+}
+
+func (p *product) getPrice(o order) (quote, error) {
+	if o.quantity < 1 {
+		return quote{}, fmt.Errorf("%s: quantity %d", p.name, o.quantity)
+	}
+	q := quote{total: newMoney(0, p.price.cur)}
+	return q.adjust(fmt.Sprintf("%s x%d", p.name, o.quantity), newMoney(p.price.amount*decimal(o.quantity), p.price.cur), "")
 }
 
-func (p *veggeMania) getPrice() int {
-	return 15
+func newVeggeMania() *product {
+	return &product{name: "veggeMania", price: newMoney(fromInt(15), currencies["USD"])}
 }
@@ -1,9 +1,23 @@
 package main
 
+import "patterns/registry"
+
 type ak47 struct {
 	gun
 }
 
+func init() {
+	guns.MustRegister(registry.Metadata{
+		Name:        "ak47",
+		Version:     "1.0.0",
+		Description: "Automatic rifle",
+	}, func(o registry.Options) (iGun, error) {
+		g := newAk47()
+		g.setPower(o.Int("power", g.getPower()))
+		return g, nil
+	})
+}
+
 func newAk47() iGun {
 	return &ak47{
 		gun: gun{
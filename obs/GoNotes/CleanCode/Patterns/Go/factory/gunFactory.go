@@ -1,13 +1,11 @@
 package main
 
-import "fmt"
+import "patterns/registry"
+
+// guns holds every gun type; each one registers itself from an init
+// function in its own file.
+var guns = registry.New[iGun]("gun")
 
 func getGun(gunType string) (iGun, error) {
-	if gunType == "ak47" {
-		return newAk47(), nil
-	}
-	if gunType == "musket" {
-		return newMusket(), nil
-	}
-	return nil, fmt.Errorf("Wrong gun type passed")
+	return guns.New(gunType, nil)
 }
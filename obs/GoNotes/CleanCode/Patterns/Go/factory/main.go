@@ -1,6 +1,10 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+
+	"patterns/registry"
+)
 
 func main() {
 	ak47, _ := getGun("ak47")
@@ -8,6 +12,33 @@ func main() {
 
 	printDetails(ak47)
 	printDetails(musket)
+
+	fmt.Println()
+	for _, m := range guns.List() {
+		fmt.Printf("Available: %s %s (%s)\n", m.Name, m.Version, m.Description)
+	}
+
+	if _, err := getGun("ak74"); err != nil {
+		fmt.Println(err)
+	}
+
+	specs, err := registry.ParseYAML([]byte(`
+- name: musket
+  options:
+    power: 2
+`))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	configured, err := guns.BuildAll(specs)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, g := range configured {
+		printDetails(g)
+	}
 }
 
 func printDetails(g iGun) {
@@ -1,9 +1,23 @@
 package main
 
+import "patterns/registry"
+
 type musket struct {
 	gun
 }
 
+func init() {
+	guns.MustRegister(registry.Metadata{
+		Name:        "musket",
+		Version:     "1.0.0",
+		Description: "Muzzle-loaded long gun",
+	}, func(o registry.Options) (iGun, error) {
+		g := newMusket()
+		g.setPower(o.Int("power", g.getPower()))
+		return g, nil
+	})
+}
+
 func newMusket() iGun {
 	return &musket{
 		gun: gun{
@@ -1,13 +1,18 @@
 package main
 
 type counterTerroristDress struct {
-	color string
+	color   string
+	texture []byte
 }
 
 func (c *counterTerroristDress) getColor() string {
 	return c.color
 }
 
+func (c *counterTerroristDress) getTexture() []byte {
+	return c.texture
+}
+
 func newCounterTerroristDress() *counterTerroristDress {
-	return &counterTerroristDress{color: "green"}
+	return &counterTerroristDress{color: "green", texture: weave('C', textureSize)}
 }
@@ -1,5 +1,19 @@
 package main
 
+// dress is the flyweight: intrinsic state shared by every player wearing
+// it. The texture is what makes sharing worth it.
 type dress interface {
 	getColor() string
+	getTexture() []byte
 }
+
+// weave fills a texture of n bytes with a pattern derived from seed.
+func weave(seed byte, n int) []byte {
+	t := make([]byte, n)
+	for i := range t {
+		t[i] = seed ^ byte(i*31)
+	}
+	return t
+}
+
+const textureSize = 16 << 10
@@ -1,6 +1,10 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
 
 const (
 	//TerroristDressType terrorist dress type
@@ -10,30 +14,68 @@ const (
 )
 
 var (
-	dressFactorySingleInstance = &dressFactory{
-		dressMap: make(map[string]dress),
+	dressTypesMu sync.RWMutex
+	dressTypes   = map[string]func() dress{
+		TerroristDressType:         func() dress { return newTerroristDress() },
+		CounterTerrroristDressType: func() dress { return newCounterTerroristDress() },
 	}
 )
 
-type dressFactory struct {
-	dressMap map[string]dress
+// registerDressType makes a new dress available to every factory.
+func registerDressType(dressType string, create func() dress) {
+	dressTypesMu.Lock()
+	defer dressTypesMu.Unlock()
+	dressTypes[dressType] = create
 }
 
-func (d *dressFactory) getDressByType(dressType string) (dress, error) {
-	if d.dressMap[dressType] != nil {
-		return d.dressMap[dressType], nil
+// knownDressTypes lists the registered dress types in order.
+func knownDressTypes() []string {
+	dressTypesMu.RLock()
+	defer dressTypesMu.RUnlock()
+	types := make([]string, 0, len(dressTypes))
+	for t := range dressTypes {
+		types = append(types, t)
 	}
+	slices.Sort(types)
+	return types
+}
 
-	if dressType == TerroristDressType {
-		d.dressMap[dressType] = newTerroristDress()
-		return d.dressMap[dressType], nil
-	}
-	if dressType == CounterTerrroristDressType {
-		d.dressMap[dressType] = newCounterTerroristDress()
-		return d.dressMap[dressType], nil
+var (
+	dressFactorySingleInstance = newDressFactory()
+)
+
+type dressFactory struct {
+	dresses *Intern[string, sharedDress]
+}
+
+// A sharedDress is the factory's entry for one dress type. Players wear the
+// *sharedDress itself, so the entry stays alive for as long as anyone does.
+type sharedDress struct{ dress }
+
+func newDressFactory() *dressFactory {
+	return &dressFactory{
+		dresses: NewIntern(func(dressType string) (*sharedDress, error) {
+			dressTypesMu.RLock()
+			create, ok := dressTypes[dressType]
+			dressTypesMu.RUnlock()
+			if !ok {
+				return nil, fmt.Errorf("wrong dress type %q", dressType)
+			}
+			return &sharedDress{create()}, nil
+		}, func(d *sharedDress) int {
+			return len(d.getTexture()) + len(d.getColor())
+		}),
 	}
+}
 
-	return nil, fmt.Errorf("Wrong dress type passed")
+// getDressByType returns the shared dress. A dress nobody wears any more
+// is reclaimed and rebuilt on next use.
+func (d *dressFactory) getDressByType(dressType string) (dress, error) {
+	sd, err := d.dresses.Get(dressType)
+	if err != nil {
+		return nil, err
+	}
+	return sd, nil
 }
 
 func getDressFactorySingleInstance() *dressFactory {
@@ -3,23 +3,68 @@ package main
 type game struct {
 	terrorists        []*player
 	counterTerrorists []*player
+
+	// Profile data arrives as raw bytes from each client. With interning
+	// on, players with the same clan tag or spray share one copy.
+	clans  *Intern[string, string]
+	sprays *Intern[string, []byte]
 }
 
 func newGame() *game {
+	return &game{}
+}
+
+// newInterningGame returns a game that interns player profiles.
+func newInterningGame() *game {
 	return &game{
-		terrorists:        make([]*player, 1),
-		counterTerrorists: make([]*player, 1),
+		clans:  NewStringIntern(),
+		sprays: NewBytesIntern(),
 	}
 }
 
-func (c *game) addTerrorist(dressType string) {
-	player := newPlayer("T", dressType)
+func (c *game) addTerrorist(dressType string) error {
+	player, err := newPlayer("T", dressType)
+	if err != nil {
+		return err
+	}
 	c.terrorists = append(c.terrorists, player)
-	return
+	return nil
 }
 
-func (c *game) addCounterTerrorist(dressType string) {
-	player := newPlayer("CT", dressType)
+func (c *game) addCounterTerrorist(dressType string) error {
+	player, err := newPlayer("CT", dressType)
+	if err != nil {
+		return err
+	}
 	c.counterTerrorists = append(c.counterTerrorists, player)
-	return
+	return nil
+}
+
+// join adds a player with the profile their client sent.
+func (c *game) join(team string, clan, spray []byte) error {
+	dressType, add := TerroristDressType, &c.terrorists
+	if team == "CT" {
+		dressType, add = CounterTerrroristDressType, &c.counterTerrorists
+	}
+	p, err := newPlayer(team, dressType)
+	if err != nil {
+		return err
+	}
+	if c.clans != nil {
+		if p.clan, err = c.clans.Get(string(clan)); err != nil {
+			return err
+		}
+		if p.spray, err = c.sprays.Get(string(spray)); err != nil {
+			return err
+		}
+	} else {
+		tag, art := string(clan), append([]byte(nil), spray...)
+		p.clan, p.spray = &tag, &art
+	}
+	*add = append(*add, p)
+	return nil
+}
+
+func (c *game) players() int {
+	return len(c.terrorists) + len(c.counterTerrorists)
 }
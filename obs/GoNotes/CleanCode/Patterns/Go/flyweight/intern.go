@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"unsafe"
+	"weak"
+)
+
+// Intern hands out one shared *V per key. Values are built on first use,
+// at most once per key however many goroutines ask at the same moment, and
+// held only weakly: once no caller keeps the pointer the garbage collector
+// may reclaim the value, and the next Get builds a fresh one.
+//
+// Callers must keep the returned pointer, not a copy of the value, for the
+// sharing to last, and must treat the value as read-only.
+type Intern[K comparable, V any] struct {
+	create func(K) (*V, error)
+	size   func(*V) int
+
+	mu       sync.Mutex
+	entries  map[K]weak.Pointer[V]
+	inflight map[K]*internCall[V]
+	stats    InternStats
+}
+
+type internCall[V any] struct {
+	done chan struct{}
+	v    *V
+	err  error
+}
+
+// InternStats is a snapshot of an Intern's counters.
+type InternStats struct {
+	Hits       int64 // Gets served from the table
+	Misses     int64 // Gets that built a value, including failed builds
+	Shared     int64 // Gets that waited on another goroutine's build
+	Reclaimed  int64 // values collected after their last user let go
+	Live       int   // values currently in the table
+	BytesSaved int64 // sum of size(v) over Gets that did not build v
+}
+
+func (s InternStats) String() string {
+	return fmt.Sprintf("hits=%d misses=%d shared=%d reclaimed=%d live=%d saved=%s",
+		s.Hits, s.Misses, s.Shared, s.Reclaimed, s.Live, formatBytes(s.BytesSaved))
+}
+
+// NewIntern returns a table that builds values with create. size reports
+// how many bytes a value occupies, for BytesSaved; nil means the shallow
+// size of V.
+func NewIntern[K comparable, V any](create func(K) (*V, error), size func(*V) int) *Intern[K, V] {
+	if size == nil {
+		size = func(*V) int { return int(unsafe.Sizeof(*new(V))) }
+	}
+	return &Intern[K, V]{
+		create:   create,
+		size:     size,
+		entries:  make(map[K]weak.Pointer[V]),
+		inflight: make(map[K]*internCall[V]),
+	}
+}
+
+// Get returns the shared value for k, building it if needed. Failed builds
+// are not cached.
+func (in *Intern[K, V]) Get(k K) (*V, error) {
+	in.mu.Lock()
+	if wp, ok := in.entries[k]; ok {
+		if v := wp.Value(); v != nil {
+			in.stats.Hits++
+			in.stats.BytesSaved += int64(in.size(v))
+			in.mu.Unlock()
+			return v, nil
+		}
+	}
+	if c, ok := in.inflight[k]; ok {
+		in.mu.Unlock()
+		<-c.done
+		if c.err == nil {
+			in.mu.Lock()
+			in.stats.Shared++
+			in.stats.BytesSaved += int64(in.size(c.v))
+			in.mu.Unlock()
+		}
+		return c.v, c.err
+	}
+	c := &internCall[V]{done: make(chan struct{})}
+	in.inflight[k] = c
+	in.stats.Misses++
+	in.mu.Unlock()
+
+	c.v, c.err = in.build(k)
+
+	in.mu.Lock()
+	delete(in.inflight, k)
+	if c.err == nil {
+		in.entries[k] = weak.Make(c.v)
+		runtime.AddCleanup(c.v, in.reclaim, k)
+	}
+	in.mu.Unlock()
+	close(c.done)
+	return c.v, c.err
+}
+
+// build calls create, turning a panic into an error so waiters are
+// released.
+func (in *Intern[K, V]) build(k K) (v *V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			v, err = nil, fmt.Errorf("intern %v: panic: %v", k, r)
+		}
+	}()
+	v, err = in.create(k)
+	if err == nil && v == nil {
+		err = fmt.Errorf("intern %v: create returned nil", k)
+	}
+	return v, err
+}
+
+// reclaim drops k's entry once its value has been collected, unless a
+// newer value was built for k in the meantime.
+func (in *Intern[K, V]) reclaim(k K) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if wp, ok := in.entries[k]; ok && wp.Value() == nil {
+		delete(in.entries, k)
+		in.stats.Reclaimed++
+	}
+}
+
+// Stats returns the counters and the number of live values.
+func (in *Intern[K, V]) Stats() InternStats {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	s := in.stats
+	for _, wp := range in.entries {
+		if wp.Value() != nil {
+			s.Live++
+		}
+	}
+	return s
+}
+
+// stringHeader is the size of a string or slice header beyond its bytes.
+const (
+	stringHeader = int(unsafe.Sizeof(""))
+	sliceHeader  = int(unsafe.Sizeof([]byte(nil)))
+)
+
+// NewStringIntern interns strings. The key and the value share storage, so
+// pass a clone if s is a slice of some much larger string.
+func NewStringIntern() *Intern[string, string] {
+	return NewIntern(
+		func(s string) (*string, error) { return &s, nil },
+		func(s *string) int { return stringHeader + len(*s) },
+	)
+}
+
+// NewBytesIntern interns byte slices keyed by their contents; look them up
+// with Get(string(b)). Each key gets one copy of its bytes, shared by every
+// caller, so the returned slices must not be modified.
+func NewBytesIntern() *Intern[string, []byte] {
+	return NewIntern(
+		func(s string) (*[]byte, error) {
+			b := []byte(s)
+			return &b, nil
+		},
+		func(b *[]byte) int { return sliceHeader + len(*b) },
+	)
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
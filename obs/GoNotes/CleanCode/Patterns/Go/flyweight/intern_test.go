@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestInternShares(t *testing.T) {
+	in := NewStringIntern()
+	a, _ := in.Get(string([]byte("go")))
+	if s := in.Stats(); s.BytesSaved != 0 {
+		t.Errorf("first Get saved %d bytes", s.BytesSaved)
+	}
+	b, _ := in.Get(string([]byte("go")))
+	c, _ := in.Get("gopher")
+	if a != b || a == c {
+		t.Fatalf("a=%p b=%p c=%p", a, b, c)
+	}
+	s := in.Stats()
+	if s.Hits != 1 || s.Misses != 2 || s.Live != 2 || s.BytesSaved != int64(stringHeader+2) {
+		t.Errorf("stats %+v", s)
+	}
+	runtime.KeepAlive(b)
+	runtime.KeepAlive(c)
+}
+
+func TestInternSingleflight(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	in := NewIntern(func(k int) (*int, error) {
+		calls.Add(1)
+		<-release
+		v := k * 2
+		return &v, nil
+	}, nil)
+
+	const n = 50
+	results := make([]*int, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() { results[i], _ = in.Get(7) })
+	}
+	// Let every goroutine reach the table before the build finishes.
+	for in.Stats().Misses == 0 {
+		runtime.Gosched()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if c := calls.Load(); c != 1 {
+		t.Errorf("create called %d times", c)
+	}
+	for _, r := range results {
+		if r != results[0] || *r != 14 {
+			t.Fatalf("got %p (%d), want %p", r, *r, results[0])
+		}
+	}
+	s := in.Stats()
+	if s.Misses != 1 || s.Hits+s.Shared != n-1 || s.BytesSaved != (n-1)*int64(unsafe.Sizeof(0)) {
+		t.Errorf("stats %+v", s)
+	}
+}
+
+func TestInternErrorsNotCached(t *testing.T) {
+	fail := true
+	in := NewIntern(func(k string) (*string, error) {
+		if fail {
+			return nil, errors.New("boom")
+		}
+		return &k, nil
+	}, nil)
+	if _, err := in.Get("x"); err == nil {
+		t.Fatal("want error")
+	}
+	fail = false
+	if v, err := in.Get("x"); err != nil || *v != "x" {
+		t.Fatalf("retry = %v, %v", v, err)
+	}
+
+	panicky := NewIntern(func(int) (*int, error) { panic("nope") }, nil)
+	if _, err := panicky.Get(1); err == nil {
+		t.Error("panic in create not reported")
+	}
+}
+
+func TestBytesInternCopies(t *testing.T) {
+	in := NewBytesIntern()
+	buf := []byte("spray")
+	a, _ := in.Get(string(buf))
+	buf[0] = 'S'
+	b, _ := in.Get("spray") // a literal key, in read-only memory
+	if a != b || string(*a) != "spray" {
+		t.Fatalf("got %q and %q", *a, *b)
+	}
+	(*a)[0] = 'x' // the copy is writable, though callers should not
+	if s := in.Stats(); s.BytesSaved != int64(sliceHeader+5) {
+		t.Errorf("stats %+v", s)
+	}
+}
+
+func TestInternReclaims(t *testing.T) {
+	in := NewBytesIntern()
+	func() {
+		b, _ := in.Get(string(make([]byte, 1<<20)))
+		runtime.KeepAlive(b)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for in.Stats().Reclaimed == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("value never reclaimed: %+v", in.Stats())
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if s := in.Stats(); s.Live != 0 {
+		t.Errorf("stats after reclaim %+v", s)
+	}
+
+	// A later Get builds a new value.
+	if _, err := in.Get(string(make([]byte, 1<<20))); err != nil {
+		t.Fatal(err)
+	}
+	if s := in.Stats(); s.Misses != 2 || s.Live != 1 {
+		t.Errorf("stats after rebuild %+v", s)
+	}
+}
+
+func TestDressFactory(t *testing.T) {
+	f := newDressFactory()
+	a, err := f.getDressByType(TerroristDressType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := f.getDressByType(TerroristDressType)
+	if a != b || a.getColor() != "red" {
+		t.Errorf("dresses not shared")
+	}
+	if _, err := f.getDressByType("nope"); err == nil {
+		t.Error("unknown dress accepted")
+	}
+	registerDressType("goldDress", func() dress { return &terroristDress{color: "gold"} })
+	if d, err := f.getDressByType("goldDress"); err != nil || d.getColor() != "gold" {
+		t.Errorf("registered dress = %v, %v", d, err)
+	}
+}
+
+// The game benchmarks join 100k players whose profiles come off the wire as
+// fresh buffers, drawn from a few hundred clans and a few dozen sprays, and
+// report the heap the players retain.
+const benchPlayers = 100_000
+
+type profile struct{ clan, spray []byte }
+
+func benchProfiles() []profile {
+	profiles := make([]profile, benchPlayers)
+	for i := range profiles {
+		profiles[i] = profile{
+			clan:  []byte(fmt.Sprintf("[clan-%03d] the %d-th legion", i%300, i%300)),
+			spray: weave(byte(i%32), 256),
+		}
+	}
+	return profiles
+}
+
+func heapInUse() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+func benchmarkGame(b *testing.B, newGame func() *game) {
+	profiles := benchProfiles()
+	b.ResetTimer()
+	var retained uint64
+	for range b.N {
+		before := heapInUse()
+		g := newGame()
+		for i, p := range profiles {
+			team := "T"
+			if i%2 == 1 {
+				team = "CT"
+			}
+			if err := g.join(team, p.clan, p.spray); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.StopTimer()
+		retained = heapInUse() - before
+		runtime.KeepAlive(g)
+		b.StartTimer()
+	}
+	b.ReportMetric(float64(retained)/benchPlayers, "heapB/player")
+}
+
+func BenchmarkGamePlain(b *testing.B)    { benchmarkGame(b, newGame) }
+func BenchmarkGameInterned(b *testing.B) { benchmarkGame(b, newInterningGame) }
+
+func BenchmarkInternString(b *testing.B) {
+	in := NewStringIntern()
+	keep, _ := in.Get("[clan-042] the 42-th legion")
+	raw := []byte(*keep)
+	b.ReportAllocs()
+	for range b.N {
+		in.Get(string(raw))
+	}
+	runtime.KeepAlive(keep)
+}
+
+func BenchmarkInternBytes(b *testing.B) {
+	in := NewBytesIntern()
+	raw := weave(1, 256)
+	keep, _ := in.Get(string(raw))
+	b.ReportAllocs()
+	for range b.N {
+		in.Get(string(raw))
+	}
+	runtime.KeepAlive(keep)
+}
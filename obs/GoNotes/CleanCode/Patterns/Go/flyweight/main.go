@@ -1,24 +1,58 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"log"
+)
 
 func main() {
 	game := newGame()
 
 	//Add Terrorist
-	game.addTerrorist(TerroristDressType)
-	game.addTerrorist(TerroristDressType)
-	game.addTerrorist(TerroristDressType)
-	game.addTerrorist(TerroristDressType)
+	for range 4 {
+		if err := game.addTerrorist(TerroristDressType); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	//Add CounterTerrorist
-	game.addCounterTerrorist(CounterTerrroristDressType)
-	game.addCounterTerrorist(CounterTerrroristDressType)
-	game.addCounterTerrorist(CounterTerrroristDressType)
+	for range 3 {
+		if err := game.addCounterTerrorist(CounterTerrroristDressType); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := game.addTerrorist("pinkDress"); err != nil {
+		fmt.Println("Error:", err)
+	}
 
 	dressFactoryInstance := getDressFactorySingleInstance()
 
-	for dressType, dress := range dressFactoryInstance.dressMap {
+	for _, dressType := range knownDressTypes() {
+		dress, err := dressFactoryInstance.getDressByType(dressType)
+		if err != nil {
+			log.Fatal(err)
+		}
 		fmt.Printf("DressColorType: %s\nDressColor: %s\n", dressType, dress.getColor())
 	}
+	fmt.Printf("Same dress shared: %v\n", game.terrorists[0].dress == game.terrorists[3].dress)
+	fmt.Println("Dresses:", dressFactoryInstance.dresses.Stats())
+
+	// Profiles as a client would send them: every packet is a fresh
+	// buffer, but there are only a few clans and sprays in play.
+	online := newInterningGame()
+	for i := range 1000 {
+		clan := []byte(fmt.Sprintf("clan-%02d", i%10))
+		spray := weave(byte(i%4), 512)
+		team := "T"
+		if i%2 == 1 {
+			team = "CT"
+		}
+		if err := online.join(team, clan, spray); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Printf("Players online: %d\n", online.players())
+	fmt.Println("Clans:", online.clans.Stats())
+	fmt.Println("Sprays:", online.sprays.Stats())
 }
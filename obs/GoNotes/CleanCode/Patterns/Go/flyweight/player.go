@@ -3,16 +3,25 @@ package main
 type player struct {
 	dress      dress
 	playerType string
+	clan       *string // shared when the game interns profiles
+	spray      *[]byte
 	lat        int
 	long       int
 }
 
-func newPlayer(playerType, dressType string) *player {
-	dress, _ := getDressFactorySingleInstance().getDressByType(dressType)
+func newPlayer(playerType, dressType string) (*player, error) {
+	dress, err := getDressFactorySingleInstance().getDressByType(dressType)
+	if err != nil {
+		return nil, err
+	}
 	return &player{
 		playerType: playerType,
 		dress:      dress,
-	}
+	}, nil
+}
+
+func (p *player) getDress() dress {
+	return p.dress
 }
 
 func (p *player) newLocation(lat, long int) {
@@ -1,13 +1,18 @@
 package main
 
 type terroristDress struct {
-	color string
+	color   string
+	texture []byte
 }
 
 func (t *terroristDress) getColor() string {
 	return t.color
 }
 
+func (t *terroristDress) getTexture() []byte {
+	return t.texture
+}
+
 func newTerroristDress() *terroristDress {
-	return &terroristDress{color: "red"}
+	return &terroristDress{color: "red", texture: weave('T', textureSize)}
 }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"iter"
+)
+
+// FromChan yields values received from ch until it is closed or ctx is
+// done.
+func FromChan[T any](ctx context.Context, ch <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for {
+			select {
+			case v, ok := <-ch:
+				if !ok || !yield(v) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// ToChan sends every value of seq on a new channel, which is closed at the
+// end. Cancelling ctx stops the sending goroutine.
+func ToChan[T any](ctx context.Context, seq iter.Seq[T]) <-chan T {
+	ch := make(chan T)
+	go func() {
+		defer close(ch)
+		for v := range seq {
+			select {
+			case ch <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Scan yields the tokens of sc. A read error is yielded last, with an
+// empty token.
+func Scan(sc *bufio.Scanner) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for sc.Scan() {
+			if !yield(sc.Text(), nil) {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			yield("", err)
+		}
+	}
+}
+
+// Rows yields one value per row, built by scan. The rows are closed when
+// the sequence ends, including when the consumer stops early. An error from
+// scan or from the rows is yielded last.
+func Rows[T any](rows *sql.Rows, scan func(*sql.Rows) (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer rows.Close()
+		var zero T
+		for rows.Next() {
+			v, err := scan(rows)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// All adapts a classic hasNext/getNext iterator to range-over-func.
+func All(it iterator) iter.Seq[*user] {
+	return func(yield func(*user) bool) {
+		for it.hasNext() {
+			if !yield(it.getNext()) {
+				return
+			}
+		}
+	}
+}
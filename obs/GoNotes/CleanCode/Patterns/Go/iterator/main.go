@@ -1,6 +1,9 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+)
 
 func main() {
 
@@ -12,9 +15,13 @@ func main() {
 		name: "b",
 		age:  20,
 	}
+	user3 := &user{
+		name: "c",
+		age:  17,
+	}
 
 	userCollection := &userCollection{
-		users: []*user{user1, user2},
+		users: []*user{user1, user2, user3},
 	}
 
 	iterator := userCollection.createIterator()
@@ -23,4 +30,12 @@ func main() {
 		user := iterator.getNext()
 		fmt.Printf("User is %+v\n", user)
 	}
+
+	adults := Filter(userCollection.all(), func(u *user) bool { return u.age >= 18 })
+	names := Map(adults, func(u *user) string { return u.name })
+	fmt.Println("Adults:", slices.Collect(names))
+
+	for pair := range Window(Map(userCollection.all(), func(u *user) int { return u.age }), 2) {
+		fmt.Printf("Age gap %d -> %d\n", pair[0], pair[1])
+	}
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"iter"
+	"sync"
+)
+
+// ParallelMap applies f on up to workers goroutines and yields the results
+// in input order. At most about 2*workers values are in flight, so a slow
+// consumer holds back the input instead of buffering it all.
+//
+// The input is consumed on a separate goroutine. When the consumer stops
+// early, in-flight calls are allowed to finish and all goroutines exit
+// before ParallelMap returns; an input that blocks forever will still hold
+// its goroutine, as with any channel read.
+func ParallelMap[T, U any](seq iter.Seq[T], workers int, f func(T) U) iter.Seq[U] {
+	if workers < 1 {
+		workers = 1
+	}
+	return func(yield func(U) bool) {
+		type job struct {
+			v   T
+			out chan U
+		}
+		jobs := make(chan job)
+		pending := make(chan chan U, workers)
+		done := make(chan struct{})
+
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range jobs {
+					j.out <- f(j.v) // buffered: never blocks
+				}
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(pending)
+			defer close(jobs)
+			for v := range seq {
+				out := make(chan U, 1)
+				select {
+				case jobs <- job{v, out}:
+				case <-done:
+					return
+				}
+				select {
+				case pending <- out:
+				case <-done:
+					return
+				}
+			}
+		}()
+		defer func() {
+			close(done)
+			for range pending {
+			}
+			wg.Wait()
+		}()
+
+		for out := range pending {
+			if !yield(<-out) {
+				return
+			}
+		}
+	}
+}
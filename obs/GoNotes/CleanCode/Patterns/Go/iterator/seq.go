@@ -0,0 +1,148 @@
+package main
+
+import "iter"
+
+// The functions below are lazy: nothing is computed until the returned
+// sequence is ranged over, and they stop pulling from their input as soon
+// as the consumer stops.
+
+func Map[T, U any](seq iter.Seq[T], f func(T) U) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range seq {
+			if !yield(f(v)) {
+				return
+			}
+		}
+	}
+}
+
+func Filter[T any](seq iter.Seq[T], keep func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			if keep(v) && !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Take yields at most the first n values.
+func Take[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		i := 0
+		for v := range seq {
+			if !yield(v) {
+				return
+			}
+			if i++; i == n {
+				return
+			}
+		}
+	}
+}
+
+// Skip drops the first n values.
+func Skip[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		i := 0
+		for v := range seq {
+			if i < n {
+				i++
+				continue
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Chunk groups values into slices of n; the last one may be shorter. Every
+// chunk is a new slice the consumer may keep.
+func Chunk[T any](seq iter.Seq[T], n int) iter.Seq[[]T] {
+	if n <= 0 {
+		panic("Chunk: size must be positive")
+	}
+	return func(yield func([]T) bool) {
+		chunk := make([]T, 0, n)
+		for v := range seq {
+			chunk = append(chunk, v)
+			if len(chunk) == n {
+				if !yield(chunk) {
+					return
+				}
+				chunk = make([]T, 0, n)
+			}
+		}
+		if len(chunk) > 0 {
+			yield(chunk)
+		}
+	}
+}
+
+// Window yields every run of n consecutive values, sliding by one. Inputs
+// shorter than n yield nothing. Every window is a new slice.
+func Window[T any](seq iter.Seq[T], n int) iter.Seq[[]T] {
+	if n <= 0 {
+		panic("Window: size must be positive")
+	}
+	return func(yield func([]T) bool) {
+		buf := make([]T, 0, n)
+		for v := range seq {
+			if len(buf) == n {
+				buf = append(buf[:0], buf[1:]...)
+			}
+			buf = append(buf, v)
+			if len(buf) == n && !yield(append([]T(nil), buf...)) {
+				return
+			}
+		}
+	}
+}
+
+// Zip pairs up the values of a and b, stopping with the shorter one.
+func Zip[A, B any](a iter.Seq[A], b iter.Seq[B]) iter.Seq2[A, B] {
+	return func(yield func(A, B) bool) {
+		next, stop := iter.Pull(b)
+		defer stop()
+		for va := range a {
+			vb, ok := next()
+			if !ok || !yield(va, vb) {
+				return
+			}
+		}
+	}
+}
+
+// FlatMap yields every value of every sequence f returns.
+func FlatMap[T, U any](seq iter.Seq[T], f func(T) iter.Seq[U]) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range seq {
+			for u := range f(v) {
+				if !yield(u) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Dedup drops values equal to the one just before them, like uniq(1).
+func Dedup[T comparable](seq iter.Seq[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var prev T
+		first := true
+		for v := range seq {
+			if !first && v == prev {
+				continue
+			}
+			first, prev = false, v
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"iter"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// count yields 0, 1, 2, ... n-1 and records how many values were pulled.
+func count(n int, pulled *int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; i < n; i++ {
+			if pulled != nil {
+				*pulled++
+			}
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	double := func(i int) int { return 2 * i }
+	even := func(i int) bool { return i%2 == 0 }
+	for _, test := range []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"Map", slices.Collect(Map(count(4, nil), double)), []int{0, 2, 4, 6}},
+		{"Filter", slices.Collect(Filter(count(6, nil), even)), []int{0, 2, 4}},
+		{"Take", slices.Collect(Take(count(10, nil), 3)), []int{0, 1, 2}},
+		{"Take0", slices.Collect(Take(count(10, nil), 0)), []int(nil)},
+		{"Skip", slices.Collect(Skip(count(5, nil), 3)), []int{3, 4}},
+		{"Chunk", slices.Collect(Chunk(count(5, nil), 2)), [][]int{{0, 1}, {2, 3}, {4}}},
+		{"Window", slices.Collect(Window(count(4, nil), 3)), [][]int{{0, 1, 2}, {1, 2, 3}}},
+		{"WindowShort", slices.Collect(Window(count(2, nil), 3)), [][]int(nil)},
+		{"FlatMap", slices.Collect(FlatMap(count(3, nil), func(i int) iter.Seq[int] { return count(i, nil) })), []int{0, 0, 1}},
+		{"Dedup", slices.Collect(Dedup(slices.Values([]int{1, 1, 2, 1, 3, 3}))), []int{1, 2, 1, 3}},
+	} {
+		if !reflect.DeepEqual(test.got, test.want) {
+			t.Errorf("%s = %v, want %v", test.name, test.got, test.want)
+		}
+	}
+
+	var keys []string
+	var vals []int
+	for k, v := range Zip(slices.Values([]string{"a", "b", "c"}), count(2, nil)) {
+		keys = append(keys, k)
+		vals = append(vals, v)
+	}
+	if !reflect.DeepEqual(keys, []string{"a", "b"}) || !reflect.DeepEqual(vals, []int{0, 1}) {
+		t.Errorf("Zip = %v %v", keys, vals)
+	}
+}
+
+func TestLaziness(t *testing.T) {
+	pulled := 0
+	seq := Take(Map(Filter(count(1000, &pulled), func(i int) bool { return i%3 == 0 }), func(i int) int { return i }), 2)
+	if pulled != 0 {
+		t.Fatal("work done before ranging")
+	}
+	for range seq {
+	}
+	if pulled != 4 { // 0, 1, 2, 3
+		t.Errorf("pulled %d values, want 4", pulled)
+	}
+
+	pulled = 0
+	for range Window(count(1000, &pulled), 2) {
+		break
+	}
+	if pulled != 2 {
+		t.Errorf("Window pulled %d values after break", pulled)
+	}
+}
+
+func TestZipReleasesPull(t *testing.T) {
+	var stopped atomic.Bool
+	b := func(yield func(int) bool) {
+		defer stopped.Store(true)
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	for range Zip(count(3, nil), iter.Seq[int](b)) {
+	}
+	if !stopped.Load() {
+		t.Error("Zip did not stop the pulled sequence")
+	}
+}
+
+func TestParallelMapOrder(t *testing.T) {
+	slow := func(i int) int {
+		time.Sleep(time.Duration(10-i%10) * time.Millisecond)
+		return i * i
+	}
+	got := slices.Collect(ParallelMap(count(50, nil), 8, slow))
+	for i, v := range got {
+		if v != i*i {
+			t.Fatalf("got[%d] = %d, want %d", i, v, i*i)
+		}
+	}
+	if len(got) != 50 {
+		t.Fatalf("got %d results", len(got))
+	}
+}
+
+func TestParallelMapBounded(t *testing.T) {
+	var running, peak atomic.Int32
+	f := func(i int) int {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		return i
+	}
+	for range ParallelMap(count(100, nil), 4, f) {
+	}
+	if p := peak.Load(); p > 4 {
+		t.Errorf("%d calls ran at once with 4 workers", p)
+	}
+}
+
+func TestParallelMapEarlyStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	var calls atomic.Int32
+	f := func(i int) int { calls.Add(1); return i }
+	for v := range ParallelMap(count(1_000_000, nil), 4, f) {
+		if v == 10 {
+			break
+		}
+	}
+	if c := calls.Load(); c > 30 {
+		t.Errorf("%d calls after stopping at 10", c)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines: %d before, %d after", before, after)
+	}
+}
+
+func TestChanAdapters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := ToChan(ctx, count(5, nil))
+	if got := slices.Collect(FromChan(ctx, ch)); !reflect.DeepEqual(got, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("round trip = %v", got)
+	}
+
+	never := make(chan int)
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel2()
+	if got := slices.Collect(FromChan(ctx2, never)); len(got) != 0 {
+		t.Errorf("got %v from an idle channel", got)
+	}
+}
+
+type failingReader struct{ r io.Reader }
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("disk on fire")
+	}
+	return n, err
+}
+
+func TestScan(t *testing.T) {
+	var lines []string
+	for line, err := range Scan(bufio.NewScanner(strings.NewReader("a\nb\nc\n"))) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if !reflect.DeepEqual(lines, []string{"a", "b", "c"}) {
+		t.Errorf("lines = %v", lines)
+	}
+
+	var last error
+	for _, err := range Scan(bufio.NewScanner(failingReader{strings.NewReader("x\n")})) {
+		last = err
+	}
+	if last == nil {
+		t.Error("read error not yielded")
+	}
+}
+
+func TestUserCollection(t *testing.T) {
+	c := &userCollection{users: []*user{{name: "a", age: 30}, {name: "b", age: 20}}}
+	names := slices.Collect(Map(c.all(), func(u *user) string { return u.name }))
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("names = %v", names)
+	}
+	// Each call starts over.
+	if n := len(slices.Collect(c.all())); n != 2 {
+		t.Errorf("second pass saw %d users", n)
+	}
+}
+
+// A minimal database/sql driver serving one integer column 0..n-1.
+
+type fakeDriver struct{ closed *atomic.Int32 }
+type fakeConn struct{ closed *atomic.Int32 }
+type fakeStmt struct{ closed *atomic.Int32 }
+type fakeRows struct {
+	n, i   int
+	closed *atomic.Int32
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error)         { return fakeConn(d), nil }
+func (c fakeConn) Prepare(string) (driver.Stmt, error)        { return fakeStmt(c), nil }
+func (c fakeConn) Close() error                               { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                  { return nil, errors.New("no tx") }
+func (s fakeStmt) Close() error                               { return nil }
+func (s fakeStmt) NumInput() int                              { return -1 }
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errors.New("no exec") }
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{n: int(args[0].(int64)), closed: s.closed}, nil
+}
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+func (r *fakeRows) Close() error      { r.closed.Add(1); return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i == r.n {
+		return io.EOF
+	}
+	dest[0] = int64(r.i)
+	r.i++
+	return nil
+}
+
+var rowsClosed atomic.Int32
+
+func init() {
+	sql.Register("iterfake", fakeDriver{&rowsClosed})
+}
+
+func TestRows(t *testing.T) {
+	db, err := sql.Open("iterfake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	scanInt := func(r *sql.Rows) (int, error) {
+		var n int
+		err := r.Scan(&n)
+		return n, err
+	}
+
+	rows, err := db.Query("numbers", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for n, err := range Rows(rows, scanInt) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, n)
+	}
+	if !reflect.DeepEqual(got, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("rows = %v", got)
+	}
+
+	before := rowsClosed.Load()
+	rows, _ = db.Query("numbers", 100)
+	for n := range Rows(rows, scanInt) {
+		if n == 2 {
+			break
+		}
+	}
+	if rowsClosed.Load() != before+1 {
+		t.Error("rows not closed after early break")
+	}
+}
@@ -1,5 +1,7 @@
 package main
 
+import "iter"
+
 type userCollection struct {
 	users []*user
 }
@@ -9,3 +11,8 @@ func (u *userCollection) createIterator() iterator {
 		users: u.users,
 	}
 }
+
+// all ranges over the users with a fresh iterator.
+func (u *userCollection) all() iter.Seq[*user] {
+	return All(u.createIterator())
+}
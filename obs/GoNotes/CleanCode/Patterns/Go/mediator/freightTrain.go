@@ -1,25 +1,9 @@
 package main
 
-import "fmt"
-
 type freightTrain struct {
-	mediator mediator
+	schedule
 }
 
-func (g *freightTrain) arrive() {
-	if !g.mediator.canArrive(g) {
-		fmt.Println("FreightTrain: Arrival blocked, waiting")
-		return
-	}
-	fmt.Println("FreightTrain: Arrived")
-}
+func (g *freightTrain) priority() int { return 0 }
 
-func (g *freightTrain) depart() {
-	fmt.Println("FreightTrain: Leaving")
-	g.mediator.notifyAboutDeparture()
-}
-
-func (g *freightTrain) permitArrival() {
-	fmt.Println("FreightTrain: Arrival permitted")
-	g.arrive()
-}
+func (g *freightTrain) class() string { return "freight" }
@@ -1,16 +1,18 @@
 package main
 
+import (
+	"fmt"
+	"time"
+)
+
 func main() {
-	stationManager := newStationManger()
+	stationManager := newStationManger(2)
 
-	passengerTrain := &passengerTrain{
-		mediator: stationManager,
-	}
-	freightTrain := &freightTrain{
-		mediator: stationManager,
-	}
+	stationManager.add(&passengerTrain{schedule{name: "P1", arrival: 0, dwell: 10 * time.Minute}})
+	stationManager.add(&freightTrain{schedule{name: "F1", arrival: 2 * time.Minute, dwell: 30 * time.Minute}})
+	stationManager.add(&freightTrain{schedule{name: "F2", arrival: 4 * time.Minute, dwell: 20 * time.Minute}})
+	stationManager.add(&passengerTrain{schedule{name: "P2", arrival: 6 * time.Minute, dwell: 5 * time.Minute}})
+	stationManager.add(&passengerTrain{schedule{name: "P3", arrival: 12 * time.Minute, dwell: 5 * time.Minute}})
 
-	passengerTrain.arrive()
-	freightTrain.arrive()
-	passengerTrain.depart()
+	fmt.Print(stationManager.run())
 }
@@ -1,6 +1,16 @@
 package main
 
+import "time"
+
+// mediator is what a train sees of the station. Every call is a message to
+// the station's event loop and blocks until the station answers, so trains
+// never touch shared state themselves. Times are virtual, measured from the
+// start of the simulation.
 type mediator interface {
-	canArrive(train) bool
-	notifyAboutDeparture()
+	// sleepUntil returns once the virtual clock reaches at.
+	sleepUntil(t train, at time.Duration) time.Duration
+	// requestArrival returns once a platform has been assigned to t.
+	requestArrival(t train) (platform int, now time.Duration)
+	notifyAboutDeparture(t train, platform int) time.Duration
+	done(t train)
 }
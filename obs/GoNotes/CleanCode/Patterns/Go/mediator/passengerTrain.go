@@ -1,25 +1,9 @@
 package main
 
-import "fmt"
-
 type passengerTrain struct {
-	mediator mediator
+	schedule
 }
 
-func (g *passengerTrain) arrive() {
-	if !g.mediator.canArrive(g) {
-		fmt.Println("PassengerTrain: Arrival blocked, waiting")
-		return
-	}
-	fmt.Println("PassengerTrain: Arrived")
-}
+func (g *passengerTrain) priority() int { return 1 }
 
-func (g *passengerTrain) depart() {
-	fmt.Println("PassengerTrain: Leaving")
-	g.mediator.notifyAboutDeparture()
-}
-
-func (g *passengerTrain) permitArrival() {
-	fmt.Println("PassengerTrain: Arrival permitted, arriving")
-	g.arrive()
-}
+func (g *passengerTrain) class() string { return "passenger" }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type event struct {
+	at       time.Duration
+	train    string
+	what     string
+	platform int // -1 if none
+}
+
+// visit is one train's stay at the station.
+type visit struct {
+	train     train
+	requested time.Duration
+	arrived   time.Duration
+	departed  time.Duration
+	platform  int
+}
+
+func (v *visit) waited() time.Duration { return v.arrived - v.requested }
+
+type platformStats struct {
+	busy        time.Duration
+	served      int
+	utilization float64
+}
+
+type classStats struct {
+	trains  int
+	avgWait time.Duration
+	maxWait time.Duration
+}
+
+type report struct {
+	timeline  []event
+	visits    []*visit
+	duration  time.Duration
+	platforms []platformStats
+	classes   map[string]classStats
+}
+
+func (s *stationManager) report() *report {
+	r := &report{timeline: s.timeline, duration: s.now, classes: make(map[string]classStats)}
+	for _, t := range s.trains {
+		r.visits = append(r.visits, s.visits[t])
+	}
+	for p := range s.platforms {
+		ps := platformStats{busy: s.busy[p], served: s.served[p]}
+		if r.duration > 0 {
+			ps.utilization = float64(ps.busy) / float64(r.duration)
+		}
+		r.platforms = append(r.platforms, ps)
+	}
+	total := make(map[string]time.Duration)
+	for _, v := range r.visits {
+		c := r.classes[v.train.class()]
+		c.trains++
+		c.maxWait = max(c.maxWait, v.waited())
+		total[v.train.class()] += v.waited()
+		r.classes[v.train.class()] = c
+	}
+	for name, c := range r.classes {
+		c.avgWait = total[name] / time.Duration(c.trains)
+		r.classes[name] = c
+	}
+	return r
+}
+
+func (r *report) String() string {
+	var b strings.Builder
+	b.WriteString("Timeline:\n")
+	for _, e := range r.timeline {
+		fmt.Fprintf(&b, "  %s  %-4s %s", clock(e.at), e.train, e.what)
+		if e.platform >= 0 {
+			fmt.Fprintf(&b, " platform %d", e.platform+1)
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString("Platforms:\n")
+	for p, ps := range r.platforms {
+		fmt.Fprintf(&b, "  %d: %d trains, busy %s, utilization %.0f%%\n", p+1, ps.served, clock(ps.busy), 100*ps.utilization)
+	}
+	b.WriteString("Waiting:\n")
+	names := make([]string, 0, len(r.classes))
+	for name := range r.classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := r.classes[name]
+		fmt.Fprintf(&b, "  %s: %d trains, average %s, max %s\n", name, c.trains, clock(c.avgWait), clock(c.maxWait))
+	}
+	return b.String()
+}
+
+// clock formats a virtual time as hours and minutes since the start.
+func clock(d time.Duration) string {
+	m := int(d / time.Minute)
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
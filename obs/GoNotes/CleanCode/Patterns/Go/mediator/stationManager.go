@@ -1,32 +1,259 @@
 package main
 
+import (
+	"container/heap"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+type requestKind int
+
+const (
+	reqSleep requestKind = iota
+	reqArrive
+	reqDepart
+	reqDone
+)
+
+type request struct {
+	kind     requestKind
+	train    train
+	at       time.Duration // reqSleep: wake-up time; reqArrive: time of request
+	platform int
+	reply    chan reply
+}
+
+type reply struct {
+	platform int
+	now      time.Duration
+}
+
+// stationManager is the mediator: it owns the platforms and the virtual
+// clock and runs a discrete-event loop fed by the train goroutines.
+//
+// Requests are collected until every live train is blocked waiting for an
+// answer, so the order in which the goroutines happen to send them does not
+// matter. The batch is handled in registration order, and only then are
+// free platforms granted, by priority, to everyone waiting. When no train
+// is left to run the clock moves to the next wake-up time and every train
+// due then is woken together. This makes a run fully deterministic even
+// though each train is its own goroutine.
 type stationManager struct {
-	isPlatformFree bool
-	trainQueue     []train
+	platforms []train // nil when free
+	busySince []time.Duration
+	busy      []time.Duration
+	served    []int
+
+	trains   []train
+	index    map[train]int
+	requests chan request
+	now      time.Duration
+	running  int
+	timers   timerQueue
+	waiting  waitQueue
+
+	timeline []event
+	visits   map[train]*visit
 }
 
-func newStationManger() *stationManager {
+func newStationManger(platforms int) *stationManager {
 	return &stationManager{
-		isPlatformFree: true,
+		platforms: make([]train, platforms),
+		busySince: make([]time.Duration, platforms),
+		busy:      make([]time.Duration, platforms),
+		served:    make([]int, platforms),
+		index:     make(map[train]int),
+		requests:  make(chan request),
+		visits:    make(map[train]*visit),
+	}
+}
+
+func (s *stationManager) add(t train) {
+	s.index[t] = len(s.trains)
+	s.trains = append(s.trains, t)
+	s.visits[t] = &visit{train: t}
+}
+
+// run starts every train and drives the simulation until all have left.
+func (s *stationManager) run() *report {
+	for _, t := range s.trains {
+		s.running++
+		go runTrain(t, s)
+	}
+	var batch []request
+	for {
+		for s.running > 0 {
+			req := <-s.requests
+			s.running--
+			batch = append(batch, req)
+		}
+		if len(batch) > 0 {
+			s.handleAll(batch)
+			batch = batch[:0]
+			continue
+		}
+		if s.timers.Len() == 0 {
+			break
+		}
+		s.now = s.timers[0].req.at
+		for s.timers.Len() > 0 && s.timers[0].req.at == s.now {
+			tm := heap.Pop(&s.timers).(timer)
+			s.wake(tm.req, reply{now: s.now})
+		}
+	}
+	if s.waiting.Len() > 0 {
+		panic(fmt.Sprintf("simulation ended with %d trains waiting", s.waiting.Len()))
+	}
+	return s.report()
+}
+
+// handleAll handles the requests made at one instant, then lets waiting
+// trains onto the free platforms.
+func (s *stationManager) handleAll(batch []request) {
+	sort.SliceStable(batch, func(i, j int) bool {
+		return s.index[batch[i].train] < s.index[batch[j].train]
+	})
+	for _, req := range batch {
+		s.handle(req)
+	}
+	for p, occupant := range s.platforms {
+		if occupant == nil && s.waiting.Len() > 0 {
+			s.assign(p, heap.Pop(&s.waiting).(waiter).req)
+		}
+	}
+	for _, req := range batch {
+		if req.kind == reqArrive && !slices.Contains(s.platforms, req.train) {
+			s.record(req.train, "waiting", -1)
+		}
+	}
+}
+
+func (s *stationManager) handle(req request) {
+	switch req.kind {
+	case reqSleep:
+		if req.at <= s.now {
+			s.wake(req, reply{now: s.now})
+			return
+		}
+		heap.Push(&s.timers, timer{req: req, order: s.index[req.train]})
+	case reqArrive:
+		req.at = s.now
+		s.visits[req.train].requested = s.now
+		s.record(req.train, "requested arrival", -1)
+		heap.Push(&s.waiting, waiter{req: req, order: s.index[req.train]})
+	case reqDepart:
+		p := req.platform
+		if s.platforms[p] != req.train {
+			panic(fmt.Sprintf("%s departs from platform %d held by %v", req.train.getName(), p+1, s.platforms[p]))
+		}
+		s.platforms[p] = nil
+		s.busy[p] += s.now - s.busySince[p]
+		s.visits[req.train].departed = s.now
+		s.record(req.train, "departed", p)
+		s.wake(req, reply{platform: p, now: s.now})
+	case reqDone:
+	}
+}
+
+func (s *stationManager) assign(p int, req request) {
+	if s.platforms[p] != nil {
+		panic(fmt.Sprintf("platform %d assigned to %s while held by %s", p+1, req.train.getName(), s.platforms[p].getName()))
 	}
+	s.platforms[p] = req.train
+	s.busySince[p] = s.now
+	s.served[p]++
+	v := s.visits[req.train]
+	v.arrived, v.platform = s.now, p
+	s.record(req.train, "arrived", p)
+	s.wake(req, reply{platform: p, now: s.now})
+}
+
+func (s *stationManager) wake(req request, r reply) {
+	s.running++
+	req.reply <- r
+}
+
+func (s *stationManager) record(t train, what string, platform int) {
+	s.timeline = append(s.timeline, event{at: s.now, train: t.getName(), what: what, platform: platform})
+}
+
+// The mediator methods below run on the train's goroutine and talk to the
+// event loop over channels.
+
+func (s *stationManager) call(req request) reply {
+	req.reply = make(chan reply, 1)
+	s.requests <- req
+	return <-req.reply
+}
+
+func (s *stationManager) sleepUntil(t train, at time.Duration) time.Duration {
+	return s.call(request{kind: reqSleep, train: t, at: at}).now
+}
+
+func (s *stationManager) requestArrival(t train) (int, time.Duration) {
+	r := s.call(request{kind: reqArrive, train: t})
+	return r.platform, r.now
 }
 
-func (s *stationManager) canArrive(t train) bool {
-	if s.isPlatformFree {
-		s.isPlatformFree = false
-		return true
+func (s *stationManager) notifyAboutDeparture(t train, platform int) time.Duration {
+	return s.call(request{kind: reqDepart, train: t, platform: platform}).now
+}
+
+func (s *stationManager) done(t train) {
+	s.requests <- request{kind: reqDone, train: t}
+}
+
+type timer struct {
+	req   request
+	order int
+}
+
+// timerQueue orders sleeping trains by wake-up time, then registration.
+type timerQueue []timer
+
+func (q timerQueue) Len() int { return len(q) }
+func (q timerQueue) Less(i, j int) bool {
+	if q[i].req.at != q[j].req.at {
+		return q[i].req.at < q[j].req.at
 	}
-	s.trainQueue = append(s.trainQueue, t)
-	return false
+	return q[i].order < q[j].order
+}
+func (q timerQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *timerQueue) Push(x any)   { *q = append(*q, x.(timer)) }
+func (q *timerQueue) Pop() any {
+	old := *q
+	t := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return t
+}
+
+type waiter struct {
+	req   request
+	order int
 }
 
-func (s *stationManager) notifyAboutDeparture() {
-	if !s.isPlatformFree {
-		s.isPlatformFree = true
+// waitQueue orders trains waiting for a platform: higher priority first,
+// then whoever asked first, then registration order.
+type waitQueue []waiter
+
+func (q waitQueue) Len() int { return len(q) }
+func (q waitQueue) Less(i, j int) bool {
+	a, b := q[i], q[j]
+	if pa, pb := a.req.train.priority(), b.req.train.priority(); pa != pb {
+		return pa > pb
 	}
-	if len(s.trainQueue) > 0 {
-		firstTrainInQueue := s.trainQueue[0]
-		s.trainQueue = s.trainQueue[1:]
-		firstTrainInQueue.permitArrival()
+	if a.req.at != b.req.at {
+		return a.req.at < b.req.at
 	}
+	return a.order < b.order
+}
+func (q waitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *waitQueue) Push(x any)   { *q = append(*q, x.(waiter)) }
+func (q *waitQueue) Pop() any {
+	old := *q
+	w := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return w
 }
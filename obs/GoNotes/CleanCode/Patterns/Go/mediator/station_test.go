@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func randomStation(seed int64, platforms, trains int) *stationManager {
+	r := rand.New(rand.NewSource(seed))
+	s := newStationManger(platforms)
+	for i := 0; i < trains; i++ {
+		sch := schedule{
+			name:    string(rune('A'+i%26)) + string(rune('0'+i/26)),
+			arrival: time.Duration(r.Intn(120)) * time.Minute,
+			dwell:   time.Duration(1+r.Intn(30)) * time.Minute,
+		}
+		if r.Intn(2) == 0 {
+			s.add(&passengerTrain{sch})
+		} else {
+			s.add(&freightTrain{sch})
+		}
+	}
+	return s
+}
+
+func TestNoSharedPlatforms(t *testing.T) {
+	for seed := int64(0); seed < 50; seed++ {
+		platforms := 1 + int(seed%4)
+		rep := randomStation(seed, platforms, 40).run()
+
+		byPlatform := make(map[int][]*visit)
+		for _, v := range rep.visits {
+			if v.arrived < v.requested || v.departed < v.arrived {
+				t.Fatalf("seed %d: %s has inconsistent times %+v", seed, v.train.getName(), v)
+			}
+			byPlatform[v.platform] = append(byPlatform[v.platform], v)
+		}
+		for p, vs := range byPlatform {
+			if p < 0 || p >= platforms {
+				t.Fatalf("seed %d: platform %d out of range", seed, p)
+			}
+			sort.Slice(vs, func(i, j int) bool { return vs[i].arrived < vs[j].arrived })
+			for i := 1; i < len(vs); i++ {
+				if vs[i].arrived < vs[i-1].departed {
+					t.Fatalf("seed %d: %s and %s share platform %d", seed,
+						vs[i-1].train.getName(), vs[i].train.getName(), p+1)
+				}
+			}
+		}
+	}
+}
+
+func TestPassengerBeforeFreight(t *testing.T) {
+	for seed := int64(0); seed < 50; seed++ {
+		rep := randomStation(seed, 1+int(seed%3), 30).run()
+		for _, v := range rep.visits {
+			// v was let in at v.arrived; no train of higher priority may
+			// have been waiting then, including one that asked at that
+			// very instant.
+			for _, w := range rep.visits {
+				if w.train.priority() > v.train.priority() &&
+					w.requested <= v.arrived && w.arrived > v.arrived {
+					t.Fatalf("seed %d: %s let in at %v while %s waited", seed,
+						v.train.getName(), v.arrived, w.train.getName())
+				}
+			}
+		}
+	}
+}
+
+func TestSameInstantByPriority(t *testing.T) {
+	// The freight train is registered, and so started, first; the
+	// passenger train must still get the only platform.
+	for i := 0; i < 20; i++ {
+		s := newStationManger(1)
+		s.add(&freightTrain{schedule{name: "F", arrival: 5 * time.Minute, dwell: 10 * time.Minute}})
+		s.add(&passengerTrain{schedule{name: "P", arrival: 5 * time.Minute, dwell: 10 * time.Minute}})
+		rep := s.run()
+		for _, v := range rep.visits {
+			want := 5 * time.Minute
+			if v.train.getName() == "F" {
+				want = 15 * time.Minute
+			}
+			if v.arrived != want {
+				t.Fatalf("run %d: %s arrived at %v, want %v", i, v.train.getName(), v.arrived, want)
+			}
+		}
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	first := randomStation(7, 2, 30).run()
+	for i := 0; i < 5; i++ {
+		again := randomStation(7, 2, 30).run()
+		if !reflect.DeepEqual(first.timeline, again.timeline) {
+			t.Fatal("timeline differs between identical runs")
+		}
+	}
+}
+
+func TestStatistics(t *testing.T) {
+	s := newStationManger(1)
+	s.add(&freightTrain{schedule{name: "F", arrival: 0, dwell: 10 * time.Minute}})
+	s.add(&passengerTrain{schedule{name: "P", arrival: 5 * time.Minute, dwell: 10 * time.Minute}})
+	s.add(&freightTrain{schedule{name: "G", arrival: 30 * time.Minute, dwell: 10 * time.Minute}})
+	rep := s.run()
+
+	if rep.duration != 40*time.Minute {
+		t.Errorf("duration = %v", rep.duration)
+	}
+	if p := rep.platforms[0]; p.busy != 30*time.Minute || p.served != 3 || p.utilization != 0.75 {
+		t.Errorf("platform stats = %+v", p)
+	}
+	if c := rep.classes["passenger"]; c.avgWait != 5*time.Minute || c.maxWait != 5*time.Minute {
+		t.Errorf("passenger stats = %+v", c)
+	}
+	if c := rep.classes["freight"]; c.trains != 2 || c.avgWait != 0 {
+		t.Errorf("freight stats = %+v", c)
+	}
+}
@@ -1,7 +1,32 @@
 package main
 
+import "time"
+
 type train interface {
-	arrive()
-	depart()
-	permitArrival()
+	getName() string
+	// priority orders trains waiting for a platform: higher goes first.
+	priority() int
+	class() string
+	getSchedule() schedule
+}
+
+// schedule is when a train wants to arrive and how long it stays.
+type schedule struct {
+	name    string
+	arrival time.Duration
+	dwell   time.Duration
+}
+
+func (s schedule) getName() string       { return s.name }
+func (s schedule) getSchedule() schedule { return s }
+
+// runTrain is the life of a train, run on its own goroutine: wait for its
+// slot, get a platform, dwell, leave.
+func runTrain(t train, m mediator) {
+	defer m.done(t)
+	s := t.getSchedule()
+	m.sleepUntil(t, s.arrival)
+	platform, now := m.requestArrival(t)
+	m.sleepUntil(t, now+s.dwell)
+	m.notifyAboutDeparture(t, platform)
 }
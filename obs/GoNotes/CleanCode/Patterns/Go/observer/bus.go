@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// Event is a payload published on a topic.
+type Event[T any] struct {
+	Topic   string
+	Payload T
+}
+
+// Bus is a typed in-process publish/subscribe hub. It generalises the
+// subject/observer pair: anyone may publish on a topic and every
+// subscription whose pattern matches that topic receives the event.
+type Bus[T any] struct {
+	mu     sync.RWMutex
+	subs   []*Subscription[T]
+	nextID uint64
+	closed bool
+
+	wg       sync.WaitGroup
+	counters counters
+	onPanic  func(Event[T], any)
+}
+
+func NewBus[T any]() *Bus[T] {
+	return &Bus[T]{}
+}
+
+// OnPanic sets a hook called whenever a handler panics. The panic itself is
+// recovered and never reaches the publisher or other subscribers.
+func (b *Bus[T]) OnPanic(f func(ev Event[T], recovered any)) {
+	b.mu.Lock()
+	b.onPanic = f
+	b.mu.Unlock()
+}
+
+// Subscribe registers handler for every topic matching pattern. By default
+// delivery is synchronous, in subscription order, on the publisher's
+// goroutine.
+func (b *Bus[T]) Subscribe(pattern string, handler func(Event[T]), opts ...SubscribeOption) *Subscription[T] {
+	s := &Subscription[T]{
+		pattern: pattern,
+		bus:     b,
+		handler: handler,
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(&s.cfg)
+	}
+	if s.cfg.async {
+		s.queue = make(chan Event[T], s.cfg.buffer)
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		s.closed.Store(true)
+		s.once.Do(func() { close(s.done) })
+		return s
+	}
+	b.nextID++
+	s.id = b.nextID
+	b.subs = append(b.subs, s)
+	if s.cfg.async {
+		b.wg.Add(1)
+		go s.consume()
+	}
+	b.mu.Unlock()
+
+	if s.cfg.ctx != nil {
+		go s.watch(s.cfg.ctx)
+	}
+	return s
+}
+
+// SubscribeContext is Subscribe with WithContext(ctx) applied.
+func (b *Bus[T]) SubscribeContext(ctx context.Context, pattern string, handler func(Event[T]), opts ...SubscribeOption) *Subscription[T] {
+	return b.Subscribe(pattern, handler, append(opts, WithContext(ctx))...)
+}
+
+// Publish sends payload to every subscription matching topic.
+func (b *Bus[T]) Publish(topic string, payload T) {
+	b.counters.published.Add(1)
+	ev := Event[T]{Topic: topic, Payload: payload}
+
+	b.mu.RLock()
+	var matched []*Subscription[T]
+	for _, s := range b.subs {
+		if topicMatches(s.pattern, topic) {
+			matched = append(matched, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	if len(matched) == 0 {
+		b.counters.unmatched.Add(1)
+		return
+	}
+	for _, s := range matched {
+		s.deliver(ev)
+	}
+}
+
+// Close cancels every subscription and waits until asynchronous subscribers
+// have handled their queued events.
+func (b *Bus[T]) Close() {
+	b.mu.Lock()
+	b.closed = true
+	subs := append([]*Subscription[T](nil), b.subs...)
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.Unsubscribe()
+	}
+	b.wg.Wait()
+}
+
+// Metrics returns a snapshot of the delivery counters.
+func (b *Bus[T]) Metrics() Metrics {
+	b.mu.RLock()
+	n := len(b.subs)
+	b.mu.RUnlock()
+	return Metrics{
+		Published:   b.counters.published.Load(),
+		Unmatched:   b.counters.unmatched.Load(),
+		Delivered:   b.counters.delivered.Load(),
+		Dropped:     b.counters.dropped.Load(),
+		Panicked:    b.counters.panicked.Load(),
+		Subscribers: n,
+	}
+}
+
+func (b *Bus[T]) remove(s *Subscription[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, sub := range b.subs {
+		if sub == s {
+			b.subs = append(b.subs[:i], b.subs[i+1:]...)
+			return
+		}
+	}
+}
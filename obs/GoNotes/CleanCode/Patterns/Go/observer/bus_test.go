@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTopicMatches(t *testing.T) {
+	for _, test := range []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"item.shirt", "item.shirt", true},
+		{"item.*", "item.shirt", true},
+		{"item.*", "item.shirt.inStock", false},
+		{"item.*.inStock", "item.shirt.inStock", true},
+		{"item.#", "item.shirt.inStock", true},
+		{"item.#", "item", true},
+		{"#", "anything.at.all", true},
+		{"item.shoe", "item.shirt", false},
+		{"item.shirt.inStock", "item.shirt", false},
+	} {
+		if got := topicMatches(test.pattern, test.topic); got != test.want {
+			t.Errorf("topicMatches(%q, %q) = %t", test.pattern, test.topic, got)
+		}
+	}
+}
+
+func TestSyncDeliveryOrder(t *testing.T) {
+	b := NewBus[int]()
+	var got []string
+	b.Subscribe("a.*", func(ev Event[int]) { got = append(got, "first:"+ev.Topic) })
+	b.Subscribe("a.b", func(ev Event[int]) { got = append(got, "second:"+ev.Topic) })
+	b.Subscribe("c", func(ev Event[int]) { got = append(got, "never") })
+
+	b.Publish("a.b", 1)
+	b.Publish("x", 2)
+
+	want := []string{"first:a.b", "second:a.b"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	m := b.Metrics()
+	if m.Published != 2 || m.Delivered != 2 || m.Unmatched != 1 || m.Subscribers != 3 {
+		t.Errorf("unexpected metrics %+v", m)
+	}
+}
+
+func TestPanicIsolation(t *testing.T) {
+	b := NewBus[string]()
+	var recovered any
+	b.OnPanic(func(ev Event[string], r any) { recovered = r })
+
+	var reached bool
+	b.Subscribe("t", func(Event[string]) { panic("boom") })
+	b.Subscribe("t", func(Event[string]) { reached = true })
+
+	b.Publish("t", "x")
+	if !reached {
+		t.Error("subscriber after a panicking one was not called")
+	}
+	if recovered != "boom" {
+		t.Errorf("OnPanic got %v", recovered)
+	}
+	if m := b.Metrics(); m.Panicked != 1 || m.Delivered != 1 {
+		t.Errorf("unexpected metrics %+v", m)
+	}
+}
+
+func TestAsyncDelivery(t *testing.T) {
+	b := NewBus[int]()
+	var mu sync.Mutex
+	var sum int
+	b.Subscribe("n", func(ev Event[int]) {
+		mu.Lock()
+		sum += ev.Payload
+		mu.Unlock()
+	}, Async(4))
+
+	for i := 1; i <= 100; i++ {
+		b.Publish("n", i)
+	}
+	b.Close()
+
+	if sum != 5050 {
+		t.Errorf("sum = %d, want 5050", sum)
+	}
+	if m := b.Metrics(); m.Delivered != 100 || m.Dropped != 0 || m.Subscribers != 0 {
+		t.Errorf("unexpected metrics %+v", m)
+	}
+}
+
+func TestAsyncDropIfFull(t *testing.T) {
+	b := NewBus[int]()
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	b.Subscribe("n", func(Event[int]) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	}, Async(1), DropIfFull())
+
+	b.Publish("n", 1) // picked up by the consumer and blocks
+	<-started
+	b.Publish("n", 2) // fills the queue
+	b.Publish("n", 3) // dropped
+	close(release)
+	b.Close()
+
+	if m := b.Metrics(); m.Delivered != 2 || m.Dropped != 1 {
+		t.Errorf("unexpected metrics %+v", m)
+	}
+}
+
+func TestUnsubscribeFromHandler(t *testing.T) {
+	b := NewBus[int]()
+	var calls int
+	var s *Subscription[int]
+	s = b.Subscribe("n", func(Event[int]) {
+		calls++
+		s.Unsubscribe()
+	}, Async(1))
+
+	b.Publish("n", 1)
+	<-s.Done()
+	b.Publish("n", 2)
+	b.Close()
+
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+}
+
+func TestContextUnsubscribe(t *testing.T) {
+	b := NewBus[int]()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := b.SubscribeContext(ctx, "#", func(Event[int]) {})
+
+	cancel()
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatal("subscription not cancelled with its context")
+	}
+	if n := b.Metrics().Subscribers; n != 0 {
+		t.Errorf("%d subscribers left after cancel", n)
+	}
+}
+
+type recordingObserver struct {
+	id    string
+	items []string
+}
+
+func (r *recordingObserver) update(name string) { r.items = append(r.items, name) }
+func (r *recordingObserver) getID() string      { return r.id }
+
+func TestItemNotifyAll(t *testing.T) {
+	it := newItem("Shirt")
+	first := &recordingObserver{id: "a"}
+	second := &recordingObserver{id: "b"}
+	it.register(first)
+	it.register(second)
+	it.register(first)
+	it.deregister(second)
+
+	it.notifyAll()
+
+	if len(first.items) != 1 || first.items[0] != "Shirt" {
+		t.Errorf("first observer got %v", first.items)
+	}
+	if len(second.items) != 0 {
+		t.Errorf("deregistered observer got %v", second.items)
+	}
+}
@@ -3,14 +3,17 @@ package main
 import "fmt"
 
 type item struct {
-	observerList []observer
-	name         string
-	inStock      bool
+	bus           *Bus[string]
+	subscriptions map[string]*Subscription[string]
+	name          string
+	inStock       bool
 }
 
 func newItem(name string) *item {
 	return &item{
-		name: name,
+		bus:           NewBus[string](),
+		subscriptions: make(map[string]*Subscription[string]),
+		name:          name,
 	}
 }
 func (i *item) updateAvailability() {
@@ -19,26 +22,25 @@ func (i *item) updateAvailability() {
 	i.notifyAll()
 }
 func (i *item) register(o observer) {
-	i.observerList = append(i.observerList, o)
+	if _, ok := i.subscriptions[o.getID()]; ok {
+		return
+	}
+	i.subscriptions[o.getID()] = i.bus.Subscribe(i.topic(), func(ev Event[string]) {
+		o.update(ev.Payload)
+	})
 }
 
 func (i *item) deregister(o observer) {
-	i.observerList = removeFromslice(i.observerList, o)
+	if s, ok := i.subscriptions[o.getID()]; ok {
+		s.Unsubscribe()
+		delete(i.subscriptions, o.getID())
+	}
 }
 
 func (i *item) notifyAll() {
-	for _, observer := range i.observerList {
-		observer.update(i.name)
-	}
+	i.bus.Publish(i.topic(), i.name)
 }
 
-func removeFromslice(observerList []observer, observerToRemove observer) []observer {
-	observerListLength := len(observerList)
-	for i, observer := range observerList {
-		if observerToRemove.getID() == observer.getID() {
-			observerList[observerListLength-1], observerList[i] = observerList[i], observerList[observerListLength-1]
-			return observerList[:observerListLength-1]
-		}
-	}
-	return observerList
+func (i *item) topic() string {
+	return "item." + i.name + ".inStock"
 }
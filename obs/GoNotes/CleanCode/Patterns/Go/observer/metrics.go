@@ -0,0 +1,21 @@
+package main
+
+import "sync/atomic"
+
+// Metrics is a snapshot of the delivery counters of a Bus.
+type Metrics struct {
+	Published   uint64 // events passed to Publish
+	Unmatched   uint64 // events that had no matching subscriber
+	Delivered   uint64 // handler invocations that returned normally
+	Dropped     uint64 // events rejected by a full or closed queue
+	Panicked    uint64 // handler invocations that panicked
+	Subscribers int    // currently active subscriptions
+}
+
+type counters struct {
+	published atomic.Uint64
+	unmatched atomic.Uint64
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	panicked  atomic.Uint64
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// SubscribeOption configures a single subscription.
+type SubscribeOption func(*subscribeConfig)
+
+type subscribeConfig struct {
+	async      bool
+	buffer     int
+	dropIfFull bool
+	ctx        context.Context
+}
+
+// Async makes the subscription asynchronous: events are queued in a buffer of
+// the given size and handled on the subscriber's own goroutine.
+func Async(buffer int) SubscribeOption {
+	return func(c *subscribeConfig) {
+		c.async = true
+		c.buffer = buffer
+	}
+}
+
+// DropIfFull makes Publish drop events instead of blocking when the
+// subscriber's queue is full. It only affects asynchronous subscriptions.
+func DropIfFull() SubscribeOption {
+	return func(c *subscribeConfig) {
+		c.dropIfFull = true
+	}
+}
+
+// WithContext unsubscribes automatically once ctx is done.
+func WithContext(ctx context.Context) SubscribeOption {
+	return func(c *subscribeConfig) {
+		c.ctx = ctx
+	}
+}
+
+// Subscription is a handle to a registered handler.
+type Subscription[T any] struct {
+	id      uint64
+	pattern string
+	bus     *Bus[T]
+	handler func(Event[T])
+	cfg     subscribeConfig
+
+	queue chan Event[T]
+	done  chan struct{}
+	once  sync.Once
+
+	// mu is held for reading while an event is being enqueued and for
+	// writing when the subscription is closed, so that nothing can be
+	// enqueued after the consumer has drained the queue.
+	mu     sync.RWMutex
+	closed atomic.Bool
+
+	delivered atomic.Uint64
+}
+
+// Pattern returns the topic pattern the subscription was created with.
+func (s *Subscription[T]) Pattern() string {
+	return s.pattern
+}
+
+// Delivered returns the number of events the handler processed successfully.
+func (s *Subscription[T]) Delivered() uint64 {
+	return s.delivered.Load()
+}
+
+// Pending returns the number of queued events not yet handled.
+func (s *Subscription[T]) Pending() int {
+	return len(s.queue)
+}
+
+// Done is closed once the subscription has been cancelled.
+func (s *Subscription[T]) Done() <-chan struct{} {
+	return s.done
+}
+
+// Unsubscribe removes the subscription from the bus. Events already accepted
+// into an asynchronous queue are still delivered. It is safe to call more
+// than once and from inside the handler.
+func (s *Subscription[T]) Unsubscribe() {
+	s.once.Do(func() {
+		s.bus.remove(s)
+		close(s.done)
+		s.mu.Lock()
+		s.closed.Store(true)
+		s.mu.Unlock()
+	})
+}
+
+func (s *Subscription[T]) deliver(ev Event[T]) {
+	if !s.cfg.async {
+		if s.closed.Load() {
+			return
+		}
+		s.invoke(ev)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed.Load() {
+		s.bus.counters.dropped.Add(1)
+		return
+	}
+	if s.cfg.dropIfFull {
+		select {
+		case s.queue <- ev:
+		default:
+			s.bus.counters.dropped.Add(1)
+		}
+		return
+	}
+	select {
+	case s.queue <- ev:
+	case <-s.done:
+		s.bus.counters.dropped.Add(1)
+	}
+}
+
+func (s *Subscription[T]) consume() {
+	defer s.bus.wg.Done()
+	for {
+		select {
+		case ev := <-s.queue:
+			s.invoke(ev)
+		case <-s.done:
+			// Wait for in-flight senders, then flush what they left behind.
+			s.mu.Lock()
+			s.mu.Unlock()
+			for {
+				select {
+				case ev := <-s.queue:
+					s.invoke(ev)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Subscription[T]) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		s.Unsubscribe()
+	case <-s.done:
+	}
+}
+
+// invoke runs the handler, isolating the bus from a panicking subscriber.
+func (s *Subscription[T]) invoke(ev Event[T]) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.bus.counters.panicked.Add(1)
+			s.bus.mu.RLock()
+			onPanic := s.bus.onPanic
+			s.bus.mu.RUnlock()
+			if onPanic != nil {
+				onPanic(ev, r)
+			}
+		}
+	}()
+	s.handler(ev)
+	s.delivered.Add(1)
+	s.bus.counters.delivered.Add(1)
+}
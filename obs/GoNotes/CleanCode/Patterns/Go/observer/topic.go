@@ -0,0 +1,26 @@
+package main
+
+import "strings"
+
+// topicMatches reports whether topic matches pattern. Topics are dot-separated
+// segments; "*" matches exactly one segment and a trailing "#" matches any
+// number of remaining segments (including none).
+func topicMatches(pattern, topic string) bool {
+	if pattern == topic {
+		return true
+	}
+	p := strings.Split(pattern, ".")
+	t := strings.Split(topic, ".")
+	for i, seg := range p {
+		if seg == "#" && i == len(p)-1 {
+			return true
+		}
+		if i >= len(t) {
+			return false
+		}
+		if seg != "*" && seg != t[i] {
+			return false
+		}
+	}
+	return len(p) == len(t)
+}
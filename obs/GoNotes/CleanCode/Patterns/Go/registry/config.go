@@ -0,0 +1,81 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Spec is one configuration entry: which implementation to build, which
+// versions are acceptable and the options to build it with.
+type Spec struct {
+	Name    string  `json:"name"`
+	Version string  `json:"version,omitempty"`
+	Options Options `json:"options,omitempty"`
+}
+
+// Build constructs the product described by spec.
+func (r *Registry[T]) Build(spec Spec) (T, error) {
+	return r.NewVersion(spec.Name, spec.Version, spec.Options)
+}
+
+// BuildAll constructs every spec in order, stopping at the first error.
+func (r *Registry[T]) BuildAll(specs []Spec) ([]T, error) {
+	out := make([]T, 0, len(specs))
+	for i, s := range specs {
+		p, err := r.Build(s)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// ParseJSON reads either a single spec object or a list of them.
+func ParseJSON(data []byte) ([]Spec, error) {
+	data = bytes.TrimSpace(data)
+	var specs []Spec
+	if len(data) > 0 && data[0] == '{' {
+		var s Spec
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		specs = []Spec{s}
+	} else if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, err
+	}
+	for i, s := range specs {
+		if s.Name == "" {
+			return nil, fmt.Errorf("entry %d: missing name", i)
+		}
+	}
+	return specs, nil
+}
+
+// ParseYAML reads the same shapes as ParseJSON written in YAML. Only the
+// block subset of YAML is understood: nested mappings and sequences,
+// scalars, comments and single-line flow lists.
+func ParseYAML(data []byte) ([]Spec, error) {
+	v, err := parseYAML(string(data))
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJSON(b)
+}
+
+// Parse picks ParseYAML or ParseJSON from the file name extension.
+func Parse(filename string, data []byte) ([]Spec, error) {
+	switch {
+	case strings.HasSuffix(filename, ".yaml"), strings.HasSuffix(filename, ".yml"):
+		return ParseYAML(data)
+	case strings.HasSuffix(filename, ".json"):
+		return ParseJSON(data)
+	}
+	return nil, fmt.Errorf("%s: unknown configuration format", filename)
+}
@@ -0,0 +1,80 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NotFoundError reports a lookup of an unregistered name, with the closest
+// registered names as suggestions.
+type NotFoundError struct {
+	Kind        string
+	Name        string
+	Suggestions []string
+}
+
+func (e *NotFoundError) Error() string {
+	msg := fmt.Sprintf("registry %s: unknown %q", e.Kind, e.Name)
+	if len(e.Suggestions) > 0 {
+		msg += fmt.Sprintf("; did you mean %q?", strings.Join(e.Suggestions, `" or "`))
+	}
+	return msg
+}
+
+// VersionError reports that no registered version satisfies a constraint.
+type VersionError struct {
+	Kind       string
+	Name       string
+	Constraint string
+	Available  []string
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("registry %s: no version of %s matches %q (have %s)",
+		e.Kind, e.Name, e.Constraint, strings.Join(e.Available, ", "))
+}
+
+// suggest returns up to three candidates within a small edit distance of
+// name, closest first. Case differences are free.
+func suggest(name string, candidates []string) []string {
+	type scored struct {
+		name string
+		dist int
+	}
+	limit := max(2, len(name)/3)
+	var near []scored
+	for _, c := range candidates {
+		d := levenshtein(strings.ToLower(name), strings.ToLower(c))
+		if d <= limit || strings.HasPrefix(strings.ToLower(c), strings.ToLower(name)) {
+			near = append(near, scored{c, d})
+		}
+	}
+	sort.SliceStable(near, func(i, j int) bool { return near[i].dist < near[j].dist })
+	var out []string
+	for i := 0; i < len(near) && i < 3; i++ {
+		out = append(out, near[i].name)
+	}
+	return out
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,59 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Options are the settings passed to a constructor. They come either from
+// code or from the "options" object of a configuration entry.
+type Options map[string]any
+
+// String returns the string option key, or def if it is unset.
+func (o Options) String(key, def string) string {
+	if v, ok := o[key].(string); ok {
+		return v
+	}
+	return def
+}
+
+// Int returns the integer option key, or def if it is unset. Numbers
+// decoded from JSON arrive as float64 and are accepted if integral.
+func (o Options) Int(key string, def int) int {
+	switch v := o[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		if v == float64(int(v)) {
+			return int(v)
+		}
+	}
+	return def
+}
+
+// Bool returns the boolean option key, or def if it is unset.
+func (o Options) Bool(key string, def bool) bool {
+	if v, ok := o[key].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// Decode fills the struct pointed to by into from the options, using its
+// json tags. Unknown options are an error so that typos in configuration
+// do not go unnoticed.
+func (o Options) Decode(into any) error {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(into); err != nil {
+		return fmt.Errorf("options: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// Package registry is a plugin-style factory: products register a
+// constructor under a name and version, and callers build them by name,
+// optionally from a JSON or YAML configuration.
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Metadata describes a registered implementation.
+type Metadata struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// Constructor builds a product from its options.
+type Constructor[T any] func(opts Options) (T, error)
+
+type entry[T any] struct {
+	meta    Metadata
+	version version
+	ctor    Constructor[T]
+}
+
+// Registry holds the constructors for one kind of product.
+type Registry[T any] struct {
+	mu      sync.RWMutex
+	kind    string
+	entries map[string][]*entry[T] // by name, newest version first
+}
+
+// New returns an empty registry. kind names the product in error messages.
+func New[T any](kind string) *Registry[T] {
+	return &Registry[T]{kind: kind, entries: make(map[string][]*entry[T])}
+}
+
+// Register adds ctor under meta.Name and meta.Version. An empty version
+// means 0.0.0. Registering the same name and version twice is an error.
+func (r *Registry[T]) Register(meta Metadata, ctor Constructor[T]) error {
+	if meta.Name == "" {
+		return fmt.Errorf("registry %s: empty name", r.kind)
+	}
+	if meta.Version == "" {
+		meta.Version = "0.0.0"
+	}
+	v, err := parseVersion(meta.Version)
+	if err != nil {
+		return fmt.Errorf("registry %s: %s: %v", r.kind, meta.Name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := r.entries[meta.Name]
+	for _, e := range list {
+		if e.version.compare(v) == 0 {
+			return fmt.Errorf("registry %s: %s %s registered twice", r.kind, meta.Name, meta.Version)
+		}
+	}
+	list = append(list, &entry[T]{meta: meta, version: v, ctor: ctor})
+	sort.Slice(list, func(i, j int) bool { return list[i].version.compare(list[j].version) > 0 })
+	r.entries[meta.Name] = list
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It is meant for init
+// functions, where a bad registration is a programming error.
+func (r *Registry[T]) MustRegister(meta Metadata, ctor Constructor[T]) {
+	if err := r.Register(meta, ctor); err != nil {
+		panic(err)
+	}
+}
+
+// New builds the newest version of name.
+func (r *Registry[T]) New(name string, opts Options) (T, error) {
+	return r.NewVersion(name, "", opts)
+}
+
+// NewVersion builds the newest version of name that satisfies constraint,
+// e.g. ">=1.2, <2", "^1.4" or "~1.4.2". An empty constraint matches any
+// version.
+func (r *Registry[T]) NewVersion(name, constraint string, opts Options) (T, error) {
+	var zero T
+	e, err := r.lookup(name, constraint)
+	if err != nil {
+		return zero, err
+	}
+	p, err := e.ctor(opts)
+	if err != nil {
+		return zero, fmt.Errorf("registry %s: building %s %s: %w", r.kind, e.meta.Name, e.meta.Version, err)
+	}
+	return p, nil
+}
+
+func (r *Registry[T]) lookup(name, constraint string) (*entry[T], error) {
+	c, err := parseConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("registry %s: %v", r.kind, err)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list, ok := r.entries[name]
+	if !ok {
+		return nil, &NotFoundError{Kind: r.kind, Name: name, Suggestions: suggest(name, r.namesLocked())}
+	}
+	for _, e := range list {
+		if c.matches(e.version) {
+			return e, nil
+		}
+	}
+	var have []string
+	for _, e := range list {
+		have = append(have, e.meta.Version)
+	}
+	return nil, &VersionError{Kind: r.kind, Name: name, Constraint: constraint, Available: have}
+}
+
+// List returns the metadata of every registered implementation, sorted by
+// name and then newest version first.
+func (r *Registry[T]) List() []Metadata {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []Metadata
+	for _, name := range r.namesLocked() {
+		for _, e := range r.entries[name] {
+			out = append(out, e.meta)
+		}
+	}
+	return out
+}
+
+// Names returns the registered names in sorted order.
+func (r *Registry[T]) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.namesLocked()
+}
+
+func (r *Registry[T]) namesLocked() []string {
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
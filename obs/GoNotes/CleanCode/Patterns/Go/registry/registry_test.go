@@ -0,0 +1,211 @@
+package registry
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type widget struct {
+	name    string
+	version string
+	size    int
+}
+
+func newTestRegistry(t *testing.T) *Registry[*widget] {
+	r := New[*widget]("widget")
+	for _, m := range []Metadata{
+		{Name: "gear", Version: "1.0.0"},
+		{Name: "gear", Version: "1.4.2"},
+		{Name: "gear", Version: "2.0.0"},
+		{Name: "spring", Version: "0.3"},
+	} {
+		m := m
+		err := r.Register(m, func(o Options) (*widget, error) {
+			if o.Int("size", 1) < 0 {
+				return nil, errors.New("negative size")
+			}
+			return &widget{name: m.Name, version: m.Version, size: o.Int("size", 1)}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestVersionSelection(t *testing.T) {
+	r := newTestRegistry(t)
+	for _, test := range []struct {
+		constraint, want string
+	}{
+		{"", "2.0.0"},
+		{"*", "2.0.0"},
+		{"1.0.0", "1.0.0"},
+		{"<2", "1.4.2"},
+		{">=1.1, <2", "1.4.2"},
+		{"^1.0", "1.4.2"},
+		{"~1.0", "1.0.0"},
+		{">1.4.2", "2.0.0"},
+	} {
+		w, err := r.NewVersion("gear", test.constraint, nil)
+		if err != nil {
+			t.Errorf("%q: %v", test.constraint, err)
+			continue
+		}
+		if w.version != test.want {
+			t.Errorf("%q picked %s, want %s", test.constraint, w.version, test.want)
+		}
+	}
+
+	_, err := r.NewVersion("gear", ">=3", nil)
+	var verr *VersionError
+	if !errors.As(err, &verr) || len(verr.Available) != 3 {
+		t.Errorf("unsatisfiable constraint: %v", err)
+	}
+	if _, err := r.NewVersion("gear", ">=x", nil); err == nil {
+		t.Error("bad constraint accepted")
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	r := newTestRegistry(t)
+	ctor := func(Options) (*widget, error) { return &widget{}, nil }
+	if err := r.Register(Metadata{Name: "gear", Version: "1.4.2"}, ctor); err == nil {
+		t.Error("duplicate registration accepted")
+	}
+	if err := r.Register(Metadata{Name: "bolt", Version: "one"}, ctor); err == nil {
+		t.Error("bad version accepted")
+	}
+	if err := r.Register(Metadata{}, ctor); err == nil {
+		t.Error("empty name accepted")
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	r := newTestRegistry(t)
+	_, err := r.New("geer", nil)
+	var nf *NotFoundError
+	if !errors.As(err, &nf) {
+		t.Fatalf("got %v, want NotFoundError", err)
+	}
+	if !reflect.DeepEqual(nf.Suggestions, []string{"gear"}) {
+		t.Errorf("suggestions = %v", nf.Suggestions)
+	}
+	if !strings.Contains(err.Error(), `did you mean "gear"?`) {
+		t.Errorf("message = %q", err)
+	}
+	if _, err := r.New("zzzzzzzz", nil); !errors.As(err, &nf) || len(nf.Suggestions) != 0 {
+		t.Errorf("unexpected suggestions for unrelated name: %v", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	r := newTestRegistry(t)
+	var got []string
+	for _, m := range r.List() {
+		got = append(got, m.Name+"@"+m.Version)
+	}
+	want := []string{"gear@2.0.0", "gear@1.4.2", "gear@1.0.0", "spring@0.3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List = %v, want %v", got, want)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	r := newTestRegistry(t)
+	const js = `[{"name": "gear", "version": "^1", "options": {"size": 3}}, {"name": "spring"}]`
+	const yml = `
+# same thing in YAML
+- name: gear
+  version: "^1"
+  options:
+    size: 3
+- name: spring
+`
+	for _, src := range []struct{ file, data string }{{"w.json", js}, {"w.yaml", yml}} {
+		specs, err := Parse(src.file, []byte(src.data))
+		if err != nil {
+			t.Fatalf("%s: %v", src.file, err)
+		}
+		ws, err := r.BuildAll(specs)
+		if err != nil {
+			t.Fatalf("%s: %v", src.file, err)
+		}
+		if len(ws) != 2 || ws[0].version != "1.4.2" || ws[0].size != 3 || ws[1].name != "spring" {
+			t.Errorf("%s: built %+v %+v", src.file, ws[0], ws[1])
+		}
+	}
+
+	specs, _ := ParseJSON([]byte(`{"name": "gear", "options": {"size": -1}}`))
+	if _, err := r.BuildAll(specs); err == nil || !strings.Contains(err.Error(), "negative size") {
+		t.Errorf("constructor error not reported: %v", err)
+	}
+	if _, err := ParseJSON([]byte(`[{"version": "1"}]`)); err == nil {
+		t.Error("spec without name accepted")
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	got, err := parseYAML(`
+name: 'it''s'  # comment
+count: 3
+ratio: 0.5
+on: true
+none: ~
+tags: [a, "b c", 2]
+quoted: [a, "b, c", 'd, ''e''', "f\", g"]
+list:
+- one
+- two
+nested:
+  deep:
+    key: "x # not a comment"
+items:
+  - name: a
+    value: 1
+  -
+    name: b
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"name":   "it's",
+		"count":  int64(3),
+		"ratio":  0.5,
+		"on":     true,
+		"none":   nil,
+		"tags":   []any{"a", "b c", int64(2)},
+		"quoted": []any{"a", "b, c", "d, 'e'", `f", g`},
+		"list":   []any{"one", "two"},
+		"nested": map[string]any{"deep": map[string]any{"key": "x # not a comment"}},
+		"items": []any{
+			map[string]any{"name": "a", "value": int64(1)},
+			map[string]any{"name": "b"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %#v\nwant %#v", got, want)
+	}
+
+	for _, bad := range []string{"a: 1\n  b: 2", "a: 1\na: 2", "key without colon", "a: [1, 2"} {
+		if _, err := parseYAML(bad); err == nil {
+			t.Errorf("parseYAML(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestOptionsDecode(t *testing.T) {
+	var cfg struct {
+		Size int    `json:"size"`
+		Logo string `json:"logo"`
+	}
+	if err := (Options{"size": 14, "logo": "nike"}).Decode(&cfg); err != nil || cfg.Size != 14 || cfg.Logo != "nike" {
+		t.Errorf("Decode = %+v, %v", cfg, err)
+	}
+	if err := (Options{"colour": "red"}).Decode(&cfg); err == nil {
+		t.Error("unknown option accepted")
+	}
+}
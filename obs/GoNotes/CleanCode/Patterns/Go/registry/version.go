@@ -0,0 +1,109 @@
+package registry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// version is a major.minor.patch triple. Missing parts count as zero.
+type version [3]int
+
+func parseVersion(s string) (version, error) {
+	var v version
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	parts := strings.Split(s, ".")
+	if len(parts) == 0 || len(parts) > 3 || parts[0] == "" {
+		return v, fmt.Errorf("bad version %q", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("bad version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+func (v version) compare(w version) int {
+	for i := range v {
+		switch {
+		case v[i] < w[i]:
+			return -1
+		case v[i] > w[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+type bound struct {
+	op string // one of = > >= < <=
+	v  version
+}
+
+// constraint is a conjunction of bounds; the empty constraint matches all.
+type constraint []bound
+
+// parseConstraint accepts comma-separated terms of the form "1.2.3",
+// "=1.2", ">1", ">=1.2", "<2", "<=2.1", "^1.2" (same major), "~1.2"
+// (same minor) and "*".
+func parseConstraint(s string) (constraint, error) {
+	var c constraint
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" || term == "*" {
+			continue
+		}
+		op := ""
+		for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(term, prefix) {
+				op, term = prefix, strings.TrimSpace(term[len(prefix):])
+				break
+			}
+		}
+		v, err := parseVersion(term)
+		if err != nil {
+			return nil, fmt.Errorf("constraint %q: %v", s, err)
+		}
+		switch op {
+		case "", "=":
+			c = append(c, bound{"=", v})
+		case "^":
+			c = append(c, bound{">=", v}, bound{"<", version{v[0] + 1}})
+		case "~":
+			c = append(c, bound{">=", v}, bound{"<", version{v[0], v[1] + 1}})
+		default:
+			c = append(c, bound{op, v})
+		}
+	}
+	return c, nil
+}
+
+func (c constraint) matches(v version) bool {
+	for _, b := range c {
+		cmp := v.compare(b.v)
+		var ok bool
+		switch b.op {
+		case "=":
+			ok = cmp == 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
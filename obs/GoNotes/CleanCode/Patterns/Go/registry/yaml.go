@@ -0,0 +1,248 @@
+package registry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type yamlLine struct {
+	num    int // 1-based, for errors
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAML decodes block-style YAML into maps, slices and scalars.
+func parseYAML(src string) (any, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(src, "\n") {
+		text := stripComment(strings.TrimRight(raw, " \t\r"))
+		if strings.TrimSpace(text) == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimLeft(text, " "), "\t") {
+			return nil, fmt.Errorf("yaml:%d: tabs are not allowed for indentation", i+1)
+		}
+		trimmed := strings.TrimLeft(text, " ")
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+	v, err := p.node(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("yaml:%d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return v, nil
+}
+
+func (p *yamlParser) node(indent int) (any, error) {
+	if isSeqItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) sequence(indent int) (any, error) {
+	var out []any
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent != indent || !isSeqItem(l.text) {
+			break
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		if rest == "" {
+			p.pos++
+			if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+				out = append(out, nil)
+				continue
+			}
+			v, err := p.node(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			continue
+		}
+		if !strings.Contains(rest, ": ") && !strings.HasSuffix(rest, ":") && !isSeqItem(rest) {
+			v, err := scalar(rest, l.num)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			p.pos++
+			continue
+		}
+		// "- key: value" opens a mapping whose keys line up with "key".
+		p.lines[p.pos] = yamlLine{num: l.num, indent: l.indent + len(l.text) - len(rest), text: rest}
+		v, err := p.node(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (p *yamlParser) mapping(indent int) (any, error) {
+	out := make(map[string]any)
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent || isSeqItem(l.text) {
+			return nil, fmt.Errorf("yaml:%d: unexpected indentation", l.num)
+		}
+		key, value, ok := strings.Cut(l.text, ":")
+		if !ok || (value != "" && value[0] != ' ') {
+			return nil, fmt.Errorf("yaml:%d: expected \"key: value\"", l.num)
+		}
+		key = unquote(strings.TrimSpace(key))
+		if _, dup := out[key]; dup {
+			return nil, fmt.Errorf("yaml:%d: duplicate key %q", l.num, key)
+		}
+		value = strings.TrimSpace(value)
+		p.pos++
+		if value != "" {
+			v, err := scalar(value, l.num)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = v
+			continue
+		}
+		// A nested block is either indented further or, for sequences,
+		// may start at the key's own indentation.
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			if next.indent > indent || (next.indent == indent && isSeqItem(next.text)) {
+				v, err := p.node(next.indent)
+				if err != nil {
+					return nil, err
+				}
+				out[key] = v
+				continue
+			}
+		}
+		out[key] = nil
+	}
+	return out, nil
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func scalar(s string, line int) (any, error) {
+	if strings.HasPrefix(s, "[") {
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("yaml:%d: unterminated flow sequence", line)
+		}
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		list := []any{}
+		if inner == "" {
+			return list, nil
+		}
+		for _, item := range splitFlow(inner) {
+			v, err := scalar(strings.TrimSpace(item), line)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("yaml:%d: bad string %s", line, s)
+		}
+		return v, nil
+	}
+	if strings.HasPrefix(s, "'") {
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return nil, fmt.Errorf("yaml:%d: bad string %s", line, s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	switch s {
+	case "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return s, nil
+}
+
+// splitFlow splits the items of a flow sequence at the commas that are
+// not inside quotes or nested brackets.
+func splitFlow(s string) []string {
+	var items []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++ // skip the escaped character
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
+
+func unquote(s string) string {
+	if v, err := scalar(s, 0); err == nil {
+		if str, ok := v.(string); ok {
+			return str
+		}
+	}
+	return s
+}
+
+// stripComment removes a "#" comment that is not inside quotes.
+func stripComment(s string) string {
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
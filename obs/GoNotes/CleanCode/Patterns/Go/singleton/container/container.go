@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrClosed is returned by Resolve once the container has been closed.
+var ErrClosed = errors.New("container: closed")
+
+// Container holds at most one instance per type, built on first Resolve by
+// the provider registered for that type. It is the runtime counterpart of
+// what Wire generates in DependencyInjection: providers name their
+// dependencies by resolving them, and the container works out the order.
+type Container struct {
+	mu        sync.Mutex
+	providers map[reflect.Type]provider
+	closed    bool
+	closers   []closer // in init order
+
+	// build serialises construction. Constructors resolve their own
+	// dependencies while it is held, which is why a resolution carries its
+	// chain in the context: nested calls must not take it again.
+	build sync.Mutex
+	root  *scope
+}
+
+type provider func(ctx context.Context) (any, error)
+
+type closer struct {
+	typ   reflect.Type
+	close func() error
+}
+
+// scope is a cache of built instances. The container has one; every
+// Override context gets its own.
+type scope struct {
+	mu        sync.Mutex
+	instances map[reflect.Type]any
+	overrides map[reflect.Type]any
+}
+
+func newScope() *scope {
+	return &scope{instances: make(map[reflect.Type]any), overrides: make(map[reflect.Type]any)}
+}
+
+func (s *scope) get(t reflect.Type) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.overrides[t]; ok {
+		return v, true
+	}
+	v, ok := s.instances[t]
+	return v, ok
+}
+
+// NewContainer returns an empty container.
+func NewContainer() *Container {
+	return &Container{providers: make(map[reflect.Type]provider), root: newScope()}
+}
+
+// Provide registers the constructor for T, replacing any earlier one. The
+// constructor runs at most once, on first Resolve, and gets a context it
+// must pass to Resolve for its own dependencies; it is only valid during
+// the call.
+func Provide[T any](c *Container, ctor func(ctx context.Context, c *Container) (T, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.providers[reflect.TypeFor[T]()] = func(ctx context.Context) (any, error) {
+		return ctor(ctx, c)
+	}
+}
+
+// ProvideValue registers an already built T.
+func ProvideValue[T any](c *Container, v T) {
+	Provide(c, func(context.Context, *Container) (T, error) { return v, nil })
+}
+
+// Resolve returns the instance of T, building it and its dependencies
+// first if needed. Instances implementing io.Closer, or with a plain
+// Close(), are closed by Container.Close.
+func Resolve[T any](ctx context.Context, c *Container) (T, error) {
+	var zero T
+	v, err := c.resolve(ctx, reflect.TypeFor[T]())
+	if err != nil {
+		return zero, err
+	}
+	// A provider for an interface type may return nil, which is stored
+	// as a nil any and so asserts to nothing.
+	t, _ := v.(T)
+	return t, nil
+}
+
+// MustResolve is Resolve for wiring code that cannot continue without T.
+func MustResolve[T any](ctx context.Context, c *Container) T {
+	v, err := Resolve[T](ctx, c)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+type chainKey struct{}
+type scopeKey struct{}
+
+// chain is the list of types being built, outermost first.
+type chain struct {
+	c     *Container
+	types []reflect.Type
+}
+
+func (c *Container) resolve(ctx context.Context, t reflect.Type) (any, error) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil, ErrClosed
+	}
+
+	sc := c.root
+	if s, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		sc = s
+	}
+	if v, ok := sc.get(t); ok {
+		return v, nil
+	}
+
+	ch, nested := ctx.Value(chainKey{}).(*chain)
+	if !nested || ch.c != c {
+		c.build.Lock()
+		defer c.build.Unlock()
+		c.mu.Lock()
+		closed := c.closed
+		c.mu.Unlock()
+		if closed {
+			return nil, ErrClosed
+		}
+		// Someone may have built it while we waited.
+		if v, ok := sc.get(t); ok {
+			return v, nil
+		}
+		ch = &chain{c: c}
+	}
+	if slices.Contains(ch.types, t) {
+		return nil, &CycleError{Path: append(slices.Clone(ch.types[slices.Index(ch.types, t):]), t)}
+	}
+
+	c.mu.Lock()
+	p, ok := c.providers[t]
+	c.mu.Unlock()
+	if !ok {
+		return nil, &MissingError{Type: t, Chain: slices.Clone(ch.types)}
+	}
+
+	next := &chain{c: c, types: append(slices.Clone(ch.types), t)}
+	v, err := call(context.WithValue(ctx, chainKey{}, next), p)
+	if err != nil {
+		var cycle *CycleError
+		var missing *MissingError
+		if errors.As(err, &cycle) || errors.As(err, &missing) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("container: building %v: %w", t, err)
+	}
+
+	sc.mu.Lock()
+	sc.instances[t] = v
+	sc.mu.Unlock()
+	if f := closeFunc(v); f != nil {
+		c.mu.Lock()
+		c.closers = append(c.closers, closer{typ: t, close: f})
+		c.mu.Unlock()
+	}
+	return v, nil
+}
+
+// call runs a provider, turning a panic into an error.
+func call(ctx context.Context, p provider) (v any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return p(ctx)
+}
+
+func closeFunc(v any) func() error {
+	switch v := v.(type) {
+	case io.Closer:
+		return v.Close
+	case interface{ Close() }:
+		return func() error { v.Close(); return nil }
+	}
+	return nil
+}
+
+// Override returns a context in which Resolve on c yields v for T. The
+// context gets its own instance cache, so everything resolved through it
+// is rebuilt against the override and nothing leaks back into c or into
+// other tests. Overrides stack: an Override of an Override context keeps
+// the outer ones.
+func Override[T any](ctx context.Context, v T) context.Context {
+	s := newScope()
+	if parent, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		parent.mu.Lock()
+		for t, o := range parent.overrides {
+			s.overrides[t] = o
+		}
+		parent.mu.Unlock()
+	}
+	s.overrides[reflect.TypeFor[T]()] = v
+	return context.WithValue(ctx, scopeKey{}, s)
+}
+
+// Close shuts instances down in reverse order of construction, so each is
+// closed before the things it was built from. It returns every error and
+// makes later Resolve calls fail.
+func (c *Container) Close() error {
+	c.build.Lock() // let any construction in progress finish
+	defer c.build.Unlock()
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	closers := c.closers
+	c.closers = nil
+	c.mu.Unlock()
+
+	var errs []error
+	for _, cl := range slices.Backward(closers) {
+		if err := cl.close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing %v: %w", cl.typ, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CycleError reports a type that, through its providers, depends on
+// itself.
+type CycleError struct {
+	Path []reflect.Type // starts and ends with the same type
+}
+
+func (e *CycleError) Error() string {
+	return "container: dependency cycle: " + joinTypes(e.Path)
+}
+
+// MissingError reports a type with no provider.
+type MissingError struct {
+	Type  reflect.Type
+	Chain []reflect.Type // what was being built when it was needed
+}
+
+func (e *MissingError) Error() string {
+	if len(e.Chain) == 0 {
+		return fmt.Sprintf("container: no provider for %v", e.Type)
+	}
+	return fmt.Sprintf("container: no provider for %v (needed by %s)", e.Type, joinTypes(e.Chain))
+}
+
+func joinTypes(types []reflect.Type) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.String()
+	}
+	return strings.Join(names, " -> ")
+}
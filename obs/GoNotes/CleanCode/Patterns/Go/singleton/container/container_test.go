@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type (
+	a struct{ b *b }
+	b struct{ n int }
+	c struct{}
+)
+
+type closeRecorder struct {
+	name string
+	log  *[]string
+	err  error
+}
+
+func (r *closeRecorder) Close() error {
+	*r.log = append(*r.log, r.name)
+	return r.err
+}
+
+func TestLazySingleton(t *testing.T) {
+	ctx := context.Background()
+	con := NewContainer()
+	var builds atomic.Int32
+	Provide(con, func(context.Context, *Container) (*b, error) {
+		builds.Add(1)
+		return &b{n: 7}, nil
+	})
+	if builds.Load() != 0 {
+		t.Fatal("built before first Resolve")
+	}
+
+	var wg sync.WaitGroup
+	got := make([]*b, 100)
+	for i := range got {
+		wg.Go(func() { got[i] = MustResolve[*b](ctx, con) })
+	}
+	wg.Wait()
+	if n := builds.Load(); n != 1 {
+		t.Errorf("built %d times", n)
+	}
+	for _, v := range got {
+		if v != got[0] {
+			t.Fatal("different instances")
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	con := NewContainer()
+	fail := true
+	Provide(con, func(ctx context.Context, con *Container) (*a, error) {
+		dep, err := Resolve[*b](ctx, con)
+		return &a{b: dep}, err
+	})
+	Provide(con, func(context.Context, *Container) (*b, error) {
+		if fail {
+			return nil, errors.New("boom")
+		}
+		return &b{}, nil
+	})
+
+	_, err := Resolve[*a](ctx, con)
+	if err == nil || !strings.Contains(err.Error(), "building *main.a: container: building *main.b: boom") {
+		t.Errorf("err = %v", err)
+	}
+	fail = false
+	if _, err := Resolve[*a](ctx, con); err != nil {
+		t.Errorf("failure was cached: %v", err)
+	}
+
+	_, err = Resolve[*c](ctx, con)
+	var missing *MissingError
+	if !errors.As(err, &missing) || missing.Type != reflect.TypeFor[*c]() {
+		t.Errorf("missing: %v", err)
+	}
+
+	Provide(con, func(context.Context, *Container) (*c, error) { panic("oops") })
+	if _, err := Resolve[*c](ctx, con); err == nil || !strings.Contains(err.Error(), "panic: oops") {
+		t.Errorf("panic: %v", err)
+	}
+}
+
+func TestNilProvider(t *testing.T) {
+	con := NewContainer()
+	Provide(con, func(context.Context, *Container) (io.Writer, error) { return nil, nil })
+	w, err := Resolve[io.Writer](context.Background(), con)
+	if err != nil || w != nil {
+		t.Errorf("Resolve = %v, %v, want nil, nil", w, err)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	ctx := context.Background()
+	con := NewContainer()
+	Provide(con, func(ctx context.Context, con *Container) (*a, error) {
+		_, err := Resolve[*b](ctx, con)
+		return &a{}, err
+	})
+	Provide(con, func(ctx context.Context, con *Container) (*b, error) {
+		_, err := Resolve[*c](ctx, con)
+		return &b{}, err
+	})
+	Provide(con, func(ctx context.Context, con *Container) (*c, error) {
+		_, err := Resolve[*b](ctx, con)
+		return &c{}, err
+	})
+	_, err := Resolve[*a](ctx, con)
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("err = %v", err)
+	}
+	if got := joinTypes(cycle.Path); got != "*main.b -> *main.c -> *main.b" {
+		t.Errorf("cycle path %s", got)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	ctx := context.Background()
+	con := NewContainer()
+	Provide(con, func(ctx context.Context, con *Container) (*a, error) {
+		dep, err := Resolve[*b](ctx, con)
+		return &a{b: dep}, err
+	})
+	ProvideValue(con, &b{n: 1})
+	ProvideValue(con, &c{})
+
+	real := MustResolve[*a](ctx, con)
+	fake := &b{n: 2}
+	testCtx := Override(ctx, fake)
+	scoped := MustResolve[*a](testCtx, con)
+	if scoped.b != fake || scoped == real {
+		t.Errorf("override not applied: %+v", scoped)
+	}
+	if MustResolve[*a](testCtx, con) != scoped {
+		t.Error("scope does not cache")
+	}
+	if MustResolve[*a](ctx, con) != real || real.b.n != 1 {
+		t.Error("override leaked out of its context")
+	}
+
+	fakeC := &c{}
+	inner := Override(testCtx, fakeC)
+	if MustResolve[*b](inner, con) != fake || MustResolve[*c](inner, con) != fakeC {
+		t.Error("stacked overrides lost the outer one")
+	}
+}
+
+func TestCloseOrder(t *testing.T) {
+	ctx := context.Background()
+	con := NewContainer()
+	var log []string
+	type (
+		db   struct{ *closeRecorder }
+		repo struct{ *closeRecorder }
+		svc  struct{ *closeRecorder }
+	)
+	Provide(con, func(ctx context.Context, con *Container) (*svc, error) {
+		MustResolve[*repo](ctx, con)
+		return &svc{&closeRecorder{name: "svc", log: &log}}, nil
+	})
+	Provide(con, func(ctx context.Context, con *Container) (*repo, error) {
+		MustResolve[*db](ctx, con)
+		return &repo{&closeRecorder{name: "repo", log: &log, err: errors.New("flush failed")}}, nil
+	})
+	Provide(con, func(context.Context, *Container) (*db, error) {
+		return &db{&closeRecorder{name: "db", log: &log}}, nil
+	})
+	MustResolve[*svc](ctx, con)
+
+	err := con.Close()
+	if !slices.Equal(log, []string{"svc", "repo", "db"}) {
+		t.Errorf("close order %v", log)
+	}
+	if err == nil || !strings.Contains(err.Error(), "flush failed") {
+		t.Errorf("close error %v", err)
+	}
+	if err := con.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if _, err := Resolve[*svc](ctx, con); !errors.Is(err, ErrClosed) {
+		t.Errorf("resolve after close: %v", err)
+	}
+}
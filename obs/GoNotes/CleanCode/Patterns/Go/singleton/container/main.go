@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+type fakeRepo struct{}
+
+func (fakeRepo) name(id int) string { return fmt.Sprintf("fake user %d", id) }
+
+type chicken struct{}
+type egg struct{}
+
+func main() {
+	ctx := context.Background()
+	c := NewContainer()
+	register(c)
+
+	// Thirty goroutines race for the service; the database opens once.
+	var wg sync.WaitGroup
+	services := make([]*userService, 30)
+	for i := range services {
+		wg.Go(func() { services[i] = MustResolve[*userService](ctx, c) })
+	}
+	wg.Wait()
+	fmt.Println("databases opened:", databasesOpened.Load())
+	fmt.Println("same service everywhere:", services[0] == services[29])
+	fmt.Println(services[0].greet(1))
+
+	// A test swaps the repository for the duration of one context.
+	testCtx := Override[userRepo](ctx, fakeRepo{})
+	svc, err := Resolve[*userService](testCtx, c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("with override:", svc.greet(2))
+	fmt.Println("without override:", MustResolve[*userService](ctx, c).greet(2))
+
+	// Cycles and gaps in the graph are reported, not deadlocked on.
+	Provide(c, func(ctx context.Context, c *Container) (*chicken, error) {
+		_, err := Resolve[*egg](ctx, c)
+		return &chicken{}, err
+	})
+	Provide(c, func(ctx context.Context, c *Container) (*egg, error) {
+		_, err := Resolve[*chicken](ctx, c)
+		return &egg{}, err
+	})
+	_, err = Resolve[*chicken](ctx, c)
+	fmt.Println(err)
+	_, err = Resolve[fmt.Stringer](ctx, c)
+	fmt.Println(err)
+
+	fmt.Println()
+	if err := c.Close(); err != nil {
+		log.Fatal(err)
+	}
+	_, err = Resolve[*userService](ctx, c)
+	fmt.Println("after close:", err)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// A small application graph: config <- database <- userRepo <- userService.
+
+type config struct {
+	dsn string
+}
+
+type database struct {
+	dsn string
+}
+
+func (d *database) Close() error {
+	fmt.Println("closing database", d.dsn)
+	return nil
+}
+
+type userRepo interface {
+	name(id int) string
+}
+
+type sqlUserRepo struct {
+	db *database
+}
+
+func (r *sqlUserRepo) name(id int) string { return fmt.Sprintf("user-%d from %s", id, r.db.dsn) }
+
+func (r *sqlUserRepo) Close() {
+	fmt.Println("closing user repository")
+}
+
+type userService struct {
+	repo userRepo
+}
+
+func (s *userService) greet(id int) string { return "hello, " + s.repo.name(id) }
+
+// databasesOpened counts database constructor runs.
+var databasesOpened atomic.Int32
+
+// register wires the application's providers into c.
+func register(c *Container) {
+	ProvideValue(c, &config{dsn: "postgres://prod"})
+	Provide(c, func(ctx context.Context, c *Container) (*database, error) {
+		cfg, err := Resolve[*config](ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		databasesOpened.Add(1)
+		fmt.Println("opening database", cfg.dsn)
+		return &database{dsn: cfg.dsn}, nil
+	})
+	Provide(c, func(ctx context.Context, c *Container) (userRepo, error) {
+		db, err := Resolve[*database](ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		return &sqlUserRepo{db: db}, nil
+	})
+	Provide(c, func(ctx context.Context, c *Container) (*userService, error) {
+		repo, err := Resolve[userRepo](ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		return &userService{repo: repo}, nil
+	})
+}
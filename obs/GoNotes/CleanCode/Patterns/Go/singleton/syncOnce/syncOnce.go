@@ -12,14 +12,17 @@ type single struct {
 
 var singleInstance *single
 
+// getInstance leaves every check to once.Do: reading singleInstance
+// outside it would race with the write inside.
 func getInstance() *single {
-	if singleInstance == nil {
-		once.Do(
-			func() {
-				fmt.Println("Creating single instance now.")
-				singleInstance = &single{}
-			})
-	} else {
+	created := false
+	once.Do(
+		func() {
+			fmt.Println("Creating single instance now.")
+			singleInstance = &single{}
+			created = true
+		})
+	if !created {
 		fmt.Println("Single instance already created.")
 	}
 
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// channel implements the iOtp steps shared by every delivery channel; sms
+// and email embed it and supply their own message text.
+type channel struct {
+	name      string
+	generator codeGenerator
+	store     *otpStore
+	sender    sender
+	metrics   *metrics
+}
+
+func (c *channel) genRandomOTP(recipient string, length int) (string, error) {
+	code, err := c.generator.generate(recipient, length)
+	if err != nil {
+		return "", fmt.Errorf("%s: generating otp: %w", c.name, err)
+	}
+	return code, nil
+}
+
+// saveOTPCache keeps a hash of the code, unless the generator verifies its
+// own codes, in which case only the lockout applies.
+func (c *channel) saveOTPCache(recipient, otp string) error {
+	if _, ok := c.generator.(codeVerifier); ok {
+		return c.store.allow(recipient)
+	}
+	return c.store.save(recipient, otp)
+}
+
+func (c *channel) checkOTPCache(recipient, otp string) error {
+	if v, ok := c.generator.(codeVerifier); ok {
+		return c.store.checkWith(recipient, func() (bool, error) { return v.verify(recipient, otp) })
+	}
+	return c.store.check(recipient, otp)
+}
+
+func (c *channel) sendNotification(ctx context.Context, recipient, message string) error {
+	if err := c.sender.send(ctx, recipient, message); err != nil {
+		return fmt.Errorf("%s: sending to %s: %w", c.name, recipient, err)
+	}
+	return nil
+}
+
+func (c *channel) publishMetric(name string) {
+	c.metrics.inc(c.name, name)
+}
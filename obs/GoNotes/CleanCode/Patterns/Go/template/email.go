@@ -1,30 +1,13 @@
 package main
 
-import "fmt"
-
 type email struct {
-	otp
-}
-
-func (s *email) genRandomOTP(len int) string {
-	randomOTP := "1234"
-	fmt.Printf("EMAIL: generating random otp %s\n", randomOTP)
-	return randomOTP
+	channel
 }
 
-func (s *email) saveOTPCache(otp string) {
-	fmt.Printf("EMAIL: saving otp: %s to cache\n", otp)
+func newEmail(g codeGenerator, store *otpStore, s sender, m *metrics) *email {
+	return &email{channel{name: "email", generator: g, store: store, sender: s, metrics: m}}
 }
 
 func (s *email) getMessage(otp string) string {
 	return "EMAIL OTP for login is " + otp
 }
-
-func (s *email) sendNotification(message string) error {
-	fmt.Printf("EMAIL: sending email: %s\n", message)
-	return nil
-}
-
-func (s *email) publishMetric() {
-	fmt.Printf("EMAIL: publishing metrics\n")
-}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// codeGenerator produces the one-time code for a recipient.
+type codeGenerator interface {
+	generate(recipient string, digits int) (string, error)
+}
+
+// A codeVerifier is a codeGenerator that checks codes itself, rather than
+// against the copy kept in the store.
+type codeVerifier interface {
+	codeGenerator
+	verify(recipient, code string) (bool, error)
+}
+
+// randomGenerator draws every digit uniformly from crypto/rand.
+type randomGenerator struct{}
+
+func (randomGenerator) generate(_ string, digits int) (string, error) {
+	if digits < 1 {
+		return "", fmt.Errorf("otp length %d", digits)
+	}
+	n, err := rand.Int(rand.Reader, pow10(digits))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// checkHOTPDigits rejects code lengths hotp cannot produce: the truncated
+// HMAC is a 31-bit number, so it has at most 9 decimal digits to give.
+func checkHOTPDigits(digits int) error {
+	if digits < 1 || digits > 9 {
+		return fmt.Errorf("otp length %d: HOTP codes have 1 to 9 digits", digits)
+	}
+	return nil
+}
+
+// hotp computes an RFC 4226 HMAC-based one-time password of 1 to 9 digits.
+func hotp(newHash func() hash.Hash, secret []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(newHash, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3.
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
+
+// secrets holds the shared secret of every enrolled recipient.
+type secrets struct {
+	mu   sync.Mutex
+	keys map[string][]byte
+}
+
+// enroll creates and returns a fresh 20-byte secret for recipient, to be
+// shared with their authenticator.
+func (s *secrets) enroll(recipient string) ([]byte, error) {
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	s.set(recipient, key)
+	return key, nil
+}
+
+func (s *secrets) set(recipient string, key []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		s.keys = make(map[string][]byte)
+	}
+	s.keys[recipient] = key
+}
+
+func (s *secrets) get(recipient string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[recipient]
+	if !ok {
+		return nil, fmt.Errorf("recipient %q is not enrolled", recipient)
+	}
+	return key, nil
+}
+
+// hotpGenerator issues RFC 4226 codes, moving each recipient's counter on
+// after every code.
+type hotpGenerator struct {
+	secrets
+	counters map[string]uint64
+}
+
+func (g *hotpGenerator) generate(recipient string, digits int) (string, error) {
+	if err := checkHOTPDigits(digits); err != nil {
+		return "", err
+	}
+	key, err := g.get(recipient)
+	if err != nil {
+		return "", err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.counters == nil {
+		g.counters = make(map[string]uint64)
+	}
+	c := g.counters[recipient]
+	g.counters[recipient] = c + 1
+	return hotp(sha1.New, key, c, digits), nil
+}
+
+// totpGenerator issues RFC 6238 time-based codes. It verifies them itself:
+// a code is accepted during its own time step and the steps either side of
+// it, to allow for clock drift and delivery delay, and only once.
+type totpGenerator struct {
+	secrets
+	step time.Duration    // 30s if zero
+	t0   time.Time        // Unix epoch if zero
+	now  func() time.Time // time.Now if nil
+	hash func() hash.Hash // SHA-1 if nil
+
+	next map[string]uint64 // the least counter not yet accepted, per recipient
+}
+
+func (g *totpGenerator) generate(recipient string, digits int) (string, error) {
+	key, err := g.get(recipient)
+	if err != nil {
+		return "", err
+	}
+	return g.at(key, g.clock(), digits)
+}
+
+func (g *totpGenerator) at(key []byte, t time.Time, digits int) (string, error) {
+	if err := checkHOTPDigits(digits); err != nil {
+		return "", err
+	}
+	counter, err := g.counter(t)
+	if err != nil {
+		return "", err
+	}
+	return hotp(g.newHash, key, counter, digits), nil
+}
+
+// verify reports whether code is the recipient's code for the current time
+// step or one either side, and was not accepted before.
+func (g *totpGenerator) verify(recipient, code string) (bool, error) {
+	key, err := g.get(recipient)
+	if err != nil {
+		return false, err
+	}
+	if checkHOTPDigits(len(code)) != nil {
+		return false, nil
+	}
+	now, err := g.counter(g.clock())
+	if err != nil {
+		return false, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for c := max(now, 1) - 1; c <= now+1; c++ {
+		if c < g.next[recipient] {
+			continue // used already
+		}
+		want := hotp(g.newHash, key, c, len(code))
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			if g.next == nil {
+				g.next = make(map[string]uint64)
+			}
+			g.next[recipient] = c + 1
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// counter returns the number of time steps from t0 to t.
+func (g *totpGenerator) counter(t time.Time) (uint64, error) {
+	step := g.step
+	if step == 0 {
+		step = 30 * time.Second
+	}
+	if step < time.Second || step%time.Second != 0 {
+		return 0, fmt.Errorf("totp step %v is not a whole number of seconds", step)
+	}
+	t0 := g.t0
+	if t0.IsZero() {
+		t0 = time.Unix(0, 0)
+	}
+	// Whole seconds, as in the RFC; time.Duration would overflow for
+	// timestamps a few centuries out.
+	return uint64((t.Unix() - t0.Unix()) / int64(step/time.Second)), nil
+}
+
+func (g *totpGenerator) newHash() hash.Hash {
+	if g.hash != nil {
+		return g.hash()
+	}
+	return sha1.New()
+}
+
+func (g *totpGenerator) clock() time.Time {
+	if g.now != nil {
+		return g.now()
+	}
+	return time.Now()
+}
@@ -1,32 +1,79 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
 
 func main() {
-	// otp := otp{}
-
-	// smsOTP := &sms{
-	// 	otp: otp,
-	// }
+	ctx := context.Background()
+	store, err := newOTPStore(5*time.Minute, 3, 15*time.Minute)
+	if err != nil {
+		log.Fatal(err)
+	}
+	m := &metrics{}
 
-	// smsOTP.genAndSendOTP(smsOTP, 4)
+	// A stand-in SMS gateway on a loopback port that remembers the last
+	// message per number.
+	lastSMS := make(map[string]string)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	gateway := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct{ To, Message string }
+		json.NewDecoder(r.Body).Decode(&msg)
+		fmt.Printf("SMS: gateway got message for %s\n", msg.To)
+		lastSMS[msg.To] = msg.Message
+	})}
+	go gateway.Serve(ln)
+	defer gateway.Close()
 
-	// emailOTP := &email{
-	// 	otp: otp,
-	// }
-	// emailOTP.genAndSendOTP(emailOTP, 4)
-	// fmt.Scanln()
-	smsOTP := &sms{}
+	smsOTP := newSMS(randomGenerator{}, store, &webhookSender{url: "http://" + ln.Addr().String()}, m)
 	o := otp{
 		iOtp: smsOTP,
 	}
-	o.genAndSendOTP(4)
+	if err := o.genAndSendOTP(ctx, "+15550100", 6); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("SMS: wrong code:", o.verifyOTP("+15550100", "not-it"))
+	code := lastSMS["+15550100"][strings.LastIndex(lastSMS["+15550100"], " ")+1:]
+	fmt.Println("SMS: right code:", o.verifyOTP("+15550100", code))
+	fmt.Println("SMS: reused code:", o.verifyOTP("+15550100", code))
 
 	fmt.Println("")
-	emailOTP := &email{}
+	relay, err := startSMTPServer()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer relay.close()
+	totp := &totpGenerator{}
+	if _, err := totp.enroll("abc@example.com"); err != nil {
+		log.Fatal(err)
+	}
+	emailOTP := newEmail(totp, store, &smtpSender{addr: relay.addr(), from: "otp@example.com", subject: "Your login code"}, m)
 	o = otp{
 		iOtp: emailOTP,
 	}
-	o.genAndSendOTP(4)
+	if err := o.genAndSendOTP(ctx, "abc@example.com", 8); err != nil {
+		log.Fatal(err)
+	}
+	var mailed string
+	for _, mail := range relay.messages() {
+		fmt.Printf("EMAIL: relay got mail from %s to %v\n", mail.from, mail.to)
+		if _, after, ok := strings.Cut(mail.data, "login is "); ok {
+			mailed = strings.Fields(after)[0]
+		}
+	}
+	fmt.Println("EMAIL: right code:", o.verifyOTP("abc@example.com", mailed))
+	fmt.Println("EMAIL: reused code:", o.verifyOTP("abc@example.com", mailed))
 
+	fmt.Println("")
+	fmt.Print(m)
 }
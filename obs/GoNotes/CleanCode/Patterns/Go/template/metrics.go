@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// metrics counts events per channel and metric name.
+type metrics struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+func (m *metrics) inc(channel, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counts == nil {
+		m.counts = make(map[string]uint64)
+	}
+	m.counts[channel+"."+name]++
+}
+
+func (m *metrics) get(channel, name string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[channel+"."+name]
+}
+
+// String renders the counters one per line, sorted, as "otp.sms.sent 1".
+func (m *metrics) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.counts))
+	for k := range m.counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "otp.%s %d\n", k, m.counts[k])
+	}
+	return b.String()
+}
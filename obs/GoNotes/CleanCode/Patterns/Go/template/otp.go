@@ -1,40 +1,59 @@
 package main
 
+import "context"
+
+// Metric names passed to publishMetric.
+const (
+	metricGenerated  = "generated"
+	metricSent       = "sent"
+	metricSendFailed = "send_failed"
+	metricVerified   = "verified"
+	metricRejected   = "rejected"
+	metricLockedOut  = "locked_out"
+)
+
 type iOtp interface {
-	genRandomOTP(int) string
-	saveOTPCache(string)
+	genRandomOTP(recipient string, length int) (string, error)
+	saveOTPCache(recipient, otp string) error
+	checkOTPCache(recipient, otp string) error
 	getMessage(string) string
-	sendNotification(string) error
-	publishMetric()
+	sendNotification(ctx context.Context, recipient, message string) error
+	publishMetric(name string)
 }
 
-// type otp struct {
-// }
-
-// func (o *otp) genAndSendOTP(iOtp iOtp, otpLength int) error {
-// 	otp := iOtp.genRandomOTP(otpLength)
-// 	iOtp.saveOTPCache(otp)
-// 	message := iOtp.getMessage(otp)
-// 	err := iOtp.sendNotification(message)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	iOtp.publishMetric()
-// 	return nil
-// }
-
 type otp struct {
 	iOtp iOtp
 }
 
-func (o *otp) genAndSendOTP(otpLength int) error {
-	otp := o.iOtp.genRandomOTP(otpLength)
-	o.iOtp.saveOTPCache(otp)
+func (o *otp) genAndSendOTP(ctx context.Context, recipient string, otpLength int) error {
+	otp, err := o.iOtp.genRandomOTP(recipient, otpLength)
+	if err != nil {
+		return err
+	}
+	o.iOtp.publishMetric(metricGenerated)
+	if err := o.iOtp.saveOTPCache(recipient, otp); err != nil {
+		return err
+	}
 	message := o.iOtp.getMessage(otp)
-	err := o.iOtp.sendNotification(message)
+	err = o.iOtp.sendNotification(ctx, recipient, message)
 	if err != nil {
+		o.iOtp.publishMetric(metricSendFailed)
 		return err
 	}
-	o.iOtp.publishMetric()
+	o.iOtp.publishMetric(metricSent)
 	return nil
 }
+
+// verifyOTP checks a code the recipient typed in. A code can be used once.
+func (o *otp) verifyOTP(recipient, code string) error {
+	err := o.iOtp.checkOTPCache(recipient, code)
+	switch err {
+	case nil:
+		o.iOtp.publishMetric(metricVerified)
+	case errLocked:
+		o.iOtp.publishMetric(metricLockedOut)
+	default:
+		o.iOtp.publishMetric(metricRejected)
+	}
+	return err
+}
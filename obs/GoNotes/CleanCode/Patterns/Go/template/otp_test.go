@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var rfcSecret = []byte("12345678901234567890")
+
+func TestHOTPVectors(t *testing.T) {
+	// RFC 4226, appendix D.
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for i, w := range want {
+		if got := hotp(sha1.New, rfcSecret, uint64(i), 6); got != w {
+			t.Errorf("hotp(%d) = %s, want %s", i, got, w)
+		}
+	}
+
+	g := &hotpGenerator{}
+	g.set("alice", rfcSecret)
+	for _, w := range want[:3] {
+		if got, err := g.generate("alice", 6); err != nil || got != w {
+			t.Errorf("generate = %s, %v, want %s", got, err, w)
+		}
+	}
+	if _, err := g.generate("bob", 6); err == nil {
+		t.Error("generated a code for an unenrolled recipient")
+	}
+}
+
+func TestTOTPVectors(t *testing.T) {
+	// RFC 6238, appendix B, SHA-1 column.
+	for _, test := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	} {
+		now := time.Unix(test.unix, 0)
+		g := &totpGenerator{now: func() time.Time { return now }}
+		g.set("alice", rfcSecret)
+		if got, _ := g.generate("alice", 8); got != test.want {
+			t.Errorf("totp at %d = %s, want %s", test.unix, got, test.want)
+		}
+	}
+}
+
+func TestTOTPVerifyWindow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := newTestStore(t, &now)
+	g := &totpGenerator{now: func() time.Time { return now }}
+	g.set("alice", rfcSecret)
+	o := otp{iOtp: newEmail(g, store, &capture{}, &metrics{})}
+
+	for _, test := range []struct {
+		issued, checked int64
+		want            error
+	}{
+		{1000, 1000, nil},
+		{1000, 1030, nil},         // the next step
+		{1030, 1000, nil},         // the previous step, from a fast clock
+		{1000, 1065, errMismatch}, // two steps on
+		{1000, 4600, errMismatch}, // long after
+	} {
+		now = time.Unix(test.issued, 0)
+		code, _ := g.generate("alice", 6)
+		g.next = nil // forget earlier cases
+		now = time.Unix(test.checked, 0)
+		if err := o.verifyOTP("alice", code); err != test.want {
+			t.Errorf("issued at %d, checked at %d: %v, want %v", test.issued, test.checked, err, test.want)
+		}
+	}
+
+	// A code is accepted once.
+	now = time.Unix(5000, 0)
+	store = newTestStore(t, &now)
+	o = otp{iOtp: newEmail(g, store, &capture{}, &metrics{})}
+	code, _ := g.generate("alice", 6)
+	if err := o.verifyOTP("alice", code); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.verifyOTP("alice", code); err != errMismatch {
+		t.Errorf("reused code: %v", err)
+	}
+}
+
+func TestRandomGenerator(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := randomGenerator{}.generate("", 6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != 6 || strings.Trim(code, "0123456789") != "" {
+			t.Fatalf("bad code %q", code)
+		}
+		seen[code] = true
+	}
+	if len(seen) < 90 {
+		t.Errorf("only %d distinct codes in 100", len(seen))
+	}
+	if _, err := (randomGenerator{}).generate("", 0); err == nil {
+		t.Error("zero-length code accepted")
+	}
+}
+
+func newTestStore(t *testing.T, now *time.Time) *otpStore {
+	s, err := newOTPStore(time.Minute, 3, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestStoreExpiryAndLockout(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := newTestStore(t, &now)
+
+	s.save("a", "123456")
+	for _, stored := range s.codes {
+		if strings.Contains(string(stored.digest), "123456") {
+			t.Fatal("code stored in plain text")
+		}
+	}
+	now = now.Add(time.Minute)
+	if err := s.check("a", "123456"); err != errExpired {
+		t.Errorf("expired code: %v", err)
+	}
+
+	s.save("a", "123456")
+	for i, want := range []error{errMismatch, errMismatch, errLocked} {
+		if err := s.check("a", "000000"); err != want {
+			t.Errorf("attempt %d: %v, want %v", i+1, err, want)
+		}
+	}
+	if err := s.check("a", "123456"); err != errLocked {
+		t.Errorf("right code while locked: %v", err)
+	}
+	if err := s.save("a", "654321"); err != errLocked {
+		t.Errorf("save while locked: %v", err)
+	}
+	now = now.Add(10 * time.Minute)
+	if err := s.save("a", "654321"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.check("a", "654321"); err != nil {
+		t.Errorf("after lockout: %v", err)
+	}
+}
+
+func TestStoreLockoutSurvivesNewCode(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := newTestStore(t, &now)
+
+	// Asking for a fresh code between guesses must not reset the count.
+	for i, want := range []error{errMismatch, errMismatch, errLocked} {
+		if err := s.save("a", "123456"); err != nil {
+			t.Fatalf("save %d: %v", i+1, err)
+		}
+		if err := s.check("a", "000000"); err != want {
+			t.Errorf("attempt %d: %v, want %v", i+1, err, want)
+		}
+	}
+
+	// A success clears the count, and a long quiet spell forgets it.
+	now = now.Add(10 * time.Minute)
+	s.save("a", "123456")
+	s.check("a", "000000")
+	s.check("a", "123456")
+	s.save("a", "123456")
+	s.check("a", "000000")
+	now = now.Add(10 * time.Minute)
+	s.save("a", "123456")
+	for i := 0; i < 2; i++ {
+		if err := s.check("a", "000000"); err != errMismatch {
+			t.Errorf("attempt %d after success and quiet spell: %v", i+1, err)
+		}
+	}
+}
+
+func TestGeneratorLimits(t *testing.T) {
+	h := &hotpGenerator{}
+	h.set("alice", rfcSecret)
+	for _, digits := range []int{0, 10, 20} {
+		if code, err := h.generate("alice", digits); err == nil {
+			t.Errorf("hotp with %d digits = %s", digits, code)
+		}
+	}
+	if code, err := h.generate("alice", 9); err != nil || len(code) != 9 {
+		t.Errorf("hotp with 9 digits = %s, %v", code, err)
+	}
+
+	for _, step := range []time.Duration{time.Millisecond, 1500 * time.Millisecond, -time.Second} {
+		g := &totpGenerator{step: step}
+		g.set("alice", rfcSecret)
+		if code, err := g.generate("alice", 6); err == nil {
+			t.Errorf("totp with step %v = %s", step, code)
+		}
+	}
+	g := &totpGenerator{}
+	g.set("alice", rfcSecret)
+	if code, err := g.generate("alice", 10); err == nil {
+		t.Errorf("totp with 10 digits = %s", code)
+	}
+}
+
+type capture struct{ recipient, message string }
+
+func (c *capture) send(_ context.Context, recipient, message string) error {
+	c.recipient, c.message = recipient, message
+	return nil
+}
+
+func TestTemplateFlowAndMetrics(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := newTestStore(t, &now)
+	m := &metrics{}
+	sink := &capture{}
+	o := otp{iOtp: newSMS(randomGenerator{}, store, sink, m)}
+
+	if err := o.genAndSendOTP(context.Background(), "+1", 6); err != nil {
+		t.Fatal(err)
+	}
+	if sink.recipient != "+1" || !strings.HasPrefix(sink.message, "SMS OTP for login is ") {
+		t.Fatalf("sent %+v", sink)
+	}
+	code := strings.TrimPrefix(sink.message, "SMS OTP for login is ")
+	o.verifyOTP("+1", "x")
+	if err := o.verifyOTP("+1", code); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+	for name, want := range map[string]uint64{metricGenerated: 1, metricSent: 1, metricRejected: 1, metricVerified: 1} {
+		if got := m.get("sms", name); got != want {
+			t.Errorf("%s = %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestSenders(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "otp.log")
+	f := &fileSender{path: path}
+	f.send(ctx, "a", "first")
+	f.send(ctx, "b", "second")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 || !strings.HasSuffix(lines[1], "b\tsecond") {
+		t.Errorf("file sink wrote %q", data)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	if err := (&webhookSender{url: failing.URL}).send(ctx, "a", "b"); err == nil {
+		t.Error("webhook error status not reported")
+	}
+
+	relay, err := startSMTPServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer relay.close()
+	s := &smtpSender{addr: relay.addr(), from: "otp@example.com", subject: "Code"}
+	if err := s.send(ctx, "a@example.com", "EMAIL OTP for login is 1"); err != nil {
+		t.Fatal(err)
+	}
+	msgs := relay.messages()
+	if len(msgs) != 1 || msgs[0].to[0] != "a@example.com" || !strings.Contains(msgs[0].data, "EMAIL OTP for login is 1") {
+		t.Errorf("relay got %+v", msgs)
+	}
+	if err := s.send(ctx, "a@example.com\r\nBcc: x@example.com", "m"); err == nil {
+		t.Error("header injection accepted")
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sender delivers a message to a recipient.
+type sender interface {
+	send(ctx context.Context, recipient, message string) error
+}
+
+// fileSender appends one line per message to a file, for development and
+// audit trails.
+type fileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (s *fileSender) send(_ context.Context, recipient, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().UTC().Format(time.RFC3339), recipient, message)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// webhookSender posts {"to": ..., "message": ...} as JSON to url, the way
+// most SMS gateways are driven.
+type webhookSender struct {
+	url    string
+	client *http.Client // http.DefaultClient if nil
+}
+
+func (s *webhookSender) send(ctx context.Context, recipient, message string) error {
+	body, err := json.Marshal(map[string]string{"to": recipient, "message": message})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s: %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+// smtpSender sends plain-text mail through an SMTP relay.
+type smtpSender struct {
+	addr    string
+	from    string
+	subject string
+	auth    smtp.Auth // may be nil
+}
+
+func (s *smtpSender) send(ctx context.Context, recipient, message string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if strings.ContainsAny(recipient, "\r\n") {
+		return fmt.Errorf("smtp: bad recipient %q", recipient)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", s.from, recipient, s.subject, message)
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{recipient}, []byte(msg))
+}
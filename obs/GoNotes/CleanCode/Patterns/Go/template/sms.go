@@ -1,30 +1,13 @@
 package main
 
-import "fmt"
-
 type sms struct {
-	otp
-}
-
-func (s *sms) genRandomOTP(len int) string {
-	randomOTP := "1234"
-	fmt.Printf("SMS: generating random otp %s\n", randomOTP)
-	return randomOTP
+	channel
 }
 
-func (s *sms) saveOTPCache(otp string) {
-	fmt.Printf("SMS: saving otp: %s to cache\n", otp)
+func newSMS(g codeGenerator, store *otpStore, s sender, m *metrics) *sms {
+	return &sms{channel{name: "sms", generator: g, store: store, sender: s, metrics: m}}
 }
 
 func (s *sms) getMessage(otp string) string {
 	return "SMS OTP for login is " + otp
 }
-
-func (s *sms) sendNotification(message string) error {
-	fmt.Printf("SMS: sending sms: %s\n", message)
-	return nil
-}
-
-func (s *sms) publishMetric() {
-	fmt.Printf("SMS: publishing metrics\n")
-}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// mail is a message received by smtpServer.
+type mail struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer is a minimal local stand-in for a mail relay. It accepts
+// everything and keeps the messages in memory.
+type smtpServer struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	inbox []mail
+	wg    sync.WaitGroup
+}
+
+func startSMTPServer() (*smtpServer, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &smtpServer{ln: ln}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+func (s *smtpServer) addr() string { return s.ln.Addr().String() }
+
+func (s *smtpServer) messages() []mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]mail(nil), s.inbox...)
+}
+
+func (s *smtpServer) close() {
+	s.ln.Close()
+	s.wg.Wait()
+}
+
+func (s *smtpServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.session(conn)
+		}()
+	}
+}
+
+func (s *smtpServer) session(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...any) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+	reply("220 localhost stand-in SMTP")
+	var m mail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			m = mail{from: addressIn(line)}
+			reply("250 OK")
+		case "RCPT":
+			m.to = append(m.to, addressIn(line))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" || l == ".\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			m.data = data.String()
+			s.mu.Lock()
+			s.inbox = append(s.inbox, m)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// addressIn extracts the address from "MAIL FROM:<a@b>" or "RCPT TO:<a@b>".
+func addressIn(line string) string {
+	start := strings.IndexByte(line, '<')
+	end := strings.IndexByte(line, '>')
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	errNoOTP    = errors.New("otp: no code issued")
+	errExpired  = errors.New("otp: code expired")
+	errMismatch = errors.New("otp: wrong code")
+	errLocked   = errors.New("otp: too many attempts, try again later")
+)
+
+type storedOTP struct {
+	digest  []byte
+	expires time.Time
+}
+
+// failures counts a recipient's wrong guesses since their last success.
+type failures struct {
+	count int
+	last  time.Time
+}
+
+// otpStore keeps keyed hashes of issued codes, never the codes themselves.
+// A recipient who fails maxAttempts times in a row is locked out for
+// lockout, and any outstanding code is discarded. Failures are counted per
+// recipient, not per code, so asking for a fresh code does not reset them;
+// they are forgotten after a quiet spell as long as the lockout.
+type otpStore struct {
+	mu          sync.Mutex
+	key         []byte
+	ttl         time.Duration
+	maxAttempts int
+	lockout     time.Duration
+	now         func() time.Time
+
+	codes  map[string]*storedOTP
+	failed map[string]*failures
+	locked map[string]time.Time
+}
+
+func newOTPStore(ttl time.Duration, maxAttempts int, lockout time.Duration) (*otpStore, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &otpStore{
+		key:         key,
+		ttl:         ttl,
+		maxAttempts: maxAttempts,
+		lockout:     lockout,
+		now:         time.Now,
+		codes:       make(map[string]*storedOTP),
+		failed:      make(map[string]*failures),
+		locked:      make(map[string]time.Time),
+	}, nil
+}
+
+func (s *otpStore) digest(recipient, code string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(recipient))
+	mac.Write([]byte{0})
+	mac.Write([]byte(code))
+	return mac.Sum(nil)
+}
+
+// save replaces any outstanding code for recipient.
+func (s *otpStore) save(recipient, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if err := s.unlock(recipient, now); err != nil {
+		return err
+	}
+	s.sweep(now)
+	s.codes[recipient] = &storedOTP{digest: s.digest(recipient, code), expires: now.Add(s.ttl)}
+	return nil
+}
+
+// allow reports whether recipient may be sent a code that is not saved,
+// because the generator verifies it.
+func (s *otpStore) allow(recipient string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if err := s.unlock(recipient, now); err != nil {
+		return err
+	}
+	s.sweep(now)
+	return nil
+}
+
+// unlock returns errLocked while recipient is locked out, and forgets the
+// lockout once it is over. The caller holds s.mu.
+func (s *otpStore) unlock(recipient string, now time.Time) error {
+	if until, ok := s.locked[recipient]; ok {
+		if now.Before(until) {
+			return errLocked
+		}
+		delete(s.locked, recipient)
+	}
+	return nil
+}
+
+// check consumes the code on success.
+func (s *otpStore) check(recipient, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if until, ok := s.locked[recipient]; ok && now.Before(until) {
+		return errLocked
+	}
+	stored, ok := s.codes[recipient]
+	if !ok {
+		return errNoOTP
+	}
+	if !now.Before(stored.expires) {
+		delete(s.codes, recipient)
+		return errExpired
+	}
+	if subtle.ConstantTimeCompare(stored.digest, s.digest(recipient, code)) == 1 {
+		delete(s.codes, recipient)
+		delete(s.failed, recipient)
+		return nil
+	}
+	return s.fail(recipient, now)
+}
+
+// checkWith is check for codes the store does not hold: match decides
+// whether the code is right, and failures count towards lockout as usual.
+func (s *otpStore) checkWith(recipient string, match func() (bool, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if until, ok := s.locked[recipient]; ok && now.Before(until) {
+		return errLocked
+	}
+	ok, err := match()
+	if err != nil {
+		return err
+	}
+	if ok {
+		delete(s.failed, recipient)
+		return nil
+	}
+	return s.fail(recipient, now)
+}
+
+// fail counts a wrong code and locks recipient out after too many. The
+// caller holds s.mu.
+func (s *otpStore) fail(recipient string, now time.Time) error {
+	f := s.failed[recipient]
+	if f == nil {
+		f = &failures{}
+		s.failed[recipient] = f
+	}
+	f.count++
+	f.last = now
+	if f.count >= s.maxAttempts {
+		delete(s.codes, recipient)
+		delete(s.failed, recipient)
+		s.locked[recipient] = now.Add(s.lockout)
+		return errLocked
+	}
+	return errMismatch
+}
+
+// sweep drops expired codes, stale failure counts and lockouts so the store
+// does not grow without bound. The caller holds s.mu.
+func (s *otpStore) sweep(now time.Time) {
+	for r, c := range s.codes {
+		if !now.Before(c.expires) {
+			delete(s.codes, r)
+		}
+	}
+	for r, f := range s.failed {
+		if !now.Before(f.last.Add(s.lockout)) {
+			delete(s.failed, r)
+		}
+	}
+	for r, until := range s.locked {
+		if !now.Before(until) {
+			delete(s.locked, r)
+		}
+	}
+}
@@ -1,22 +1,26 @@
 package main
 
-import (
-	"fmt"
-)
+import "math"
 
 type areaCalculator struct {
-	area int
+	area float64
 }
 
 func (a *areaCalculator) visitForSquare(s *square) {
-	// Calculate area for square.
-	// Then assign in to the area instance variable.
-	fmt.Println("Calculating area for square")
+	a.area = s.side * s.side
 }
 
-func (a *areaCalculator) visitForCircle(s *circle) {
-	fmt.Println("Calculating area for circle")
+func (a *areaCalculator) visitForCircle(c *circle) {
+	a.area = math.Pi * c.radius * c.radius
 }
-func (a *areaCalculator) visitForrectangle(s *rectangle) {
-	fmt.Println("Calculating area for rectangle")
+func (a *areaCalculator) visitForrectangle(t *rectangle) {
+	a.area = t.l * t.b
+}
+
+func (a *areaCalculator) visitForEllipse(e *ellipse) {
+	a.area = math.Pi * e.rx * e.ry
+}
+
+func (a *areaCalculator) visitForPolygon(p *polygon) {
+	a.area = math.Abs(p.signedArea())
 }
@@ -0,0 +1,43 @@
+package main
+
+import "math"
+
+// boundingBox computes the smallest axis-aligned box containing a shape.
+type boundingBox struct {
+	min point
+	max point
+}
+
+func (b *boundingBox) width() float64  { return b.max.x - b.min.x }
+func (b *boundingBox) height() float64 { return b.max.y - b.min.y }
+
+func (b *boundingBox) visitForSquare(s *square) {
+	b.fit(s.corners())
+}
+
+func (b *boundingBox) visitForCircle(c *circle) {
+	b.min = point{c.center.x - c.radius, c.center.y - c.radius}
+	b.max = point{c.center.x + c.radius, c.center.y + c.radius}
+}
+
+func (b *boundingBox) visitForrectangle(t *rectangle) {
+	b.fit(t.corners())
+}
+
+func (b *boundingBox) visitForEllipse(e *ellipse) {
+	b.min = point{e.center.x - e.rx, e.center.y - e.ry}
+	b.max = point{e.center.x + e.rx, e.center.y + e.ry}
+}
+
+func (b *boundingBox) visitForPolygon(p *polygon) {
+	b.fit(p.points)
+}
+
+func (b *boundingBox) fit(points []point) {
+	b.min = point{math.Inf(1), math.Inf(1)}
+	b.max = point{math.Inf(-1), math.Inf(-1)}
+	for _, p := range points {
+		b.min = point{math.Min(b.min.x, p.x), math.Min(b.min.y, p.y)}
+		b.max = point{math.Max(b.max.x, p.x), math.Max(b.max.y, p.y)}
+	}
+}
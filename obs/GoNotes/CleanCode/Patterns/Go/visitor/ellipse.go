@@ -0,0 +1,16 @@
+package main
+
+// ellipse is axis-aligned with semi-axes rx along x and ry along y.
+type ellipse struct {
+	center point
+	rx     float64
+	ry     float64
+}
+
+func (e *ellipse) accept(v visitor) {
+	v.visitForEllipse(e)
+}
+
+func (e *ellipse) getType() string {
+	return "Ellipse"
+}
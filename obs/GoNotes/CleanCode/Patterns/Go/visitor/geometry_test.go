@@ -0,0 +1,263 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+const eps = 1e-9
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= eps*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+func area(s shape) float64 {
+	a := &areaCalculator{}
+	s.accept(a)
+	return a.area
+}
+
+func perimeter(s shape) float64 {
+	p := &perimeterCalculator{}
+	s.accept(p)
+	return p.perimeter
+}
+
+func centroid(s shape) point {
+	m := &middleCoordinates{}
+	s.accept(m)
+	return point{m.x, m.y}
+}
+
+func bounds(s shape) boundingBox {
+	var b boundingBox
+	s.accept(&b)
+	return b
+}
+
+func contains(s shape, p point) bool {
+	v := &pointInShape{target: p}
+	s.accept(v)
+	return v.inside
+}
+
+// randomShape generates shapes with coordinates in [-100, 100) and sizes in
+// (0, 50]. Polygons are convex: points on a circle at sorted angles.
+type randomShape struct{ shape }
+
+func (randomShape) Generate(r *rand.Rand, _ int) reflect.Value {
+	coord := func() float64 { return r.Float64()*200 - 100 }
+	size := func() float64 { return 50 - r.Float64()*50 + 1e-3 }
+	origin := point{coord(), coord()}
+	var s shape
+	switch r.Intn(5) {
+	case 0:
+		s = &square{origin: origin, side: size()}
+	case 1:
+		s = &circle{center: origin, radius: size()}
+	case 2:
+		s = &rectangle{origin: origin, l: size(), b: size()}
+	case 3:
+		s = &ellipse{center: origin, rx: size(), ry: size()}
+	default:
+		s = convexPolygon(r, origin, size(), 3+r.Intn(8))
+	}
+	return reflect.ValueOf(randomShape{s})
+}
+
+func convexPolygon(r *rand.Rand, center point, radius float64, n int) *polygon {
+	angles := make([]float64, n)
+	for i := range angles {
+		angles[i] = 2 * math.Pi * (float64(i) + r.Float64()*0.9) / float64(n)
+	}
+	p := &polygon{}
+	for _, a := range angles {
+		p.points = append(p.points, point{center.x + radius*math.Cos(a), center.y + radius*math.Sin(a)})
+	}
+	return p
+}
+
+func TestCentroidInsideBounds(t *testing.T) {
+	f := func(rs randomShape) bool {
+		c := centroid(rs.shape)
+		b := bounds(rs.shape)
+		return c.x >= b.min.x-eps && c.x <= b.max.x+eps && c.y >= b.min.y-eps && c.y <= b.max.y+eps
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConvexShapesContainCentroid(t *testing.T) {
+	f := func(rs randomShape) bool {
+		return contains(rs.shape, centroid(rs.shape))
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAreaFitsInBounds(t *testing.T) {
+	f := func(rs randomShape) bool {
+		b := bounds(rs.shape)
+		a := area(rs.shape)
+		return a > 0 && a <= b.width()*b.height()*(1+eps)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Squares and rectangles must agree with the equivalent polygon, whichever
+// way round its vertices are listed.
+func TestBoxesMatchPolygons(t *testing.T) {
+	f := func(x, y float64, w, h uint16) bool {
+		r := &rectangle{origin: point{x, y}, l: float64(w) + 1, b: float64(h) + 1}
+		s := &square{origin: point{x, y}, side: float64(w) + 1}
+		for _, pair := range [][2]shape{
+			{r, &polygon{points: r.corners()}},
+			{s, &polygon{points: s.corners()}},
+			{r, &polygon{points: reversed(r.corners())}},
+		} {
+			box, poly := pair[0], pair[1]
+			if !near(area(box), area(poly)) || !near(perimeter(box), perimeter(poly)) {
+				return false
+			}
+			c1, c2 := centroid(box), centroid(poly)
+			if !near(c1.x, c2.x) || !near(c1.y, c2.y) {
+				return false
+			}
+		}
+		return true
+	}
+	cfg := &quick.Config{Values: func(v []reflect.Value, r *rand.Rand) {
+		v[0] = reflect.ValueOf(r.Float64()*200 - 100)
+		v[1] = reflect.ValueOf(r.Float64()*200 - 100)
+		v[2] = reflect.ValueOf(uint16(r.Intn(100)))
+		v[3] = reflect.ValueOf(uint16(r.Intn(100)))
+	}}
+	if err := quick.Check(f, cfg); err != nil {
+		t.Error(err)
+	}
+}
+
+func reversed(ps []point) []point {
+	out := make([]point, len(ps))
+	for i, p := range ps {
+		out[len(ps)-1-i] = p
+	}
+	return out
+}
+
+// Translating and scaling a polygon moves its centroid along and scales its
+// area by the square of the factor and its perimeter linearly.
+func TestEmptyPolygonCentroid(t *testing.T) {
+	m := &middleCoordinates{x: 1, y: 1, ok: true}
+	(&polygon{}).accept(m)
+	if m.ok || m.x != 0 || m.y != 0 {
+		t.Errorf("centroid of empty polygon = (%g, %g), ok %t", m.x, m.y, m.ok)
+	}
+	(&square{side: 2}).accept(m)
+	if !m.ok {
+		t.Errorf("centroid of square not ok")
+	}
+}
+
+func TestPolygonTransform(t *testing.T) {
+	f := func(rs randomShape, dx, dy int8, k uint8) bool {
+		p, ok := rs.shape.(*polygon)
+		if !ok {
+			return true
+		}
+		factor := float64(k%10) + 1
+		moved := &polygon{}
+		for _, v := range p.points {
+			moved.points = append(moved.points, point{v.x*factor + float64(dx), v.y*factor + float64(dy)})
+		}
+		c, mc := centroid(p), centroid(moved)
+		return near(area(moved), area(p)*factor*factor) &&
+			near(perimeter(moved), perimeter(p)*factor) &&
+			math.Abs(mc.x-(c.x*factor+float64(dx))) < 1e-6 &&
+			math.Abs(mc.y-(c.y*factor+float64(dy))) < 1e-6
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEllipseAgreesWithCircle(t *testing.T) {
+	f := func(r uint16) bool {
+		radius := float64(r) + 1
+		c := &circle{radius: radius}
+		e := &ellipse{rx: radius, ry: radius}
+		return near(area(c), area(e)) && near(perimeter(c), perimeter(e))
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEllipsePerimeter(t *testing.T) {
+	// Reference value for a = 4, b = 1 from the complete elliptic integral.
+	got := perimeter(&ellipse{rx: 4, ry: 1})
+	if math.Abs(got-17.156843550313) > 1e-4 {
+		t.Errorf("perimeter = %v", got)
+	}
+}
+
+func TestPointInConcavePolygon(t *testing.T) {
+	// A "U" shape: the notch between the arms is outside.
+	u := &polygon{points: []point{{0, 0}, {3, 0}, {3, 3}, {2, 3}, {2, 1}, {1, 1}, {1, 3}, {0, 3}}}
+	for _, test := range []struct {
+		p    point
+		want bool
+	}{
+		{point{0.5, 2}, true},
+		{point{2.5, 2}, true},
+		{point{1.5, 2}, false},
+		{point{1.5, 0.5}, true},
+		{point{4, 1}, false},
+	} {
+		if got := contains(u, test.p); got != test.want {
+			t.Errorf("contains(%v) = %t", test.p, got)
+		}
+	}
+}
+
+func TestOperationRegistry(t *testing.T) {
+	op := newOperation[float64]("area")
+	fromVisitor(op, func() *areaCalculator { return &areaCalculator{} }, func(a *areaCalculator) float64 { return a.area })
+	got, err := op.apply(&rectangle{l: 2, b: 3})
+	if err != nil || got != 6 {
+		t.Errorf("apply = %v, %v", got, err)
+	}
+
+	names := newOperation[string]("name")
+	define(names, func(c *circle) string { return "round" })
+	if got, _ := names.apply(&circle{}); got != "round" {
+		t.Errorf("apply = %q", got)
+	}
+	if _, err := names.apply(&square{}); err == nil {
+		t.Error("no error for missing implementation")
+	}
+}
+
+func TestSVG(t *testing.T) {
+	r := newSVGRenderer("")
+	(&circle{center: point{1, 1}, radius: 1}).accept(r)
+	(&polygon{points: []point{{2, 0}, {4, 0}, {3, 5}}}).accept(r)
+	got := r.svg()
+	for _, want := range []string{
+		`viewBox="0 0 4 5"`,
+		`<circle cx="1" cy="1" r="1"/>`,
+		`<polygon points="2,0 4,0 3,5"/>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("svg missing %s:\n%s", want, got)
+		}
+	}
+}
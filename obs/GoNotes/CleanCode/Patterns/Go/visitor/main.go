@@ -4,18 +4,57 @@ import "fmt"
 
 func main() {
 	square := &square{side: 2}
-	circle := &circle{radius: 3}
-	rectangle := &rectangle{l: 2, b: 3}
+	circle := &circle{center: point{5, 5}, radius: 3}
+	rectangle := &rectangle{origin: point{1, 1}, l: 2, b: 3}
+	ellipse := &ellipse{center: point{10, 2}, rx: 4, ry: 1}
+	triangle := &polygon{points: []point{{0, 0}, {4, 0}, {0, 3}}}
+	shapes := []shape{square, circle, rectangle, ellipse, triangle}
 
-	areaCalculator := &areaCalculator{}
+	for _, s := range shapes {
+		areaCalculator := &areaCalculator{}
+		perimeterCalculator := &perimeterCalculator{}
+		middleCoordinates := &middleCoordinates{}
+		boundingBox := &boundingBox{}
+		s.accept(areaCalculator)
+		s.accept(perimeterCalculator)
+		s.accept(middleCoordinates)
+		s.accept(boundingBox)
+		fmt.Printf("%-9s area %7.3f  perimeter %7.3f  middle (%.3g, %.3g)  box (%g, %g)-(%g, %g)\n",
+			s.getType(), areaCalculator.area, perimeterCalculator.perimeter,
+			middleCoordinates.x, middleCoordinates.y,
+			boundingBox.min.x, boundingBox.min.y, boundingBox.max.x, boundingBox.max.y)
+	}
 
-	square.accept(areaCalculator)
-	circle.accept(areaCalculator)
-	rectangle.accept(areaCalculator)
+	fmt.Println()
+	inside := &pointInShape{target: point{1, 1}}
+	for _, s := range shapes {
+		s.accept(inside)
+		fmt.Printf("(1, 1) inside %s: %t\n", s.getType(), inside.inside)
+	}
+
+	// An operation added without touching visitor or the shapes.
+	vertices := newVerticesOperation()
+	fmt.Println()
+	for _, s := range shapes {
+		if n, err := vertices.apply(s); err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Printf("%s has %d vertices\n", s.getType(), n)
+		}
+	}
 
 	fmt.Println()
-	middleCoordinates := &middleCoordinates{}
-	square.accept(middleCoordinates)
-	circle.accept(middleCoordinates)
-	rectangle.accept(middleCoordinates)
+	svg := newSVGRenderer("fill:none;stroke:black")
+	for _, s := range shapes {
+		s.accept(svg)
+	}
+	fmt.Print(svg.svg())
+}
+
+func newVerticesOperation() *operation[int] {
+	vertices := newOperation[int]("vertices")
+	define(vertices, func(*square) int { return 4 })
+	define(vertices, func(*rectangle) int { return 4 })
+	define(vertices, func(p *polygon) int { return len(p.points) })
+	return vertices
 }
@@ -1,21 +1,57 @@
 package main
 
-import "fmt"
-
+// middleCoordinates computes the centroid of a shape's area. ok is false
+// for a polygon with no points, which has no centroid.
 type middleCoordinates struct {
-	x int
-	y int
+	x  float64
+	y  float64
+	ok bool
 }
 
 func (a *middleCoordinates) visitForSquare(s *square) {
-	// Calculate middle point coordinates for square.
-	// Then assign in to the x and y instance variable.
-	fmt.Println("Calculating middle point coordinates for square")
+	a.ok = true
+	a.x = s.origin.x + s.side/2
+	a.y = s.origin.y + s.side/2
 }
 
 func (a *middleCoordinates) visitForCircle(c *circle) {
-	fmt.Println("Calculating middle point coordinates for circle")
+	a.ok = true
+	a.x, a.y = c.center.x, c.center.y
 }
 func (a *middleCoordinates) visitForrectangle(t *rectangle) {
-	fmt.Println("Calculating middle point coordinates for rectangle")
+	a.ok = true
+	a.x = t.origin.x + t.l/2
+	a.y = t.origin.y + t.b/2
+}
+
+func (a *middleCoordinates) visitForEllipse(e *ellipse) {
+	a.ok = true
+	a.x, a.y = e.center.x, e.center.y
+}
+
+func (a *middleCoordinates) visitForPolygon(p *polygon) {
+	a.x, a.y, a.ok = 0, 0, len(p.points) > 0
+	if !a.ok {
+		return
+	}
+	area := p.signedArea()
+	if area == 0 {
+		// Degenerate polygon: fall back to the vertex average.
+		for _, v := range p.points {
+			a.x += v.x
+			a.y += v.y
+		}
+		a.x /= float64(len(p.points))
+		a.y /= float64(len(p.points))
+		return
+	}
+	var cx, cy float64
+	for i, v := range p.points {
+		w := p.points[(i+1)%len(p.points)]
+		cross := v.x*w.y - w.x*v.y
+		cx += (v.x + w.x) * cross
+		cy += (v.y + w.y) * cross
+	}
+	a.x = cx / (6 * area)
+	a.y = cy / (6 * area)
 }
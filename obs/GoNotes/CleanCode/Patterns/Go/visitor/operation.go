@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// operation is an open alternative to visitor: implementations are
+// registered per concrete shape type at run time, so new operations (and
+// new shapes) can be added without touching the visitor interface or any
+// existing shape.
+type operation[R any] struct {
+	name  string
+	mu    sync.RWMutex
+	impls map[reflect.Type]func(shape) R
+}
+
+func newOperation[R any](name string) *operation[R] {
+	return &operation[R]{name: name, impls: make(map[reflect.Type]func(shape) R)}
+}
+
+// define registers f as op's implementation for shapes of type S.
+func define[S shape, R any](op *operation[R], f func(S) R) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.impls[reflect.TypeFor[S]()] = func(s shape) R { return f(s.(S)) }
+}
+
+// apply dispatches on the dynamic type of s.
+func (op *operation[R]) apply(s shape) (R, error) {
+	op.mu.RLock()
+	f, ok := op.impls[reflect.TypeOf(s)]
+	op.mu.RUnlock()
+	if !ok {
+		var zero R
+		return zero, fmt.Errorf("%s: no implementation for %s", op.name, s.getType())
+	}
+	return f(s), nil
+}
+
+// fromVisitor defines op for every built-in shape by running a fresh visitor
+// from newVisitor and reading the result back with result.
+func fromVisitor[V visitor, R any](op *operation[R], newVisitor func() V, result func(V) R) {
+	run := func(s shape) R {
+		v := newVisitor()
+		s.accept(v)
+		return result(v)
+	}
+	define(op, func(s *square) R { return run(s) })
+	define(op, func(s *circle) R { return run(s) })
+	define(op, func(s *rectangle) R { return run(s) })
+	define(op, func(s *ellipse) R { return run(s) })
+	define(op, func(s *polygon) R { return run(s) })
+}
@@ -0,0 +1,35 @@
+package main
+
+import "math"
+
+type perimeterCalculator struct {
+	perimeter float64
+}
+
+func (c *perimeterCalculator) visitForSquare(s *square) {
+	c.perimeter = 4 * s.side
+}
+
+func (c *perimeterCalculator) visitForCircle(ci *circle) {
+	c.perimeter = 2 * math.Pi * ci.radius
+}
+
+func (c *perimeterCalculator) visitForrectangle(t *rectangle) {
+	c.perimeter = 2 * (t.l + t.b)
+}
+
+// visitForEllipse uses Ramanujan's second approximation, exact for circles
+// and within a few parts per million for anything short of a degenerate
+// ellipse.
+func (c *perimeterCalculator) visitForEllipse(e *ellipse) {
+	a, b := e.rx, e.ry
+	h := (a - b) * (a - b) / ((a + b) * (a + b))
+	c.perimeter = math.Pi * (a + b) * (1 + 3*h/(10+math.Sqrt(4-3*h)))
+}
+
+func (c *perimeterCalculator) visitForPolygon(p *polygon) {
+	c.perimeter = 0
+	for i, a := range p.points {
+		c.perimeter += a.distance(p.points[(i+1)%len(p.points)])
+	}
+}
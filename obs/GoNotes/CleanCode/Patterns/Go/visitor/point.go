@@ -0,0 +1,14 @@
+package main
+
+import "math"
+
+type point struct {
+	x float64
+	y float64
+}
+
+func (p point) add(q point) point { return point{p.x + q.x, p.y + q.y} }
+
+func (p point) distance(q point) float64 {
+	return math.Hypot(q.x-p.x, q.y-p.y)
+}
@@ -0,0 +1,46 @@
+package main
+
+// pointInShape reports whether target lies inside a shape. Points on the
+// boundary count as inside for squares, rectangles, circles and ellipses;
+// for polygons the even-odd rule decides.
+type pointInShape struct {
+	target point
+	inside bool
+}
+
+func (p *pointInShape) visitForSquare(s *square) {
+	p.inside = inBox(p.target, s.origin, s.side, s.side)
+}
+
+func (p *pointInShape) visitForCircle(c *circle) {
+	p.inside = c.center.distance(p.target) <= c.radius
+}
+
+func (p *pointInShape) visitForrectangle(t *rectangle) {
+	p.inside = inBox(p.target, t.origin, t.l, t.b)
+}
+
+func (p *pointInShape) visitForEllipse(e *ellipse) {
+	dx := (p.target.x - e.center.x) / e.rx
+	dy := (p.target.y - e.center.y) / e.ry
+	p.inside = dx*dx+dy*dy <= 1
+}
+
+// visitForPolygon casts a ray towards +x and counts edge crossings.
+func (p *pointInShape) visitForPolygon(poly *polygon) {
+	p.inside = false
+	t := p.target
+	for i, a := range poly.points {
+		b := poly.points[(i+1)%len(poly.points)]
+		if (a.y > t.y) != (b.y > t.y) {
+			x := a.x + (t.y-a.y)*(b.x-a.x)/(b.y-a.y)
+			if t.x < x {
+				p.inside = !p.inside
+			}
+		}
+	}
+}
+
+func inBox(t, origin point, w, h float64) bool {
+	return t.x >= origin.x && t.x <= origin.x+w && t.y >= origin.y && t.y <= origin.y+h
+}
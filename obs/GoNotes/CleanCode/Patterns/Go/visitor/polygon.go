@@ -0,0 +1,25 @@
+package main
+
+// polygon is a simple (non self-intersecting) polygon given by its vertices
+// in order; the last vertex connects back to the first.
+type polygon struct {
+	points []point
+}
+
+func (p *polygon) accept(v visitor) {
+	v.visitForPolygon(p)
+}
+
+func (p *polygon) getType() string {
+	return "Polygon"
+}
+
+// signedArea is the shoelace sum: positive for counter-clockwise vertices.
+func (p *polygon) signedArea() float64 {
+	var sum float64
+	for i, a := range p.points {
+		b := p.points[(i+1)%len(p.points)]
+		sum += a.x*b.y - b.x*a.y
+	}
+	return sum / 2
+}
@@ -1,8 +1,11 @@
 package main
 
+// rectangle is axis-aligned with its lower-left corner at origin, l wide
+// along x and b high along y.
 type rectangle struct {
-	l int
-	b int
+	origin point
+	l      float64
+	b      float64
 }
 
 func (t *rectangle) accept(v visitor) {
@@ -12,3 +15,12 @@ func (t *rectangle) accept(v visitor) {
 func (t *rectangle) getType() string {
 	return "rectangle"
 }
+
+func (t *rectangle) corners() []point {
+	return []point{
+		t.origin,
+		t.origin.add(point{t.l, 0}),
+		t.origin.add(point{t.l, t.b}),
+		t.origin.add(point{0, t.b}),
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
+// square is axis-aligned with its lower-left corner at origin.
 type square struct {
-	side int
+	origin point
+	side   float64
 }
 
 func (s *square) accept(v visitor) {
@@ -11,3 +13,12 @@ func (s *square) accept(v visitor) {
 func (s *square) getType() string {
 	return "Square"
 }
+
+func (s *square) corners() []point {
+	return []point{
+		s.origin,
+		s.origin.add(point{s.side, 0}),
+		s.origin.add(point{s.side, s.side}),
+		s.origin.add(point{0, s.side}),
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// svgRenderer collects SVG elements for every shape it visits. Coordinates
+// are written as they are, so y grows downwards in the rendered image.
+type svgRenderer struct {
+	style    string
+	elements []string
+	box      boundingBox
+	empty    bool
+}
+
+func newSVGRenderer(style string) *svgRenderer {
+	return &svgRenderer{style: style, empty: true}
+}
+
+func (r *svgRenderer) visitForSquare(s *square) {
+	r.add(s, fmt.Sprintf(`<rect x="%g" y="%g" width="%g" height="%g"`, s.origin.x, s.origin.y, s.side, s.side))
+}
+
+func (r *svgRenderer) visitForCircle(c *circle) {
+	r.add(c, fmt.Sprintf(`<circle cx="%g" cy="%g" r="%g"`, c.center.x, c.center.y, c.radius))
+}
+
+func (r *svgRenderer) visitForrectangle(t *rectangle) {
+	r.add(t, fmt.Sprintf(`<rect x="%g" y="%g" width="%g" height="%g"`, t.origin.x, t.origin.y, t.l, t.b))
+}
+
+func (r *svgRenderer) visitForEllipse(e *ellipse) {
+	r.add(e, fmt.Sprintf(`<ellipse cx="%g" cy="%g" rx="%g" ry="%g"`, e.center.x, e.center.y, e.rx, e.ry))
+}
+
+func (r *svgRenderer) visitForPolygon(p *polygon) {
+	var pts []string
+	for _, v := range p.points {
+		pts = append(pts, fmt.Sprintf("%g,%g", v.x, v.y))
+	}
+	r.add(p, fmt.Sprintf(`<polygon points="%s"`, strings.Join(pts, " ")))
+}
+
+func (r *svgRenderer) add(s shape, element string) {
+	if r.style != "" {
+		element += fmt.Sprintf(` style="%s"`, r.style)
+	}
+	r.elements = append(r.elements, element+"/>")
+
+	var b boundingBox
+	s.accept(&b)
+	if r.empty {
+		r.box, r.empty = b, false
+		return
+	}
+	r.box.min = point{math.Min(r.box.min.x, b.min.x), math.Min(r.box.min.y, b.min.y)}
+	r.box.max = point{math.Max(r.box.max.x, b.max.x), math.Max(r.box.max.y, b.max.y)}
+}
+
+// svg returns a complete document whose view box fits every rendered shape.
+func (r *svgRenderer) svg() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="%g %g %g %g">`+"\n",
+		r.box.min.x, r.box.min.y, r.box.width(), r.box.height())
+	for _, e := range r.elements {
+		fmt.Fprintf(&b, "  %s\n", e)
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
@@ -14,9 +14,12 @@ import (
 
 //!+Display
 
+// Display prints the value x, named name, one element per line.
+// A pointer, map or slice that is reached again prints as a reference
+// to where it was first displayed, so that cyclic values terminate.
 func Display(name string, x interface{}) {
 	fmt.Printf("Display %s (%T):\n", name, x)
-	display(name, reflect.ValueOf(x))
+	display(name, reflect.ValueOf(x), make(map[reference]*visit))
 }
 
 //!-Display
@@ -50,37 +53,90 @@ func formatAtom(v reflect.Value) string {
 	}
 }
 
+// A reference identifies the pointer, map or slice through which a
+// value may be reached more than once.
+type reference struct {
+	ptr uintptr
+	len int // of a slice
+	t   reflect.Type
+}
+
+// A visit records where a reference was first displayed, and whether
+// that display is still in progress.
+type visit struct {
+	path   string
+	active bool
+}
+
+// referenceOf returns the reference held by v, if display should show
+// what it refers to only once. Pointers to zero-sized values and empty
+// slices are left out: unrelated ones may have the same address, and
+// display would wrongly print the second as a path to the first.
+func referenceOf(v reflect.Value) (reference, bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() && v.Type().Elem().Size() > 0 {
+			return reference{v.Pointer(), 0, v.Type()}, true
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			return reference{v.Pointer(), 0, v.Type()}, true
+		}
+	case reflect.Slice:
+		if v.Len() > 0 && v.Type().Elem().Size() > 0 {
+			return reference{v.Pointer(), v.Len(), v.Type()}, true
+		}
+	}
+	return reference{}, false
+}
+
 //!+display
-func display(path string, v reflect.Value) {
+func display(path string, v reflect.Value, seen map[reference]*visit) {
+	// A reference seen again is displayed as the path it was first
+	// reached by, not the path of what it refers to.
+	if r, ok := referenceOf(v); ok {
+		if prev := seen[r]; prev != nil {
+			if prev.active {
+				fmt.Printf("%s = <cycle to %s>\n", path, prev.path)
+			} else {
+				fmt.Printf("%s = <same as %s>\n", path, prev.path)
+			}
+			return
+		}
+		vis := &visit{path, true}
+		seen[r] = vis
+		defer func() { vis.active = false }()
+	}
+
 	switch v.Kind() {
 	case reflect.Invalid:
 		fmt.Printf("%s = invalid\n", path)
 	case reflect.Slice, reflect.Array:
 		for i := 0; i < v.Len(); i++ {
-			display(fmt.Sprintf("%s[%d]", path, i), v.Index(i))
+			display(fmt.Sprintf("%s[%d]", path, i), v.Index(i), seen)
 		}
 	case reflect.Struct:
 		for i := 0; i < v.NumField(); i++ {
 			fieldPath := fmt.Sprintf("%s.%s", path, v.Type().Field(i).Name)
-			display(fieldPath, v.Field(i))
+			display(fieldPath, v.Field(i), seen)
 		}
 	case reflect.Map:
 		for _, key := range v.MapKeys() {
 			display(fmt.Sprintf("%s[%s]", path,
-				formatAtom(key)), v.MapIndex(key))
+				formatAtom(key)), v.MapIndex(key), seen)
 		}
 	case reflect.Ptr:
 		if v.IsNil() {
 			fmt.Printf("%s = nil\n", path)
 		} else {
-			display(fmt.Sprintf("(*%s)", path), v.Elem())
+			display(fmt.Sprintf("(*%s)", path), v.Elem(), seen)
 		}
 	case reflect.Interface:
 		if v.IsNil() {
 			fmt.Printf("%s = nil\n", path)
 		} else {
 			fmt.Printf("%s.type = %s\n", path, v.Elem().Type())
-			display(path+".value", v.Elem())
+			display(path+".value", v.Elem(), seen)
 		}
 	default: // basic types, channels, funcs
 		fmt.Printf("%s = %s\n", path, formatAtom(v))
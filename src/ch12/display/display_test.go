@@ -31,6 +31,8 @@ func Example_expr() {
 	// e.args[0].value.x.value = "A"
 	// e.args[0].value.y.type = eval.Var
 	// e.args[0].value.y.value = "pi"
+	// e.pos.line = 1
+	// e.pos.col = 1
 }
 
 func Example_slice() {
@@ -206,40 +208,42 @@ func Test(t *testing.T) {
 	// (*rV.typ).hash = 871609668
 	// (*rV.typ)._ = 0
 	// ...
+}
 
+func Example_ptrCycle() {
 	// a pointer that points to itself
 	type P *P
 	var p P
 	p = &p
-	if false {
-		Display("p", p)
-		// Output:
-		// Display p (display.P):
-		// ...stuck, no output...
-	}
+	Display("p", p)
+	// Output:
+	// Display p (display.P):
+	// (*p) = <cycle to p>
+}
 
+func Example_mapCycle() {
 	// a map that contains itself
 	type M map[string]M
 	m := make(M)
 	m[""] = m
-	if false {
-		Display("m", m)
-		// Output:
-		// Display m (display.M):
-		// ...stuck, no output...
-	}
+	Display("m", m)
+	// Output:
+	// Display m (display.M):
+	// m[""] = <cycle to m>
+}
 
+func Example_sliceCycle() {
 	// a slice that contains itself
 	type S []S
 	s := make(S, 1)
 	s[0] = s
-	if false {
-		Display("s", s)
-		// Output:
-		// Display s (display.S):
-		// ...stuck, no output...
-	}
+	Display("s", s)
+	// Output:
+	// Display s (display.S):
+	// s[0] = <cycle to s>
+}
 
+func Example_structCycle() {
 	// a linked list that eats its own tail
 	type Cycle struct {
 		Value int
@@ -247,13 +251,20 @@ func Test(t *testing.T) {
 	}
 	var c Cycle
 	c = Cycle{42, &c}
-	if false {
-		Display("c", c)
-		// Output:
-		// Display c (display.Cycle):
-		// c.Value = 42
-		// (*c.Tail).Value = 42
-		// (*(*c.Tail).Tail).Value = 42
-		// ...ad infinitum...
-	}
+	Display("c", c)
+	// Output:
+	// Display c (display.Cycle):
+	// c.Value = 42
+	// (*c.Tail).Value = 42
+	// (*c.Tail).Tail = <cycle to c.Tail>
+}
+
+func Example_shared() {
+	type Pair struct{ A, B *int }
+	x := 1
+	Display("pair", Pair{&x, &x})
+	// Output:
+	// Display pair (display.Pair):
+	// (*pair.A) = 1
+	// pair.B = <same as pair.A>
 }
@@ -0,0 +1,142 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package params
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// A field is a struct field bound to a request parameter.
+type field struct {
+	name   string // dotted, for a field of a nested struct
+	source string
+	index  []int // for reflect.Value.FieldByIndex
+	rules  rules
+}
+
+var sources = map[string]bool{
+	"form": true, "query": true, "header": true,
+	"path": true, "cookie": true, "json": true,
+}
+
+var fieldCache sync.Map // map[reflect.Type][]*field or error
+
+// fieldsOf returns the parameter fields of struct type t, including
+// those of nested structs, in order.
+func fieldsOf(t reflect.Type) ([]*field, error) {
+	if f, ok := fieldCache.Load(t); ok {
+		if err, ok := f.(error); ok {
+			return nil, err
+		}
+		return f.([]*field), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("params: %s is not a struct", t)
+	}
+	var fields []*field
+	err := addFields(&fields, t, nil, "", "form")
+	if err == nil {
+		seen := make(map[string]bool)
+		for _, f := range fields {
+			key := f.source + " " + f.name
+			if seen[key] {
+				err = fmt.Errorf("params: %s: duplicate %s parameter %q", t, f.source, f.name)
+				break
+			}
+			seen[key] = true
+		}
+	}
+	if err != nil {
+		fieldCache.Store(t, err)
+		return nil, err
+	}
+	fieldCache.Store(t, fields)
+	return fields, nil
+}
+
+// addFields appends to fields those of struct type t, whose parameter
+// names begin with prefix and whose default source is source.
+func addFields(fields *[]*field, t reflect.Type, index []int, prefix, source string) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue // unexported
+		}
+		name, src, _ := strings.Cut(sf.Tag.Get("http"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		if src == "" {
+			src = source
+		} else if !sources[src] {
+			return fmt.Errorf("params: field %s.%s: unknown source %q", t, sf.Name, src)
+		}
+		f := &field{
+			name:   prefix + name,
+			source: src,
+			index:  append(index[:len(index):len(index)], i),
+		}
+
+		if src != "json" && sf.Type.Kind() == reflect.Struct && !supported(sf.Type) {
+			// A nested struct. The fields of an untagged
+			// embedded struct are named as if they were t's.
+			nested := f.name + "."
+			if sf.Anonymous && sf.Tag.Get("http") == "" {
+				nested = prefix
+			}
+			if err := addFields(fields, sf.Type, f.index, nested, src); err != nil {
+				return err
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue // an embedded unexported non-struct
+		}
+		if src != "json" {
+			elem := sf.Type
+			if repeated(elem) {
+				elem = elem.Elem()
+			}
+			if !supported(elem) {
+				return fmt.Errorf("params: field %s.%s: unsupported type %s", t, sf.Name, sf.Type)
+			}
+		}
+		r, err := parseRules(sf.Tag.Get("validate"), sf.Type)
+		if err != nil {
+			return fmt.Errorf("params: field %s.%s: %v", t, sf.Name, err)
+		}
+		f.rules = r
+		*fields = append(*fields, f)
+	}
+	return nil
+}
+
+// repeated reports whether a field of type t takes every value of its
+// parameter, rather than just one.
+func repeated(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// supported reports whether populate can parse a value of type t.
+func supported(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Ptr:
+		return t.Elem().Kind() != reflect.Ptr && supported(t.Elem())
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package params
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// Pack returns a copy of u with the fields of the struct v, or of the
+// struct to which v points, encoded as Unpack would decode them: form
+// and query fields as query parameters, and path fields in place of
+// their wildcards in u.Path. Query parameters of u with the same names
+// are replaced. Nil pointers and empty slices are left out, as are
+// fields from other sources, which cannot be part of a URL.
+func Pack(u *url.URL, v interface{}) (*url.URL, error) {
+	s := reflect.Indirect(reflect.ValueOf(v))
+	fields, err := fieldsOf(s.Type())
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	path := u.Path
+	for _, f := range fields {
+		fv := s.FieldByIndex(f.index)
+		switch f.source {
+		case "form", "query":
+			query.Del(f.name)
+			if repeated(fv.Type()) {
+				for i := 0; i < fv.Len(); i++ {
+					query.Add(f.name, format(fv.Index(i)))
+				}
+			} else if fv.Kind() != reflect.Ptr || !fv.IsNil() {
+				query.Add(f.name, format(fv))
+			}
+		case "path":
+			wildcard := "{" + f.name + "}"
+			if !strings.Contains(path, wildcard) {
+				return nil, fmt.Errorf("params: no %s in path %q", wildcard, path)
+			}
+			path = strings.Replace(path, wildcard, url.PathEscape(format(fv)), 1)
+		}
+	}
+	r := *u
+	r.RawQuery = query.Encode()
+	r.Path, r.RawPath = path, ""
+	return &r, nil
+}
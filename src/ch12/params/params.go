@@ -4,76 +4,262 @@
 // See page 349.
 
 // Package params provides a reflection-based parser for URL parameters.
+//
+// The fields of a struct are bound to request parameters by a tag of
+// the form `http:"name,source"`. The name defaults to the lower-case
+// field name, and the source is one of
+//
+//	form	the URL query or a form body (the default)
+//	query	the URL query only
+//	header	a request header
+//	path	a wildcard of the ServeMux pattern, such as {id}
+//	cookie	a cookie
+//	json	a member of a JSON object body
+//
+// A field named "-" is ignored. The fields of a nested struct are named
+// by the struct's name, a dot and their own name, and take their source
+// from the struct unless they have one of their own.
+//
+// A second tag, `validate:"rule,..."`, checks the values present in the
+// request:
+//
+//	required	the parameter must be present
+//	min=n, max=n	bounds on a number or duration, the length of a
+//			string, or the number of values of a slice
+//	oneof=a b c	the value must be one of those listed
+//	regexp=re	the whole value must match re, which is the rest
+//			of the tag and so must come last
 package params
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// An Error describes a problem with one parameter.
+type Error struct {
+	Param  string `json:"param"`
+	Source string `json:"source"`
+	Value  string `json:"value,omitempty"`
+	Msg    string `json:"error"`
+}
+
+func (e *Error) Error() string { return e.Param + ": " + e.Msg }
+
+// Errors holds all the problems found by Unpack, in field order and
+// then by the names of unknown parameters.
+type Errors []*Error
+
+func (errs Errors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
 //!+Unpack
 
 // Unpack populates the fields of the struct pointed to by ptr
 // from the HTTP request parameters in req.
+//
+// Problems with the request, including form parameters that match no
+// field, are reported together as Errors. Problems with the struct
+// type are reported as other errors.
 func Unpack(req *http.Request, ptr interface{}) error {
 	if err := req.ParseForm(); err != nil {
 		return err
 	}
 
-	// Build map of fields keyed by effective name.
-	fields := make(map[string]reflect.Value)
 	v := reflect.ValueOf(ptr).Elem() // the struct variable
-	for i := 0; i < v.NumField(); i++ {
-		fieldInfo := v.Type().Field(i) // a reflect.StructField
-		tag := fieldInfo.Tag           // a reflect.StructTag
-		name := tag.Get("http")
-		if name == "" {
-			name = strings.ToLower(fieldInfo.Name)
-		}
-		fields[name] = v.Field(i)
+	fields, err := fieldsOf(v.Type())
+	if err != nil {
+		return err
 	}
 
-	// Update struct field for each parameter in the request.
-	for name, values := range req.Form {
-		f := fields[name]
-		if !f.IsValid() {
-			continue // ignore unrecognized HTTP parameters
+	var errs Errors
+	body, err := jsonBody(req, fields)
+	if err != nil {
+		errs = append(errs, &Error{Param: "body", Source: "json", Msg: err.Error()})
+	}
+
+	// Update each struct field from its parameter in the request.
+	known := make(map[string]bool)
+	for _, f := range fields {
+		if f.source == "form" || f.source == "query" {
+			known[f.name] = true
 		}
-		for _, value := range values {
-			if f.Kind() == reflect.Slice {
-				elem := reflect.New(f.Type().Elem()).Elem()
-				if err := populate(elem, value); err != nil {
-					return fmt.Errorf("%s: %v", name, err)
+		fv := v.FieldByIndex(f.index)
+		var present bool
+		if f.source == "json" {
+			raw, ok := body[f.name]
+			if ok {
+				present = true
+				p := reflect.New(fv.Type())
+				if err := json.Unmarshal(raw, p.Interface()); err != nil {
+					errs = append(errs, f.error(string(raw), err.Error()))
+					continue
 				}
-				f.Set(reflect.Append(f, elem))
-			} else {
-				if err := populate(f, value); err != nil {
-					return fmt.Errorf("%s: %v", name, err)
+				fv.Set(p.Elem())
+			}
+		} else {
+			values := f.values(req)
+			present = len(values) > 0
+			ok := true
+			for _, value := range values {
+				if repeated(fv.Type()) {
+					elem := reflect.New(fv.Type().Elem()).Elem()
+					if err := populate(elem, value); err != nil {
+						errs = append(errs, f.error(value, err.Error()))
+						ok = false
+						continue
+					}
+					fv.Set(reflect.Append(fv, elem))
+				} else {
+					if err := populate(fv, value); err != nil {
+						errs = append(errs, f.error(value, err.Error()))
+						ok = false
+					}
 				}
 			}
+			if !ok {
+				continue
+			}
+		}
+		if !present {
+			if f.rules.required {
+				errs = append(errs, f.error("", "required"))
+			}
+			continue
+		}
+		if msg := f.rules.check(fv); msg != "" {
+			errs = append(errs, f.error(format(fv), msg))
+		}
+	}
+
+	var unknown []string
+	for name := range req.Form {
+		if !known[name] {
+			unknown = append(unknown, name)
 		}
 	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, &Error{Param: name, Source: "form",
+			Value: req.Form.Get(name), Msg: "unknown parameter"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
 
 //!-Unpack
 
+// values returns the values of f's parameter in req.
+func (f *field) values(req *http.Request) []string {
+	switch f.source {
+	case "form":
+		return req.Form[f.name]
+	case "query":
+		return req.URL.Query()[f.name]
+	case "header":
+		return req.Header.Values(f.name)
+	case "path":
+		if value := req.PathValue(f.name); value != "" {
+			return []string{value}
+		}
+	case "cookie":
+		var values []string
+		for _, c := range req.Cookies() {
+			if c.Name == f.name {
+				values = append(values, c.Value)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func (f *field) error(value, msg string) *Error {
+	return &Error{Param: f.name, Source: f.source, Value: value, Msg: msg}
+}
+
+// jsonBody returns the members of the JSON object in the body of req,
+// if it has one and some field has the json source.
+func jsonBody(req *http.Request, fields []*field) (map[string]json.RawMessage, error) {
+	need := false
+	for _, f := range fields {
+		need = need || f.source == "json"
+	}
+	if !need || req.Body == nil {
+		return nil, nil
+	}
+	if t, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); t != "application/json" {
+		return nil, nil
+	}
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return body, nil
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
 //!+populate
 func populate(v reflect.Value, value string) error {
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
 	switch v.Kind() {
 	case reflect.String:
 		v.SetString(value)
 
-	case reflect.Int:
-		i, err := strconv.ParseInt(value, 10, 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
 		if err != nil {
 			return err
 		}
 		v.SetInt(i)
 
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+
 	case reflect.Bool:
 		b, err := strconv.ParseBool(value)
 		if err != nil {
@@ -81,6 +267,13 @@ func populate(v reflect.Value, value string) error {
 		}
 		v.SetBool(b)
 
+	case reflect.Ptr: // an optional value
+		elem := reflect.New(v.Type().Elem())
+		if err := populate(elem.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(elem)
+
 	default:
 		return fmt.Errorf("unsupported kind %s", v.Type())
 	}
@@ -88,3 +281,41 @@ func populate(v reflect.Value, value string) error {
 }
 
 //!-populate
+
+// format is the inverse of populate.
+func format(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return ""
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(text)
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Ptr:
+		return format(v.Elem())
+	case reflect.Slice:
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = format(v.Index(i))
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
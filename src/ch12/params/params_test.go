@@ -0,0 +1,224 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package params
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type page struct {
+	Size   uint `validate:"min=1,max=50"`
+	Number int
+}
+
+type request struct {
+	ID      int           `http:"id,path"`
+	Query   string        `http:"q,query" validate:"required,min=2"`
+	Labels  []string      `http:"l" validate:"max=3,oneof=go rust c"`
+	Score   float64       `validate:"min=0,max=1"`
+	Timeout time.Duration `validate:"min=1s"`
+	Since   time.Time
+	Addr    net.IP
+	Limit   *int
+	Page    page
+	Agent   string   `http:"User-Agent,header"`
+	Session string   `http:"session,cookie" validate:"regexp=[0-9a-f]{4,8}"`
+	Filter  []string `http:"filter,json"`
+	Skipped string   `http:"-"`
+	private int
+}
+
+func newRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.SetPathValue("id", "7")
+	req.Header.Set("User-Agent", "fetch/1.0")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "beef"})
+	return req
+}
+
+func TestUnpack(t *testing.T) {
+	req := newRequest("POST", "/items/7?q=hello&l=go&l=c&score=0.5&timeout=1m30s"+
+		"&since=2016-01-02T15:04:05Z&addr=10.0.0.1&limit=3&page.size=20&page.number=2",
+		`{"filter": ["new", "used"], "other": 1}`)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	var got request
+	got.Labels = []string{"rust"} // values are appended
+	if err := Unpack(req, &got); err != nil {
+		t.Fatal(err)
+	}
+	limit := 3
+	want := request{
+		ID:      7,
+		Query:   "hello",
+		Labels:  []string{"rust", "go", "c"},
+		Score:   0.5,
+		Timeout: 90 * time.Second,
+		Since:   time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC),
+		Addr:    net.ParseIP("10.0.0.1"),
+		Limit:   &limit,
+		Page:    page{20, 2},
+		Agent:   "fetch/1.0",
+		Session: "beef",
+		Filter:  []string{"new", "used"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestUnpackErrors(t *testing.T) {
+	req := newRequest("POST", "/items/7?l=go&l=java&l=c&l=rust&score=2&timeout=10ms"+
+		"&limit=x&page.size=0&page.number=-&extra=1&since=yesterday",
+		`{"filter": "new"}`)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", "session=XYZ")
+	var r request
+	err := Unpack(req, &r)
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Unpack returned %v, want Errors", err)
+	}
+	want := []string{
+		"q: required",
+		"l: must have at most 3 values",
+		"score: must be at most 1",
+		"timeout: must be at least 1s",
+		`since: parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": cannot parse "yesterday" as "2006"`,
+		`limit: strconv.ParseInt: parsing "x": invalid syntax`,
+		"page.size: must be at least 1",
+		`page.number: strconv.ParseInt: parsing "-": invalid syntax`,
+		"session: must match [0-9a-f]{4,8}",
+		"filter: json: cannot unmarshal string into Go value of type []string",
+		"extra: unknown parameter",
+	}
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if e := errs[1]; e.Source != "form" || e.Value != "go,java,c,rust" {
+		t.Errorf("errs[1] = %+v", e)
+	}
+	if e := errs[8]; e.Source != "cookie" || e.Value != "XYZ" {
+		t.Errorf("errs[8] = %+v", e)
+	}
+}
+
+func TestRules(t *testing.T) {
+	type form struct {
+		Name  string   `validate:"min=2,max=4"`
+		Sort  string   `validate:"oneof=asc desc"`
+		Codes []string `validate:"min=2,regexp=[A-Z]{2},[0-9]"`
+	}
+	for _, test := range []struct {
+		query, want string
+	}{
+		{"name=ab&sort=asc&codes=AB,1&codes=CD,2", ""},
+		{"name=été", ""},
+		{"name=a", "name: must be at least 2 characters"},
+		{"name=abcde", "name: must be at most 4 characters"},
+		{"sort=up", "sort: must be one of asc, desc"},
+		{"codes=AB,1", "codes: must have at least 2 values"},
+		{"codes=AB,1&codes=CD", "codes: must match [A-Z]{2},[0-9]"},
+	} {
+		var p form
+		err := Unpack(httptest.NewRequest("GET", "/?"+test.query, nil), &p)
+		if got := errString(err); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestBadStruct(t *testing.T) {
+	for _, test := range []struct {
+		ptr  interface{}
+		want string
+	}{
+		{new(struct{ C chan int }), "params: field struct { C chan int }.C: unsupported type chan int"},
+		{new(struct {
+			A int `http:",body"`
+		}), `params: field struct { A int "http:\",body\"" }.A: unknown source "body"`},
+		{new(struct {
+			A int `validate:"min=x"`
+		}), `params: field struct { A int "validate:\"min=x\"" }.A: min=x: strconv.ParseFloat: parsing "x": invalid syntax`},
+		{new(struct {
+			A bool `validate:"max=1"`
+		}), `params: field struct { A bool "validate:\"max=1\"" }.A: max=1: does not apply to bool`},
+		{new(struct {
+			A int `validate:"positive"`
+		}), `params: field struct { A int "validate:\"positive\"" }.A: unknown rule "positive"`},
+		{new(struct {
+			A int
+			B int `http:"a"`
+		}), `params: struct { A int; B int "http:\"a\"" }: duplicate form parameter "a"`},
+	} {
+		err := Unpack(httptest.NewRequest("GET", "/", nil), test.ptr)
+		if got := errString(err); got != test.want {
+			t.Errorf("got  %s\nwant %s", got, test.want)
+		}
+	}
+}
+
+func TestPack(t *testing.T) {
+	type search struct {
+		ID     int      `http:"id,path"`
+		Labels []string `http:"l"`
+		Max    int
+		Since  time.Time
+		Limit  *int
+		Page   page
+		Agent  string `http:"User-Agent,header"`
+	}
+	in := search{
+		ID:     42,
+		Labels: []string{"a b", "c&d"},
+		Max:    10,
+		Since:  time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC),
+		Page:   page{Size: 5},
+		Agent:  "not packed",
+	}
+	base, _ := url.Parse("http://example.com/items/{id}?l=old&keep=1")
+	u, err := Pack(base, &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "http://example.com/items/42?keep=1&l=a+b&l=c%26d&max=10" +
+		"&page.number=0&page.size=5&since=2016-01-02T00%3A00%3A00Z"
+	if u.String() != want {
+		t.Errorf("Pack = %s, want %s", u, want)
+	}
+
+	u.RawQuery = strings.Replace(u.RawQuery, "keep=1&", "", 1)
+	req := httptest.NewRequest("GET", u.String(), nil)
+	req.SetPathValue("id", "42")
+	var out search
+	if err := Unpack(req, &out); err != nil {
+		t.Fatal(err)
+	}
+	in.Agent = ""
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip: got %+v, want %+v", out, in)
+	}
+
+	if _, err := Pack(&url.URL{Path: "/items"}, in); err == nil {
+		t.Errorf("Pack without {id} in path succeeded")
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package params
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// rules are the checks of a validate tag.
+type rules struct {
+	required       bool
+	hasMin, hasMax bool
+	min, max       float64 // a duration is in nanoseconds
+	oneof          []string
+	re             *regexp.Regexp
+	reSrc          string
+}
+
+// parseRules parses the validate tag of a field of type t.
+func parseRules(tag string, t reflect.Type) (rules, error) {
+	var r rules
+	for tag != "" {
+		item, rest, _ := strings.Cut(tag, ",")
+		tag = rest
+		key, val, _ := strings.Cut(item, "=")
+		switch key {
+		case "required":
+			r.required = true
+		case "min", "max":
+			x, err := parseBound(val, t)
+			if err != nil {
+				return r, fmt.Errorf("%s=%s: %v", key, val, err)
+			}
+			if key == "min" {
+				r.hasMin, r.min = true, x
+			} else {
+				r.hasMax, r.max = true, x
+			}
+		case "oneof":
+			r.oneof = strings.Fields(val)
+			if len(r.oneof) == 0 {
+				return r, fmt.Errorf("oneof needs values")
+			}
+		case "regexp":
+			// The pattern may contain commas, so it takes the
+			// rest of the tag.
+			if rest != "" {
+				val += "," + rest
+			}
+			re, err := regexp.Compile("^(?:" + val + ")$")
+			if err != nil {
+				return r, err
+			}
+			r.re, r.reSrc = re, val
+			tag = ""
+		default:
+			return r, fmt.Errorf("unknown rule %q", item)
+		}
+	}
+	return r, nil
+}
+
+// parseBound parses the limit of a min or max rule for type t.
+func parseBound(val string, t reflect.Type) (float64, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		d, err := time.ParseDuration(val)
+		return float64(d), err
+	case t.Kind() == reflect.String || repeated(t):
+		n, err := strconv.ParseUint(val, 10, 32)
+		return float64(n), err
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		// not a number
+	case reflect.Zero(t).CanInt(), reflect.Zero(t).CanUint(), reflect.Zero(t).CanFloat():
+		return strconv.ParseFloat(val, 64)
+	}
+	return 0, fmt.Errorf("does not apply to %s", t)
+}
+
+// check returns a message describing the first rule that v breaks,
+// or "" if it breaks none.
+func (r *rules) check(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if !repeated(v.Type()) {
+		return r.checkValue(v)
+	}
+	n := float64(v.Len())
+	if r.hasMin && n < r.min {
+		return fmt.Sprintf("must have at least %g values", r.min)
+	}
+	if r.hasMax && n > r.max {
+		return fmt.Sprintf("must have at most %g values", r.max)
+	}
+	for i := 0; i < v.Len(); i++ {
+		if msg := r.checkText(format(v.Index(i))); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+func (r *rules) checkValue(v reflect.Value) string {
+	if r.hasMin || r.hasMax {
+		var x float64
+		var unit string
+		show := func(b float64) string { return strconv.FormatFloat(b, 'g', -1, 64) }
+		switch {
+		case v.Type() == durationType:
+			x = float64(v.Int())
+			show = func(b float64) string { return time.Duration(b).String() }
+		case v.Kind() == reflect.String:
+			x, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+		case v.CanInt():
+			x = float64(v.Int())
+		case v.CanUint():
+			x = float64(v.Uint())
+		case v.CanFloat():
+			x = v.Float()
+		}
+		if r.hasMin && x < r.min {
+			return "must be at least " + show(r.min) + unit
+		}
+		if r.hasMax && x > r.max {
+			return "must be at most " + show(r.max) + unit
+		}
+	}
+	return r.checkText(format(v))
+}
+
+// checkText applies the oneof and regexp rules to the text of a value.
+func (r *rules) checkText(s string) string {
+	if r.oneof != nil {
+		found := false
+		for _, x := range r.oneof {
+			found = found || x == s
+		}
+		if !found {
+			return "must be one of " + strings.Join(r.oneof, ", ")
+		}
+	}
+	if r.re != nil && !r.re.MatchString(s) {
+		return "must match " + r.reSrc
+	}
+	return ""
+}
@@ -19,8 +19,8 @@ import "gopl.io/ch12/params"
 // search implements the /search URL endpoint.
 func search(resp http.ResponseWriter, req *http.Request) {
 	var data struct {
-		Labels     []string `http:"l"`
-		MaxResults int      `http:"max"`
+		Labels     []string `http:"l" validate:"max=5,regexp=[a-z]+"`
+		MaxResults int      `http:"max" validate:"min=1,max=100"`
 		Exact      bool     `http:"x"`
 	}
 	data.MaxResults = 10 // set default
@@ -31,6 +31,11 @@ func search(resp http.ResponseWriter, req *http.Request) {
 
 	// ...rest of handler...
 	fmt.Fprintf(resp, "Search: %+v\n", data)
+	if data.MaxResults < 100 {
+		data.MaxResults *= 2
+		more, _ := params.Pack(req.URL, &data)
+		fmt.Fprintf(resp, "More: %s\n", more)
+	}
 }
 
 //!-
@@ -46,15 +51,22 @@ $ go build gopl.io/ch12/search
 $ ./search &
 $ ./fetch 'http://localhost:12345/search'
 Search: {Labels:[] MaxResults:10 Exact:false}
+More: /search?max=20&x=false
 $ ./fetch 'http://localhost:12345/search?l=golang&l=programming'
 Search: {Labels:[golang programming] MaxResults:10 Exact:false}
+More: /search?l=golang&l=programming&max=20&x=false
 $ ./fetch 'http://localhost:12345/search?l=golang&l=programming&max=100'
 Search: {Labels:[golang programming] MaxResults:100 Exact:false}
 $ ./fetch 'http://localhost:12345/search?x=true&l=golang&l=programming'
 Search: {Labels:[golang programming] MaxResults:10 Exact:true}
+More: /search?l=golang&l=programming&max=20&x=true
 $ ./fetch 'http://localhost:12345/search?q=hello&x=123'
 x: strconv.ParseBool: parsing "123": invalid syntax
-$ ./fetch 'http://localhost:12345/search?q=hello&max=lots'
+q: unknown parameter
+$ ./fetch 'http://localhost:12345/search?l=Go&max=lots'
+l: must match [a-z]+
 max: strconv.ParseInt: parsing "lots": invalid syntax
+$ ./fetch 'http://localhost:12345/search?max=1000'
+max: must be at most 100
 //!-output
 */
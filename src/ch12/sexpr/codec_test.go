@@ -0,0 +1,495 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package sexpr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type point struct{ X, Y int }
+
+// A shape is an interface satisfied by registered types.
+type shape interface{ area() float64 }
+
+type circle struct{ R float64 }
+type rect struct{ Min, Max point }
+
+func (c circle) area() float64 { return math.Pi * c.R * c.R }
+func (r *rect) area() float64 {
+	return float64((r.Max.X - r.Min.X) * (r.Max.Y - r.Min.Y))
+}
+
+func init() {
+	Register(point{})
+	Register(circle{})
+	RegisterName("rect", &rect{})
+}
+
+type kinds struct {
+	B       bool
+	F       bool
+	I       int
+	I8      int8
+	I64     int64
+	U8      uint8
+	U64     uint64
+	F32     float32
+	F64     float64
+	C64     complex64
+	C128    complex128
+	S       string
+	A       [3]int
+	Sl      []string
+	Nil     []int
+	M       map[string]int
+	PtKeys  map[point]string
+	P       *point
+	NilP    *point
+	Any     interface{}
+	Shapes  []shape
+	Nested  [][]float64
+	Renamed string `sexpr:"renamed"`
+	Omitted int    `sexpr:",omitempty"`
+	Skipped string `sexpr:"-"`
+	private int
+}
+
+func sampleKinds() kinds {
+	return kinds{
+		B:    true,
+		I:    -42,
+		I8:   math.MinInt8,
+		I64:  math.MaxInt64,
+		U8:   255,
+		U64:  math.MaxUint64,
+		F32:  1.5,
+		F64:  -2.25e-300,
+		C64:  complex(1, -2),
+		C128: complex(math.Inf(1), 0.1),
+		S:    "tab\t\"quote\" ünïcode",
+		A:    [3]int{1, 2, 3},
+		Sl:   []string{"a", "", "c"},
+		M:    map[string]int{"one": 1, "two": 2},
+		PtKeys: map[point]string{
+			{1, 2}: "a", {-1, 0}: "b",
+		},
+		P:       &point{3, 4},
+		Any:     []interface{}{1, "two", 3.5, nil, point{5, 6}},
+		Shapes:  []shape{circle{1}, &rect{point{0, 0}, point{2, 3}}, nil},
+		Nested:  [][]float64{{1, 2}, nil, {}},
+		Renamed: "renamed",
+	}
+}
+
+func TestKinds(t *testing.T) {
+	want := sampleKinds()
+	for _, marshal := range []func(interface{}) ([]byte, error){Marshal, MarshalIndent} {
+		data, err := marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got kinds
+		if err := Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("round trip of %s:\ngot  %+v\nwant %+v", data, got, want)
+		}
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	nan := math.NaN()
+	for _, test := range []struct {
+		v    interface{}
+		want string
+	}{
+		{true, "t"},
+		{false, "nil"},
+		{-7, "-7"},
+		{3.0, "3"},
+		{[]float64{nan, math.Inf(1), math.Inf(-1), 0.1}, "(NaN Inf -Inf 0.1)"},
+		{float32(0.1), "0.1"},
+		{complex(1, -2.5), "#C(1 -2.5)"},
+		{map[string]bool{"b": true, "a": false}, `(("a" nil) ("b" t))`},
+		{[]interface{}{1, "x", nil}, `(("int" 1) ("string" "x") nil)`},
+		{[]shape{&rect{}}, `(("rect" ((Min ((X 0) (Y 0))) (Max ((X 0) (Y 0))))))`},
+		{struct {
+			A int `sexpr:"a,omitempty"`
+			B int `sexpr:"b,omitempty"`
+			C int `sexpr:"-"`
+			D string
+		}{B: 1, C: 2}, `((b 1) (D ""))`},
+		{(*point)(nil), "nil"},
+		{[]byte("hi"), "(104 105)"},
+	} {
+		got, err := Marshal(test.v)
+		if err != nil {
+			t.Errorf("Marshal(%#v): %v", test.v, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("Marshal(%#v) = %s, want %s", test.v, got, test.want)
+		}
+	}
+
+	for _, v := range []interface{}{
+		make(chan int),
+		func() {},
+		struct{ F func() }{},
+		struct {
+			A int `sexpr:"x-y"`
+		}{},
+		struct {
+			A int `sexpr:"B"`
+			B int
+		}{},
+	} {
+		if data, err := Marshal(v); err == nil {
+			t.Errorf("Marshal(%T) = %s, want error", v, data)
+		}
+	}
+}
+
+// A date encodes itself as a string such as "2016-01-02".
+type date struct{ time.Time }
+
+func (d date) MarshalSexpr() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", d.Format("2006-01-02"))), nil
+}
+
+func (d *date) UnmarshalSexpr(data []byte) error {
+	var s string
+	if err := Unmarshal(data, &s); err != nil {
+		return err
+	}
+	t, err := time.Parse("2006-01-02", s)
+	d.Time = t
+	return err
+}
+
+// A pair encodes itself as a two-element list, and is lenient about
+// layout so that it must be canonicalized.
+type pair [2]int
+
+func (p pair) MarshalSexpr() ([]byte, error) {
+	return []byte(fmt.Sprintf("(  %d\n  %d )", p[0], p[1])), nil
+}
+
+func (p *pair) UnmarshalSexpr(data []byte) error {
+	_, err := fmt.Sscanf(string(data), "(%d %d)", &p[0], &p[1])
+	return err
+}
+
+type bad struct{}
+
+func (bad) MarshalSexpr() ([]byte, error) { return []byte("(unbalanced"), nil }
+
+func TestMarshaler(t *testing.T) {
+	type event struct {
+		Name  string
+		When  date
+		Also  *date
+		Span  pair
+		Spans map[string]pair
+	}
+	want := event{
+		Name:  "launch",
+		When:  date{time.Date(1957, 10, 4, 0, 0, 0, 0, time.UTC)},
+		Also:  &date{time.Date(1969, 7, 20, 0, 0, 0, 0, time.UTC)},
+		Span:  pair{-1, 2},
+		Spans: map[string]pair{"x": {3, 4}},
+	}
+	data, err := Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const text = `((Name "launch") (When "1957-10-04") (Also "1969-07-20") (Span (-1 2)) (Spans (("x" (3 4)))))`
+	if string(data) != text {
+		t.Errorf("Marshal = %s, want %s", data, text)
+	}
+	var got event
+	if err := Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal = %+v, want %+v", got, want)
+	}
+
+	if _, err := Marshal(bad{}); err == nil {
+		t.Errorf("Marshal accepted malformed MarshalSexpr output")
+	}
+	err = Unmarshal([]byte(`((When "yesterday"))`), &got)
+	if err == nil || !strings.HasPrefix(err.Error(), "sexpr: 1:8: sexpr.date.UnmarshalSexpr: ") {
+		t.Errorf("bad date: got %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	type rec struct {
+		N int8
+		U uint
+		F float32
+		S string
+		B bool
+		A [2]int
+		I interface{}
+		Z shape
+	}
+	for _, test := range []struct{ input, want string }{
+		{"", "sexpr: 1:1: empty input"},
+		{"(", "sexpr: 1:2: unexpected end of file in list"},
+		{"((N 1)) ()", `sexpr: 1:9: unexpected "(" after value`},
+		{"((N 128))", "sexpr: 1:5: cannot decode 128 into int8"},
+		{"((N -129))", "sexpr: 1:5: cannot decode -129 into int8"},
+		{"((N 1.5))", "sexpr: 1:5: cannot decode 1.5 into int8"},
+		{"((U -1))", "sexpr: 1:5: cannot decode -1 into uint"},
+		{"((F 1e39))", "sexpr: 1:5: cannot decode 1e39 into float32"},
+		{"((F nan))", "sexpr: 1:5: cannot decode nan into float32"},
+		{"((F \"1\"))", `sexpr: 1:5: got "\"1\"", want number`},
+		{"((S 1))", `sexpr: 1:5: cannot decode "1" into string`},
+		{"((B 1))", `sexpr: 1:5: cannot decode "1" into bool`},
+		{"((A (1 2 3)))", "sexpr: 1:10: too many elements for [2]int"},
+		{"((Q 1))", "sexpr: 1:3: unknown field Q in sexpr.rec"},
+		{"((1 1))", `sexpr: 1:3: got "1", want field name`},
+		{"((I (int 1)))", `sexpr: 1:6: got "int", want type name of interface {}`},
+		{`((I ("chan int" nil)))`, `sexpr: 1:6: unregistered type "chan int"`},
+		{`((I ("int" 1 2)))`, "sexpr: 1:5: interface value has more than one element"},
+		{`((Z ("sexpr.point" ((X 1)))))`, "sexpr: 1:6: sexpr.point does not implement sexpr.shape"},
+		{"((S \"abc))", "sexpr: 1:5: literal not terminated"},
+		{"((N 1)\n (S\n  x))", `sexpr: 3:3: cannot decode "x" into string`},
+	} {
+		var r rec
+		err := Unmarshal([]byte(test.input), &r)
+		if err == nil {
+			t.Errorf("Unmarshal(%q) succeeded, want %s", test.input, test.want)
+			continue
+		}
+		var serr *Error
+		if !errors.As(err, &serr) {
+			t.Errorf("Unmarshal(%q) error %T, want *Error", test.input, err)
+		}
+		if err.Error() != test.want {
+			t.Errorf("Unmarshal(%q) = %s, want %s", test.input, err, test.want)
+		}
+	}
+
+	var x int
+	if err := Unmarshal([]byte("1"), x); err == nil {
+		t.Errorf("Unmarshal into non-pointer succeeded")
+	}
+}
+
+func TestStream(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	points := []point{{1, 2}, {3, 4}}
+	for _, p := range points {
+		if err := enc.Encode(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	enc.SetIndent(true)
+	if err := enc.Encode(sampleKinds()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "((X 1) (Y 2))\n((X 3) (Y 4))\n((B t) (F nil)") {
+		t.Errorf("encoded stream:\n%s", buf.String())
+	}
+
+	dec := NewDecoder(&buf)
+	for _, want := range points {
+		var got point
+		if err := dec.Decode(&got); err != nil || got != want {
+			t.Fatalf("Decode = %v, %v; want %v", got, err, want)
+		}
+	}
+	var k kinds
+	if err := dec.Decode(&k); err != nil || !reflect.DeepEqual(k, sampleKinds()) {
+		t.Fatalf("Decode = %+v, %v", k, err)
+	}
+	if err := dec.Decode(&k); err != io.EOF {
+		t.Errorf("Decode at end = %v, want io.EOF", err)
+	}
+}
+
+type node struct {
+	Value int
+	Next  *node
+}
+
+type selfMap map[string]selfMap
+type selfSlice []selfSlice
+
+func TestShared(t *testing.T) {
+	ring := &node{Value: 1}
+	ring.Next = &node{2, ring}
+	m := selfMap{"a": nil}
+	m["b"] = m
+	s := make(selfSlice, 2)
+	s[0] = s
+	inner := selfSlice{s, nil} // s[1] refers to a list that refers to s
+	s[1] = inner
+	p := &point{1, 2}
+	for _, test := range []struct {
+		v     interface{}
+		want  string
+		check func(v interface{}) bool
+	}{
+		{ring, "#1=((Value 1) (Next ((Value 2) (Next #1#))))",
+			func(v interface{}) bool { r := v.(*node); return r.Next.Next == r }},
+		{m, `#1=(("a" nil) ("b" #1#))`,
+			func(v interface{}) bool {
+				m := v.(selfMap)
+				return reflect.ValueOf(m["b"]).Pointer() == reflect.ValueOf(m).Pointer()
+			}},
+		{s, "#1=(#1# (#1# nil))",
+			func(v interface{}) bool {
+				s := v.(selfSlice)
+				return &s[0][0] == &s[0] && len(s[0]) == 2 && &s[1][0][0] == &s[0]
+			}},
+		{struct{ A, B *point }{p, p}, "((A #1=((X 1) (Y 2))) (B #1#))",
+			func(v interface{}) bool { x := v.(struct{ A, B *point }); return x.A == x.B }},
+		{[]*node{ring.Next, ring}, "(#1=((Value 2) (Next #2=((Value 1) (Next #1#)))) #2#)",
+			func(v interface{}) bool { x := v.([]*node); return x[0].Next == x[1] && x[1].Next == x[0] }},
+	} {
+		data, err := Marshal(test.v)
+		if err != nil {
+			t.Errorf("Marshal(%T): %v", test.v, err)
+			continue
+		}
+		if string(data) != test.want {
+			t.Errorf("Marshal(%T) = %s, want %s", test.v, data, test.want)
+		}
+		indented, err := MarshalIndent(test.v)
+		if err != nil {
+			t.Errorf("MarshalIndent(%T): %v", test.v, err)
+			continue
+		}
+		for _, data := range [][]byte{data, indented} {
+			out := reflect.New(reflect.TypeOf(test.v))
+			if err := Unmarshal(data, out.Interface()); err != nil {
+				t.Errorf("Unmarshal(%s): %v", data, err)
+				continue
+			}
+			got := out.Elem().Interface()
+			if !test.check(got) {
+				t.Errorf("Unmarshal(%s) did not restore the shared references", data)
+			}
+			if !reflect.DeepEqual(got, test.v) {
+				t.Errorf("Unmarshal(%s) = %v, want %v", data, got, test.v)
+			}
+		}
+	}
+
+	if _, err := Marshal(map[*node]int{ring: 1}); err == nil {
+		t.Errorf("Marshal of map with cyclic key succeeded")
+	}
+
+	for _, test := range []struct {
+		input string
+		out   interface{}
+		want  string
+	}{
+		{"#1#", new(*node), "sexpr: 1:1: undefined label #1"},
+		{"((Value 1) (Next #1#))", new(*node), "sexpr: 1:18: undefined label #1"},
+		{"#1=((Next #1=nil))", new(*node), "sexpr: 1:11: label #1 defined twice"},
+		{"#1=5", new(int), "sexpr: 1:1: cannot label int"},
+		{"#0=5", new(*int), `sexpr: 1:2: bad label #0`},
+		{"#1!", new(*int), `sexpr: 1:3: got "!", want '=' or '#' after label`},
+		{"((A #1=((X 1) (Y 2))) (B #1#))", new(struct {
+			A *point
+			B *node
+		}), "sexpr: 1:26: label #1 is a *sexpr.point, not *sexpr.node"},
+		{"(#1=(1 2) #1#)", new([][]string), `sexpr: 1:6: cannot decode "1" into string`},
+		{"#1=(1 x)", new([]int), "sexpr: 1:7: cannot decode x into int"},
+	} {
+		err := Unmarshal([]byte(test.input), test.out)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("Unmarshal(%q) = %v, want %s", test.input, err, test.want)
+		}
+	}
+}
+
+// FuzzRoundTrip checks that values survive MarshalIndent and Unmarshal.
+func FuzzRoundTrip(f *testing.F) {
+	f.Add("Dr. Strangelove", int64(1964), uint64(0), 0.5, true, []byte{})
+	f.Add("", int64(-1), uint64(math.MaxUint64), math.Inf(-1), false, []byte("\x00\xff"))
+	f.Add("\"\\\n\t☺", int64(math.MinInt64), uint64(1), math.NaN(), true, []byte("(()"))
+	f.Fuzz(func(t *testing.T, s string, i int64, u uint64, x float64, b bool, raw []byte) {
+		type value struct {
+			S  string
+			I  int64
+			U  uint64
+			X  float64
+			C  complex128
+			B  bool `sexpr:"flag,omitempty"`
+			R  []byte
+			M  map[string][]interface{}
+			P  *value
+			Sh shape
+		}
+		v := value{S: s, I: i, U: u, X: x, C: complex(x, float64(i)), B: b, R: raw,
+			M:  map[string][]interface{}{s: {s, i, x, b}},
+			P:  &value{S: strings.Repeat(s, 3), X: -x},
+			Sh: circle{x}}
+		data, err := MarshalIndent(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got value
+		if err := Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		// NaN != NaN, so compare encodings rather than values.
+		again, err := MarshalIndent(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(again, data) {
+			t.Errorf("round trip changed value:\n%s\n%s", data, again)
+		}
+	})
+}
+
+// FuzzDecode checks that Unmarshal rejects bad input without panicking,
+// and that what it accepts re-encodes stably.
+func FuzzDecode(f *testing.F) {
+	for _, s := range []string{
+		"nil", "(1 2 3)", `(("int" 1) ("sexpr.point" ((X 1) (Y 2))))`,
+		"#C(1 2)", `((a -1.5) (b "x"))`, "(((", "-", "#", "((X 1) (Y 2)",
+		`("[]interface {}" #1=(("[]interface {}" #1#) ("int" 1)))`, "#1=(#1#)", "#1=#1#",
+	} {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v interface{}
+		var list []interface{}
+		var m map[string]float64
+		for _, out := range []interface{}{&v, &list, &m} {
+			if err := Unmarshal(data, out); err != nil {
+				continue
+			}
+			enc, err := Marshal(out)
+			if err != nil {
+				t.Fatalf("Marshal after Unmarshal(%q): %v", data, err)
+			}
+			if err := Unmarshal(enc, out); err != nil {
+				t.Fatalf("Unmarshal(%s): %v", enc, err)
+			}
+			again, _ := Marshal(out)
+			if !bytes.Equal(enc, again) {
+				t.Errorf("unstable encoding: %s then %s", enc, again)
+			}
+		}
+	})
+}
@@ -10,110 +10,438 @@ package sexpr
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
 	"strconv"
+	"strings"
 	"text/scanner"
 )
 
 //!+Unmarshal
 // Unmarshal parses S-expression data and populates the variable
-// whose address is in the non-nil pointer out.
-func Unmarshal(data []byte, out interface{}) (err error) {
-	lex := &lexer{scan: scanner.Scanner{Mode: scanner.GoTokens}}
-	lex.scan.Init(bytes.NewReader(data))
-	lex.next() // get the first token
+// whose address is in the non-nil pointer out. The data must hold
+// exactly one S-expression, in the form written by Marshal.
+//
+// Lists replace the contents of slices and add to maps; fields of a
+// struct that are not mentioned keep their values. A field name with no
+// corresponding field is an error. The labels #n= and #n# that Marshal
+// writes for shared values make the same pointers, maps and slices
+// shared again. Errors are of type *Error.
+func Unmarshal(data []byte, out interface{}) error {
+	dec := NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(out); err != nil {
+		if err == io.EOF {
+			return &Error{1, 1, "empty input"}
+		}
+		return err
+	}
+	if dec.lex.token != scanner.EOF {
+		return dec.lex.errorAt(dec.lex.pos, "unexpected %s after value", dec.lex.describe())
+	}
+	return nil
+}
+
+//!-Unmarshal
+
+// An Unmarshaler can decode an S-expression of itself, as written by
+// its MarshalSexpr method.
+type Unmarshaler interface {
+	UnmarshalSexpr([]byte) error
+}
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+// An Error describes a problem with the input, at line and column.
+type Error struct {
+	Line, Col int
+	Msg       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("sexpr: %d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// A Decoder reads S-expressions from an input stream.
+type Decoder struct {
+	lex     *lexer
+	started bool
+}
+
+// NewDecoder returns a decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	lex := &lexer{}
+	lex.scan.Init(r)
+	lex.scan.Mode = scanner.GoTokens
+	lex.scan.Error = func(s *scanner.Scanner, msg string) {
+		pos := s.Position // of the token, if it has begun
+		if !pos.IsValid() {
+			pos = s.Pos()
+		}
+		panic(lex.errorAt(pos, "%s", msg))
+	}
+	return &Decoder{lex: lex}
+}
+
+// Decode reads the next S-expression from the input and stores it in
+// the variable pointed to by out. At the end of the input it returns
+// io.EOF.
+func (dec *Decoder) Decode(out interface{}) (err error) {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("sexpr: cannot decode into %T, want non-nil pointer", out)
+	}
 	defer func() {
-		// NOTE: this is not an example of ideal error handling.
 		if x := recover(); x != nil {
-			err = fmt.Errorf("error at %s: %v", lex.scan.Position, x)
+			e, ok := x.(*Error)
+			if !ok {
+				panic(x)
+			}
+			err = e
 		}
 	}()
-	read(lex, reflect.ValueOf(out).Elem())
+	if !dec.started {
+		dec.started = true
+		dec.lex.next() // get the first token
+	}
+	if dec.lex.token == scanner.EOF {
+		return io.EOF
+	}
+	dec.lex.labels = make(map[int]reflect.Value)
+	read(dec.lex, v.Elem())
 	return nil
 }
 
-//!-Unmarshal
+// A lexeme is a token as scanned.
+type lexeme struct {
+	token rune
+	str   string
+	pos   scanner.Position
+}
 
 //!+lexer
 type lexer struct {
-	scan  scanner.Scanner
-	token rune // the current token
+	scan   scanner.Scanner
+	lexeme                       // the current token
+	ahead  []lexeme              // scanned after the current token
+	record *[]lexeme             // if non-nil, collects the tokens passed over
+	labels map[int]reflect.Value // defined so far by #n=
+}
+
+func (lex *lexer) next() {
+	if lex.record != nil {
+		*lex.record = append(*lex.record, lex.lexeme)
+	}
+	if len(lex.ahead) > 0 {
+		lex.lexeme, lex.ahead = lex.ahead[0], lex.ahead[1:]
+		return
+	}
+	lex.lexeme = lex.scanLexeme()
 }
 
-func (lex *lexer) next()        { lex.token = lex.scan.Scan() }
-func (lex *lexer) text() string { return lex.scan.TokenText() }
+func (lex *lexer) text() string { return lex.str }
 
 func (lex *lexer) consume(want rune) {
-	if lex.token != want { // NOTE: Not an example of good error handling.
-		panic(fmt.Sprintf("got %q, want %q", lex.text(), want))
+	if lex.token != want {
+		lex.fail("got %s, want %q", lex.describe(), want)
 	}
 	lex.next()
 }
 
 //!-lexer
 
-// The read function is a decoder for a small subset of well-formed
-// S-expressions.  For brevity of our example, it takes many dubious
-// shortcuts.
-//
-// The parser assumes
-// - that the S-expression input is well-formed; it does no error checking.
-// - that the S-expression input corresponds to the type of the variable.
-// - that all numbers in the input are non-negative decimal integers.
-// - that all keys in ((key value) ...) struct syntax are unquoted symbols.
-// - that the input does not contain dotted lists such as (1 2 . 3).
-// - that the input does not contain Lisp reader macros such 'x and #'x.
-//
-// The reflection logic assumes
-// - that v is always a variable of the appropriate type for the
-//   S-expression value.  For example, v must not be a boolean,
-//   interface, channel, or function, and if v is an array, the input
-//   must have the correct number of elements.
-// - that v in the top-level call to read has the zero value of its
-//   type and doesn't need clearing.
-// - that if v is a numeric variable, it is a signed integer.
+func (lex *lexer) scanLexeme() lexeme {
+	token := lex.scan.Scan()
+	return lexeme{token, lex.scan.TokenText(), lex.scan.Position}
+}
+
+// peek returns the token after the current one.
+func (lex *lexer) peek() lexeme {
+	if len(lex.ahead) == 0 {
+		lex.ahead = append(lex.ahead, lex.scanLexeme())
+	}
+	return lex.ahead[0]
+}
+
+// countList returns the number of elements in the list that begins at
+// the current token. It reads ahead to the end of the list, then backs
+// up to the '(' again.
+func (lex *lexer) countList() int {
+	var list []lexeme
+	lex.record = &list
+	lex.next() // consume '('
+	n := 0
+	for ; !endList(lex); n++ {
+		writeDatum(lex, new(strings.Builder))
+	}
+	lex.record = nil
+	list = append(list, lex.lexeme) // ')'
+	lex.lexeme, lex.ahead = list[0], append(list[1:], lex.ahead...)
+	return n
+}
+
+func (lex *lexer) describe() string {
+	if lex.token == scanner.EOF {
+		return "end of file"
+	}
+	return strconv.Quote(lex.text())
+}
+
+func (lex *lexer) errorAt(pos scanner.Position, format string, args ...interface{}) *Error {
+	return &Error{pos.Line, pos.Column, fmt.Sprintf(format, args...)}
+}
+
+// fail reports an error at the current token.
+func (lex *lexer) fail(format string, args ...interface{}) {
+	panic(lex.errorAt(lex.pos, format, args...))
+}
 
 //!+read
 func read(lex *lexer, v reflect.Value) {
-	switch lex.token {
-	case scanner.Ident:
-		// The only valid identifiers are
-		// "nil" and struct field names.
-		if lex.text() == "nil" {
-			v.Set(reflect.Zero(v.Type()))
+	// nil is the zero value of every type.
+	if lex.token == scanner.Ident && lex.text() == "nil" {
+		v.Set(reflect.Zero(v.Type()))
+		lex.next()
+		return
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(unmarshalerType) {
+		pos := lex.pos
+		data := []byte(datum(lex))
+		if err := v.Addr().Interface().(Unmarshaler).UnmarshalSexpr(data); err != nil {
+			panic(lex.errorAt(pos, "%s.UnmarshalSexpr: %v", v.Type(), err))
+		}
+		return
+	}
+	if lex.token == '#' && lex.peek().token == scanner.Int {
+		readLabeled(lex, v)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		read(lex, v.Elem())
+		return
+
+	case reflect.Bool:
+		if lex.token == scanner.Ident && lex.text() == "t" {
+			v.SetBool(true)
 			lex.next()
 			return
 		}
-	case scanner.String:
-		s, _ := strconv.Unquote(lex.text()) // NOTE: ignoring errors
-		v.SetString(s)
-		lex.next()
+
+	case reflect.String:
+		if lex.token == scanner.String || lex.token == scanner.RawString {
+			s, err := strconv.Unquote(lex.text())
+			if err != nil {
+				lex.fail("bad string %s", lex.text())
+			}
+			v.SetString(s)
+			lex.next()
+			return
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16,
+		reflect.Int32, reflect.Int64:
+		pos, text := number(lex)
+		i, err := strconv.ParseInt(text, 0, v.Type().Bits())
+		if err != nil {
+			panic(lex.errorAt(pos, "cannot decode %s into %s", text, v.Type()))
+		}
+		v.SetInt(i)
 		return
-	case scanner.Int:
-		i, _ := strconv.Atoi(lex.text()) // NOTE: ignoring errors
-		v.SetInt(int64(i))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		pos, text := number(lex)
+		u, err := strconv.ParseUint(text, 0, v.Type().Bits())
+		if err != nil {
+			panic(lex.errorAt(pos, "cannot decode %s into %s", text, v.Type()))
+		}
+		v.SetUint(u)
+		return
+
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float(lex, v.Type().Bits()))
+		return
+
+	case reflect.Complex64, reflect.Complex128: // #C(re im)
+		lex.consume('#')
+		if lex.token != scanner.Ident || lex.text() != "C" {
+			lex.fail("got %s, want #C(re im)", lex.describe())
+		}
 		lex.next()
+		lex.consume('(')
+		re := float(lex, v.Type().Bits()/2)
+		im := float(lex, v.Type().Bits()/2)
+		lex.consume(')')
+		v.SetComplex(complex(re, im))
 		return
-	case '(':
+
+	case reflect.Interface: // ("type" value)
+		pos := lex.pos
+		lex.consume('(')
+		if lex.token != scanner.String {
+			lex.fail("got %s, want type name of %s", lex.describe(), v.Type())
+		}
+		name, _ := strconv.Unquote(lex.text())
+		t, ok := typeByName(name)
+		if !ok {
+			lex.fail("unregistered type %q", name)
+		}
+		if !t.Implements(v.Type()) {
+			lex.fail("%s does not implement %s", t, v.Type())
+		}
+		lex.next()
+		elem := reflect.New(t).Elem()
+		read(lex, elem)
+		if lex.token != ')' {
+			panic(lex.errorAt(pos, "interface value has more than one element"))
+		}
 		lex.next()
-		readList(lex, v)
-		lex.next() // consume ')'
+		v.Set(elem)
 		return
+
+	case reflect.Array, reflect.Slice, reflect.Struct, reflect.Map:
+		if lex.token == '(' {
+			lex.next()
+			readList(lex, v)
+			lex.next() // consume ')'
+			return
+		}
+
+	default:
+		lex.fail("cannot decode into %s", v.Type())
 	}
-	panic(fmt.Sprintf("unexpected token %q", lex.text()))
+	lex.fail("cannot decode %s into %s", lex.describe(), v.Type())
 }
 
 //!-read
 
+// readLabeled reads a value with a label, #n=value, or a reference to
+// one, #n#. The pointer, map or slice defined by a label is recorded
+// before its contents are read, so that they may refer back to it.
+func readLabeled(lex *lexer, v reflect.Value) {
+	pos := lex.pos
+	lex.next() // consume '#'
+	n, err := strconv.Atoi(lex.text())
+	if err != nil || n < 1 {
+		lex.fail("bad label #%s", lex.text())
+	}
+	lex.next()
+	switch lex.token {
+	case '#':
+		lex.next()
+		ref, ok := lex.labels[n]
+		if !ok {
+			panic(lex.errorAt(pos, "undefined label #%d", n))
+		}
+		if ref.Type() != v.Type() {
+			panic(lex.errorAt(pos, "label #%d is a %s, not %s", n, ref.Type(), v.Type()))
+		}
+		v.Set(ref)
+
+	case '=':
+		lex.next()
+		if _, ok := lex.labels[n]; ok {
+			panic(lex.errorAt(pos, "label #%d defined twice", n))
+		}
+		switch v.Kind() {
+		case reflect.Ptr:
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			lex.labels[n] = reflect.ValueOf(v.Interface())
+			read(lex, v.Elem())
+
+		case reflect.Map:
+			if lex.token != '(' {
+				lex.fail("got %s, want list after #%d=", lex.describe(), n)
+			}
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			lex.labels[n] = reflect.ValueOf(v.Interface())
+			read(lex, v)
+
+		case reflect.Slice:
+			// The elements are read in place, so the slice
+			// must have its final length from the start.
+			if lex.token != '(' {
+				lex.fail("got %s, want list after #%d=", lex.describe(), n)
+			}
+			size := lex.countList()
+			v.Set(reflect.MakeSlice(v.Type(), size, size))
+			lex.labels[n] = reflect.ValueOf(v.Interface())
+			lex.next() // consume '('
+			for i := 0; !endList(lex); i++ {
+				read(lex, v.Index(i))
+			}
+			lex.next() // consume ')'
+
+		default:
+			panic(lex.errorAt(pos, "cannot label %s", v.Type()))
+		}
+
+	default:
+		lex.fail("got %s, want '=' or '#' after label", lex.describe())
+	}
+}
+
+// number returns the text of an integer or floating-point literal,
+// which may have a sign.
+func number(lex *lexer) (scanner.Position, string) {
+	pos, sign := lex.pos, ""
+	if lex.token == '-' || lex.token == '+' {
+		sign = lex.text()
+		lex.next()
+	}
+	switch lex.token {
+	case scanner.Int, scanner.Float, scanner.Ident:
+		text := sign + lex.text()
+		lex.next()
+		return pos, text
+	}
+	panic(lex.errorAt(pos, "got %s, want number", lex.describe()))
+}
+
+// float reads a floating-point literal, NaN, Inf or -Inf.
+func float(lex *lexer, bits int) float64 {
+	pos, text := number(lex)
+	switch text {
+	case "NaN":
+		return math.NaN()
+	case "Inf", "+Inf":
+		return math.Inf(1)
+	case "-Inf":
+		return math.Inf(-1)
+	}
+	f, err := strconv.ParseFloat(text, bits)
+	if err != nil || strings.ContainsAny(text, "nN") { // no "nan", "inf"
+		panic(lex.errorAt(pos, "cannot decode %s into float%d", text, bits))
+	}
+	return f
+}
+
 //!+readlist
 func readList(lex *lexer, v reflect.Value) {
 	switch v.Kind() {
 	case reflect.Array: // (item ...)
-		for i := 0; !endList(lex); i++ {
+		i := 0
+		for ; !endList(lex); i++ {
+			if i >= v.Len() {
+				lex.fail("too many elements for %s", v.Type())
+			}
 			read(lex, v.Index(i))
 		}
+		for ; i < v.Len(); i++ {
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
 
 	case reflect.Slice: // (item ...)
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
 		for !endList(lex) {
 			item := reflect.New(v.Type().Elem()).Elem()
 			read(lex, item)
@@ -121,19 +449,35 @@ func readList(lex *lexer, v reflect.Value) {
 		}
 
 	case reflect.Struct: // ((name value) ...)
+		fields, err := fieldsOf(v.Type())
+		if err != nil {
+			lex.fail("%v", err)
+		}
 		for !endList(lex) {
 			lex.consume('(')
 			if lex.token != scanner.Ident {
-				panic(fmt.Sprintf("got token %q, want field name", lex.text()))
+				lex.fail("got %s, want field name", lex.describe())
 			}
 			name := lex.text()
+			f := -1
+			for _, fld := range fields {
+				if fld.name == name {
+					f = fld.index
+					break
+				}
+			}
+			if f < 0 {
+				lex.fail("unknown field %s in %s", name, v.Type())
+			}
 			lex.next()
-			read(lex, v.FieldByName(name))
+			read(lex, v.Field(f))
 			lex.consume(')')
 		}
 
 	case reflect.Map: // ((key value) ...)
-		v.Set(reflect.MakeMap(v.Type()))
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
 		for !endList(lex) {
 			lex.consume('(')
 			key := reflect.New(v.Type().Key()).Elem()
@@ -143,16 +487,13 @@ func readList(lex *lexer, v reflect.Value) {
 			v.SetMapIndex(key, value)
 			lex.consume(')')
 		}
-
-	default:
-		panic(fmt.Sprintf("cannot decode list into %v", v.Type()))
 	}
 }
 
 func endList(lex *lexer) bool {
 	switch lex.token {
 	case scanner.EOF:
-		panic("end of file")
+		lex.fail("unexpected end of file in list")
 	case ')':
 		return true
 	}
@@ -160,3 +501,88 @@ func endList(lex *lexer) bool {
 }
 
 //!-readlist
+
+// datum consumes one S-expression and returns it in canonical form:
+// one space between the elements of a list and none inside parentheses.
+func datum(lex *lexer) string {
+	var buf strings.Builder
+	writeDatum(lex, &buf)
+	return buf.String()
+}
+
+func writeDatum(lex *lexer, buf *strings.Builder) {
+	switch lex.token {
+	case scanner.EOF:
+		lex.fail("unexpected end of file")
+	case ')':
+		lex.fail("unexpected ')'")
+	case '(':
+		buf.WriteByte('(')
+		lex.next()
+		for first := true; !endList(lex); first = false {
+			if !first {
+				buf.WriteByte(' ')
+			}
+			writeDatum(lex, buf)
+		}
+		buf.WriteByte(')')
+		lex.next()
+	case '-', '+': // a signed number
+		buf.WriteString(lex.text())
+		lex.next()
+		if lex.token != scanner.Int && lex.token != scanner.Float && lex.token != scanner.Ident {
+			lex.fail("got %s, want number", lex.describe())
+		}
+		buf.WriteString(lex.text())
+		lex.next()
+	case '#': // a reader macro such as #C(re im), or a label
+		buf.WriteByte('#')
+		lex.next()
+		if lex.token == scanner.Int {
+			buf.WriteString(lex.text())
+			lex.next()
+			def := lex.token == '='
+			if !def && lex.token != '#' {
+				lex.fail("got %s, want '=' or '#' after label", lex.describe())
+			}
+			buf.WriteString(lex.text())
+			lex.next()
+			if def {
+				writeDatum(lex, buf)
+			}
+			return
+		}
+		if lex.token != scanner.Ident {
+			lex.fail("got %s after '#'", lex.describe())
+		}
+		buf.WriteString(lex.text())
+		lex.next()
+		if lex.token == '(' {
+			writeDatum(lex, buf)
+		}
+	default:
+		buf.WriteString(lex.text())
+		lex.next()
+	}
+}
+
+// canonical checks that data holds a single S-expression and returns
+// its canonical form.
+func canonical(data []byte) (s string, err error) {
+	dec := NewDecoder(bytes.NewReader(data))
+	defer func() {
+		if x := recover(); x != nil {
+			e, ok := x.(*Error)
+			if !ok {
+				panic(x)
+			}
+			err = e
+		}
+	}()
+	dec.lex.next()
+	s = datum(dec.lex)
+	if dec.lex.token != scanner.EOF {
+		dec.lex.fail("unexpected %s after value", dec.lex.describe())
+	}
+	return s, nil
+}
@@ -8,13 +8,38 @@ package sexpr
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
+	"sort"
+	"strconv"
 )
 
 //!+Marshal
 // Marshal encodes a Go value in S-expression form.
+//
+//	nil pointer, interface, map or slice	nil
+//	bool					t or nil
+//	integer					42
+//	float					3.5, NaN, Inf, -Inf
+//	complex					#C(1 -2.5)
+//	string					"hello"
+//	array, slice				(value ...)
+//	struct					((name value) ...)
+//	map					((key value) ...), sorted by key
+//	interface				("type" value)
+//	shared pointer, map or slice		#1=value ... #1#
+//
+// Struct fields may be renamed or omitted by a tag such as
+// `sexpr:"name,omitempty"`, as with encoding/json; a tag of "-" skips
+// the field. Unexported fields are not encoded. The type names used for
+// interfaces are those given to Register, or else reflect's names.
+// A pointer, map or slice reached more than once, as in a cyclic
+// value, is labeled #n= where it first appears and written as #n#
+// thereafter, as in Common Lisp. Channels and functions cannot be
+// encoded.
 func Marshal(v interface{}) ([]byte, error) {
-	var buf bytes.Buffer
+	var buf compact
 	if err := encode(&buf, reflect.ValueOf(v)); err != nil {
 		return nil, err
 	}
@@ -23,75 +48,317 @@ func Marshal(v interface{}) ([]byte, error) {
 
 //!-Marshal
 
-// encode writes to buf an S-expression representation of v.
+// A Marshaler can encode itself as an S-expression.
+type Marshaler interface {
+	MarshalSexpr() ([]byte, error)
+}
+
+var marshalerType = reflect.TypeOf((*Marshaler)(nil)).Elem()
+
+// A sink receives the tokens of an S-expression. Marshal writes them
+// out directly; MarshalIndent lays them out first.
+type sink interface {
+	string(s string) // an atom
+	begin()          // '('
+	space()          // between list elements
+	end()            // ')'
+}
+
+// compact is the sink for Marshal.
+type compact struct{ bytes.Buffer }
+
+func (c *compact) string(s string) { c.WriteString(s) }
+func (c *compact) begin()          { c.WriteByte('(') }
+func (c *compact) space()          { c.WriteByte(' ') }
+func (c *compact) end()            { c.WriteByte(')') }
+
+// encode writes to out an S-expression representation of v.
+func encode(out sink, v reflect.Value) error {
+	return newEncoder(out, v).encode(v)
+}
+
+// newEncoder returns an encoder for v that labels the references
+// reached more than once from it.
+func newEncoder(out sink, v reflect.Value) *encoder {
+	e := &encoder{out: out, shared: make(map[reference]int)}
+	counts := make(map[reference]int)
+	countRefs(v, counts)
+	for r, n := range counts {
+		if n > 1 {
+			e.shared[r] = 0 // not yet labeled
+		}
+	}
+	return e
+}
+
+// An encoder holds the state of one call to encode.
+type encoder struct {
+	out    sink
+	shared map[reference]int // label of each shared reference, once written
+	labels int               // labels written so far
+}
+
 //!+encode
-func encode(buf *bytes.Buffer, v reflect.Value) error {
+func (e *encoder) encode(v reflect.Value) error {
+	out := e.out
+	if isMarshaler(v) {
+		return encodeMarshaler(out, v)
+	}
+
+	if r, ok := referenceOf(v); ok {
+		if label, ok := e.shared[r]; ok {
+			if label > 0 {
+				out.string("#" + strconv.Itoa(label) + "#")
+				return nil
+			}
+			e.labels++
+			e.shared[r] = e.labels
+			out.string("#" + strconv.Itoa(e.labels) + "=")
+		}
+	}
+
 	switch v.Kind() {
 	case reflect.Invalid:
-		buf.WriteString("nil")
+		out.string("nil")
+
+	case reflect.Bool:
+		if v.Bool() {
+			out.string("t")
+		} else {
+			out.string("nil")
+		}
 
 	case reflect.Int, reflect.Int8, reflect.Int16,
 		reflect.Int32, reflect.Int64:
-		fmt.Fprintf(buf, "%d", v.Int())
+		out.string(strconv.FormatInt(v.Int(), 10))
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16,
 		reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		fmt.Fprintf(buf, "%d", v.Uint())
+		out.string(strconv.FormatUint(v.Uint(), 10))
+
+	case reflect.Float32, reflect.Float64:
+		out.string(formatFloat(v.Float(), v.Type().Bits()))
+
+	case reflect.Complex64, reflect.Complex128: // #C(re im)
+		c, bits := v.Complex(), v.Type().Bits()/2
+		out.string("#C")
+		out.begin()
+		out.string(formatFloat(real(c), bits))
+		out.space()
+		out.string(formatFloat(imag(c), bits))
+		out.end()
 
 	case reflect.String:
-		fmt.Fprintf(buf, "%q", v.String())
+		out.string(strconv.Quote(v.String()))
 
 	case reflect.Ptr:
-		return encode(buf, v.Elem())
+		if v.IsNil() {
+			out.string("nil")
+			return nil
+		}
+		return e.encode(v.Elem())
+
+	case reflect.Interface: // ("type" value)
+		if v.IsNil() {
+			out.string("nil")
+			return nil
+		}
+		out.begin()
+		out.string(strconv.Quote(typeName(v.Elem().Type())))
+		out.space()
+		if err := e.encode(v.Elem()); err != nil {
+			return err
+		}
+		out.end()
 
 	case reflect.Array, reflect.Slice: // (value ...)
-		buf.WriteByte('(')
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			out.string("nil")
+			return nil
+		}
+		out.begin()
 		for i := 0; i < v.Len(); i++ {
 			if i > 0 {
-				buf.WriteByte(' ')
+				out.space()
 			}
-			if err := encode(buf, v.Index(i)); err != nil {
+			if err := e.encode(v.Index(i)); err != nil {
 				return err
 			}
 		}
-		buf.WriteByte(')')
+		out.end()
 
 	case reflect.Struct: // ((name value) ...)
-		buf.WriteByte('(')
-		for i := 0; i < v.NumField(); i++ {
-			if i > 0 {
-				buf.WriteByte(' ')
+		fields, err := fieldsOf(v.Type())
+		if err != nil {
+			return err
+		}
+		out.begin()
+		first := true
+		for _, f := range fields {
+			fv := v.Field(f.index)
+			if f.omitEmpty && isEmpty(fv) {
+				continue
+			}
+			if !first {
+				out.space()
 			}
-			fmt.Fprintf(buf, "(%s ", v.Type().Field(i).Name)
-			if err := encode(buf, v.Field(i)); err != nil {
+			first = false
+			out.begin()
+			out.string(f.name)
+			out.space()
+			if err := e.encode(fv); err != nil {
 				return err
 			}
-			buf.WriteByte(')')
+			out.end()
 		}
-		buf.WriteByte(')')
+		out.end()
 
 	case reflect.Map: // ((key value) ...)
-		buf.WriteByte('(')
-		for i, key := range v.MapKeys() {
+		if v.IsNil() {
+			out.string("nil")
+			return nil
+		}
+		keys, err := sortedKeys(v)
+		if err != nil {
+			return err
+		}
+		out.begin()
+		for i, key := range keys {
 			if i > 0 {
-				buf.WriteByte(' ')
+				out.space()
 			}
-			buf.WriteByte('(')
-			if err := encode(buf, key); err != nil {
+			out.begin()
+			out.string(key.text)
+			out.space()
+			if err := e.encode(v.MapIndex(key.value)); err != nil {
 				return err
 			}
-			buf.WriteByte(' ')
-			if err := encode(buf, v.MapIndex(key)); err != nil {
-				return err
-			}
-			buf.WriteByte(')')
+			out.end()
 		}
-		buf.WriteByte(')')
+		out.end()
 
-	default: // float, complex, bool, chan, func, interface
-		return fmt.Errorf("unsupported type: %s", v.Type())
+	default: // chan, func, unsafe.Pointer
+		return fmt.Errorf("sexpr: unsupported type: %s", v.Type())
 	}
 	return nil
 }
 
 //!-encode
+
+func formatFloat(f float64, bits int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bits)
+}
+
+// isMarshaler reports whether v is encoded by its MarshalSexpr method.
+func isMarshaler(v reflect.Value) bool {
+	return v.Kind() != reflect.Interface && v.IsValid() && v.Type().Implements(marshalerType) ||
+		v.CanAddr() && v.Addr().Type().Implements(marshalerType)
+}
+
+// encodeMarshaler emits the output of a MarshalSexpr method, which must
+// be a single S-expression, in canonical form.
+func encodeMarshaler(out sink, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		out.string("nil")
+		return nil
+	}
+	m, ok := v.Interface().(Marshaler)
+	if !ok {
+		m = v.Addr().Interface().(Marshaler)
+	}
+	data, err := m.MarshalSexpr()
+	if err != nil {
+		return fmt.Errorf("sexpr: %s.MarshalSexpr: %v", v.Type(), err)
+	}
+	text, err := canonical(data)
+	if err != nil {
+		return fmt.Errorf("sexpr: %s.MarshalSexpr returned %v", v.Type(), err)
+	}
+	out.string(text)
+	return nil
+}
+
+type mapKey struct {
+	text  string
+	value reflect.Value
+}
+
+// sortedKeys returns the keys of map v in the order of their encodings.
+// Each key is encoded on its own, so it may not contain labels.
+func sortedKeys(v reflect.Value) ([]mapKey, error) {
+	keys := make([]mapKey, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		var buf compact
+		e := newEncoder(&buf, k)
+		if len(e.shared) > 0 {
+			return nil, fmt.Errorf("sexpr: map key of type %s has shared references", k.Type())
+		}
+		if err := e.encode(k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, mapKey{buf.String(), k})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].text < keys[j].text })
+	return keys, nil
+}
+
+// isEmpty reports whether v is omitted by an omitempty tag.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Complex64, reflect.Complex128:
+		return v.Complex() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// An Encoder writes S-expressions to an output stream, one per line
+// unless indented.
+type Encoder struct {
+	w      io.Writer
+	indent bool
+}
+
+// NewEncoder returns an encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// SetIndent makes subsequent calls to Encode lay out their output as
+// MarshalIndent does.
+func (enc *Encoder) SetIndent(indent bool) { enc.indent = indent }
+
+// Encode writes the S-expression for v followed by a newline.
+func (enc *Encoder) Encode(v interface{}) error {
+	var data []byte
+	var err error
+	if enc.indent {
+		data, err = MarshalIndent(v)
+	} else {
+		data, err = Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = enc.w.Write(append(data, '\n'))
+	return err
+}
@@ -12,9 +12,11 @@ import (
 	"reflect"
 )
 
+// MarshalIndent is like Marshal but breaks lines and indents lists so
+// that the output fits within 80 columns where possible.
 func MarshalIndent(v interface{}) ([]byte, error) {
 	p := printer{width: margin}
-	if err := pretty(&p, reflect.ValueOf(v)); err != nil {
+	if err := encode(&p, reflect.ValueOf(v)); err != nil {
 		return nil, err
 	}
 	return p.Bytes(), nil
@@ -107,77 +109,3 @@ func (p *printer) print(t *token) {
 		}
 	}
 }
-func (p *printer) stringf(format string, args ...interface{}) {
-	p.string(fmt.Sprintf(format, args...))
-}
-
-func pretty(p *printer, v reflect.Value) error {
-	switch v.Kind() {
-	case reflect.Invalid:
-		p.string("nil")
-
-	case reflect.Int, reflect.Int8, reflect.Int16,
-		reflect.Int32, reflect.Int64:
-		p.stringf("%d", v.Int())
-
-	case reflect.Uint, reflect.Uint8, reflect.Uint16,
-		reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		p.stringf("%d", v.Uint())
-
-	case reflect.String:
-		p.stringf("%q", v.String())
-
-	case reflect.Array, reflect.Slice: // (value ...)
-		p.begin()
-		for i := 0; i < v.Len(); i++ {
-			if i > 0 {
-				p.space()
-			}
-			if err := pretty(p, v.Index(i)); err != nil {
-				return err
-			}
-		}
-		p.end()
-
-	case reflect.Struct: // ((name value ...)
-		p.begin()
-		for i := 0; i < v.NumField(); i++ {
-			if i > 0 {
-				p.space()
-			}
-			p.begin()
-			p.string(v.Type().Field(i).Name)
-			p.space()
-			if err := pretty(p, v.Field(i)); err != nil {
-				return err
-			}
-			p.end()
-		}
-		p.end()
-
-	case reflect.Map: // ((key value ...)
-		p.begin()
-		for i, key := range v.MapKeys() {
-			if i > 0 {
-				p.space()
-			}
-			p.begin()
-			if err := pretty(p, key); err != nil {
-				return err
-			}
-			p.space()
-			if err := pretty(p, v.MapIndex(key)); err != nil {
-				return err
-			}
-			p.end()
-		}
-		p.end()
-
-	case reflect.Ptr:
-		return pretty(p, v.Elem())
-
-	default: // float, complex, bool, chan, func, interface
-		return fmt.Errorf("unsupported type: %s", v.Type())
-	}
-	return nil
-}
@@ -0,0 +1,76 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package sexpr
+
+import "reflect"
+
+// A reference identifies a pointer, map or slice, which may be reached
+// more than once within a value. Pointers and maps of different types
+// are different references even at the same address, and so are slices
+// of different lengths.
+type reference struct {
+	ptr uintptr
+	len int // of a slice
+	t   reflect.Type
+}
+
+// referenceOf returns the reference held by v, if the encoder may label
+// it. Pointers to zero-sized values and empty slices are never labeled:
+// distinct ones may have the same address, and a #n# for them would make
+// the decoder alias values that were never aliased.
+func referenceOf(v reflect.Value) (reference, bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() && v.Type().Elem().Size() > 0 {
+			return reference{v.Pointer(), 0, v.Type()}, true
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			return reference{v.Pointer(), 0, v.Type()}, true
+		}
+	case reflect.Slice:
+		if v.Len() > 0 && v.Type().Elem().Size() > 0 {
+			return reference{v.Pointer(), v.Len(), v.Type()}, true
+		}
+	}
+	return reference{}, false
+}
+
+// countRefs counts the times each reference is reached in encoding v,
+// without following any reference twice. Map keys are encoded on their
+// own, so they are not counted.
+func countRefs(v reflect.Value, counts map[reference]int) {
+	if isMarshaler(v) {
+		return
+	}
+	if r, ok := referenceOf(v); ok {
+		counts[r]++
+		if counts[r] > 1 {
+			return
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			countRefs(v.Elem(), counts)
+		}
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			countRefs(v.Index(i), counts)
+		}
+	case reflect.Struct:
+		fields, _ := fieldsOf(v.Type()) // encode reports the error
+		for _, f := range fields {
+			fv := v.Field(f.index)
+			if !f.omitEmpty || !isEmpty(fv) {
+				countRefs(fv, counts)
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			countRefs(iter.Value(), counts)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package sexpr
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"text/scanner"
+)
+
+// The registry maps the type names written before interface values to
+// types, and back.
+var registry struct {
+	sync.RWMutex
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+}
+
+// Register records the type of value under its reflect name, such as
+// "main.Point" or "*main.Point", so that interface values of that type
+// can be decoded.
+func Register(value interface{}) {
+	RegisterName(reflect.TypeOf(value).String(), value)
+}
+
+// RegisterName is like Register but uses the given name. Like
+// encoding/gob, it panics if the name or the type is already registered
+// differently.
+func RegisterName(name string, value interface{}) {
+	if name == "" {
+		panic("sexpr: attempt to register empty name")
+	}
+	t := reflect.TypeOf(value)
+	registry.Lock()
+	defer registry.Unlock()
+	if prev, ok := registry.types[name]; ok && prev != t {
+		panic(fmt.Sprintf("sexpr: registering duplicate types for %q: %s != %s", name, prev, t))
+	}
+	if prev, ok := registry.names[t]; ok && prev != name {
+		panic(fmt.Sprintf("sexpr: registering duplicate names for %s: %q != %q", t, prev, name))
+	}
+	registry.types[name] = t
+	registry.names[t] = name
+}
+
+// typeName returns the name written for an interface value of type t.
+func typeName(t reflect.Type) string {
+	registry.RLock()
+	defer registry.RUnlock()
+	if name, ok := registry.names[t]; ok {
+		return name
+	}
+	return t.String()
+}
+
+func typeByName(name string) (reflect.Type, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	t, ok := registry.types[name]
+	return t, ok
+}
+
+func init() {
+	registry.types = make(map[string]reflect.Type)
+	registry.names = make(map[reflect.Type]string)
+	for _, v := range []interface{}{
+		false, "",
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0), uintptr(0),
+		float32(0), float64(0), complex64(0), complex128(0),
+		[]interface{}(nil), map[string]interface{}(nil), []byte(nil), []string(nil),
+	} {
+		Register(v)
+	}
+}
+
+// A field is an encoded field of a struct.
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field or error
+
+// fieldsOf returns the encoded fields of struct type t in order.
+func fieldsOf(t reflect.Type) ([]field, error) {
+	if f, ok := fieldCache.Load(t); ok {
+		if err, ok := f.(error); ok {
+			return nil, err
+		}
+		return f.([]field), nil
+	}
+	var fields []field
+	var err error
+	seen := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("sexpr")
+		if sf.PkgPath != "" || tag == "-" {
+			continue // unexported or skipped
+		}
+		f := field{name: sf.Name, index: i}
+		if tag != "" {
+			name, opts, _ := strings.Cut(tag, ",")
+			if name != "" {
+				f.name = name
+			}
+			for _, opt := range strings.Split(opts, ",") {
+				if opt == "omitempty" {
+					f.omitEmpty = true
+				}
+			}
+		}
+		if !isSymbol(f.name) {
+			err = fmt.Errorf("sexpr: field %s.%s: bad name %q", t, sf.Name, f.name)
+			break
+		}
+		if seen[f.name] {
+			err = fmt.Errorf("sexpr: field %s.%s: duplicate name %q", t, sf.Name, f.name)
+			break
+		}
+		seen[f.name] = true
+		fields = append(fields, f)
+	}
+	if err != nil {
+		fieldCache.Store(t, err)
+		return nil, err
+	}
+	fieldCache.Store(t, fields)
+	return fields, nil
+}
+
+// isSymbol reports whether s reads as a single symbol other than nil or t.
+func isSymbol(s string) bool {
+	if s == "nil" || s == "t" {
+		return false
+	}
+	var sc scanner.Scanner
+	sc.Init(strings.NewReader(s))
+	sc.Mode = scanner.ScanIdents
+	sc.Error = func(*scanner.Scanner, string) {}
+	return sc.Scan() == scanner.Ident && sc.TokenText() == s && sc.Scan() == scanner.EOF
+}
@@ -29,4 +29,17 @@ int bz2compress(bz_stream *s, int action,
   return r;
 }
 
+int bz2decompress(bz_stream *s,
+                  char *in, unsigned *inlen, char *out, unsigned *outlen) {
+  s->next_in = in;
+  s->avail_in = *inlen;
+  s->next_out = out;
+  s->avail_out = *outlen;
+  int r = BZ2_bzDecompress(s);
+  *inlen -= s->avail_in;
+  *outlen -= s->avail_out;
+  s->next_in = s->next_out = NULL;
+  return r;
+}
+
 //!-
@@ -22,7 +22,8 @@
 
 //!+
 
-// Package bzip provides a writer that uses bzip2 compression (bzip.org).
+// Package bzip provides a writer that uses bzip2 compression (bzip.org),
+// and a reader for the compressed streams.
 package bzip
 
 /*
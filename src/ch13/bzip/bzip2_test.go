@@ -6,10 +6,12 @@ package bzip_test
 import (
 	"bytes"
 	"compress/bzip2" // reader
+	"fmt"
 	"io"
 	"testing"
+	"testing/iotest"
 
-	"gopl.io/ch13/bzip" // writer
+	"gopl.io/ch13/bzip" // writers, and another reader
 )
 
 func TestBzip2(t *testing.T) {
@@ -38,3 +40,147 @@ func TestBzip2(t *testing.T) {
 		t.Error("decompression yielded a different message")
 	}
 }
+
+// testData returns n bytes of text that compresses moderately well.
+func testData(n int) []byte {
+	var buf bytes.Buffer
+	for i := 0; buf.Len() < n; i++ {
+		fmt.Fprintf(&buf, "line %d: %x\n", i, i*i*2654435761)
+	}
+	return buf.Bytes()[:n]
+}
+
+func TestParallel(t *testing.T) {
+	for _, n := range []int{0, 1, 900000, 900001, 2500000} {
+		data := testData(n)
+		var first []byte
+		for _, workers := range []int{1, 3, 8} {
+			var compressed bytes.Buffer
+			w := bzip.NewParallelWriter(&compressed, workers)
+			// Write in pieces that do not align with blocks.
+			for rest := data; len(rest) > 0; {
+				k := 70001
+				if k > len(rest) {
+					k = len(rest)
+				}
+				if _, err := w.Write(rest[:k]); err != nil {
+					t.Fatal(err)
+				}
+				rest = rest[k:]
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// The blocks are independent, so the result
+			// does not depend on the number of workers.
+			if first == nil {
+				first = compressed.Bytes()
+			} else if !bytes.Equal(first, compressed.Bytes()) {
+				t.Errorf("%d bytes: output with %d workers differs", n, workers)
+			}
+
+			for _, r := range []io.Reader{
+				bzip2.NewReader(bytes.NewReader(compressed.Bytes())),
+				bzip.NewReader(bytes.NewReader(compressed.Bytes())),
+			} {
+				got, err := io.ReadAll(r)
+				if err != nil {
+					t.Errorf("%d bytes, %d workers: %T: %v", n, workers, r, err)
+				} else if !bytes.Equal(got, data) {
+					t.Errorf("%d bytes, %d workers: %T: decompressed %d bytes that differ",
+						n, workers, r, len(got))
+				}
+			}
+		}
+	}
+}
+
+type failWriter struct{ n int }
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if w.n--; w.n < 0 {
+		return 0, fmt.Errorf("disk full")
+	}
+	return len(p), nil
+}
+
+func TestParallelWriteError(t *testing.T) {
+	w := bzip.NewParallelWriter(&failWriter{1}, 2)
+	data := testData(500000)
+	var err error
+	for i := 0; i < 20 && err == nil; i++ {
+		_, err = w.Write(data)
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil || err.Error() != "disk full" {
+		t.Errorf("got error %v, want disk full", err)
+	}
+}
+
+func TestReader(t *testing.T) {
+	data := testData(300000)
+	var compressed bytes.Buffer
+	w := bzip.NewWriter(&compressed)
+	w.Write(data)
+	w.Close()
+	// Two streams, one after the other, read as one.
+	stream := compressed.Bytes()
+	twice := append(append([]byte(nil), stream...), stream...)
+
+	r := bzip.NewReader(iotest.OneByteReader(bytes.NewReader(twice)))
+	var got bytes.Buffer
+	buf := make([]byte, 1000) // smaller reads than the input buffer
+	for {
+		n, err := r.Read(buf)
+		got.Write(buf[:n])
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Close()
+	if want := append(append([]byte(nil), data...), data...); !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("read %d bytes that differ from the %d written", got.Len(), len(want))
+	}
+
+	corrupt := append([]byte(nil), stream...)
+	corrupt[len(corrupt)/2] ^= 0xff
+	for _, test := range []struct {
+		input []byte
+		want  string
+	}{
+		{nil, io.ErrUnexpectedEOF.Error()},
+		{stream[:len(stream)-10], io.ErrUnexpectedEOF.Error()},
+		{[]byte("hello, world"), "bzip: not bzip2 data"},
+		{append(append([]byte(nil), stream...), "junk"...), "bzip: not bzip2 data"},
+		{corrupt, "bzip: corrupt data"},
+	} {
+		r := bzip.NewReader(bytes.NewReader(test.input))
+		_, err := io.ReadAll(r)
+		r.Close()
+		if err == nil || err.Error() != test.want {
+			t.Errorf("reading %d bytes: got %v, want %s", len(test.input), err, test.want)
+		}
+	}
+}
+
+func BenchmarkWriter(b *testing.B) {
+	data := testData(8 * 900000)
+	b.SetBytes(int64(len(data)))
+	for _, workers := range []int{0, 1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				w := bzip.NewWriter(io.Discard)
+				if workers > 0 {
+					w = bzip.NewParallelWriter(io.Discard, workers)
+				}
+				w.Write(data)
+				w.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package bzip
+
+/*
+#include <bzlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"unsafe"
+)
+
+// blockBytes is the input size of one block at the largest block size,
+// 9, which NewWriter uses too.
+const blockBytes = 9 * 100000
+
+// A parallelWriter compresses each block of its input as a separate
+// bzip2 stream, on a pool of workers, and writes the streams in order.
+// Like the output of pbzip2, the result is a valid multi-stream file.
+type parallelWriter struct {
+	w      io.Writer // underlying output stream
+	block  []byte    // input not yet sent to a worker
+	blocks int       // sent so far
+	jobs   chan job
+	order  chan chan []byte // results, in input order
+	done   chan struct{}    // closed when the output goroutine returns
+	closed bool
+
+	mu  sync.Mutex
+	err error // the first error writing to w
+}
+
+type job struct {
+	data   []byte
+	result chan<- []byte
+}
+
+// NewParallelWriter returns a writer for bzip2-compressed streams that
+// compresses blocks of 900KB on the given number of goroutines.
+// Its output decompresses to the same data as that of NewWriter,
+// though it is a little larger.
+func NewParallelWriter(out io.Writer, workers int) io.WriteCloser {
+	if workers < 1 {
+		workers = 1
+	}
+	w := &parallelWriter{
+		w:     out,
+		block: make([]byte, 0, blockBytes),
+		jobs:  make(chan job),
+		order: make(chan chan []byte, 2*workers), // bounds the blocks in memory
+		done:  make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for j := range w.jobs {
+				j.result <- compressBlock(j.data)
+			}
+		}()
+	}
+	go w.output()
+	return w
+}
+
+// output writes the compressed blocks in order. After an error it
+// keeps receiving them, so that the workers do not block.
+func (w *parallelWriter) output() {
+	defer close(w.done)
+	for result := range w.order {
+		data := <-result
+		if w.error() == nil {
+			if _, err := w.w.Write(data); err != nil {
+				w.mu.Lock()
+				w.err = err
+				w.mu.Unlock()
+			}
+		}
+	}
+}
+
+func (w *parallelWriter) error() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *parallelWriter) Write(data []byte) (int, error) {
+	if w.closed {
+		panic("closed")
+	}
+	var total int // uncompressed bytes accepted
+	for len(data) > 0 {
+		if err := w.error(); err != nil {
+			return total, err
+		}
+		n := copy(w.block[len(w.block):cap(w.block)], data)
+		w.block = w.block[:len(w.block)+n]
+		total += n
+		data = data[n:]
+		if len(w.block) == blockBytes {
+			w.send()
+		}
+	}
+	return total, nil
+}
+
+// send passes the current block to a worker.
+func (w *parallelWriter) send() {
+	result := make(chan []byte, 1)
+	w.order <- result
+	w.jobs <- job{w.block, result}
+	w.block = make([]byte, 0, blockBytes)
+	w.blocks++
+}
+
+// Close compresses the last block, waits for all the output to be
+// written, and stops the workers.
+// It does not close the underlying io.Writer.
+func (w *parallelWriter) Close() error {
+	if w.closed {
+		panic("closed")
+	}
+	w.closed = true
+	if len(w.block) > 0 || w.blocks == 0 {
+		w.send() // empty input still needs one stream
+	}
+	close(w.jobs)
+	close(w.order)
+	<-w.done
+	return w.error()
+}
+
+// compressBlock returns data compressed as one bzip2 stream.
+func compressBlock(data []byte) []byte {
+	const blockSize = 9
+	const verbosity = 0
+	const workFactor = 30
+	// The documented bound on the size of the output.
+	out := make([]byte, len(data)+len(data)/100+600)
+	in := data
+	if len(in) == 0 {
+		in = []byte{0} // a valid pointer, to zero bytes
+	}
+	outlen := C.uint(len(out))
+	r := C.BZ2_bzBuffToBuffCompress(
+		(*C.char)(unsafe.Pointer(&out[0])), &outlen,
+		(*C.char)(unsafe.Pointer(&in[0])), C.uint(len(data)),
+		blockSize, verbosity, workFactor)
+	if r != C.BZ_OK {
+		panic(fmt.Sprintf("bzip: BZ2_bzBuffToBuffCompress returned %d", r))
+	}
+	return out[:outlen]
+}
@@ -0,0 +1,128 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package bzip
+
+/*
+#include <bzlib.h>
+bz_stream* bz2alloc();
+int bz2decompress(bz_stream *s,
+                  char *in, unsigned *inlen, char *out, unsigned *outlen);
+void bz2free(bz_stream* s);
+*/
+import "C"
+
+import (
+	"fmt"
+	"io"
+	"unsafe"
+)
+
+// Decompression parameters: no diagnostics, and the faster algorithm
+// rather than the one for small memory.
+const (
+	readVerbosity = 0
+	readSmall     = 0
+)
+
+type reader struct {
+	r      io.Reader // underlying input stream
+	stream *C.bz_stream
+	inbuf  [64 * 1024]byte
+	in     []byte // unconsumed part of inbuf
+	eof    bool   // r is exhausted
+	ended  bool   // the current bzip2 stream has ended
+	err    error  // from starting the decompressor; returned by every Read
+}
+
+// NewReader returns a reader that decompresses the bzip2 data from in.
+// Like the output of a parallel writer, the data may hold several
+// streams one after another, which are read as one.
+func NewReader(in io.Reader) io.ReadCloser {
+	r := &reader{r: in, stream: C.bz2alloc()}
+	r.err = r.init()
+	return r
+}
+
+// init prepares the decompressor for a new stream.
+func (r *reader) init() error {
+	if ret := C.BZ2_bzDecompressInit(r.stream, readVerbosity, readSmall); ret != C.BZ_OK {
+		return fmt.Errorf("bzip: BZ2_bzDecompressInit returned %d", ret)
+	}
+	return nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.stream == nil {
+		panic("closed")
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		if len(r.in) == 0 && !r.eof {
+			n, err := r.r.Read(r.inbuf[:])
+			r.in = r.inbuf[:n]
+			if err == io.EOF {
+				r.eof = true
+			} else if err != nil {
+				return 0, err
+			}
+		}
+		if r.ended {
+			// Start the next stream, if there is one.
+			if len(r.in) == 0 {
+				if r.eof {
+					return 0, io.EOF
+				}
+				continue
+			}
+			C.BZ2_bzDecompressEnd(r.stream)
+			if r.err = r.init(); r.err != nil {
+				return 0, r.err
+			}
+			r.ended = false
+		}
+
+		var in *C.char
+		if len(r.in) > 0 {
+			in = (*C.char)(unsafe.Pointer(&r.in[0]))
+		}
+		inlen, outlen := C.uint(len(r.in)), C.uint(len(p))
+		ret := C.bz2decompress(r.stream, in, &inlen,
+			(*C.char)(unsafe.Pointer(&p[0])), &outlen)
+		r.in = r.in[inlen:]
+		switch ret {
+		case C.BZ_OK:
+		case C.BZ_STREAM_END:
+			r.ended = true
+		case C.BZ_DATA_ERROR_MAGIC:
+			return int(outlen), fmt.Errorf("bzip: not bzip2 data")
+		case C.BZ_DATA_ERROR:
+			return int(outlen), fmt.Errorf("bzip: corrupt data")
+		default:
+			return int(outlen), fmt.Errorf("bzip: BZ2_bzDecompress returned %d", ret)
+		}
+		if outlen > 0 {
+			return int(outlen), nil
+		}
+		if !r.ended && inlen == 0 && len(r.in) == 0 && r.eof {
+			return 0, io.ErrUnexpectedEOF
+		}
+	}
+}
+
+// Close releases the decompressor.
+// It does not close the underlying io.Reader.
+func (r *reader) Close() error {
+	if r.stream == nil {
+		panic("closed")
+	}
+	C.BZ2_bzDecompressEnd(r.stream)
+	C.bz2free(r.stream)
+	r.stream = nil
+	return nil
+}
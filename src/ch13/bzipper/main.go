@@ -6,18 +6,33 @@
 //!+
 
 // Bzipper reads input, bzip2-compresses it, and writes it out.
+//
+// With -workers n, for n > 1, it compresses blocks of the input on n
+// goroutines at once, writing one stream per block.
 package main
 
 import (
+	"flag"
 	"io"
 	"log"
 	"os"
+	"runtime"
 
 	"gopl.io/ch13/bzip"
 )
 
+var workers = flag.Int("workers", 1, "number of blocks to compress in parallel (0 means one per CPU)")
+
 func main() {
+	flag.Parse()
+	n := *workers
+	if n == 0 {
+		n = runtime.NumCPU()
+	}
 	w := bzip.NewWriter(os.Stdout)
+	if n > 1 {
+		w = bzip.NewParallelWriter(os.Stdout, n)
+	}
 	if _, err := io.Copy(w, os.Stdin); err != nil {
 		log.Fatalf("bzipper: %v\n", err)
 	}
@@ -0,0 +1,522 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package equal
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// A Difference is one way in which two values differ.
+type Difference struct {
+	Path string // such as .Items[2].Name, or . for the values themselves
+	X, Y string // the differing values, or <absent>
+}
+
+func (d Difference) String() string {
+	return d.Path + ": " + d.X + " != " + d.Y
+}
+
+const absent = "<absent>"
+
+// An Option changes how Diff compares values.
+type Option func(*config)
+
+type config struct {
+	tolerance        float64
+	ignorePaths      [][]string
+	ignoreTags       [][2]string // key, value
+	ignoreUnexported bool
+	unordered        bool
+	distinguishNil   bool
+	comparers        map[reflect.Type]reflect.Value
+}
+
+// FloatTolerance makes floating-point numbers, and the parts of complex
+// numbers, equal if they differ by at most tol, either absolutely or
+// relative to the larger of the two.
+func FloatTolerance(tol float64) Option {
+	return func(c *config) { c.tolerance = tol }
+}
+
+// IgnorePath skips the values at the given paths, written as Diff
+// reports them. In a pattern, .* matches any field and [*] any element
+// or map key, as in .Items[*].ID. IgnorePath panics if a pattern is
+// malformed.
+func IgnorePath(patterns ...string) Option {
+	var parsed [][]string
+	for _, p := range patterns {
+		segs, err := splitPath(p)
+		if err != nil {
+			panic(fmt.Sprintf("equal.IgnorePath(%q): %v", p, err))
+		}
+		parsed = append(parsed, segs)
+	}
+	return func(c *config) { c.ignorePaths = append(c.ignorePaths, parsed...) }
+}
+
+// IgnoreTag skips struct fields whose tag for key is value, such as
+// IgnoreTag("json", "-") or IgnoreTag("equal", "ignore").
+func IgnoreTag(key, value string) Option {
+	return func(c *config) { c.ignoreTags = append(c.ignoreTags, [2]string{key, value}) }
+}
+
+// IgnoreUnexported skips unexported struct fields, which are otherwise
+// compared like the rest.
+func IgnoreUnexported() Option {
+	return func(c *config) { c.ignoreUnexported = true }
+}
+
+// Unordered compares slices and arrays as multisets: each element of one
+// must equal a distinct element of the other, in any order.
+func Unordered() Option {
+	return func(c *config) { c.unordered = true }
+}
+
+// DistinguishNil makes a nil slice or map differ from an empty one.
+func DistinguishNil() Option {
+	return func(c *config) { c.distinguishNil = true }
+}
+
+// Comparer compares values of type T with f, which must be a function
+// of type func(T, T) bool, in place of the usual rules. Comparer panics
+// if f has some other type.
+func Comparer(f interface{}) Option {
+	fv := reflect.ValueOf(f)
+	t := fv.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 2 || t.In(0) != t.In(1) ||
+		t.NumOut() != 1 || t.Out(0).Kind() != reflect.Bool {
+		panic(fmt.Sprintf("equal.Comparer: %s is not func(T, T) bool", t))
+	}
+	return func(c *config) {
+		if c.comparers == nil {
+			c.comparers = make(map[reflect.Type]reflect.Value)
+		}
+		c.comparers[t.In(0)] = fv
+	}
+}
+
+// Diff returns the differences between x and y, in the order of their
+// fields, elements and sorted map keys, or none if they are deeply
+// equal as Equal reports, subject to the options. Each cycle is
+// followed once, as by Equal.
+func Diff(x, y interface{}, opts ...Option) []Difference {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	d := &differ{config: &c, seen: make(map[comparison]bool)}
+	d.diff(addressable(x), addressable(y))
+	return d.diffs
+}
+
+// A differ holds the state of one call to Diff.
+type differ struct {
+	*config
+	seen  map[comparison]bool // pairs compared, or being compared
+	path  []string            // segments such as ".Name", "[2]", `["k"]`
+	diffs []Difference
+
+	trials int          // depth of calls to same
+	added  []comparison // pairs added to seen during a trial, to undo
+}
+
+func (d *differ) report(x, y string) {
+	path := strings.Join(d.path, "")
+	if !strings.HasPrefix(path, ".") {
+		path = "." + path // as in jq: . or .[1]
+	}
+	d.diffs = append(d.diffs, Difference{path, x, y})
+}
+
+func (d *differ) push(seg string) { d.path = append(d.path, seg) }
+func (d *differ) pop()            { d.path = d.path[:len(d.path)-1] }
+
+func (d *differ) diff(x, y reflect.Value) {
+	if d.ignored() {
+		return
+	}
+	if !x.IsValid() || !y.IsValid() {
+		if x.IsValid() != y.IsValid() {
+			d.report(describeTyped(x), describeTyped(y))
+		}
+		return
+	}
+	if x.Type() != y.Type() {
+		d.report(describeTyped(x), describeTyped(y))
+		return
+	}
+
+	// cycle check, as in equal
+	if x.CanAddr() && y.CanAddr() {
+		xptr := unsafe.Pointer(x.UnsafeAddr())
+		yptr := unsafe.Pointer(y.UnsafeAddr())
+		if xptr == yptr {
+			return // identical references
+		}
+		c := comparison{xptr, yptr, x.Type()}
+		if d.seen[c] {
+			return // already seen
+		}
+		d.seen[c] = true
+		if d.trials > 0 {
+			d.added = append(d.added, c)
+		}
+	}
+
+	if f, ok := d.comparers[x.Type()]; ok {
+		xi, yi := exposed(x), exposed(y)
+		if xi.IsValid() && yi.IsValid() {
+			if !f.Call([]reflect.Value{xi, yi})[0].Bool() {
+				d.report(describe(x), describe(y))
+			}
+			return
+		}
+	}
+
+	switch x.Kind() {
+	case reflect.Bool:
+		if x.Bool() != y.Bool() {
+			d.report(describe(x), describe(y))
+		}
+
+	case reflect.String:
+		if x.String() != y.String() {
+			d.report(describe(x), describe(y))
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		if x.Int() != y.Int() {
+			d.report(describe(x), describe(y))
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		if x.Uint() != y.Uint() {
+			d.report(describe(x), describe(y))
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if !d.close(x.Float(), y.Float()) {
+			d.report(describe(x), describe(y))
+		}
+
+	case reflect.Complex64, reflect.Complex128:
+		cx, cy := x.Complex(), y.Complex()
+		if !d.close(real(cx), real(cy)) || !d.close(imag(cx), imag(cy)) {
+			d.report(describe(x), describe(y))
+		}
+
+	case reflect.Chan, reflect.UnsafePointer, reflect.Func:
+		if x.Pointer() != y.Pointer() {
+			d.report(describe(x), describe(y))
+		}
+
+	case reflect.Ptr, reflect.Interface:
+		if x.IsNil() || y.IsNil() {
+			if x.IsNil() != y.IsNil() {
+				d.report(describe(x), describe(y))
+			}
+			return
+		}
+		d.diff(x.Elem(), y.Elem())
+
+	case reflect.Array, reflect.Slice:
+		if x.Kind() == reflect.Slice && d.distinguishNil && x.IsNil() != y.IsNil() {
+			d.report(describe(x), describe(y))
+			return
+		}
+		if d.unordered {
+			d.diffUnordered(x, y)
+			return
+		}
+		for i := 0; i < x.Len() || i < y.Len(); i++ {
+			d.push("[" + strconv.Itoa(i) + "]")
+			switch {
+			case i >= y.Len():
+				if !d.ignored() {
+					d.report(describe(x.Index(i)), absent)
+				}
+			case i >= x.Len():
+				if !d.ignored() {
+					d.report(absent, describe(y.Index(i)))
+				}
+			default:
+				d.diff(x.Index(i), y.Index(i))
+			}
+			d.pop()
+		}
+
+	case reflect.Struct:
+		t := x.Type()
+		for i, n := 0, x.NumField(); i < n; i++ {
+			if d.skipField(t.Field(i)) {
+				continue
+			}
+			d.push("." + t.Field(i).Name)
+			d.diff(x.Field(i), y.Field(i))
+			d.pop()
+		}
+
+	case reflect.Map:
+		if d.distinguishNil && x.IsNil() != y.IsNil() {
+			d.report(describe(x), describe(y))
+			return
+		}
+		for _, k := range unionKeys(x, y) {
+			d.push("[" + describe(k) + "]")
+			xv, yv := x.MapIndex(k), y.MapIndex(k)
+			switch {
+			case !yv.IsValid():
+				if !d.ignored() {
+					d.report(describe(xv), absent)
+				}
+			case !xv.IsValid():
+				if !d.ignored() {
+					d.report(absent, describe(yv))
+				}
+			default:
+				d.diff(xv, yv)
+			}
+			d.pop()
+		}
+	}
+}
+
+// diffUnordered matches each element of x with an equal element of y,
+// and reports those left over on either side.
+func (d *differ) diffUnordered(x, y reflect.Value) {
+	matched := make([]bool, y.Len())
+	var extra []int // unmatched elements of x
+	for i := 0; i < x.Len(); i++ {
+		d.push("[" + strconv.Itoa(i) + "]")
+		found := d.ignored()
+		for j := 0; j < y.Len() && !found; j++ {
+			if !matched[j] && d.same(x.Index(i), y.Index(j)) {
+				matched[j], found = true, true
+			}
+		}
+		d.pop()
+		if !found {
+			extra = append(extra, i)
+		}
+	}
+	for _, i := range extra {
+		d.push("[" + strconv.Itoa(i) + "]")
+		d.report(describe(x.Index(i)), absent)
+		d.pop()
+	}
+	for j, ok := range matched {
+		d.push("[" + strconv.Itoa(j) + "]")
+		if !ok && !d.ignored() {
+			d.report(absent, describe(y.Index(j)))
+		}
+		d.pop()
+	}
+}
+
+// same reports whether x and y have no differences, without recording
+// any. A failed trial must not leave its pairs in seen, so they are
+// logged in added and removed again.
+func (d *differ) same(x, y reflect.Value) bool {
+	n, mark := len(d.diffs), len(d.added)
+	d.trials++
+	d.diff(x, y)
+	d.trials--
+	ok := len(d.diffs) == n
+	d.diffs = d.diffs[:n]
+	if !ok {
+		for _, c := range d.added[mark:] {
+			delete(d.seen, c)
+		}
+		d.added = d.added[:mark]
+	}
+	if d.trials == 0 {
+		d.added = d.added[:0] // nothing left to undo
+	}
+	return ok
+}
+
+func (d *differ) close(x, y float64) bool {
+	if x == y {
+		return true
+	}
+	diff := math.Abs(x - y)
+	return diff <= d.tolerance ||
+		diff <= d.tolerance*math.Max(math.Abs(x), math.Abs(y))
+}
+
+func (d *differ) skipField(f reflect.StructField) bool {
+	if d.ignoreUnexported && f.PkgPath != "" {
+		return true
+	}
+	for _, kv := range d.ignoreTags {
+		if v, ok := f.Tag.Lookup(kv[0]); ok && v == kv[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// ignored reports whether the current path matches an IgnorePath pattern.
+func (d *differ) ignored() bool {
+outer:
+	for _, p := range d.ignorePaths {
+		if len(p) != len(d.path) {
+			continue
+		}
+		for i, seg := range p {
+			if seg != d.path[i] &&
+				!(seg == ".*" && d.path[i][0] == '.') &&
+				!(seg == "[*]" && d.path[i][0] == '[') {
+				continue outer
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// splitPath splits a path into its segments: .Field, [index] and
+// ["key"], in which the key may be quoted.
+func splitPath(path string) ([]string, error) {
+	if strings.HasPrefix(path, ".[") || path == "." {
+		path = path[1:]
+	}
+	var segs []string
+	for path != "" {
+		var n int
+		switch path[0] {
+		case '.':
+			n = 1 + strings.IndexAny(path[1:]+"[", ".[")
+		case '[':
+			n = strings.IndexByte(path, ']') + 1
+			if len(path) > 1 && path[1] == '"' {
+				q, err := strconv.QuotedPrefix(path[1:])
+				if err != nil {
+					return nil, err
+				}
+				n = 1 + len(q) + 1
+				if n > len(path) || path[n-1] != ']' {
+					return nil, fmt.Errorf("missing ] after %s", q)
+				}
+			}
+			if n <= 0 {
+				return nil, fmt.Errorf("missing ]")
+			}
+		default:
+			return nil, fmt.Errorf("segment %q does not begin with . or [", path)
+		}
+		if n == 1 {
+			return nil, fmt.Errorf("empty field name")
+		}
+		segs = append(segs, path[:n])
+		path = path[n:]
+	}
+	return segs, nil
+}
+
+// unionKeys returns the keys of maps x and y, sorted by description.
+func unionKeys(x, y reflect.Value) []reflect.Value {
+	keys := x.MapKeys()
+	for _, k := range y.MapKeys() {
+		if !x.MapIndex(k).IsValid() {
+			keys = append(keys, k)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return describe(keys[i]) < describe(keys[j])
+	})
+	return keys
+}
+
+// addressable returns a Value for a copy of x whose fields can be
+// exposed to comparers.
+func addressable(x interface{}) reflect.Value {
+	if x == nil {
+		return reflect.Value{}
+	}
+	v := reflect.New(reflect.TypeOf(x)).Elem()
+	v.Set(reflect.ValueOf(x))
+	return v
+}
+
+// exposed returns v, or a copy of v that may be passed to a comparer
+// even though it was reached through an unexported field, or an
+// invalid value if there is no such copy.
+func exposed(v reflect.Value) reflect.Value {
+	if v.CanInterface() {
+		return v
+	}
+	if v.CanAddr() {
+		return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+	}
+	return reflect.Value{}
+}
+
+// describe formats v for a Difference, like formatAtom in
+// gopl.io/ch12/display but showing the elements of short composites.
+func describe(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return "nil"
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16,
+		reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	case reflect.Complex64, reflect.Complex128:
+		return strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits())
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return "nil"
+		}
+		if v.Kind() == reflect.Interface {
+			return describeTyped(v.Elem())
+		}
+		if k := v.Elem().Kind(); k == reflect.Ptr || k == reflect.Interface {
+			break // may be a cycle
+		}
+		return "&" + describe(v.Elem())
+	case reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			return v.Type().String() + "(nil)"
+		}
+		fallthrough
+	case reflect.Array:
+		if v.Len() == 0 {
+			return v.Type().String() + "{}"
+		}
+		return fmt.Sprintf("%s (len %d)", v.Type(), v.Len())
+	case reflect.Struct:
+		return v.Type().String() + "{...}"
+	}
+	// chan, func, unsafe.Pointer, or a pointer to a pointer
+	return v.Type().String() + " 0x" + strconv.FormatUint(uint64(v.Pointer()), 16)
+}
+
+// describeTyped is like describe, but shows the type of a basic value.
+func describeTyped(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return v.Type().String() + "(" + describe(v) + ")"
+	}
+	return describe(v)
+}
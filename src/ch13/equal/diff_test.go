@@ -0,0 +1,170 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package equal
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+type item struct {
+	ID    int
+	Name  string
+	Price float64
+	Tags  []string
+	Attrs map[string]interface{}
+	Next  *item
+	note  string
+	Etag  string `json:"-"`
+}
+
+func TestDiff(t *testing.T) {
+	a := item{ID: 1, Name: "pen", Price: 1.5, Tags: []string{"red", "cheap"},
+		Attrs: map[string]interface{}{"ink": "blue", "len": 14}, note: "x"}
+	b := a
+	b.Tags = []string{"red", "cheap"}
+	b.Attrs = map[string]interface{}{"ink": "blue", "len": 14}
+
+	// A ring of one item and a ring of two, all with the same name.
+	r1, r2 := &item{Name: "r"}, &item{Name: "r"}
+	r1.Next, r2.Next = r1, &item{Name: "r", Next: r2}
+
+	for _, test := range []struct {
+		x, y interface{}
+		opts []Option
+		want string
+	}{
+		{a, b, nil, ""},
+		{1, 2, nil, ".: 1 != 2"},
+		{1, "1", nil, `.: int(1) != string("1")`},
+		{nil, 1, nil, ".: nil != int(1)"},
+		{a, item{ID: 2, Name: "pen", Price: 1.5000001, Tags: []string{"red"},
+			Attrs: map[string]interface{}{"ink": "black", "cap": true}, note: "y"},
+			nil,
+			`.ID: 1 != 2
+.Price: 1.5 != 1.5000001
+.Tags[1]: "cheap" != <absent>
+.Attrs["cap"]: <absent> != bool(true)
+.Attrs["ink"]: "blue" != "black"
+.Attrs["len"]: int(14) != <absent>
+.note: "x" != "y"`},
+		{[]int{1}, []int{1, 2}, nil, ".[1]: <absent> != 2"},
+		{&a, &item{}, []Option{IgnorePath(".Tags", ".Attrs", ".Name", ".note", ".Price")},
+			".ID: 1 != 0"},
+		{[]item{a, a}, []item{{ID: 1}, {ID: 1}},
+			[]Option{IgnorePath("[*].Tags", "[*].Attrs", "[*].*")},
+			""},
+		{map[string][]int{"a": {1, 2}}, map[string][]int{"a": {1, 3}},
+			[]Option{IgnorePath(`["a"][1]`)}, ""},
+		{1.0, 1.0 + 1e-12, []Option{FloatTolerance(1e-9)}, ""},
+		{1e20, 1e20 + 1e6, []Option{FloatTolerance(1e-9)}, ""},
+		{complex(1, 2), complex(1, 2.1), []Option{FloatTolerance(1e-9)}, ".: (1+2i) != (1+2.1i)"},
+		{math.NaN(), math.NaN(), nil, ".: NaN != NaN"},
+		{item{note: "a", Etag: "1"}, item{note: "b", Etag: "2"},
+			[]Option{IgnoreUnexported(), IgnoreTag("json", "-")}, ""},
+		{[]string{"a", "b", "b", "c"}, []string{"b", "c", "a", "d"}, []Option{Unordered()},
+			`.[2]: "b" != <absent>
+.[3]: <absent> != "d"`},
+		{[][]int{{1, 2}, {3}}, [][]int{{3}, {2, 1}}, []Option{Unordered()}, ""},
+		{[]int(nil), []int{}, nil, ""},
+		{[]int(nil), []int{}, []Option{DistinguishNil()}, ".: []int(nil) != []int{}"},
+		{map[int]bool{}, map[int]bool(nil), []Option{DistinguishNil()}, ".: map[int]bool{} != map[int]bool(nil)"},
+		{[]string{"Go", "go"}, []string{"GO", "Rust"},
+			[]Option{Comparer(strings.EqualFold)}, `.[1]: "go" != "Rust"`},
+		{item{note: "A"}, item{note: "a"}, []Option{Comparer(strings.EqualFold)}, ""},
+		{time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2016, 1, 1, 1, 0, 0, 0, time.FixedZone("", 3600)),
+			[]Option{Comparer(time.Time.Equal)}, ""},
+		{r1, r2, nil, ""},
+		{r1, r2, []Option{Comparer(func(x, y *item) bool { return x == y })}, ".: &equal.item{...} != &equal.item{...}"},
+	} {
+		var got []string
+		for _, d := range Diff(test.x, test.y, test.opts...) {
+			got = append(got, d.String())
+		}
+		if s := strings.Join(got, "\n"); s != test.want {
+			t.Errorf("Diff(%v, %v) =\n%s\nwant\n%s", test.x, test.y, s, test.want)
+		}
+		if len(test.opts) == 0 && Equal(test.x, test.y) != (test.want == "") {
+			t.Errorf("Equal(%v, %v) disagrees with Diff", test.x, test.y)
+		}
+	}
+}
+
+func TestDiffCycle(t *testing.T) {
+	type CycleSlice []CycleSlice
+	var s1, s2 CycleSlice
+	s1 = append(s1, s1)
+	s2 = append(s2, s2, nil)
+	got := Diff(s1, s2)
+	if len(got) != 1 || got[0].String() != ".[1]: <absent> != equal.CycleSlice(nil)" {
+		t.Errorf("Diff of cyclic slices = %v", got)
+	}
+
+	// a -> b -> a and c -> d -> c, differing in one value each time round.
+	type link struct {
+		value int
+		tail  *link
+	}
+	a, b, c, d := &link{value: 1}, &link{value: 2}, &link{value: 1}, &link{value: 3}
+	a.tail, b.tail, c.tail, d.tail = b, a, d, c
+	got = Diff(a, c)
+	if len(got) != 1 || got[0].String() != ".tail.value: 2 != 3" {
+		t.Errorf("Diff of cyclic lists = %v", got)
+	}
+}
+
+func BenchmarkDiffUnordered(b *testing.B) {
+	// Each element of x matches the element of y at the mirror index,
+	// so matching tries half of y on average, and every comparison of
+	// an item adds to seen.
+	const n = 1000
+	x, y := make([]*item, n), make([]*item, n)
+	for i := range x {
+		x[i] = &item{ID: i, Name: "item", Tags: []string{"a", "b"}}
+		y[n-1-i] = &item{ID: i, Name: "item", Tags: []string{"a", "b"}}
+	}
+	for b.Loop() {
+		if diffs := Diff(x, y, Unordered()); len(diffs) > 0 {
+			b.Fatal(diffs)
+		}
+	}
+}
+
+func TestBadOptions(t *testing.T) {
+	for _, f := range []func(){
+		func() { IgnorePath("Name") },
+		func() { IgnorePath(".a[1") },
+		func() { IgnorePath(`["a]`) },
+		func() { Comparer(func(int, string) bool { return false }) },
+		func() { Comparer(42) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("no panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func ExampleDiff() {
+	type Point struct{ X, Y float64 }
+	type Shape struct {
+		Name   string
+		Points []Point
+	}
+	x := Shape{"triangle", []Point{{0, 0}, {1, 0}, {0, 1}}}
+	y := Shape{"Triangle", []Point{{0, 0}, {1, 1e-12}, {0, 2}}}
+	for _, d := range Diff(x, y, FloatTolerance(1e-9)) {
+		fmt.Println(d)
+	}
+	// Output:
+	// .Name: "triangle" != "Triangle"
+	// .Points[2].Y: 1 != 2
+}
@@ -0,0 +1,506 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package intset
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// A container holds the low 16 bits of the elements of a Sparse that
+// share their high bits. It is never empty.
+//
+// There are three kinds, and each operation returns the kind best
+// suited to its result:
+//
+//	array	a sorted list, for at most arrayMax elements
+//	bitmap	a bit vector of all 1<<16 values, for more
+//	run	a sorted list of intervals, for long runs of values
+//
+// Run containers come only from Optimize, AddRange, and operations on
+// other run containers.
+type container interface {
+	has(x uint16) bool
+	add(x uint16) container
+	remove(x uint16) container // may return nil
+	card() int
+	next(x uint16) (uint16, bool) // the least element ≥ x
+	rank(x uint16) int            // the number of elements ≤ x
+	selectAt(i int) uint16        // the element of rank i+1
+	each(f func(x uint16))
+	bitmap() *bitmap // a new bitmap with the same elements
+	clone() container
+}
+
+const (
+	arrayMax = 4096 // an array this long is as large as a bitmap
+	runMax   = 2048 // likewise for a run container
+)
+
+//-- array --
+
+type array []uint16
+
+func (a array) search(x uint16) int {
+	return sort.Search(len(a), func(i int) bool { return a[i] >= x })
+}
+
+func (a array) has(x uint16) bool {
+	i := a.search(x)
+	return i < len(a) && a[i] == x
+}
+
+func (a array) add(x uint16) container {
+	i := a.search(x)
+	if i < len(a) && a[i] == x {
+		return a
+	}
+	if len(a) == arrayMax {
+		b := a.bitmap()
+		b.add(x)
+		return b
+	}
+	a = append(a, 0)
+	copy(a[i+1:], a[i:])
+	a[i] = x
+	return a
+}
+
+func (a array) remove(x uint16) container {
+	i := a.search(x)
+	if i == len(a) || a[i] != x {
+		return a
+	}
+	if len(a) == 1 {
+		return nil
+	}
+	return append(a[:i], a[i+1:]...)
+}
+
+func (a array) card() int { return len(a) }
+
+func (a array) next(x uint16) (uint16, bool) {
+	if i := a.search(x); i < len(a) {
+		return a[i], true
+	}
+	return 0, false
+}
+
+func (a array) rank(x uint16) int {
+	return sort.Search(len(a), func(i int) bool { return a[i] > x })
+}
+
+func (a array) selectAt(i int) uint16 { return a[i] }
+
+func (a array) each(f func(x uint16)) {
+	for _, x := range a {
+		f(x)
+	}
+}
+
+func (a array) bitmap() *bitmap {
+	b := &bitmap{n: len(a)}
+	for _, x := range a {
+		b.words[x/64] |= 1 << (x % 64)
+	}
+	return b
+}
+
+func (a array) clone() container { return append(array(nil), a...) }
+
+//-- bitmap --
+
+type bitmap struct {
+	words [1 << 16 / 64]uint64
+	n     int // the number of elements
+}
+
+func (b *bitmap) has(x uint16) bool {
+	return b.words[x/64]&(1<<(x%64)) != 0
+}
+
+func (b *bitmap) add(x uint16) container {
+	if !b.has(x) {
+		b.words[x/64] |= 1 << (x % 64)
+		b.n++
+	}
+	return b
+}
+
+func (b *bitmap) remove(x uint16) container {
+	if !b.has(x) {
+		return b
+	}
+	b.words[x/64] &^= 1 << (x % 64)
+	b.n--
+	if b.n <= arrayMax {
+		return b.array()
+	}
+	return b
+}
+
+func (b *bitmap) card() int { return b.n }
+
+func (b *bitmap) next(x uint16) (uint16, bool) {
+	i := int(x / 64)
+	word := b.words[i] &^ (1<<(x%64) - 1)
+	for {
+		if word != 0 {
+			return uint16(64*i + bits.TrailingZeros64(word)), true
+		}
+		if i++; i == len(b.words) {
+			return 0, false
+		}
+		word = b.words[i]
+	}
+}
+
+func (b *bitmap) rank(x uint16) int {
+	n := 0
+	for _, word := range b.words[:x/64] {
+		n += bits.OnesCount64(word)
+	}
+	mask := uint64(1)<<(x%64+1) - 1 // all ones if x%64 == 63
+	return n + bits.OnesCount64(b.words[x/64]&mask)
+}
+
+func (b *bitmap) selectAt(i int) uint16 {
+	for w, word := range b.words {
+		if c := bits.OnesCount64(word); i >= c {
+			i -= c
+			continue
+		}
+		for ; i > 0; i-- {
+			word &= word - 1
+		}
+		return uint16(64*w + bits.TrailingZeros64(word))
+	}
+	panic("intset: select out of range")
+}
+
+func (b *bitmap) each(f func(x uint16)) {
+	for w, word := range b.words {
+		for word != 0 {
+			f(uint16(64*w + bits.TrailingZeros64(word)))
+			word &= word - 1
+		}
+	}
+}
+
+func (b *bitmap) bitmap() *bitmap {
+	c := *b
+	return &c
+}
+
+func (b *bitmap) clone() container { return b.bitmap() }
+
+// array returns the elements of b as an array.
+func (b *bitmap) array() array {
+	a := make(array, 0, b.n)
+	b.each(func(x uint16) { a = append(a, x) })
+	return a
+}
+
+// count recomputes b.n after its words have been changed directly.
+func (b *bitmap) count() {
+	b.n = 0
+	for _, word := range b.words {
+		b.n += bits.OnesCount64(word)
+	}
+}
+
+//-- run --
+
+// An interval holds the values start through last. The intervals of a
+// run container neither overlap nor touch.
+type interval struct{ start, last uint16 }
+
+type run []interval
+
+// search returns the index of the first interval ending at or after x.
+func (r run) search(x uint16) int {
+	return sort.Search(len(r), func(i int) bool { return r[i].last >= x })
+}
+
+func (r run) has(x uint16) bool {
+	i := r.search(x)
+	return i < len(r) && r[i].start <= x
+}
+
+func (r run) add(x uint16) container {
+	i := r.search(x)
+	if i < len(r) && r[i].start <= x {
+		return r
+	}
+	joinPrev := i > 0 && r[i-1].last+1 == x
+	joinNext := i < len(r) && x+1 == r[i].start
+	switch {
+	case joinPrev && joinNext:
+		r[i-1].last = r[i].last
+		r = append(r[:i], r[i+1:]...)
+	case joinPrev:
+		r[i-1].last = x
+	case joinNext:
+		r[i].start = x
+	default:
+		r = append(r, interval{})
+		copy(r[i+1:], r[i:])
+		r[i] = interval{x, x}
+	}
+	return r.shrink()
+}
+
+func (r run) remove(x uint16) container {
+	i := r.search(x)
+	if i == len(r) || r[i].start > x {
+		return r
+	}
+	switch iv := r[i]; {
+	case iv.start == iv.last:
+		if len(r) == 1 {
+			return nil
+		}
+		r = append(r[:i], r[i+1:]...)
+	case x == iv.start:
+		r[i].start++
+	case x == iv.last:
+		r[i].last--
+	default:
+		r = append(r, interval{})
+		copy(r[i+1:], r[i:])
+		r[i].last = x - 1
+		r[i+1].start = x + 1
+	}
+	return r.shrink()
+}
+
+// shrink returns r, or the same elements in a smaller container if
+// r has grown too many intervals.
+func (r run) shrink() container {
+	if len(r) > runMax {
+		return best(r)
+	}
+	return r
+}
+
+func (r run) card() int {
+	n := 0
+	for _, iv := range r {
+		n += int(iv.last-iv.start) + 1
+	}
+	return n
+}
+
+func (r run) next(x uint16) (uint16, bool) {
+	i := r.search(x)
+	if i == len(r) {
+		return 0, false
+	}
+	if r[i].start > x {
+		return r[i].start, true
+	}
+	return x, true
+}
+
+func (r run) rank(x uint16) int {
+	n := 0
+	for _, iv := range r {
+		if iv.start > x {
+			break
+		}
+		if iv.last >= x {
+			return n + int(x-iv.start) + 1
+		}
+		n += int(iv.last-iv.start) + 1
+	}
+	return n
+}
+
+func (r run) selectAt(i int) uint16 {
+	for _, iv := range r {
+		if n := int(iv.last-iv.start) + 1; i >= n {
+			i -= n
+			continue
+		}
+		return iv.start + uint16(i)
+	}
+	panic("intset: select out of range")
+}
+
+func (r run) each(f func(x uint16)) {
+	for _, iv := range r {
+		for x := int(iv.start); x <= int(iv.last); x++ {
+			f(uint16(x))
+		}
+	}
+}
+
+func (r run) bitmap() *bitmap {
+	b := new(bitmap)
+	r.each(func(x uint16) { b.words[x/64] |= 1 << (x % 64) })
+	b.n = r.card()
+	return b
+}
+
+func (r run) clone() container { return append(run(nil), r...) }
+
+//-- conversions --
+
+// best returns the smallest container holding the elements of c,
+// or nil if c is empty.
+func best(c container) container {
+	n := c.card()
+	if n == 0 {
+		return nil
+	}
+	var intervals run
+	if r, ok := c.(run); ok {
+		intervals = r
+	} else {
+		c.each(func(x uint16) {
+			if k := len(intervals); k > 0 && intervals[k-1].last+1 == x {
+				intervals[k-1].last = x
+			} else {
+				intervals = append(intervals, interval{x, x})
+			}
+		})
+	}
+	size := 8 * len(bitmap{}.words)
+	if n <= arrayMax {
+		size = 2 * n
+	}
+	if 4*len(intervals) < size {
+		return intervals
+	}
+	switch c := c.(type) {
+	case array:
+		return c
+	case *bitmap:
+		return fromBitmap(c)
+	}
+	return fromBitmap(c.bitmap())
+}
+
+// fromBitmap returns b, or its elements as an array if there are
+// few enough, or nil if there are none.
+func fromBitmap(b *bitmap) container {
+	switch {
+	case b.n == 0:
+		return nil
+	case b.n <= arrayMax:
+		return b.array()
+	}
+	return b
+}
+
+//-- set algebra --
+
+// These operations return a new container, or nil if the result is
+// empty. Neither operand is changed.
+
+func union(a, b container) container {
+	if x, ok := a.(array); ok {
+		if y, ok := b.(array); ok {
+			m := merge(x, y, func(inX, inY bool) bool { return true })
+			if len(m) > arrayMax {
+				return m.bitmap()
+			}
+			return m
+		}
+	}
+	return combine(a, b, func(x, y uint64) uint64 { return x | y })
+}
+
+func intersect(a, b container) container {
+	if x, ok := a.(array); ok {
+		return filter(x, b, true)
+	}
+	if y, ok := b.(array); ok {
+		return filter(y, a, true)
+	}
+	return combine(a, b, func(x, y uint64) uint64 { return x & y })
+}
+
+func difference(a, b container) container {
+	if x, ok := a.(array); ok {
+		return filter(x, b, false)
+	}
+	return combine(a, b, func(x, y uint64) uint64 { return x &^ y })
+}
+
+func xor(a, b container) container {
+	if x, ok := a.(array); ok {
+		if y, ok := b.(array); ok {
+			m := merge(x, y, func(inX, inY bool) bool { return inX != inY })
+			switch {
+			case len(m) == 0:
+				return nil
+			case len(m) > arrayMax:
+				return m.bitmap()
+			}
+			return m
+		}
+	}
+	return combine(a, b, func(x, y uint64) uint64 { return x ^ y })
+}
+
+// merge returns the elements of x and y for which keep returns true.
+func merge(x, y array, keep func(inX, inY bool) bool) array {
+	var m array
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case j == len(y) || i < len(x) && x[i] < y[j]:
+			if keep(true, false) {
+				m = append(m, x[i])
+			}
+			i++
+		case i == len(x) || y[j] < x[i]:
+			if keep(false, true) {
+				m = append(m, y[j])
+			}
+			j++
+		default:
+			if keep(true, true) {
+				m = append(m, x[i])
+			}
+			i++
+			j++
+		}
+	}
+	return m
+}
+
+// filter returns the elements of x that are (or, if !in, are not) in c.
+func filter(x array, c container, in bool) container {
+	var m array
+	for _, v := range x {
+		if c.has(v) == in {
+			m = append(m, v)
+		}
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// combine applies op to the words of bitmaps of a and b.
+func combine(a, b container, op func(x, y uint64) uint64) container {
+	r := a.bitmap()
+	y, ok := b.(*bitmap)
+	if !ok {
+		y = b.bitmap()
+	}
+	for i := range r.words {
+		r.words[i] = op(r.words[i], y.words[i])
+	}
+	r.count()
+	_, runA := a.(run)
+	_, runB := b.(run)
+	if runA || runB {
+		return best(r)
+	}
+	return fromBitmap(r)
+}
@@ -3,12 +3,15 @@
 
 // See page 165.
 
-// Package intset provides a set of integers based on a bit vector.
+// Package intset provides sets of integers: IntSet, based on a bit
+// vector, and Sparse, a compressed set in the manner of a roaring
+// bitmap, for large or scattered values.
 package intset
 
 import (
 	"bytes"
 	"fmt"
+	"math/bits"
 )
 
 //!+intset
@@ -71,3 +74,78 @@ func (s *IntSet) String() string {
 }
 
 //!-string
+
+// Remove removes x from the set.
+func (s *IntSet) Remove(x int) {
+	word, bit := x/64, uint(x%64)
+	if word < len(s.words) {
+		s.words[word] &^= 1 << bit
+	}
+}
+
+// AddAll adds each of xs to the set.
+func (s *IntSet) AddAll(xs ...int) {
+	for _, x := range xs {
+		s.Add(x)
+	}
+}
+
+// Len returns the number of elements.
+func (s *IntSet) Len() int {
+	n := 0
+	for _, word := range s.words {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// Clear removes all elements from the set.
+func (s *IntSet) Clear() { s.words = nil }
+
+// Copy returns a copy of the set.
+func (s *IntSet) Copy() *IntSet {
+	return &IntSet{append([]uint64(nil), s.words...)}
+}
+
+// IntersectWith sets s to the intersection of s and t.
+func (s *IntSet) IntersectWith(t *IntSet) {
+	for i := range s.words {
+		if i < len(t.words) {
+			s.words[i] &= t.words[i]
+		} else {
+			s.words[i] = 0
+		}
+	}
+}
+
+// DifferenceWith sets s to the elements of s not in t.
+func (s *IntSet) DifferenceWith(t *IntSet) {
+	for i := range s.words {
+		if i < len(t.words) {
+			s.words[i] &^= t.words[i]
+		}
+	}
+}
+
+// SymmetricDifference sets s to the elements in s or t but not both.
+func (s *IntSet) SymmetricDifference(t *IntSet) {
+	for i, tword := range t.words {
+		if i < len(s.words) {
+			s.words[i] ^= tword
+		} else {
+			s.words = append(s.words, tword)
+		}
+	}
+}
+
+// Elems returns the elements of the set in increasing order.
+func (s *IntSet) Elems() []int {
+	var elems []int
+	for i, word := range s.words {
+		for word != 0 {
+			elems = append(elems, 64*i+bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+	return elems
+}
@@ -0,0 +1,198 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package intset
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// The binary form of a Sparse is a version byte and the number of
+// groups, then for each group in increasing order its key, a byte
+// giving the kind of its container, and the container:
+//
+//	array	the number of elements, then each as 2 bytes
+//	bitmap	1024 words of 8 bytes
+//	run	the number of intervals, then the start and last of each
+//		as 2 bytes apiece
+//
+// Counts and keys are uvarints; the rest is little-endian.
+const version = 1
+
+const (
+	kindArray = iota
+	kindBitmap
+	kindRun
+)
+
+var errShort = errors.New("intset: binary data too short")
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (s *Sparse) MarshalBinary() ([]byte, error) {
+	data := []byte{version}
+	data = binary.AppendUvarint(data, uint64(len(s.keys)))
+	for i, c := range s.cs {
+		data = binary.AppendUvarint(data, s.keys[i])
+		switch c := c.(type) {
+		case array:
+			data = append(data, kindArray)
+			data = binary.AppendUvarint(data, uint64(len(c)))
+			for _, x := range c {
+				data = binary.LittleEndian.AppendUint16(data, x)
+			}
+		case *bitmap:
+			data = append(data, kindBitmap)
+			for _, word := range c.words {
+				data = binary.LittleEndian.AppendUint64(data, word)
+			}
+		case run:
+			data = append(data, kindRun)
+			data = binary.AppendUvarint(data, uint64(len(c)))
+			for _, iv := range c {
+				data = binary.LittleEndian.AppendUint16(data, iv.start)
+				data = binary.LittleEndian.AppendUint16(data, iv.last)
+			}
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+// It replaces the contents of s.
+func (s *Sparse) UnmarshalBinary(data []byte) error {
+	d := decoder{data: data}
+	if v := d.byte(); d.err == nil && v != version {
+		return fmt.Errorf("intset: unknown version %d", v)
+	}
+	n := d.count(len(data)) // each group takes at least one byte
+	var t Sparse
+	for i := 0; i < n && d.err == nil; i++ {
+		key := d.uvarint()
+		if key >= 1<<48 || i > 0 && key <= t.keys[i-1] {
+			d.fail("bad group key")
+		}
+		var c container
+		switch kind := d.byte(); kind {
+		case kindArray:
+			a := make(array, d.count(arrayMax))
+			for j := range a {
+				a[j] = d.uint16()
+				if j > 0 && a[j] <= a[j-1] {
+					d.fail("array out of order")
+				}
+			}
+			if len(a) == 0 {
+				d.fail("empty array")
+			}
+			c = a
+		case kindBitmap:
+			b := new(bitmap)
+			for j := range b.words {
+				b.words[j] = d.uint64()
+			}
+			b.count()
+			if b.n <= arrayMax {
+				d.fail("bitmap too small")
+			}
+			c = b
+		case kindRun:
+			r := make(run, d.count(runMax))
+			for j := range r {
+				r[j] = interval{d.uint16(), d.uint16()}
+				if r[j].start > r[j].last || j > 0 && int(r[j].start) <= int(r[j-1].last)+1 {
+					d.fail("bad interval")
+				}
+			}
+			if len(r) == 0 {
+				d.fail("empty run")
+			}
+			c = r
+		default:
+			d.fail(fmt.Sprintf("unknown container kind %d", kind))
+		}
+		t.keys = append(t.keys, key)
+		t.cs = append(t.cs, c)
+	}
+	if d.err == nil && len(d.data) > 0 {
+		d.fail("trailing data")
+	}
+	if d.err != nil {
+		return d.err
+	}
+	*s = t
+	return nil
+}
+
+// A decoder reads the binary form, recording the first error.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) fail(msg string) {
+	if d.err == nil {
+		d.err = errors.New("intset: " + msg)
+	}
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.data) < n {
+		d.err = errShort
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *decoder) byte() byte {
+	if b := d.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) uint16() uint16 {
+	if b := d.take(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if b := d.take(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(d.data)
+	if n == 0 {
+		d.err = errShort
+		return 0
+	} else if n < 0 {
+		d.fail("uvarint overflows")
+		return 0
+	}
+	d.data = d.data[n:]
+	return x
+}
+
+// count reads a count, which must not exceed max.
+func (d *decoder) count(max int) int {
+	x := d.uvarint()
+	if x > uint64(max) {
+		d.fail(fmt.Sprintf("count %d too large", x))
+		return 0
+	}
+	return int(x)
+}
@@ -0,0 +1,25 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package intset
+
+// A Set is a set of integers. IntSet holds only small non-negative
+// ones; Sparse holds any.
+//
+// The set operations such as UnionWith are methods of each type, as
+// they combine two sets of the same type.
+type Set interface {
+	Has(x int) bool
+	Add(x int)
+	AddAll(xs ...int)
+	Remove(x int)
+	Len() int
+	Clear()
+	Elems() []int // in increasing order
+	String() string
+}
+
+var (
+	_ Set = (*IntSet)(nil)
+	_ Set = (*Sparse)(nil)
+)
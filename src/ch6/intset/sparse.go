@@ -0,0 +1,324 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package intset
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// A Sparse is a set of integers of any size and sign, stored in the
+// manner of a roaring bitmap: the elements are grouped by their high
+// bits, and the low 16 bits of each group are held in whichever
+// container is smallest for them. Its memory use is proportional to
+// the number of elements, or less when they are clustered.
+// Its zero value represents the empty set.
+type Sparse struct {
+	keys []uint64    // the high bits of each group, in increasing order
+	cs   []container // the low bits
+}
+
+// split returns the key and low bits of x. Flipping the sign bit maps
+// the ints in order onto the uint64s, so negative numbers come first.
+func split(x int) (key uint64, low uint16) {
+	u := uint64(x) ^ 1<<63
+	return u >> 16, uint16(u)
+}
+
+func join(key uint64, low uint16) int {
+	return int((key<<16 | uint64(low)) ^ 1<<63)
+}
+
+// find returns the index of key in s.keys, or where it would go.
+func (s *Sparse) find(key uint64) (int, bool) {
+	i := sort.Search(len(s.keys), func(i int) bool { return s.keys[i] >= key })
+	return i, i < len(s.keys) && s.keys[i] == key
+}
+
+// Has reports whether the set contains x.
+func (s *Sparse) Has(x int) bool {
+	key, low := split(x)
+	i, ok := s.find(key)
+	return ok && s.cs[i].has(low)
+}
+
+// Add adds x to the set.
+func (s *Sparse) Add(x int) {
+	key, low := split(x)
+	i, ok := s.find(key)
+	if ok {
+		s.cs[i] = s.cs[i].add(low)
+		return
+	}
+	s.insert(i, key, array{low})
+}
+
+// AddAll adds each of xs to the set. It is quicker than calling
+// Add for each when they fall in many groups not yet in the set.
+func (s *Sparse) AddAll(xs ...int) {
+	sorted := append([]int(nil), xs...)
+	sort.Ints(sorted)
+	var t Sparse
+	for _, x := range sorted {
+		key, low := split(x)
+		if n := len(t.keys); n > 0 && t.keys[n-1] == key {
+			t.cs[n-1] = t.cs[n-1].add(low)
+		} else {
+			t.keys = append(t.keys, key)
+			t.cs = append(t.cs, array{low})
+		}
+	}
+	if len(s.keys) == 0 {
+		*s = t
+		return
+	}
+	s.UnionWith(&t)
+}
+
+// AddRange adds lo, lo+1, ..., hi-1 to the set.
+func (s *Sparse) AddRange(lo, hi int) {
+	for lo < hi {
+		key, low := split(lo)
+		last := uint16(0xffff)
+		if n := uint64(hi) - uint64(lo); n <= uint64(last-low) {
+			last = low + uint16(n-1)
+		}
+		var r container = run{{low, last}}
+		i, ok := s.find(key)
+		if ok {
+			s.cs[i] = union(s.cs[i], r)
+		} else {
+			s.insert(i, key, best(r))
+		}
+		next := lo + int(last-low) + 1
+		if next < lo {
+			break // overflow
+		}
+		lo = next
+	}
+}
+
+func (s *Sparse) insert(i int, key uint64, c container) {
+	s.keys = append(s.keys, 0)
+	copy(s.keys[i+1:], s.keys[i:])
+	s.keys[i] = key
+	s.cs = append(s.cs, nil)
+	copy(s.cs[i+1:], s.cs[i:])
+	s.cs[i] = c
+}
+
+func (s *Sparse) delete(i int) {
+	s.keys = append(s.keys[:i], s.keys[i+1:]...)
+	s.cs = append(s.cs[:i], s.cs[i+1:]...)
+}
+
+// Remove removes x from the set.
+func (s *Sparse) Remove(x int) {
+	key, low := split(x)
+	i, ok := s.find(key)
+	if !ok {
+		return
+	}
+	if s.cs[i] = s.cs[i].remove(low); s.cs[i] == nil {
+		s.delete(i)
+	}
+}
+
+// Len returns the number of elements.
+func (s *Sparse) Len() int {
+	n := 0
+	for _, c := range s.cs {
+		n += c.card()
+	}
+	return n
+}
+
+// Clear removes all elements from the set.
+func (s *Sparse) Clear() {
+	s.keys, s.cs = nil, nil
+}
+
+// Copy returns a copy of the set.
+func (s *Sparse) Copy() *Sparse {
+	t := &Sparse{
+		keys: append([]uint64(nil), s.keys...),
+		cs:   make([]container, len(s.cs)),
+	}
+	for i, c := range s.cs {
+		t.cs[i] = c.clone()
+	}
+	return t
+}
+
+// Optimize converts each group to its smallest container, which is
+// worthwhile for sets with long runs of consecutive elements.
+func (s *Sparse) Optimize() {
+	for i, c := range s.cs {
+		s.cs[i] = best(c)
+	}
+}
+
+// UnionWith sets s to the union of s and t.
+func (s *Sparse) UnionWith(t *Sparse) {
+	s.combine(t, union, true, true)
+}
+
+// IntersectWith sets s to the intersection of s and t.
+func (s *Sparse) IntersectWith(t *Sparse) {
+	s.combine(t, intersect, false, false)
+}
+
+// DifferenceWith sets s to the elements of s not in t.
+func (s *Sparse) DifferenceWith(t *Sparse) {
+	s.combine(t, difference, true, false)
+}
+
+// SymmetricDifference sets s to the elements in s or t but not both.
+func (s *Sparse) SymmetricDifference(t *Sparse) {
+	s.combine(t, xor, true, true)
+}
+
+// combine sets s to the result of applying op to each pair of groups
+// with the same key. The groups found only in s or only in t are kept
+// if onlyS or onlyT is true.
+func (s *Sparse) combine(t *Sparse, op func(a, b container) container, onlyS, onlyT bool) {
+	var keys []uint64
+	var cs []container
+	i, j := 0, 0
+	for i < len(s.keys) || j < len(t.keys) {
+		var key uint64
+		var c container
+		switch {
+		case j == len(t.keys) || i < len(s.keys) && s.keys[i] < t.keys[j]:
+			key = s.keys[i]
+			if onlyS {
+				c = s.cs[i]
+			}
+			i++
+		case i == len(s.keys) || t.keys[j] < s.keys[i]:
+			key = t.keys[j]
+			if onlyT {
+				c = t.cs[j].clone()
+			}
+			j++
+		default:
+			key = s.keys[i]
+			c = op(s.cs[i], t.cs[j])
+			i++
+			j++
+		}
+		if c != nil {
+			keys = append(keys, key)
+			cs = append(cs, c)
+		}
+	}
+	s.keys, s.cs = keys, cs
+}
+
+// Rank returns the number of elements less than or equal to x.
+func (s *Sparse) Rank(x int) int {
+	key, low := split(x)
+	n := 0
+	for i, k := range s.keys {
+		if k > key {
+			break
+		}
+		if k == key {
+			return n + s.cs[i].rank(low)
+		}
+		n += s.cs[i].card()
+	}
+	return n
+}
+
+// Select returns the element of s with i smaller elements,
+// or false if s has no more than i elements.
+func (s *Sparse) Select(i int) (int, bool) {
+	if i < 0 {
+		return 0, false
+	}
+	for j, c := range s.cs {
+		if n := c.card(); i >= n {
+			i -= n
+			continue
+		}
+		return join(s.keys[j], c.selectAt(i)), true
+	}
+	return 0, false
+}
+
+// Elems returns the elements of the set in increasing order.
+func (s *Sparse) Elems() []int {
+	var elems []int
+	for i, c := range s.cs {
+		key := s.keys[i]
+		c.each(func(x uint16) { elems = append(elems, join(key, x)) })
+	}
+	return elems
+}
+
+// String returns the set as a string of the form "{1 2 3}".
+func (s *Sparse) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, x := range s.Elems() {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", x)
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// An Iterator visits the elements of a Sparse in increasing order.
+// The set must not be changed while it is in use.
+//
+//	for it := s.Iter(); it.Next(); {
+//		x := it.Value()
+//		...
+//	}
+type Iterator struct {
+	s     *Sparse
+	i     int    // the current group
+	low   uint16 // the least low bits not yet visited in group i
+	value int
+}
+
+// Iter returns an Iterator positioned before the least element of s.
+func (s *Sparse) Iter() *Iterator { return &Iterator{s: s} }
+
+// Next advances the iterator to the next element, and reports whether
+// there is one.
+func (it *Iterator) Next() bool {
+	for it.i < len(it.s.cs) {
+		x, ok := it.s.cs[it.i].next(it.low)
+		if ok {
+			it.value = join(it.s.keys[it.i], x)
+			if x == 0xffff {
+				it.i, it.low = it.i+1, 0
+			} else {
+				it.low = x + 1
+			}
+			return true
+		}
+		it.i, it.low = it.i+1, 0
+	}
+	return false
+}
+
+// Value returns the element at which the iterator stands.
+func (it *Iterator) Value() int { return it.value }
+
+// Seek positions the iterator before the least element not less
+// than x, so that Next will advance to it.
+func (it *Iterator) Seek(x int) {
+	key, low := split(x)
+	it.i, _ = it.s.find(key)
+	it.low = 0
+	if it.i < len(it.s.keys) && it.s.keys[it.i] == key {
+		it.low = low
+	}
+}
@@ -0,0 +1,422 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package intset
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// model is the obvious implementation of a set, for comparison.
+type model map[int]bool
+
+func (m model) elems() []int {
+	var elems []int
+	for x := range m {
+		elems = append(elems, x)
+	}
+	sort.Ints(elems)
+	return elems
+}
+
+// randomSparse returns a Sparse and its model, with elements in
+// clusters chosen to need every kind of container.
+func randomSparse(rng *rand.Rand) (*Sparse, model) {
+	s, m := new(Sparse), make(model)
+	add := func(x int) { s.Add(x); m[x] = true }
+	for _, x := range []int{math.MinInt64, math.MaxInt64, -1, 0, 65535, 65536} {
+		if rng.Intn(2) == 0 {
+			add(x)
+		}
+	}
+	for i := rng.Intn(6); i > 0; i-- {
+		base := rng.Intn(8)<<16 - 4<<16
+		switch rng.Intn(4) {
+		case 0: // dense: a bitmap
+			for j := 0; j < 6000; j++ {
+				add(base + rng.Intn(1<<16))
+			}
+		case 1: // scattered: arrays
+			xs := make([]int, 300)
+			for j := range xs {
+				xs[j] = rng.Int() - rng.Int()
+				m[xs[j]] = true
+			}
+			s.AddAll(xs...)
+		case 2: // a few runs, some crossing groups
+			for j := rng.Intn(5); j > 0; j-- {
+				lo := base + rng.Intn(1<<17)
+				hi := lo + rng.Intn(1<<15)
+				s.AddRange(lo, hi)
+				for x := lo; x < hi; x++ {
+					m[x] = true
+				}
+			}
+		case 3: // a sprinkling
+			for j := 0; j < 100; j++ {
+				add(base + rng.Intn(1<<16))
+			}
+		}
+	}
+	if rng.Intn(2) == 0 {
+		s.Optimize()
+	}
+	return s, m
+}
+
+// check reports the differences between s and m.
+func check(t *testing.T, what string, s *Sparse, m model) {
+	t.Helper()
+	want := m.elems()
+	if got := s.Elems(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("%s: Elems has %d elements, want %d", what, len(got), len(want))
+	}
+	if s.Len() != len(want) {
+		t.Fatalf("%s: Len = %d, want %d", what, s.Len(), len(want))
+	}
+	var got []int
+	for it := s.Iter(); it.Next(); {
+		got = append(got, it.Value())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("%s: iterator visits %d elements, want %d", what, len(got), len(want))
+	}
+	for i, x := range want {
+		if !s.Has(x) {
+			t.Fatalf("%s: Has(%d) = false", what, x)
+		}
+		if i%97 == 0 {
+			if r := s.Rank(x); r != i+1 {
+				t.Fatalf("%s: Rank(%d) = %d, want %d", what, x, r, i+1)
+			}
+			if y, ok := s.Select(i); !ok || y != x {
+				t.Fatalf("%s: Select(%d) = %d, %t, want %d", what, i, y, ok, x)
+			}
+			if x > math.MinInt64 && !m[x-1] {
+				if r := s.Rank(x - 1); r != i {
+					t.Fatalf("%s: Rank(%d) = %d, want %d", what, x-1, r, i)
+				}
+			}
+		}
+	}
+	if _, ok := s.Select(len(want)); ok {
+		t.Fatalf("%s: Select(Len()) succeeded", what)
+	}
+}
+
+func TestSparse(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		s, m := randomSparse(rng)
+		check(t, "random", s, m)
+
+		// Remove elements until some containers shrink or vanish.
+		for _, x := range m.elems() {
+			if rng.Intn(3) > 0 {
+				s.Remove(x)
+				delete(m, x)
+			}
+		}
+		s.Remove(12345678) // usually absent
+		delete(m, 12345678)
+		check(t, "after Remove", s, m)
+
+		c := s.Copy()
+		s.Clear()
+		check(t, "after Clear", s, nil)
+		check(t, "copy", c, m)
+	}
+}
+
+func TestSparseAlgebra(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 50; i++ {
+		s, ms := randomSparse(rng)
+		u, mu := randomSparse(rng)
+		for _, test := range []struct {
+			name string
+			op   func(s, t *Sparse)
+			in   func(inS, inT bool) bool
+		}{
+			{"UnionWith", (*Sparse).UnionWith, func(x, y bool) bool { return x || y }},
+			{"IntersectWith", (*Sparse).IntersectWith, func(x, y bool) bool { return x && y }},
+			{"DifferenceWith", (*Sparse).DifferenceWith, func(x, y bool) bool { return x && !y }},
+			{"SymmetricDifference", (*Sparse).SymmetricDifference, func(x, y bool) bool { return x != y }},
+		} {
+			want := make(model)
+			for x := range ms {
+				if test.in(true, mu[x]) {
+					want[x] = true
+				}
+			}
+			for x := range mu {
+				if test.in(ms[x], true) {
+					want[x] = true
+				}
+			}
+			r := s.Copy()
+			test.op(r, u)
+			check(t, test.name, r, want)
+			check(t, test.name+" operand", u, mu)
+		}
+	}
+
+	var s Sparse
+	s.AddRange(-10, 10)
+	s.UnionWith(&s)
+	check(t, "self union", &s, model{-10: true, -9: true, -8: true, -7: true, -6: true,
+		-5: true, -4: true, -3: true, -2: true, -1: true, 0: true, 1: true, 2: true,
+		3: true, 4: true, 5: true, 6: true, 7: true, 8: true, 9: true})
+	s.SymmetricDifference(&s)
+	check(t, "self symmetric difference", &s, nil)
+}
+
+func TestSparseContainers(t *testing.T) {
+	var s Sparse
+	kind := func() string { return fmt.Sprintf("%T", s.cs[0]) }
+
+	for x := 0; x < 2*arrayMax; x += 2 {
+		s.Add(x)
+	}
+	if kind() != "intset.array" {
+		t.Errorf("%d scattered elements in %s", s.Len(), kind())
+	}
+	s.Add(1)
+	if kind() != "*intset.bitmap" {
+		t.Errorf("%d scattered elements in %s", s.Len(), kind())
+	}
+	s.Remove(0)
+	if kind() != "intset.array" {
+		t.Errorf("%d scattered elements in %s after Remove", s.Len(), kind())
+	}
+
+	s.Clear()
+	s.AddRange(100, 60000)
+	if kind() != "intset.run" {
+		t.Errorf("one long run in %s", kind())
+	}
+	for x := 200; x < 60000; x += 20 {
+		s.Remove(x) // each splits a run
+	}
+	if kind() != "*intset.bitmap" || s.Len() != 60000-100-(60000-200)/20 {
+		t.Errorf("many short runs in %s, Len = %d", kind(), s.Len())
+	}
+	s.AddRange(0, 1<<16)
+	s.Optimize()
+	if kind() != "intset.run" || len(s.cs) != 1 || s.Len() != 1<<16 {
+		t.Errorf("a full group in %s", kind())
+	}
+	s.AddRange(math.MaxInt64-2, math.MaxInt64)
+	if !s.Has(math.MaxInt64-1) || s.Has(math.MaxInt64) || s.Len() != 1<<16+2 {
+		t.Errorf("AddRange near MaxInt64: %v", s.Elems()[1<<16:])
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 20; i++ {
+		s, m := randomSparse(rng)
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var u Sparse
+		u.Add(42) // to be replaced
+		if err := u.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		check(t, "unmarshaled", &u, m)
+		if !reflect.DeepEqual(u.cs, s.cs) {
+			t.Errorf("unmarshaled containers differ")
+		}
+	}
+
+	var s Sparse
+	s.AddRange(-5, 5)
+	s.Add(1 << 40)
+	s.Optimize()
+	data, _ := s.MarshalBinary()
+	for _, test := range []struct {
+		data []byte
+		want string
+	}{
+		{nil, "intset: binary data too short"},
+		{data[:len(data)-1], "intset: binary data too short"},
+		{append(data[:len(data):len(data)], 0), "intset: trailing data"},
+		{[]byte{2, 0}, "intset: unknown version 2"},
+		{[]byte{1, 1, 0, 3}, "intset: unknown container kind 3"},
+		{[]byte{1, 1, 0, kindArray, 0}, "intset: empty array"},
+		{[]byte{1, 1, 0, kindArray, 2, 5, 0, 4, 0}, "intset: array out of order"},
+		{[]byte{1, 1, 0, kindRun, 2, 1, 0, 2, 0, 3, 0, 4, 0}, "intset: bad interval"},
+		{[]byte{1, 2, 5, kindArray, 1, 0, 0, 5, kindArray, 1, 0, 0}, "intset: bad group key"},
+		{[]byte{1, 1, 0, kindArray, 0xff, 0xff, 0xff, 0xff, 0x0f}, "intset: count 4294967295 too large"},
+	} {
+		var u Sparse
+		if err := u.UnmarshalBinary(test.data); err == nil || err.Error() != test.want {
+			t.Errorf("UnmarshalBinary(%v) = %v, want %s", test.data, err, test.want)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	for _, s := range []Set{new(IntSet), new(Sparse)} {
+		s.Add(1)
+		s.AddAll(144, 9, 42, 9)
+		s.Remove(144)
+		s.Remove(1000)
+		if got := s.String(); got != "{1 9 42}" || s.Len() != 3 || !s.Has(42) || s.Has(144) {
+			t.Errorf("%T: got %s, Len %d", s, got, s.Len())
+		}
+		if got := fmt.Sprint(s.Elems()); got != "[1 9 42]" {
+			t.Errorf("%T: Elems() = %s", s, got)
+		}
+		s.Clear()
+		if s.Len() != 0 || s.String() != "{}" {
+			t.Errorf("%T: after Clear, %s", s, s)
+		}
+	}
+}
+
+func Example_sparse() {
+	var x, y Sparse
+	x.Add(-1)
+	x.Add(1 << 40)
+	x.AddRange(10, 15)
+	y.AddRange(12, 20)
+	x.SymmetricDifference(&y)
+	fmt.Println(&x)
+	fmt.Println(x.Rank(12), x.Rank(15))
+	fmt.Println(x.Select(3))
+	for it := x.Iter(); it.Next(); {
+		if it.Value() > 16 {
+			break
+		}
+		fmt.Print(it.Value(), " ")
+	}
+	fmt.Println()
+
+	// Output:
+	// {-1 10 11 15 16 17 18 19 1099511627776}
+	// 3 4
+	// 15 true
+	// -1 10 11 15 16
+}
+
+func Example_algebra() {
+	var x, y IntSet
+	for _, v := range []int{1, 9, 42, 144} {
+		x.Add(v)
+	}
+	y.Add(9)
+	y.Add(200)
+
+	z := x.Copy()
+	z.IntersectWith(&y)
+	fmt.Println(z)
+	z = x.Copy()
+	z.DifferenceWith(&y)
+	fmt.Println(z)
+	z = x.Copy()
+	z.SymmetricDifference(&y)
+	fmt.Println(z, z.Len())
+	fmt.Println(&x)
+
+	// Output:
+	// {9}
+	// {1 42 144}
+	// {1 42 144 200} 4
+	// {1 9 42 144}
+}
+
+// ids returns n random IDs below max.
+func ids(n, max int) []int {
+	rng := rand.New(rand.NewSource(4))
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = rng.Intn(max)
+	}
+	return ids
+}
+
+// A million IDs: dense ones below 2M, which suit the bit vector, and
+// sparse ones below 1<<30, for which it needs 128MB.
+var dense, sparse = ids(1e6, 2e6), ids(1e6, 1<<30)
+
+func benchmarkAdd(b *testing.B, s Set, ids []int) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s.Clear()
+		for _, x := range ids {
+			s.Add(x)
+		}
+	}
+}
+
+func benchmarkAddAll(b *testing.B, s Set, ids []int) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s.Clear()
+		s.AddAll(ids...)
+	}
+}
+
+func benchmarkHas(b *testing.B, s Set, ids []int) {
+	s.AddAll(ids...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Has(ids[i%len(ids)] + i%2)
+	}
+}
+
+func BenchmarkIntSetAddDense(b *testing.B)     { benchmarkAdd(b, new(IntSet), dense) }
+func BenchmarkSparseAddDense(b *testing.B)     { benchmarkAdd(b, new(Sparse), dense) }
+func BenchmarkIntSetAddSparse(b *testing.B)    { benchmarkAdd(b, new(IntSet), sparse) }
+func BenchmarkSparseAddSparse(b *testing.B)    { benchmarkAdd(b, new(Sparse), sparse) }
+func BenchmarkSparseAddAllDense(b *testing.B)  { benchmarkAddAll(b, new(Sparse), dense) }
+func BenchmarkSparseAddAllSparse(b *testing.B) { benchmarkAddAll(b, new(Sparse), sparse) }
+
+func BenchmarkIntSetHasDense(b *testing.B)  { benchmarkHas(b, new(IntSet), dense) }
+func BenchmarkSparseHasDense(b *testing.B)  { benchmarkHas(b, new(Sparse), dense) }
+func BenchmarkIntSetHasSparse(b *testing.B) { benchmarkHas(b, new(IntSet), sparse) }
+func BenchmarkSparseHasSparse(b *testing.B) { benchmarkHas(b, new(Sparse), sparse) }
+
+// halves returns two sets holding alternate elements of ids.
+func halves(x, y Set, ids []int) {
+	for i, id := range ids {
+		if i%2 == 0 {
+			x.Add(id)
+		} else {
+			y.Add(id)
+		}
+	}
+}
+
+func benchmarkIntSetUnion(b *testing.B, ids []int) {
+	var x, y IntSet
+	halves(&x, &y, ids)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Copy().UnionWith(&y)
+	}
+}
+
+func benchmarkSparseUnion(b *testing.B, ids []int) {
+	var x, y Sparse
+	halves(&x, &y, ids)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Copy().UnionWith(&y)
+	}
+}
+
+func BenchmarkIntSetUnionDense(b *testing.B)  { benchmarkIntSetUnion(b, dense) }
+func BenchmarkSparseUnionDense(b *testing.B)  { benchmarkSparseUnion(b, dense) }
+func BenchmarkIntSetUnionSparse(b *testing.B) { benchmarkIntSetUnion(b, sparse) }
+func BenchmarkSparseUnionSparse(b *testing.B) { benchmarkSparseUnion(b, sparse) }
@@ -21,20 +21,60 @@ type literal float64
 
 // A unary represents a unary operator expression, e.g., -x.
 type unary struct {
-	op rune // one of '+', '-'
+	op rune // one of '+', '-', '!'
 	x  Expr
 }
 
 // A binary represents a binary operator expression, e.g., x+y.
 type binary struct {
-	op   rune // one of '+', '-', '*', '/'
+	op   rune // one of '+', '-', '*', '/', '%', '^', '<', '>', or an op* constant
 	x, y Expr
 }
 
 // A call represents a function call expression, e.g., sin(x).
 type call struct {
-	fn   string // one of "pow", "sin", "sqrt"
+	fn   string // a function registered with Register, e.g. "sin"
 	args []Expr
+	pos  position // of fn, for errors found by Check
+}
+
+// A cond represents a conditional expression, c ? a : b or if(c, a, b).
+type cond struct {
+	c, a, b Expr
 }
 
 //!-ast
+
+// Two-character operators are stored in binary.op as single runes.
+const (
+	opEq  = '=' // ==
+	opNe  = '≠' // !=
+	opLe  = '≤' // <=
+	opGe  = '≥' // >=
+	opAnd = '&' // &&
+	opOr  = '|' // ||
+)
+
+// opString returns an operator as it is written in expressions.
+func opString(op rune) string {
+	switch op {
+	case opEq:
+		return "=="
+	case opNe:
+		return "!="
+	case opLe:
+		return "<="
+	case opGe:
+		return ">="
+	case opAnd:
+		return "&&"
+	case opOr:
+		return "||"
+	}
+	return string(op)
+}
+
+// position is a location in the input, counting from 1.
+type position struct {
+	line, col int
+}
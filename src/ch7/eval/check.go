@@ -20,14 +20,14 @@ func (literal) Check(vars map[Var]bool) error {
 }
 
 func (u unary) Check(vars map[Var]bool) error {
-	if !strings.ContainsRune("+-", u.op) {
+	if !strings.ContainsRune("+-!", u.op) {
 		return fmt.Errorf("unexpected unary op %q", u.op)
 	}
 	return u.x.Check(vars)
 }
 
 func (b binary) Check(vars map[Var]bool) error {
-	if !strings.ContainsRune("+-*/", b.op) {
+	if !strings.ContainsRune("+-*/%^<>=≠≤≥&|", b.op) {
 		return fmt.Errorf("unexpected binary op %q", b.op)
 	}
 	if err := b.x.Check(vars); err != nil {
@@ -37,13 +37,13 @@ func (b binary) Check(vars map[Var]bool) error {
 }
 
 func (c call) Check(vars map[Var]bool) error {
-	arity, ok := numParams[c.fn]
+	f, ok := lookup(c.fn)
 	if !ok {
-		return fmt.Errorf("unknown function %q", c.fn)
+		return errorAt(c.pos, "unknown function %q", c.fn)
 	}
-	if len(c.args) != arity {
-		return fmt.Errorf("call to %s has %d args, want %d",
-			c.fn, len(c.args), arity)
+	if !f.accepts(len(c.args)) {
+		return errorAt(c.pos, "call to %s has %d args, want %s",
+			c.fn, len(c.args), f.arity())
 	}
 	for _, arg := range c.args {
 		if err := arg.Check(vars); err != nil {
@@ -53,6 +53,13 @@ func (c call) Check(vars map[Var]bool) error {
 	return nil
 }
 
-var numParams = map[string]int{"pow": 2, "sin": 1, "sqrt": 1}
+func (c cond) Check(vars map[Var]bool) error {
+	for _, e := range []Expr{c.c, c.a, c.b} {
+		if err := e.Check(vars); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
 //!-Check
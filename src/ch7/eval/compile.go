@@ -0,0 +1,420 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// A Program is an Expr compiled to bytecode for a small stack machine.
+// Variables live in numbered slots instead of an Env map, constant
+// subexpressions are computed once by Compile, and Exec runs the code
+// in a single loop with no allocation.
+//
+// A Program is immutable and safe for concurrent use. It is itself an
+// Expr: Eval, Check and Format behave as they do for the source.
+type Program struct {
+	src      Expr
+	code     []instr
+	consts   []float64
+	vars     []Var // by slot
+	calls    []registered
+	maxStack int
+}
+
+type opcode uint8
+
+const (
+	insConst opcode = iota // push consts[arg]
+	insLoad                // push slots[arg]
+	insNeg
+	insNot
+	insBool // replace top with 1 if non-zero, else 0
+	insAdd
+	insSub
+	insMul
+	insDiv
+	insMod
+	insPow
+	insLt
+	insGt
+	insLe
+	insGe
+	insEq
+	insNe
+	insJump  // pc = arg
+	insJumpZ // pop; if zero, pc = arg
+	insJumpNZ
+	insCall1 // replace top with calls[arg].f1(top)
+	insCall2
+	insCall // pop argc values, push calls[arg].Fn(values)
+)
+
+var opcodeNames = [...]string{
+	insConst: "const", insLoad: "load", insNeg: "neg", insNot: "not",
+	insBool: "bool", insAdd: "add", insSub: "sub", insMul: "mul",
+	insDiv: "div", insMod: "mod", insPow: "pow", insLt: "lt", insGt: "gt",
+	insLe: "le", insGe: "ge", insEq: "eq", insNe: "ne", insJump: "jump",
+	insJumpZ: "jumpz", insJumpNZ: "jumpnz", insCall1: "call1",
+	insCall2: "call2", insCall: "call",
+}
+
+type instr struct {
+	op   opcode
+	argc uint16 // of insCall
+	arg  int32
+}
+
+var binaryOpcodes = map[rune]opcode{
+	'+': insAdd, '-': insSub, '*': insMul, '/': insDiv, '%': insMod,
+	'^': insPow, '<': insLt, '>': insGt, opLe: insLe, opGe: insGe,
+	opEq: insEq, opNe: insNe,
+}
+
+// Compile checks e and compiles it to a Program. It reports the same
+// errors as Check.
+func Compile(e Expr) (*Program, error) {
+	if p, ok := e.(*Program); ok {
+		return p, nil
+	}
+	if err := e.Check(map[Var]bool{}); err != nil {
+		return nil, err
+	}
+	c := compiler{p: &Program{src: e}, slots: make(map[Var]int)}
+	c.expr(fold(e))
+	if c.depth != 1 {
+		panic(fmt.Sprintf("eval: compiled stack depth %d", c.depth))
+	}
+	return c.p, nil
+}
+
+// Vars returns the variables of the program, indexed by slot.
+func (p *Program) Vars() []Var { return append([]Var(nil), p.vars...) }
+
+// Slot returns the slot of variable v, or -1 if the program does not use v.
+func (p *Program) Slot(v Var) int {
+	for i, w := range p.vars {
+		if w == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// Exec runs the program with slots[i] as the value of p.Vars()[i].
+// It panics if slots is shorter than p.Vars().
+func (p *Program) Exec(slots []float64) float64 {
+	var inline [32]float64
+	stack := inline[:]
+	if p.maxStack > len(inline) {
+		stack = make([]float64, p.maxStack)
+	}
+	slots = slots[:len(p.vars)]
+	sp := 0 // stack[sp-1] is the top
+	code := p.code
+	for pc := 0; pc < len(code); pc++ {
+		in := code[pc]
+		switch in.op {
+		case insConst:
+			stack[sp] = p.consts[in.arg]
+			sp++
+		case insLoad:
+			stack[sp] = slots[in.arg]
+			sp++
+		case insNeg:
+			stack[sp-1] = -stack[sp-1]
+		case insNot:
+			stack[sp-1] = truth(stack[sp-1] == 0)
+		case insBool:
+			stack[sp-1] = truth(stack[sp-1] != 0)
+		case insAdd:
+			sp--
+			stack[sp-1] += stack[sp]
+		case insSub:
+			sp--
+			stack[sp-1] -= stack[sp]
+		case insMul:
+			sp--
+			stack[sp-1] *= stack[sp]
+		case insDiv:
+			sp--
+			stack[sp-1] /= stack[sp]
+		case insMod:
+			sp--
+			stack[sp-1] = math.Mod(stack[sp-1], stack[sp])
+		case insPow:
+			sp--
+			stack[sp-1] = math.Pow(stack[sp-1], stack[sp])
+		case insLt:
+			sp--
+			stack[sp-1] = truth(stack[sp-1] < stack[sp])
+		case insGt:
+			sp--
+			stack[sp-1] = truth(stack[sp-1] > stack[sp])
+		case insLe:
+			sp--
+			stack[sp-1] = truth(stack[sp-1] <= stack[sp])
+		case insGe:
+			sp--
+			stack[sp-1] = truth(stack[sp-1] >= stack[sp])
+		case insEq:
+			sp--
+			stack[sp-1] = truth(stack[sp-1] == stack[sp])
+		case insNe:
+			sp--
+			stack[sp-1] = truth(stack[sp-1] != stack[sp])
+		case insJump:
+			pc = int(in.arg) - 1
+		case insJumpZ:
+			sp--
+			if stack[sp] == 0 {
+				pc = int(in.arg) - 1
+			}
+		case insJumpNZ:
+			sp--
+			if stack[sp] != 0 {
+				pc = int(in.arg) - 1
+			}
+		case insCall1:
+			stack[sp-1] = p.calls[in.arg].f1(stack[sp-1])
+		case insCall2:
+			sp--
+			stack[sp-1] = p.calls[in.arg].f2(stack[sp-1], stack[sp])
+		case insCall:
+			// Copy the arguments so that the stack does not escape
+			// through an arbitrary registered function.
+			n := int(in.argc)
+			args := append([]float64(nil), stack[sp-n:sp]...)
+			sp -= n
+			stack[sp] = p.calls[in.arg].Fn(args)
+			sp++
+		default:
+			panic(fmt.Sprintf("eval: bad opcode %d", in.op))
+		}
+	}
+	return stack[0]
+}
+
+// Eval runs the program with variables taken from env.
+func (p *Program) Eval(env Env) float64 {
+	var inline [8]float64
+	slots := inline[:0]
+	for _, v := range p.vars {
+		slots = append(slots, env[v])
+	}
+	return p.Exec(slots)
+}
+
+// Check reports the variables of the source expression. A Program has
+// already been checked, so Check never fails.
+func (p *Program) Check(vars map[Var]bool) error {
+	return p.src.Check(vars)
+}
+
+// Disasm returns a listing of the bytecode, one instruction per line.
+func (p *Program) Disasm() string {
+	var buf bytes.Buffer
+	for pc, in := range p.code {
+		fmt.Fprintf(&buf, "%d\t%s", pc, opcodeNames[in.op])
+		switch in.op {
+		case insConst:
+			fmt.Fprintf(&buf, " %g", p.consts[in.arg])
+		case insLoad:
+			fmt.Fprintf(&buf, " %s", p.vars[in.arg])
+		case insJump, insJumpZ, insJumpNZ:
+			fmt.Fprintf(&buf, " %d", in.arg)
+		case insCall1, insCall2:
+			fmt.Fprintf(&buf, " #%d", in.arg)
+		case insCall:
+			fmt.Fprintf(&buf, " #%d/%d", in.arg, in.argc)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// ---- compiler ----
+
+type compiler struct {
+	p     *Program
+	slots map[Var]int
+	depth int // current stack depth
+}
+
+func (c *compiler) emit(op opcode, arg int) int {
+	c.p.code = append(c.p.code, instr{op: op, arg: int32(arg)})
+	return len(c.p.code) - 1
+}
+
+// push records that the stack grew by n.
+func (c *compiler) push(n int) {
+	c.depth += n
+	if c.depth > c.p.maxStack {
+		c.p.maxStack = c.depth
+	}
+}
+
+// patch points the jump at pc to the next instruction.
+func (c *compiler) patch(pc int) {
+	c.p.code[pc].arg = int32(len(c.p.code))
+}
+
+func (c *compiler) constant(x float64) {
+	k := len(c.p.consts)
+	for i, y := range c.p.consts {
+		if math.Float64bits(x) == math.Float64bits(y) {
+			k = i
+			break
+		}
+	}
+	if k == len(c.p.consts) {
+		c.p.consts = append(c.p.consts, x)
+	}
+	c.emit(insConst, k)
+	c.push(1)
+}
+
+func (c *compiler) expr(e Expr) {
+	switch e := e.(type) {
+	case literal:
+		c.constant(float64(e))
+
+	case Var:
+		slot, ok := c.slots[e]
+		if !ok {
+			slot = len(c.p.vars)
+			c.slots[e] = slot
+			c.p.vars = append(c.p.vars, e)
+		}
+		c.emit(insLoad, slot)
+		c.push(1)
+
+	case unary:
+		c.expr(e.x)
+		switch e.op {
+		case '-':
+			c.emit(insNeg, 0)
+		case '!':
+			c.emit(insNot, 0)
+		}
+
+	case binary:
+		switch e.op {
+		case opAnd, opOr:
+			// x && y: x; jumpz F; y; bool; jump E; F: const 0; E:
+			// x || y: x; jumpnz T; y; bool; jump E; T: const 1; E:
+			jump, short := insJumpZ, 0.0
+			if e.op == opOr {
+				jump, short = insJumpNZ, 1
+			}
+			c.expr(e.x)
+			skip := c.emit(jump, 0)
+			c.depth--
+			c.expr(e.y)
+			c.emit(insBool, 0)
+			end := c.emit(insJump, 0)
+			c.patch(skip)
+			c.depth--
+			c.constant(short)
+			c.patch(end)
+			return
+		}
+		c.expr(e.x)
+		c.expr(e.y)
+		c.emit(binaryOpcodes[e.op], 0)
+		c.depth--
+
+	case cond:
+		c.expr(e.c)
+		otherwise := c.emit(insJumpZ, 0)
+		c.depth--
+		c.expr(e.a)
+		end := c.emit(insJump, 0)
+		c.patch(otherwise)
+		c.depth--
+		c.expr(e.b)
+		c.patch(end)
+
+	case call:
+		f, _ := lookup(e.fn) // checked by Compile
+		k := len(c.p.calls)
+		c.p.calls = append(c.p.calls, f)
+		for _, arg := range e.args {
+			c.expr(arg)
+		}
+		switch {
+		case f.f1 != nil:
+			c.emit(insCall1, k)
+		case f.f2 != nil:
+			c.emit(insCall2, k)
+			c.depth--
+		default:
+			pc := c.emit(insCall, k)
+			c.p.code[pc].argc = uint16(len(e.args))
+			c.depth -= len(e.args)
+			c.push(1)
+		}
+
+	default:
+		panic(fmt.Sprintf("unknown Expr: %T", e))
+	}
+}
+
+// fold returns e with every subexpression that does not depend on a
+// variable replaced by its value.
+func fold(e Expr) Expr {
+	switch e := e.(type) {
+	case unary:
+		x := fold(e.x)
+		if e.op == '+' {
+			return x
+		}
+		if _, ok := x.(literal); ok {
+			return literal(unary{e.op, x}.Eval(nil))
+		}
+		return unary{e.op, x}
+
+	case binary:
+		x, y := fold(e.x), fold(e.y)
+		_, xconst := x.(literal)
+		_, yconst := y.(literal)
+		switch {
+		case xconst && yconst:
+			return literal(binary{e.op, x, y}.Eval(nil))
+		case xconst && (e.op == opAnd || e.op == opOr):
+			// The left operand alone may decide the result.
+			if (x.(literal) != 0) == (e.op == opOr) {
+				return literal(truth(e.op == opOr))
+			}
+		}
+		return binary{e.op, x, y}
+
+	case cond:
+		c := fold(e.c)
+		if c, ok := c.(literal); ok {
+			if c != 0 {
+				return fold(e.a)
+			}
+			return fold(e.b)
+		}
+		return cond{c, fold(e.a), fold(e.b)}
+
+	case call:
+		args := make([]Expr, len(e.args))
+		constant := true
+		for i, arg := range e.args {
+			args[i] = fold(arg)
+			_, ok := args[i].(literal)
+			constant = constant && ok
+		}
+		folded := call{e.fn, args, e.pos}
+		if constant {
+			return literal(folded.Eval(nil))
+		}
+		return folded
+	}
+	return e
+}
@@ -0,0 +1,200 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+var compileExprs = []string{
+	"sqrt(A / pi)",
+	"pow(x, 3) + pow(y, 3)",
+	"5 / 9 * (F - 32)",
+	"-1 + -x",
+	"+x - -y * +2",
+	"x % 3 + y ^ 2 ^ 0.5",
+	"x < y && y <= 2 || !(x == y) && x != 1",
+	"x >= 0 ? (y > 0 ? 1 : 2) : 3",
+	"if(x < 0, -x, x) + abs(y)",
+	"max(x, y, 3) - min(x, 1 / y)",
+	"hypot(x, y) / atan2(y, x)",
+	"x && 1 / 0",
+	"0 && x",
+	"1 || x",
+	"x || 0",
+	"sin(r) / r",
+	"1 - x * x - y * y",
+	"x ^ 0.5",
+}
+
+// TestCompile checks that compiled programs agree with the tree walker.
+func TestCompile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	vals := []float64{0, 1, -1, 2, 0.5, math.Inf(1), math.NaN()}
+	for _, s := range compileExprs {
+		expr, err := Parse(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		prog, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if got, want := Format(prog), Format(expr); got != want {
+			t.Errorf("Format(Compile(%s)) = %s, want %s", s, got, want)
+		}
+		for i := 0; i < 200; i++ {
+			env := Env{}
+			for _, v := range []Var{"x", "y", "r", "A", "F", "pi"} {
+				if i < len(vals)*len(vals) {
+					env[v] = vals[(i+len(v))%len(vals)]
+				} else {
+					env[v] = rng.NormFloat64() * 10
+				}
+			}
+			env["x"] = vals[i%len(vals)]
+			want := expr.Eval(env)
+			got := prog.Eval(env)
+			if !same(got, want) {
+				t.Errorf("%s in %v: compiled %g, tree %g\n%s",
+					s, env, got, want, prog.Disasm())
+				break
+			}
+		}
+	}
+}
+
+func same(x, y float64) bool {
+	return x == y || math.IsNaN(x) && math.IsNaN(y)
+}
+
+func TestCompileCheck(t *testing.T) {
+	expr, err := Parse("sqrt(1, 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Compile(expr); err == nil || err.Error() != "1:1: call to sqrt has 2 args, want 1" {
+		t.Errorf("Compile(sqrt(1, 2)) error = %v", err)
+	}
+
+	expr, _ = Parse("y * x + y")
+	prog, err := Compile(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[Var]bool{}
+	if err := prog.Check(vars); err != nil || len(vars) != 2 || !vars["x"] || !vars["y"] {
+		t.Errorf("Check = %v, vars %v", err, vars)
+	}
+	if got := prog.Vars(); len(got) != 2 || got[0] != "y" || got[1] != "x" {
+		t.Errorf("Vars = %v, want [y x]", got)
+	}
+	if prog.Slot("x") != 1 || prog.Slot("z") != -1 {
+		t.Errorf("Slot(x) = %d, Slot(z) = %d", prog.Slot("x"), prog.Slot("z"))
+	}
+	if got := prog.Exec([]float64{2, 3}); got != 8 {
+		t.Errorf("Exec = %g, want 8", got)
+	}
+	if again, _ := Compile(prog); again != prog {
+		t.Errorf("Compile(*Program) recompiled")
+	}
+}
+
+func TestConstantFolding(t *testing.T) {
+	for _, test := range []struct{ expr, want string }{
+		{"2 * pow(3, 2) + x", "const 18;load x;add"},
+		{"-(1 + 1) * +x", "const -2;load x;mul"},
+		{"1 < 2 ? x : y", "load x"},
+		{"0 ? x : y", "load y"},
+		{"0 && x", "const 0"},
+		{"1 || x", "const 1"},
+		{"max(1, 2, 3)", "const 3"},
+		{"sin(x)", "load x;call1 #0"},
+		{"atan2(y, 1)", "load y;const 1;call2 #0"},
+		{"min(x, 2 - 1)", "load x;const 1;call #0/2"},
+		{"x * x", "load x;load x;mul"},
+	} {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prog, err := Compile(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ops []string
+		for _, line := range strings.Split(strings.TrimSpace(prog.Disasm()), "\n") {
+			ops = append(ops, strings.SplitN(line, "\t", 2)[1])
+		}
+		if got := strings.Join(ops, ";"); got != test.want {
+			t.Errorf("Compile(%s) = %s, want %s", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestExecAllocs(t *testing.T) {
+	expr, _ := Parse("x > 0 ? sin(r) / r * hypot(x, y) : -pow(y, 2)")
+	prog, err := Compile(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slots := make([]float64, len(prog.Vars()))
+	if n := testing.AllocsPerRun(100, func() { prog.Exec(slots) }); n != 0 {
+		t.Errorf("Exec allocates %g times per run, want 0", n)
+	}
+}
+
+// The surface benchmarks evaluate one of the book's example plots at the
+// four corners of each cell of a 100x100 grid, as gopl.io/ch7/surface does.
+
+const surfaceExpr = "sin(-x) * pow(1.5, -r)"
+
+func surfaceGrid(f func(x, y, r float64) float64) (sum float64) {
+	const cells, xyrange = 100, 30.0
+	for i := 0; i < cells; i++ {
+		for j := 0; j < cells; j++ {
+			for _, c := range [4][2]int{{i + 1, j}, {i, j}, {i, j + 1}, {i + 1, j + 1}} {
+				x := xyrange * (float64(c[0])/cells - 0.5)
+				y := xyrange * (float64(c[1])/cells - 0.5)
+				sum += f(x, y, math.Hypot(x, y))
+			}
+		}
+	}
+	return sum
+}
+
+func BenchmarkSurfaceTree(b *testing.B) {
+	expr, _ := Parse(surfaceExpr)
+	for i := 0; i < b.N; i++ {
+		surfaceGrid(func(x, y, r float64) float64 {
+			return expr.Eval(Env{"x": x, "y": y, "r": r})
+		})
+	}
+}
+
+func BenchmarkSurfaceCompiled(b *testing.B) {
+	expr, _ := Parse(surfaceExpr)
+	prog, err := Compile(expr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	slots := make([]float64, len(prog.Vars()))
+	sx, sy, sr := prog.Slot("x"), prog.Slot("y"), prog.Slot("r")
+	for i := 0; i < b.N; i++ {
+		surfaceGrid(func(x, y, r float64) float64 {
+			for _, s := range [...]struct {
+				slot int
+				v    float64
+			}{{sx, x}, {sy, y}, {sr, r}} {
+				if s.slot >= 0 {
+					slots[s.slot] = s.v
+				}
+			}
+			return prog.Exec(slots)
+		})
+	}
+}
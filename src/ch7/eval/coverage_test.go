@@ -16,10 +16,13 @@ func TestCoverage(t *testing.T) {
 		env   Env
 		want  string // expected error from Parse/Check or result from Eval
 	}{
-		{"x % 2", nil, "unexpected '%'"},
-		{"!true", nil, "unexpected '!'"},
-		{"log(10)", nil, `unknown function "log"`},
-		{"sqrt(1, 2)", nil, "call to sqrt has 2 args, want 1"},
+		{"x = 2", nil, "1:3: unexpected '='"},
+		{"x ? 1", nil, "1:6: got end of file, want ':'"},
+		{"exp2(10)", nil, `1:1: unknown function "exp2"`},
+		{"sqrt(1, 2)", nil, "1:1: call to sqrt has 2 args, want 1"},
+		{"x % 2 == 1 ? -x : +x", Env{"x": 3}, "-3"},
+		{"!(x < 1) || y", Env{"x": 0}, "0"},
+		{"if(x, log(x), hypot(3, 4))", Env{"x": 0}, "5"},
 		{"sqrt(A / pi)", Env{"A": 87616, "pi": math.Pi}, "167"},
 		{"pow(x, 3) + pow(y, 3)", Env{"x": 9, "y": 10}, "1729"},
 		{"5 / 9 * (F - 32)", Env{"F": -40}, "-40"},
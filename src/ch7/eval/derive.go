@@ -0,0 +1,210 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import "fmt"
+
+// Derive returns the derivative of e with respect to v, simplified.
+//
+// Comparisons, logical operators and functions such as floor are
+// piecewise constant, so their derivative is 0 wherever it exists; a
+// conditional is differentiated branch by branch. Functions registered
+// by the user have no known rule and are differentiated numerically by a
+// central difference written out in the result.
+func Derive(e Expr, v Var) Expr {
+	return Simplify(derive(e, v))
+}
+
+func derive(e Expr, v Var) Expr {
+	switch e := e.(type) {
+	case literal:
+		return num(0)
+
+	case Var:
+		if e == v {
+			return num(1)
+		}
+		return num(0)
+
+	case unary:
+		switch e.op {
+		case '+':
+			return derive(e.x, v)
+		case '-':
+			return neg(derive(e.x, v))
+		}
+		return num(0)
+
+	case binary:
+		u, w := e.x, e.y
+		switch e.op {
+		case '+':
+			return add(derive(u, v), derive(w, v))
+		case '-':
+			return sub(derive(u, v), derive(w, v))
+		case '*':
+			return add(mul(derive(u, v), w), mul(u, derive(w, v)))
+		case '/':
+			return div(sub(mul(derive(u, v), w), mul(u, derive(w, v))), pow(w, num(2)))
+		case '%':
+			// u % w is u - trunc(u/w)*w, and trunc is piecewise constant.
+			return sub(derive(u, v), mul(fn("trunc", div(u, w)), derive(w, v)))
+		case '^':
+			return derivePow(u, w, v)
+		}
+		return num(0)
+
+	case cond:
+		return cond{e.c, derive(e.a, v), derive(e.b, v)}
+
+	case call:
+		switch e.fn {
+		case "min", "max":
+			return deriveMinMax(e, v)
+		case "pow":
+			return derivePow(e.args[0], e.args[1], v)
+		}
+		var d Expr = num(0)
+		for i, p := range partials(e) {
+			d = add(d, mul(p, derive(e.args[i], v)))
+		}
+		return d
+
+	case *Program:
+		return derive(e.src, v)
+	}
+	panic(fmt.Sprintf("unknown Expr: %T", e))
+}
+
+// derivePow differentiates u^w, using the power rule when the exponent
+// does not depend on v and the general rule otherwise.
+func derivePow(u, w Expr, v Var) Expr {
+	du, dw := derive(u, v), derive(w, v)
+	switch {
+	case !uses(w, v):
+		return mul(mul(w, pow(u, sub(w, num(1)))), du)
+	case !uses(u, v):
+		return mul(mul(pow(u, w), fn("log", u)), dw)
+	}
+	return mul(pow(u, w), add(mul(dw, fn("log", u)), div(mul(w, du), u)))
+}
+
+// deriveMinMax differentiates min(a, ...) as a <= min(...) ? a' : min(...)'
+// and max likewise.
+func deriveMinMax(e call, v Var) Expr {
+	if len(e.args) == 1 {
+		return derive(e.args[0], v)
+	}
+	rest := Expr(call{e.fn, e.args[1:], e.pos})
+	if len(e.args) == 2 {
+		rest = e.args[1]
+	}
+	op := opLe
+	if e.fn == "max" {
+		op = opGe
+	}
+	return cond{binary{op, e.args[0], rest}, derive(e.args[0], v), derive(rest, v)}
+}
+
+// partials returns the partial derivatives of a call with respect to
+// each of its arguments.
+func partials(e call) []Expr {
+	if rule, ok := derivatives[e.fn]; ok {
+		return rule(e.args)
+	}
+	ps := make([]Expr, len(e.args))
+	for i, arg := range e.args {
+		// (f(..., a+h, ...) - f(..., a-h, ...)) / 2h, h relative to a.
+		h := mul(num(1e-6), fn("max", num(1), fn("abs", arg)))
+		lo := append([]Expr(nil), e.args...)
+		hi := append([]Expr(nil), e.args...)
+		lo[i], hi[i] = sub(arg, h), add(arg, h)
+		ps[i] = div(sub(call{e.fn, hi, e.pos}, call{e.fn, lo, e.pos}), mul(num(2), h))
+	}
+	return ps
+}
+
+// derivatives holds the partial derivatives of the built-in functions
+// other than min, max and pow.
+var derivatives = map[string]func(a []Expr) []Expr{
+	"sin":  func(a []Expr) []Expr { return one(fn("cos", a[0])) },
+	"cos":  func(a []Expr) []Expr { return one(neg(fn("sin", a[0]))) },
+	"tan":  func(a []Expr) []Expr { return one(add(num(1), pow(fn("tan", a[0]), num(2)))) },
+	"asin": func(a []Expr) []Expr { return one(div(num(1), fn("sqrt", sub(num(1), pow(a[0], num(2)))))) },
+	"acos": func(a []Expr) []Expr { return one(neg(div(num(1), fn("sqrt", sub(num(1), pow(a[0], num(2))))))) },
+	"atan": func(a []Expr) []Expr { return one(div(num(1), add(num(1), pow(a[0], num(2))))) },
+	"sinh": func(a []Expr) []Expr { return one(fn("cosh", a[0])) },
+	"cosh": func(a []Expr) []Expr { return one(fn("sinh", a[0])) },
+	"tanh": func(a []Expr) []Expr { return one(sub(num(1), pow(fn("tanh", a[0]), num(2)))) },
+	"sqrt": func(a []Expr) []Expr { return one(div(num(1), mul(num(2), fn("sqrt", a[0])))) },
+	"cbrt": func(a []Expr) []Expr { return one(div(num(1), mul(num(3), pow(fn("cbrt", a[0]), num(2))))) },
+	"exp":  func(a []Expr) []Expr { return one(fn("exp", a[0])) },
+	"log":  func(a []Expr) []Expr { return one(div(num(1), a[0])) },
+	"log2": func(a []Expr) []Expr { return one(div(num(1), mul(a[0], fn("log", num(2))))) },
+	"log10": func(a []Expr) []Expr {
+		return one(div(num(1), mul(a[0], fn("log", num(10)))))
+	},
+	"abs":   func(a []Expr) []Expr { return one(div(a[0], fn("abs", a[0]))) },
+	"floor": flat,
+	"ceil":  flat,
+	"round": flat,
+	"trunc": flat,
+	"atan2": func(a []Expr) []Expr {
+		y, x := a[0], a[1]
+		r2 := add(pow(x, num(2)), pow(y, num(2)))
+		return []Expr{div(x, r2), neg(div(y, r2))}
+	},
+	"hypot": func(a []Expr) []Expr {
+		h := fn("hypot", a[0], a[1])
+		return []Expr{div(a[0], h), div(a[1], h)}
+	},
+	"mod": func(a []Expr) []Expr {
+		return []Expr{num(1), neg(fn("trunc", div(a[0], a[1])))}
+	},
+}
+
+func one(e Expr) []Expr { return []Expr{e} }
+
+// flat is the rule for piecewise constant functions.
+func flat(a []Expr) []Expr {
+	ps := make([]Expr, len(a))
+	for i := range ps {
+		ps[i] = num(0)
+	}
+	return ps
+}
+
+// uses reports whether e mentions v.
+func uses(e Expr, v Var) bool {
+	switch e := e.(type) {
+	case Var:
+		return e == v
+	case unary:
+		return uses(e.x, v)
+	case binary:
+		return uses(e.x, v) || uses(e.y, v)
+	case cond:
+		return uses(e.c, v) || uses(e.a, v) || uses(e.b, v)
+	case call:
+		for _, arg := range e.args {
+			if uses(arg, v) {
+				return true
+			}
+		}
+	case *Program:
+		return uses(e.src, v)
+	}
+	return false
+}
+
+// Constructors for the trees built by Derive.
+
+func num(x float64) Expr                { return literal(x) }
+func neg(x Expr) Expr                   { return unary{'-', x} }
+func add(x, y Expr) Expr                { return binary{'+', x, y} }
+func sub(x, y Expr) Expr                { return binary{'-', x, y} }
+func mul(x, y Expr) Expr                { return binary{'*', x, y} }
+func div(x, y Expr) Expr                { return binary{'/', x, y} }
+func pow(x, y Expr) Expr                { return binary{'^', x, y} }
+func fn(name string, args ...Expr) Expr { return call{fn: name, args: args} }
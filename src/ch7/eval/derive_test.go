@@ -0,0 +1,197 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	for _, test := range []struct{ expr, want string }{
+		{"x + 0", "x"},
+		{"0 + x * 1", "x"},
+		{"x - 0", "x"},
+		{"0 - x", "(-x)"},
+		{"--x", "x"},
+		{"+x", "x"},
+		{"x * 0 + y", "y"},
+		{"x / 1", "x"},
+		{"x ^ 1", "x"},
+		{"x ^ 0", "1"},
+		{"pow(x, 1)", "x"},
+		{"2 * 3 + x", "(6 + x)"},
+		{"x + 2 * 3", "(x + 6)"},
+		{"x + x", "(2 * x)"},
+		{"x + 2 * x - y", "((3 * x) - y)"},
+		{"x - x", "0"},
+		{"x * y - y * x", "0"},
+		{"x * x * x", "(x ^ 3)"},
+		{"x * y / x", "y"},
+		{"2 * x / (4 * y)", "((0.5 * x) / y)"},
+		{"x ^ 2 ^ 2", "(x ^ 4)"},
+		{"(x ^ 2) ^ 0.5", "((x ^ 2) ^ 0.5)"},
+		{"-x * -y", "(x * y)"},
+		{"sin(x) + 2 * sin(x)", "(3 * sin(x))"},
+		{"sqrt(4) + cos(0) * x", "(2 + x)"},
+		{"1 < 2 ? x : y", "x"},
+		{"y ? x + 0 : 1 * x", "x"},
+		{"0 && x", "0"},
+		{"x && 1 + 1", "(x && 2)"},
+		{"x / 0", "(x / 0)"},
+		{"sqrt(-1) + x", "(sqrt((-1)) + x)"},
+	} {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", test.expr, err)
+		}
+		got := Simplify(expr)
+		if Format(got) != test.want {
+			t.Errorf("Simplify(%s) = %s, want %s", test.expr, Format(got), test.want)
+		}
+		if err := got.Check(map[Var]bool{}); err != nil {
+			t.Errorf("Simplify(%s): %v", test.expr, err)
+		}
+	}
+}
+
+func TestSimplifyFormatRoundTrip(t *testing.T) {
+	for _, src := range []string{
+		"sin(4) ^ x",
+		"(0 - 2) ^ y",
+		"x ^ (1 - 3)",
+		"cos(3) * x ^ 2 - y",
+		"x - 2 * 3 % y",
+		"pow(sin(4), x) + -(2 ^ y)",
+	} {
+		expr, err := Parse(src)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		simple := Format(Simplify(expr))
+		again, err := Parse(simple)
+		if err != nil {
+			t.Errorf("%s: reparse %s: %v", src, simple, err)
+			continue
+		}
+		for _, env := range []Env{{"x": 0.5, "y": 3}, {"x": -2, "y": 2}, {"x": 3, "y": -1}} {
+			want, got := expr.Eval(env), again.Eval(env)
+			if got != want && !(math.IsNaN(got) && math.IsNaN(want)) &&
+				math.Abs(got-want) > 1e-12*math.Max(1, math.Abs(want)) {
+				t.Errorf("%s at %v: %s gives %g, want %g", src, env, simple, got, want)
+			}
+		}
+	}
+}
+
+func TestDerive(t *testing.T) {
+	for _, test := range []struct {
+		expr string
+		v    Var
+		want string
+	}{
+		{"x", "x", "1"},
+		{"y", "x", "0"},
+		{"3 * x + 2", "x", "3"},
+		{"x ^ 3", "x", "(3 * (x ^ 2))"},
+		{"x * y", "y", "x"},
+		{"sin(x)", "x", "cos(x)"},
+		{"cos(2 * x)", "x", "(-(2 * sin((2 * x))))"},
+		{"x * sin(x)", "x", "(sin(x) + (x * cos(x)))"},
+		{"1 / x", "x", "(-(1 / (x ^ 2)))"},
+		{"exp(x)", "x", "exp(x)"},
+		{"log(x)", "x", "(1 / x)"},
+		{"2 ^ x", "x", "(0.6931471805599453 * (2 ^ x))"},
+		{"x < 1 ? x * x : x", "x", "((x < 1) ? (2 * x) : 1)"},
+		{"floor(x) + x", "x", "1"},
+		{"x > 0 && x < 1", "x", "0"},
+	} {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", test.expr, err)
+		}
+		if got := Format(Derive(expr, test.v)); got != test.want {
+			t.Errorf("d/d%s %s = %s, want %s", test.v, test.expr, got, test.want)
+		}
+	}
+}
+
+// TestGradient checks Derive against central differences at random points.
+func TestGradient(t *testing.T) {
+	Register2("userfn", func(x, y float64) float64 { return x*x*y + math.Sin(y) })
+	exprs := []string{
+		"x * y + x / y - y % 1.5",
+		"pow(x, y) + x ^ 2.5 + 2 ^ y",
+		"sin(x) * cos(y) + tan(x / 3)",
+		"asin(x / 4) + acos(y / 4) + atan(x * y)",
+		"atan2(y, x) + hypot(x, y)",
+		"sinh(x / 2) - cosh(y / 2) + tanh(x * y)",
+		"sqrt(x) + cbrt(y) + exp(-x * y)",
+		"log(x) + log2(y) + log10(x * y)",
+		"abs(x - y) + floor(x) * y + ceil(y) + round(x) + trunc(y)",
+		"min(x, y, 2) + max(x * y, 1)",
+		"mod(x * 3, y)",
+		"x > y ? x * x : y * y * y",
+		"if(x < 2, sin(x), -x) * y",
+		"userfn(x, y * 2)",
+		"(x + y) ^ 3 / (1 + x * x)",
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, s := range exprs {
+		expr, err := Parse(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		for _, v := range []Var{"x", "y"} {
+			d := Derive(expr, v)
+			if err := d.Check(map[Var]bool{}); err != nil {
+				t.Errorf("d/d%s %s = %s: %v", v, s, Format(d), err)
+				continue
+			}
+			for i := 0; i < 50; i++ {
+				env := Env{"x": 0.2 + 3*rng.Float64(), "y": 0.2 + 3*rng.Float64()}
+				want := numeric(expr, env, v)
+				got := d.Eval(env)
+				if math.IsNaN(want) || math.Abs(want) > 1e6 {
+					continue // near a discontinuity or pole
+				}
+				if math.Abs(got-want) > 1e-4*math.Max(1, math.Abs(want)) {
+					t.Errorf("d/d%s %s at %v = %g, numerically %g\n\t%s",
+						v, s, env, got, want, Format(d))
+					break
+				}
+			}
+		}
+	}
+}
+
+// numeric returns the central difference of e in v at env, or NaN if e
+// is not smooth there.
+func numeric(e Expr, env Env, v Var) float64 {
+	const h = 1e-6
+	at := func(dv float64) float64 {
+		moved := Env{}
+		for k, x := range env {
+			moved[k] = x
+		}
+		moved[v] += dv
+		return e.Eval(moved)
+	}
+	d1 := (at(h) - at(-h)) / (2 * h)
+	d2 := (at(2*h) - at(-2*h)) / (4 * h)
+	if math.Abs(d1-d2) > 1e-4*math.Max(1, math.Abs(d1)) {
+		return math.NaN()
+	}
+	return d1
+}
+
+func TestSubstitute(t *testing.T) {
+	expr, _ := Parse("sin(r) / r + x")
+	r, _ := Parse("hypot(x, y)")
+	got := Substitute(expr, "r", r)
+	if want := "((sin(hypot(x, y)) / hypot(x, y)) + x)"; Format(got) != want {
+		t.Errorf("Substitute = %s, want %s", Format(got), want)
+	}
+}
@@ -37,6 +37,8 @@ func (u unary) Eval(env Env) float64 {
 		return +u.x.Eval(env)
 	case '-':
 		return -u.x.Eval(env)
+	case '!':
+		return truth(u.x.Eval(env) == 0)
 	}
 	panic(fmt.Sprintf("unsupported unary operator: %q", u.op))
 }
@@ -51,20 +53,55 @@ func (b binary) Eval(env Env) float64 {
 		return b.x.Eval(env) * b.y.Eval(env)
 	case '/':
 		return b.x.Eval(env) / b.y.Eval(env)
+	case '%':
+		return math.Mod(b.x.Eval(env), b.y.Eval(env))
+	case '^':
+		return math.Pow(b.x.Eval(env), b.y.Eval(env))
+	case '<':
+		return truth(b.x.Eval(env) < b.y.Eval(env))
+	case '>':
+		return truth(b.x.Eval(env) > b.y.Eval(env))
+	case opLe:
+		return truth(b.x.Eval(env) <= b.y.Eval(env))
+	case opGe:
+		return truth(b.x.Eval(env) >= b.y.Eval(env))
+	case opEq:
+		return truth(b.x.Eval(env) == b.y.Eval(env))
+	case opNe:
+		return truth(b.x.Eval(env) != b.y.Eval(env))
+	case opAnd:
+		return truth(b.x.Eval(env) != 0 && b.y.Eval(env) != 0)
+	case opOr:
+		return truth(b.x.Eval(env) != 0 || b.y.Eval(env) != 0)
 	}
 	panic(fmt.Sprintf("unsupported binary operator: %q", b.op))
 }
 
 func (c call) Eval(env Env) float64 {
-	switch c.fn {
-	case "pow":
-		return math.Pow(c.args[0].Eval(env), c.args[1].Eval(env))
-	case "sin":
-		return math.Sin(c.args[0].Eval(env))
-	case "sqrt":
-		return math.Sqrt(c.args[0].Eval(env))
+	f, ok := lookup(c.fn)
+	if !ok {
+		panic(fmt.Sprintf("unsupported function call: %s", c.fn))
 	}
-	panic(fmt.Sprintf("unsupported function call: %s", c.fn))
+	args := make([]float64, len(c.args))
+	for i, arg := range c.args {
+		args[i] = arg.Eval(env)
+	}
+	return f.Fn(args)
+}
+
+func (c cond) Eval(env Env) float64 {
+	if c.c.Eval(env) != 0 {
+		return c.a.Eval(env)
+	}
+	return c.b.Eval(env)
 }
 
 //!-Eval2
+
+// truth converts a boolean to the 1 or 0 that expressions use.
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
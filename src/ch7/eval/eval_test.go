@@ -26,6 +26,18 @@ func TestEval(t *testing.T) {
 		// additional tests that don't appear in the book
 		{"-1 + -x", Env{"x": 1}, "-2"},
 		{"-1 - x", Env{"x": 1}, "-2"},
+		{"x % 3", Env{"x": 7}, "1"},
+		{"-2^2", nil, "-4"},
+		{"2^3^2", nil, "512"},
+		{"1 + 2 * 3 < 7", nil, "0"},
+		{"x >= 0 && x <= 10", Env{"x": 5}, "1"},
+		{"x >= 0 && x <= 10", Env{"x": 11}, "0"},
+		{"x == 0 || 1 / x > 1", Env{"x": 0}, "1"},
+		{"!x", Env{"x": 2}, "0"},
+		{"x != 0 ? 1 / x : 0", Env{"x": 0}, "0"},
+		{"x < 0 ? -1 : x > 0 ? 1 : 0", Env{"x": 3}, "1"},
+		{"if(x < 0, -x, x)", Env{"x": -4}, "4"},
+		{"max(x, y, 3) - min(x)", Env{"x": 1, "y": 2}, "2"},
 		//!+Eval
 	}
 	var prevExpr string
@@ -73,16 +85,104 @@ pow(x, 3) + pow(y, 3)
 
 -1 + -x
 	map[x:1] => -2
+
+x % 3
+	map[x:7] => 1
+
+x >= 0 && x <= 10
+	map[x:5] => 1
+	map[x:11] => 0
+
+x < 0 ? -1 : x > 0 ? 1 : 0
+	map[x:3] => 1
 */
 
+func TestFormat(t *testing.T) {
+	for _, test := range []struct{ expr, want string }{
+		{"-x^2", "(-(x ^ 2))"},
+		{"a || b && !c", "(a || (b && (!c)))"},
+		{"a <= b == c != d", "(((a <= b) == c) != d)"},
+		{"a ? b : c ? d : e", "(a ? b : (c ? d : e))"},
+		{"if(a, b, c) + 1", "((a ? b : c) + 1)"},
+		{"max(a, b % c)", "max(a, (b % c))"},
+	} {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		if got := Format(expr); got != test.want {
+			t.Errorf("Format(%s) = %s, want %s", test.expr, got, test.want)
+		}
+		// The formatted expression must parse to the same tree.
+		again, err := Parse(Format(expr))
+		if err != nil {
+			t.Errorf("reparse %s: %v", Format(expr), err)
+			continue
+		}
+		if Format(again) != test.want {
+			t.Errorf("reparse %s: got %s", test.want, Format(again))
+		}
+	}
+}
+
+// unregister removes a function added by a test, so that the package-wide
+// registry is as the test found it.
+func unregister(name string) {
+	funcs.Lock()
+	defer funcs.Unlock()
+	delete(funcs.m, name)
+}
+
+func TestRegister(t *testing.T) {
+	if err := Register2("clamp01test", func(x, _ float64) float64 {
+		return math.Max(0, math.Min(1, x))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unregister("clamp01test") })
+	expr, err := Parse("clamp01test(x, 0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expr.Check(map[Var]bool{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := expr.Eval(Env{"x": 3}); got != 1 {
+		t.Errorf("clamp01test(3, 0) = %g, want 1", got)
+	}
+
+	for _, test := range []struct {
+		name string
+		f    Func
+	}{
+		{"clamp01test", Func{2, 2, func([]float64) float64 { return 0 }}}, // duplicate
+		{"sin", Func{1, 1, func([]float64) float64 { return 0 }}},         // built-in
+		{"if", Func{3, 3, func([]float64) float64 { return 0 }}},          // reserved
+		{"2x", Func{1, 1, func([]float64) float64 { return 0 }}},          // not an identifier
+		{"", Func{1, 1, func([]float64) float64 { return 0 }}},
+		{"nilfn", Func{1, 1, nil}},
+		{"badarity", Func{2, 1, func([]float64) float64 { return 0 }}},
+	} {
+		if err := Register(test.name, test.f); err == nil {
+			t.Errorf("Register(%q) succeeded, want error", test.name)
+		}
+	}
+}
+
 func TestErrors(t *testing.T) {
 	for _, test := range []struct{ expr, wantErr string }{
-		{"x % 2", "unexpected '%'"},
-		{"math.Pi", "unexpected '.'"},
-		{"!true", "unexpected '!'"},
-		{`"hello"`, "unexpected '\"'"},
-		{"log(10)", `unknown function "log"`},
-		{"sqrt(1, 2)", "call to sqrt has 2 args, want 1"},
+		{"x = 2", "1:3: unexpected '='"},
+		{"math.Pi", "1:5: unexpected '.'"},
+		{"x &| y", "1:3: unexpected '&'"},
+		{`"hello"`, "1:1: unexpected '\"'"},
+		{"1 +\n  * 2", "2:3: unexpected '*'"},
+		{"x ? 1", "1:6: got end of file, want ':'"},
+
+		{"exp2(10)", `1:1: unknown function "exp2"`},
+		{"sqrt(1, 2)", "1:1: call to sqrt has 2 args, want 1"},
+		{"1 + min()", "1:5: call to min has 0 args, want at least 1"},
+		{"if(x, 1)", "1:1: call to if has 2 args, want 3"},
 	} {
 		expr, err := Parse(test.expr)
 		if err == nil {
@@ -102,12 +202,17 @@ func TestErrors(t *testing.T) {
 
 /*
 //!+errors
-x % 2               unexpected '%'
-math.Pi             unexpected '.'
-!true               unexpected '!'
-"hello"             unexpected '"'
+x = 2               1:3: unexpected '='
+math.Pi             1:5: unexpected '.'
+x &| y              1:3: unexpected '&'
+"hello"             1:1: unexpected '"'
+1 +
+  * 2               2:3: unexpected '*'
+x ? 1               1:6: got end of file, want ':'
 
-log(10)             unknown function "log"
-sqrt(1, 2)          call to sqrt has 2 args, want 1
+exp2(10)            1:1: unknown function "exp2"
+sqrt(1, 2)          1:1: call to sqrt has 2 args, want 1
+1 + min()           1:5: call to min has 0 args, want at least 1
+if(x, 1)            1:1: call to if has 2 args, want 3
 //!-errors
 */
@@ -0,0 +1,157 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"text/scanner"
+)
+
+// A Func is a Go function callable from expressions.
+type Func struct {
+	MinArgs int
+	MaxArgs int // -1 for no limit
+	Fn      func(args []float64) float64
+}
+
+// arity describes the accepted argument counts, for error messages.
+func (f Func) arity() string {
+	switch {
+	case f.MinArgs == f.MaxArgs:
+		return fmt.Sprint(f.MinArgs)
+	case f.MaxArgs < 0:
+		return fmt.Sprintf("at least %d", f.MinArgs)
+	}
+	return fmt.Sprintf("%d to %d", f.MinArgs, f.MaxArgs)
+}
+
+func (f Func) accepts(n int) bool {
+	return n >= f.MinArgs && (f.MaxArgs < 0 || n <= f.MaxArgs)
+}
+
+// A registered function keeps the unwrapped form of functions added by
+// Register1 and Register2 so that compiled programs can call them without
+// building an argument slice.
+type registered struct {
+	Func
+	f1 func(float64) float64
+	f2 func(x, y float64) float64
+}
+
+var funcs struct {
+	sync.RWMutex
+	m map[string]registered
+}
+
+// Register makes f callable as name in every expression. The name must be
+// an identifier, must not be "if", and must not already be registered.
+// f.Fn must be a pure function of its arguments: Compile evaluates calls
+// with constant arguments once, ahead of time.
+func Register(name string, f Func) error {
+	return register(name, registered{Func: f})
+}
+
+func register(name string, r registered) error {
+	f := r.Func
+	if !isIdent(name) || name == "if" {
+		return fmt.Errorf("eval: cannot register %q: not a function name", name)
+	}
+	if f.Fn == nil || f.MinArgs < 0 || f.MaxArgs >= 0 && f.MaxArgs < f.MinArgs {
+		return fmt.Errorf("eval: cannot register %s: bad arity or nil function", name)
+	}
+	funcs.Lock()
+	defer funcs.Unlock()
+	if _, ok := funcs.m[name]; ok {
+		return fmt.Errorf("eval: %s already registered", name)
+	}
+	funcs.m[name] = r
+	return nil
+}
+
+// Register1 registers a function of one argument.
+func Register1(name string, fn func(float64) float64) error {
+	if fn == nil {
+		return Register(name, Func{1, 1, nil})
+	}
+	return register(name, registered{
+		Func: Func{1, 1, func(a []float64) float64 { return fn(a[0]) }},
+		f1:   fn,
+	})
+}
+
+// Register2 registers a function of two arguments.
+func Register2(name string, fn func(x, y float64) float64) error {
+	if fn == nil {
+		return Register(name, Func{2, 2, nil})
+	}
+	return register(name, registered{
+		Func: Func{2, 2, func(a []float64) float64 { return fn(a[0], a[1]) }},
+		f2:   fn,
+	})
+}
+
+// Funcs returns the registered function names in order.
+func Funcs() []string {
+	funcs.RLock()
+	defer funcs.RUnlock()
+	names := make([]string, 0, len(funcs.m))
+	for name := range funcs.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookup(name string) (registered, bool) {
+	funcs.RLock()
+	defer funcs.RUnlock()
+	f, ok := funcs.m[name]
+	return f, ok
+}
+
+func isIdent(s string) bool {
+	var sc scanner.Scanner
+	sc.Init(strings.NewReader(s))
+	sc.Mode = scanner.ScanIdents
+	sc.Error = func(*scanner.Scanner, string) {}
+	return sc.Scan() == scanner.Ident && sc.TokenText() == s && sc.Scan() == scanner.EOF
+}
+
+func init() {
+	funcs.m = make(map[string]registered)
+	for name, fn := range map[string]func(float64) float64{
+		"sin": math.Sin, "cos": math.Cos, "tan": math.Tan,
+		"asin": math.Asin, "acos": math.Acos, "atan": math.Atan,
+		"sinh": math.Sinh, "cosh": math.Cosh, "tanh": math.Tanh,
+		"sqrt": math.Sqrt, "cbrt": math.Cbrt,
+		"exp": math.Exp, "log": math.Log, "log2": math.Log2, "log10": math.Log10,
+		"abs": math.Abs, "floor": math.Floor, "ceil": math.Ceil,
+		"round": math.Round, "trunc": math.Trunc,
+	} {
+		Register1(name, fn)
+	}
+	for name, fn := range map[string]func(float64, float64) float64{
+		"pow": math.Pow, "atan2": math.Atan2, "hypot": math.Hypot, "mod": math.Mod,
+	} {
+		Register2(name, fn)
+	}
+	Register("min", Func{1, -1, func(a []float64) float64 {
+		m := a[0]
+		for _, x := range a[1:] {
+			m = math.Min(m, x)
+		}
+		return m
+	}})
+	Register("max", Func{1, -1, func(a []float64) float64 {
+		m := a[0]
+		for _, x := range a[1:] {
+			m = math.Max(m, x)
+		}
+		return m
+	}})
+}
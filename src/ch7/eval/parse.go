@@ -10,18 +10,60 @@ import (
 	"text/scanner"
 )
 
+// An Error is a syntax or check error, located in the input.
+type Error struct {
+	Line, Col int
+	Msg       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+func errorAt(pos position, format string, args ...interface{}) *Error {
+	return &Error{Line: pos.line, Col: pos.col, Msg: fmt.Sprintf(format, args...)}
+}
+
 // ---- lexer ----
 
 // This lexer is similar to the one described in Chapter 13.
 type lexer struct {
 	scan  scanner.Scanner
-	token rune // current lookahead token
+	token rune     // current lookahead token
+	pos   position // of token
+	lit   string   // text of token; two-character operators are combined
+}
+
+// pairs maps two-character operators to their tokens.
+var pairs = map[[2]rune]rune{
+	{'=', '='}: opEq, {'!', '='}: opNe, {'<', '='}: opLe,
+	{'>', '='}: opGe, {'&', '&'}: opAnd, {'|', '|'}: opOr,
+}
+
+// next scans the next token, combining the two-character operators.
+func (lex *lexer) next() {
+	lex.token = lex.scan.Scan()
+	lex.pos = position{lex.scan.Position.Line, lex.scan.Position.Column}
+	lex.lit = lex.scan.TokenText()
+	if op, ok := pairs[[2]rune{lex.token, lex.scan.Peek()}]; ok {
+		lex.scan.Next()
+		lex.token = op
+		lex.lit = opString(op)
+	} else if lex.token == '=' || lex.token == '&' || lex.token == '|' {
+		// Only valid doubled; keep them apart from opEq, opAnd, opOr.
+		lex.token = invalid
+	}
 }
 
-func (lex *lexer) next()        { lex.token = lex.scan.Scan() }
-func (lex *lexer) text() string { return lex.scan.TokenText() }
+func (lex *lexer) text() string { return lex.lit }
 
-type lexPanic string
+// invalid is the token for a lone '=', '&' or '|'.
+const invalid = -100
+
+// fail aborts parsing with an error at the current token.
+func (lex *lexer) fail(format string, args ...interface{}) {
+	panic(errorAt(lex.pos, format, args...))
+}
 
 // describe returns a string describing the current token, for use in errors.
 func (lex *lexer) describe() string {
@@ -32,15 +74,27 @@ func (lex *lexer) describe() string {
 		return fmt.Sprintf("identifier %s", lex.text())
 	case scanner.Int, scanner.Float:
 		return fmt.Sprintf("number %s", lex.text())
+	case opEq, opNe, opLe, opGe, opAnd, opOr:
+		return fmt.Sprintf("%q", lex.text())
+	case invalid:
+		return fmt.Sprintf("%q", []rune(lex.text())[0])
 	}
 	return fmt.Sprintf("%q", rune(lex.token)) // any other rune
 }
 
 func precedence(op rune) int {
 	switch op {
-	case '*', '/':
-		return 2
+	case '*', '/', '%':
+		return 6
 	case '+', '-':
+		return 5
+	case '<', '>', opLe, opGe:
+		return 4
+	case opEq, opNe:
+		return 3
+	case opAnd:
+		return 2
+	case opOr:
 		return 1
 	}
 	return 0
@@ -50,19 +104,26 @@ func precedence(op rune) int {
 
 // Parse parses the input string as an arithmetic expression.
 //
-//   expr = num                         a literal number, e.g., 3.14159
-//        | id                          a variable name, e.g., x
-//        | id '(' expr ',' ... ')'     a function call
-//        | '-' expr                    a unary operator (+-)
-//        | expr '+' expr               a binary operator (+-*/)
+//	expr = num                         a literal number, e.g., 3.14159
+//	     | id                          a variable name, e.g., x
+//	     | id '(' expr ',' ... ')'     a function call
+//	     | '-' expr                    a unary operator (+-!)
+//	     | expr '+' expr               a binary operator (+-*/%^ < <= > >= == != && ||)
+//	     | expr '?' expr ':' expr      a conditional, also written if(c, a, b)
+//
+// Comparisons and logical operators yield 1 for true and 0 for false, and
+// treat any non-zero operand as true. From loosest to tightest binding the
+// operators are ?:, ||, &&, == !=, < <= > >=, + -, * / %, unary + - !,
+// and ^, which groups to the right: -2^2 is -4 and 2^3^2 is 512.
 //
+// Errors are of type *Error.
 func Parse(input string) (_ Expr, err error) {
 	defer func() {
 		switch x := recover().(type) {
 		case nil:
 			// no panic
-		case lexPanic:
-			err = fmt.Errorf("%s", x)
+		case *Error:
+			err = x
 		default:
 			// unexpected panic: resume state of panic.
 			panic(x)
@@ -71,15 +132,31 @@ func Parse(input string) (_ Expr, err error) {
 	lex := new(lexer)
 	lex.scan.Init(strings.NewReader(input))
 	lex.scan.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats
+	lex.scan.Error = func(s *scanner.Scanner, msg string) {
+		panic(errorAt(position{s.Position.Line, s.Position.Column}, "%s", msg))
+	}
 	lex.next() // initial lookahead
 	e := parseExpr(lex)
 	if lex.token != scanner.EOF {
-		return nil, fmt.Errorf("unexpected %s", lex.describe())
+		lex.fail("unexpected %s", lex.describe())
 	}
 	return e, nil
 }
 
-func parseExpr(lex *lexer) Expr { return parseBinary(lex, 1) }
+// expr = binary ['?' expr ':' expr]
+func parseExpr(lex *lexer) Expr {
+	c := parseBinary(lex, 1)
+	if lex.token != '?' {
+		return c
+	}
+	lex.next() // consume '?'
+	a := parseExpr(lex)
+	if lex.token != ':' {
+		lex.fail("got %s, want ':'", lex.describe())
+	}
+	lex.next() // consume ':'
+	return cond{c, a, parseExpr(lex)}
+}
 
 // binary = unary ('+' binary)*
 // parseBinary stops when it encounters an
@@ -97,14 +174,24 @@ func parseBinary(lex *lexer, prec1 int) Expr {
 	return lhs
 }
 
-// unary = '+' expr | primary
+// unary = ('+' | '-' | '!') unary | power
 func parseUnary(lex *lexer) Expr {
-	if lex.token == '+' || lex.token == '-' {
+	if lex.token == '+' || lex.token == '-' || lex.token == '!' {
 		op := lex.token
-		lex.next() // consume '+' or '-'
+		lex.next() // consume operator
 		return unary{op, parseUnary(lex)}
 	}
-	return parsePrimary(lex)
+	return parsePower(lex)
+}
+
+// power = primary ['^' unary]
+func parsePower(lex *lexer) Expr {
+	x := parsePrimary(lex)
+	if lex.token != '^' {
+		return x
+	}
+	lex.next() // consume '^'
+	return binary{'^', x, parseUnary(lex)}
 }
 
 // primary = id
@@ -114,7 +201,7 @@ func parseUnary(lex *lexer) Expr {
 func parsePrimary(lex *lexer) Expr {
 	switch lex.token {
 	case scanner.Ident:
-		id := lex.text()
+		id, pos := lex.text(), lex.pos
 		lex.next() // consume Ident
 		if lex.token != '(' {
 			return Var(id)
@@ -130,31 +217,35 @@ func parsePrimary(lex *lexer) Expr {
 				lex.next() // consume ','
 			}
 			if lex.token != ')' {
-				msg := fmt.Sprintf("got %q, want ')'", lex.token)
-				panic(lexPanic(msg))
+				lex.fail("got %s, want ')'", lex.describe())
 			}
 		}
 		lex.next() // consume ')'
-		return call{id, args}
+		if id == "if" {
+			if len(args) != 3 {
+				panic(errorAt(pos, "call to if has %d args, want 3", len(args)))
+			}
+			return cond{args[0], args[1], args[2]}
+		}
+		return call{id, args, pos}
 
 	case scanner.Int, scanner.Float:
 		f, err := strconv.ParseFloat(lex.text(), 64)
 		if err != nil {
-			panic(lexPanic(err.Error()))
+			lex.fail("%s", err)
 		}
 		lex.next() // consume number
 		return literal(f)
 
 	case '(':
-		lex.next() // consume ')'
+		lex.next() // consume '('
 		e := parseExpr(lex)
 		if lex.token != ')' {
-			msg := fmt.Sprintf("got %s, want ')'", lex.describe())
-			panic(lexPanic(msg))
+			lex.fail("got %s, want ')'", lex.describe())
 		}
 		lex.next() // consume ')'
 		return e
 	}
-	msg := fmt.Sprintf("unexpected %s", lex.describe())
-	panic(lexPanic(msg))
+	lex.fail("unexpected %s", lex.describe())
+	panic("unreachable")
 }
@@ -19,7 +19,12 @@ func Format(e Expr) string {
 func write(buf *bytes.Buffer, e Expr) {
 	switch e := e.(type) {
 	case literal:
-		fmt.Fprintf(buf, "%g", e)
+		if e < 0 {
+			// Parenthesized, or -2^x would read back as -(2^x).
+			fmt.Fprintf(buf, "(%g)", e)
+		} else {
+			fmt.Fprintf(buf, "%g", e)
+		}
 
 	case Var:
 		fmt.Fprintf(buf, "%s", e)
@@ -32,7 +37,7 @@ func write(buf *bytes.Buffer, e Expr) {
 	case binary:
 		buf.WriteByte('(')
 		write(buf, e.x)
-		fmt.Fprintf(buf, " %c ", e.op)
+		fmt.Fprintf(buf, " %s ", opString(e.op))
 		write(buf, e.y)
 		buf.WriteByte(')')
 
@@ -46,6 +51,18 @@ func write(buf *bytes.Buffer, e Expr) {
 		}
 		buf.WriteByte(')')
 
+	case cond:
+		buf.WriteByte('(')
+		write(buf, e.c)
+		buf.WriteString(" ? ")
+		write(buf, e.a)
+		buf.WriteString(" : ")
+		write(buf, e.b)
+		buf.WriteByte(')')
+
+	case *Program:
+		write(buf, e.src)
+
 	default:
 		panic(fmt.Sprintf("unknown Expr: %T", e))
 	}
@@ -0,0 +1,284 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package eval
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Simplify returns an expression equivalent to e with constant
+// subexpressions computed, identities such as x*1, x+0 and x^1 removed,
+// and like terms and factors collected: x + 2*x is 3*x and x*y/x is y.
+//
+// Like any computer algebra system it assumes that variables are finite:
+// x*0 and x-x simplify to 0 although they are NaN when x is infinite,
+// and x/x simplifies to 1 although it is NaN when x is 0.
+func Simplify(e Expr) Expr {
+	switch e := e.(type) {
+	case unary:
+		x := Simplify(e.x)
+		switch e.op {
+		case '+':
+			return x
+		case '-':
+			return sum(unary{e.op, x})
+		}
+		return constant(unary{e.op, x})
+
+	case binary:
+		x, y := Simplify(e.x), Simplify(e.y)
+		switch e.op {
+		case '+', '-', '*', '/', '^':
+			return sum(binary{e.op, x, y})
+		case opAnd, opOr:
+			// The left operand alone may decide the result.
+			if x, ok := x.(literal); ok && (x != 0) == (e.op == opOr) {
+				return literal(truth(e.op == opOr))
+			}
+		}
+		return constant(binary{e.op, x, y})
+
+	case cond:
+		c, a, b := Simplify(e.c), Simplify(e.a), Simplify(e.b)
+		if c, ok := c.(literal); ok {
+			if c != 0 {
+				return a
+			}
+			return b
+		}
+		if Format(a) == Format(b) {
+			return a
+		}
+		return cond{c, a, b}
+
+	case call:
+		args := make([]Expr, len(e.args))
+		for i, arg := range e.args {
+			args[i] = Simplify(arg)
+		}
+		if e.fn == "pow" {
+			return sum(binary{'^', args[0], args[1]})
+		}
+		return constant(call{e.fn, args, e.pos})
+
+	case *Program:
+		return Simplify(e.src)
+	}
+	return e
+}
+
+// constant returns the value of e if its operands are literals and the
+// value is finite. NaN and ±Inf have no literal syntax, so they stay as
+// the expressions that produce them.
+func constant(e Expr) Expr {
+	var operands []Expr
+	switch e := e.(type) {
+	case unary:
+		operands = []Expr{e.x}
+	case binary:
+		operands = []Expr{e.x, e.y}
+	case call:
+		operands = e.args
+	}
+	for _, x := range operands {
+		if _, ok := x.(literal); !ok {
+			return e
+		}
+	}
+	if v := e.Eval(nil); !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return literal(v)
+	}
+	return e
+}
+
+// A term is a product coef * base₀^exp₀ * base₁^exp₁ * ...
+type term struct {
+	coef    float64
+	factors []factor
+}
+
+type factor struct {
+	base Expr
+	exp  float64
+	key  string // Format(base)
+}
+
+// key identifies the terms that can be added together.
+func (t *term) key() string {
+	var keys []string
+	for _, f := range t.factors {
+		if f.exp != 0 {
+			keys = append(keys, fmt.Sprintf("%s^%g", f.key, f.exp))
+		}
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, " ")
+}
+
+// mul multiplies t by e^exp.
+func (t *term) mul(e Expr, exp float64) {
+	switch e := e.(type) {
+	case literal:
+		t.coef *= math.Pow(float64(e), exp)
+		return
+	case unary:
+		if e.op == '-' && exp == math.Trunc(exp) {
+			t.coef *= math.Pow(-1, exp)
+			t.mul(e.x, exp)
+			return
+		}
+	case binary:
+		switch e.op {
+		case '*':
+			t.mul(e.x, exp)
+			t.mul(e.y, exp)
+			return
+		case '/':
+			t.mul(e.x, exp)
+			t.mul(e.y, -exp)
+			return
+		case '^':
+			// (b^k)^exp is b^(k*exp) only when exp is an integer.
+			if k, ok := e.y.(literal); ok && exp == math.Trunc(exp) {
+				t.mul(e.x, float64(k)*exp)
+				return
+			}
+		}
+	}
+	key := Format(e)
+	for i := range t.factors {
+		if t.factors[i].key == key {
+			t.factors[i].exp += exp
+			return
+		}
+	}
+	t.factors = append(t.factors, factor{e, exp, key})
+}
+
+// expr returns the term as an expression, ignoring the sign of coef.
+func (t *term) expr() Expr {
+	var num, den Expr
+	times := func(x, y Expr) Expr {
+		if x == nil {
+			return y
+		}
+		return binary{'*', x, y}
+	}
+	for _, f := range t.factors {
+		switch {
+		case f.exp > 0:
+			num = times(num, power(f.base, f.exp))
+		case f.exp < 0:
+			den = times(den, power(f.base, -f.exp))
+		}
+	}
+	switch c := literal(math.Abs(t.coef)); {
+	case num == nil:
+		num = c
+	case c != 1:
+		num = binary{'*', c, num}
+	}
+	if den != nil {
+		return binary{'/', num, den}
+	}
+	return num
+}
+
+func power(base Expr, exp float64) Expr {
+	if exp == 1 {
+		return base
+	}
+	return binary{'^', base, literal(exp)}
+}
+
+// sum simplifies a sum of products whose operands are already simple.
+func sum(e Expr) Expr {
+	var terms []*term
+	index := make(map[string]*term)
+	var add func(e Expr, sign float64)
+	add = func(e Expr, sign float64) {
+		switch e := e.(type) {
+		case binary:
+			switch e.op {
+			case '+':
+				add(e.x, sign)
+				add(e.y, sign)
+				return
+			case '-':
+				add(e.x, sign)
+				add(e.y, -sign)
+				return
+			}
+		case unary:
+			if e.op == '-' {
+				add(e.x, -sign)
+				return
+			}
+		}
+		t := &term{coef: sign}
+		t.mul(e, 1)
+		if math.IsNaN(t.coef) || math.IsInf(t.coef, 0) {
+			// Leave terms such as x/0 as they are; see constant.
+			t = &term{coef: sign, factors: []factor{{e, 1, Format(e)}}}
+		}
+		k := t.key()
+		if prev, ok := index[k]; ok {
+			prev.coef += t.coef
+			return
+		}
+		index[k] = t
+		terms = append(terms, t)
+	}
+	add(e, 1)
+
+	var result Expr
+	for _, t := range terms {
+		if t.coef == 0 {
+			continue
+		}
+		x := t.expr()
+		switch {
+		case result == nil && t.coef < 0:
+			result = unary{'-', x}
+		case result == nil:
+			result = x
+		case t.coef < 0:
+			result = binary{'-', result, x}
+		default:
+			result = binary{'+', result, x}
+		}
+	}
+	if result == nil {
+		return literal(0)
+	}
+	return result
+}
+
+// Substitute returns e with every occurrence of v replaced by x.
+func Substitute(e Expr, v Var, x Expr) Expr {
+	switch e := e.(type) {
+	case Var:
+		if e == v {
+			return x
+		}
+	case unary:
+		return unary{e.op, Substitute(e.x, v, x)}
+	case binary:
+		return binary{e.op, Substitute(e.x, v, x), Substitute(e.y, v, x)}
+	case cond:
+		return cond{Substitute(e.c, v, x), Substitute(e.a, v, x), Substitute(e.b, v, x)}
+	case call:
+		args := make([]Expr, len(e.args))
+		for i, arg := range e.args {
+			args[i] = Substitute(arg, v, x)
+		}
+		return call{e.fn, args, e.pos}
+	case *Program:
+		return Substitute(e.src, v, x)
+	}
+	return e
+}
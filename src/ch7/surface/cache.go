@@ -0,0 +1,62 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"sync"
+
+	"gopl.io/ch9/memo4"
+)
+
+// Limits on what the cache holds. Queries are arbitrary, so without
+// them the memo would grow until the server ran out of memory.
+const (
+	maxCached      = 1000     // plots
+	maxCachedBytes = 64 << 20 // of keys and plot bodies
+)
+
+// A cache memoizes a render function with gopl.io/ch9/memo4, which
+// never forgets. To stay within its limits the cache starts a fresh
+// memo once the current one is full; the plots in the old one are
+// rendered again if they are asked for.
+type cache struct {
+	f     memo.Func
+	mu    sync.Mutex
+	memo  *memo.Memo
+	keys  map[string]bool // the keys in memo
+	bytes int             // their total size
+}
+
+func newCache(f func(key string) (*plot, error)) *cache {
+	c := &cache{f: func(key string) (interface{}, error) { return f(key) }}
+	c.reset()
+	return c
+}
+
+func (c *cache) reset() {
+	c.memo, c.keys, c.bytes = memo.New(c.f), make(map[string]bool), 0
+}
+
+func (c *cache) get(key string) (*plot, error) {
+	c.mu.Lock()
+	m := c.memo
+	c.mu.Unlock()
+
+	v, err := m.Get(key)
+	pl, _ := v.(*plot)
+
+	c.mu.Lock()
+	if m == c.memo && !c.keys[key] {
+		c.keys[key] = true
+		c.bytes += len(key)
+		if pl != nil {
+			c.bytes += len(pl.body)
+		}
+		if len(c.keys) >= maxCached || c.bytes >= maxCachedBytes {
+			c.reset()
+		}
+	}
+	c.mu.Unlock()
+	return pl, err
+}
@@ -0,0 +1,183 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+
+	"gopl.io/ch7/eval"
+)
+
+// A plotParams describes one plot. Every field comes from the query
+// string of a /plot request; see parseParams for names and defaults.
+type plotParams struct {
+	expr       eval.Expr // checked, in terms of x and y only
+	xmin, xmax float64
+	ymin, ymax float64
+	cells      int     // grid resolution along each axis
+	width      int     // of each panel, in pixels
+	height     int     // of each panel, in pixels
+	zscale     float64 // pixels per z unit, or 0 to fit the panel
+	colormap   string
+	format     string // "svg" or "png"
+	partials   bool   // also plot ∂f/∂x and ∂f/∂y
+}
+
+// Limits on parameters, to bound the work done for one request.
+const (
+	maxCells = 400
+	maxSize  = 4000 // pixels
+)
+
+// A paramError is a problem with one query parameter. It is sent to the
+// client as the JSON body of a 400 response.
+type paramError struct {
+	Param string `json:"param"`
+	Value string `json:"value"`
+	Msg   string `json:"error"`
+	Line  int    `json:"line,omitempty"` // of an error in expr
+	Col   int    `json:"col,omitempty"`
+}
+
+func (e *paramError) Error() string {
+	return fmt.Sprintf("bad %s %q: %s", e.Param, e.Value, e.Msg)
+}
+
+// parseParams validates the query of a /plot request.
+//
+//	expr      the function of x and y to plot; r stands for hypot(x, y)
+//	xmin...   the domain, -15 to 15 on each axis by default
+//	cells     grid cells along each axis (100)
+//	width     width of each panel in pixels (600)
+//	height    height of each panel in pixels (320)
+//	zscale    pixels per unit of height (0.4 * height), or "fit"
+//	colormap  none, gray, heat or viridis, by height (none)
+//	format    svg or png (svg)
+//	partials  if true, also plot the partial derivatives in x and y
+func parseParams(q url.Values) (*plotParams, error) {
+	p := &plotParams{format: "svg", colormap: "none"}
+	var err error
+	if p.expr, err = parseAndCheck(q.Get("expr")); err != nil {
+		perr := &paramError{Param: "expr", Value: q.Get("expr"), Msg: err.Error()}
+		var eerr *eval.Error
+		if errors.As(err, &eerr) {
+			perr.Msg, perr.Line, perr.Col = eerr.Msg, eerr.Line, eerr.Col
+		}
+		return nil, perr
+	}
+
+	// All the numeric parameters are checked the same way.
+	floats := []struct {
+		name     string
+		ptr      *float64
+		def      float64
+		min, max float64
+	}{
+		{"xmin", &p.xmin, -15, -1e9, 1e9},
+		{"xmax", &p.xmax, 15, -1e9, 1e9},
+		{"ymin", &p.ymin, -15, -1e9, 1e9},
+		{"ymax", &p.ymax, 15, -1e9, 1e9},
+	}
+	for _, f := range floats {
+		*f.ptr = f.def
+		if s := q.Get(f.name); s != "" {
+			x, err := strconv.ParseFloat(s, 64)
+			if err != nil || math.IsNaN(x) || x < f.min || x > f.max {
+				return nil, &paramError{f.name, s,
+					fmt.Sprintf("want a number between %g and %g", f.min, f.max), 0, 0}
+			}
+			*f.ptr = x
+		}
+	}
+	if p.xmin >= p.xmax {
+		return nil, &paramError{"xmax", q.Get("xmax"), "must be greater than xmin", 0, 0}
+	}
+	if p.ymin >= p.ymax {
+		return nil, &paramError{"ymax", q.Get("ymax"), "must be greater than ymin", 0, 0}
+	}
+
+	ints := []struct {
+		name     string
+		ptr      *int
+		def      int
+		min, max int
+	}{
+		{"cells", &p.cells, 100, 1, maxCells},
+		{"width", &p.width, 600, 16, maxSize},
+		{"height", &p.height, 320, 16, maxSize},
+	}
+	for _, f := range ints {
+		*f.ptr = f.def
+		if s := q.Get(f.name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < f.min || n > f.max {
+				return nil, &paramError{f.name, s,
+					fmt.Sprintf("want an integer between %d and %d", f.min, f.max), 0, 0}
+			}
+			*f.ptr = n
+		}
+	}
+
+	switch s := q.Get("zscale"); s {
+	case "":
+		p.zscale = float64(p.height) * 0.4
+	case "fit":
+		p.zscale = 0
+	default:
+		z, err := strconv.ParseFloat(s, 64)
+		if err != nil || !(z > 0) || math.IsInf(z, 0) {
+			return nil, &paramError{"zscale", s, `want a positive number or "fit"`, 0, 0}
+		}
+		p.zscale = z
+	}
+
+	if s := q.Get("colormap"); s != "" {
+		if _, ok := colormaps[s]; !ok {
+			return nil, &paramError{"colormap", s, "want none, gray, heat or viridis", 0, 0}
+		}
+		p.colormap = s
+	}
+	if s := q.Get("format"); s != "" {
+		if s != "svg" && s != "png" {
+			return nil, &paramError{"format", s, "want svg or png", 0, 0}
+		}
+		p.format = s
+	}
+	if s := q.Get("partials"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, &paramError{"partials", s, "want true or false", 0, 0}
+		}
+		p.partials = b
+	}
+	return p, nil
+}
+
+// key returns a canonical query string for p, which parseParams accepts.
+// Requests that differ only in spelling share a key, and so a cache entry.
+func (p *plotParams) key() string {
+	q := url.Values{}
+	q.Set("expr", eval.Format(p.expr))
+	for name, x := range map[string]float64{
+		"xmin": p.xmin, "xmax": p.xmax, "ymin": p.ymin, "ymax": p.ymax,
+	} {
+		q.Set(name, strconv.FormatFloat(x, 'g', -1, 64))
+	}
+	q.Set("cells", strconv.Itoa(p.cells))
+	q.Set("width", strconv.Itoa(p.width))
+	q.Set("height", strconv.Itoa(p.height))
+	if p.zscale == 0 {
+		q.Set("zscale", "fit")
+	} else {
+		q.Set("zscale", strconv.FormatFloat(p.zscale, 'g', -1, 64))
+	}
+	q.Set("colormap", p.colormap)
+	q.Set("format", p.format)
+	q.Set("partials", strconv.FormatBool(p.partials))
+	return q.Encode() // sorted by name
+}
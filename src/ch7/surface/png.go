@@ -0,0 +1,145 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"slices"
+)
+
+var edge = color.RGBA{0x80, 0x80, 0x80, 0xff} // the SVG stroke, grey
+
+// workPerPixel bounds the drawing done for one PNG: pixels painted plus
+// scanlines visited, per pixel of the image. A smooth surface paints
+// each pixel about once; a spiky one can overdraw without limit.
+const workPerPixel = 8
+
+var errTooDetailed = errors.New("plot too detailed to draw; try fewer cells or a smaller zscale")
+
+// writePNG draws the panels side by side as a PNG image. Labels are
+// drawn only in SVG.
+func writePNG(w io.Writer, p *plotParams, ps []panel) error {
+	img := image.NewRGBA(image.Rect(0, 0, p.width*len(ps), p.height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	c := &canvas{img: img, work: workPerPixel * img.Bounds().Dx() * img.Bounds().Dy()}
+	for k, pan := range ps {
+		dx := float64(k * p.width)
+		c.clip = image.Rect(k*p.width, 0, (k+1)*p.width, p.height)
+		for _, q := range pan.quads {
+			var pts [4][2]float64
+			for i, pt := range q.pts {
+				pts[i] = [2]float64{pt[0] + dx, pt[1]}
+			}
+			c.fill(pts, q.fill)
+			for i := range pts {
+				c.line(pts[i], pts[(i+1)%4], edge)
+			}
+			if c.work < 0 {
+				return errTooDetailed
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// A canvas draws into one panel of an image, within a budget.
+type canvas struct {
+	img  *image.RGBA
+	clip image.Rectangle // the current panel
+	work int             // pixels and scanlines left to spend
+}
+
+// fill paints the pixels whose centers lie inside the quadrilateral.
+func (c *canvas) fill(pts [4][2]float64, col color.RGBA) {
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, pt := range pts {
+		minY, maxY = math.Min(minY, pt[1]), math.Max(maxY, pt[1])
+	}
+	y0 := max(c.clip.Min.Y, int(math.Floor(minY)))
+	y1 := min(c.clip.Max.Y-1, int(math.Ceil(maxY)))
+	for y := y0; y <= y1 && c.work >= 0; y++ {
+		c.work--
+		cy := float64(y) + 0.5
+		// Find where the edges cross this scanline.
+		var crossings [4]float64
+		xs := crossings[:0]
+		for i := range pts {
+			a, b := pts[i], pts[(i+1)%4]
+			if (a[1] <= cy) != (b[1] <= cy) {
+				xs = append(xs, a[0]+(cy-a[1])/(b[1]-a[1])*(b[0]-a[0]))
+			}
+		}
+		// Fill between pairs of crossings (even-odd rule).
+		slices.Sort(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			x0 := max(c.clip.Min.X, int(math.Ceil(xs[i]-0.5)))
+			x1 := min(c.clip.Max.X-1, int(math.Ceil(xs[i+1]-0.5))-1)
+			if x0 > x1 {
+				continue
+			}
+			row := c.img.Pix[c.img.PixOffset(x0, y):c.img.PixOffset(x1+1, y)]
+			for j := 0; j < len(row); j += 4 {
+				row[j], row[j+1], row[j+2], row[j+3] = col.R, col.G, col.B, col.A
+			}
+			c.work -= x1 - x0 + 1
+		}
+	}
+}
+
+// line draws a one-pixel line from a to b.
+func (c *canvas) line(a, b [2]float64, col color.RGBA) {
+	a, b, ok := clipLine(c.clip, a, b)
+	if !ok {
+		return
+	}
+	n := int(math.Max(math.Abs(b[0]-a[0]), math.Abs(b[1]-a[1]))) + 1
+	c.work -= n
+	for i := 0; i <= n; i++ {
+		t := float64(i) / float64(n)
+		pt := image.Pt(int(a[0]+t*(b[0]-a[0])), int(a[1]+t*(b[1]-a[1])))
+		if pt.In(c.clip) {
+			c.img.SetRGBA(pt.X, pt.Y, col)
+		}
+	}
+}
+
+// clipLine returns the part of the segment from a to b inside r, by the
+// Liang-Barsky method, or false if there is none.
+func clipLine(r image.Rectangle, a, b [2]float64) ([2]float64, [2]float64, bool) {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	t0, t1 := 0.0, 1.0
+	for _, pq := range [4][2]float64{
+		{-dx, a[0] - float64(r.Min.X)},
+		{dx, float64(r.Max.X) - a[0]},
+		{-dy, a[1] - float64(r.Min.Y)},
+		{dy, float64(r.Max.Y) - a[1]},
+	} {
+		p, q := pq[0], pq[1]
+		if p == 0 {
+			if q < 0 {
+				return a, b, false // parallel to this edge and outside it
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			if t > t1 {
+				return a, b, false
+			}
+			t0 = math.Max(t0, t)
+		} else {
+			if t < t0 {
+				return a, b, false
+			}
+			t1 = math.Min(t1, t)
+		}
+	}
+	return [2]float64{a[0] + t0*dx, a[1] + t0*dy}, [2]float64{a[0] + t1*dx, a[1] + t1*dy}, true
+}
@@ -0,0 +1,181 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"gopl.io/ch7/eval"
+)
+
+var sin30, cos30 = 0.5, math.Sqrt(3.0 / 4.0) // sin(30°), cos(30°)
+
+const offPanel = 2 // panel heights beyond which a cell is not drawn
+
+// A panel is one projected surface, ready to draw.
+type panel struct {
+	label string
+	quads []quad // back to front
+}
+
+// A quad is the projection of one grid cell.
+type quad struct {
+	pts  [4][2]float64 // (sx, sy) in pixels
+	fill color.RGBA
+}
+
+// panels computes the surfaces to draw for p: f, and its partial
+// derivatives if requested.
+func panels(p *plotParams) ([]panel, error) {
+	exprs := []eval.Expr{p.expr}
+	labels := []string{eval.Format(p.expr)}
+	if p.partials {
+		for _, v := range []eval.Var{"x", "y"} {
+			d := eval.Derive(p.expr, v)
+			exprs = append(exprs, d)
+			labels = append(labels, "∂/∂"+string(v)+" = "+eval.Format(d))
+		}
+	}
+	var ps []panel
+	for i, e := range exprs {
+		prog, err := eval.Compile(e)
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, panel{labels[i], project(p, sample(p, compiled(prog)))})
+	}
+	return ps, nil
+}
+
+// compiled returns the surface function computed by prog, which may use
+// the variables x and y.
+func compiled(prog *eval.Program) func(x, y float64) float64 {
+	slots := make([]float64, len(prog.Vars()))
+	sx, sy := prog.Slot("x"), prog.Slot("y")
+	return func(x, y float64) float64 {
+		if sx >= 0 {
+			slots[sx] = x
+		}
+		if sy >= 0 {
+			slots[sy] = y
+		}
+		return prog.Exec(slots)
+	}
+}
+
+// A grid holds the height of the surface at each corner of each cell.
+type grid struct {
+	n          int       // cells along each axis
+	z          []float64 // z[i*(n+1)+j] is the height at corner (i, j)
+	zmin, zmax float64   // over finite heights
+}
+
+func (g *grid) at(i, j int) float64 { return g.z[i*(g.n+1)+j] }
+
+func sample(p *plotParams, f func(x, y float64) float64) *grid {
+	n := p.cells
+	g := &grid{n: n, z: make([]float64, (n+1)*(n+1)),
+		zmin: math.Inf(1), zmax: math.Inf(-1)}
+	for i := 0; i <= n; i++ {
+		x := p.xmin + (p.xmax-p.xmin)*float64(i)/float64(n)
+		for j := 0; j <= n; j++ {
+			y := p.ymin + (p.ymax-p.ymin)*float64(j)/float64(n)
+			z := f(x, y)
+			g.z[i*(n+1)+j] = z
+			if finite(z) {
+				g.zmin = math.Min(g.zmin, z)
+				g.zmax = math.Max(g.zmax, z)
+			}
+		}
+	}
+	return g
+}
+
+func finite(z float64) bool { return !math.IsNaN(z) && !math.IsInf(z, 0) }
+
+// project returns the cells of g as seen from above at 30°, in the
+// order the book draws them. A cell with a corner where the function
+// is NaN or infinite is left out, leaving a hole in the surface, as is
+// one that reaches far off the panel, which would only be overdrawn.
+func project(p *plotParams, g *grid) []quad {
+	w, h := float64(p.width), float64(p.height)
+	zscale, zmid := p.zscale, 0.0
+	if zscale == 0 && g.zmax > g.zmin {
+		// Span the heights as the default zscale spans -1 to 1.
+		zscale = 0.8 * h / (g.zmax - g.zmin)
+		zmid = (g.zmin + g.zmax) / 2
+	}
+	cmap := colormaps[p.colormap]
+	corner := func(i, j int) (float64, float64, float64) {
+		// Like the book, map the domain to a square of side w/2.
+		u := float64(i)/float64(g.n) - 0.5
+		v := float64(j)/float64(g.n) - 0.5
+		z := g.at(i, j)
+		sx := w/2 + (u-v)*cos30*w/2
+		sy := h/2 + (u+v)*sin30*w/2 - (z-zmid)*zscale
+		return sx, sy, z
+	}
+	var quads []quad
+	for i := 0; i < g.n; i++ {
+		for j := 0; j < g.n; j++ {
+			var q quad
+			var zsum float64
+			ok := true
+			for k, c := range [4][2]int{{i + 1, j}, {i, j}, {i, j + 1}, {i + 1, j + 1}} {
+				sx, sy, z := corner(c[0], c[1])
+				if !finite(z) || math.Abs(sy) > offPanel*h {
+					ok = false
+					break
+				}
+				q.pts[k] = [2]float64{sx, sy}
+				zsum += z
+			}
+			if !ok {
+				continue
+			}
+			t := 0.5
+			if g.zmax > g.zmin {
+				t = (zsum/4 - g.zmin) / (g.zmax - g.zmin)
+			}
+			q.fill = cmap(t)
+			quads = append(quads, q)
+		}
+	}
+	return quads
+}
+
+// A colormap maps a height, scaled to [0, 1], to a fill color.
+type colormap func(t float64) color.RGBA
+
+var colormaps = map[string]colormap{
+	"none": func(float64) color.RGBA { return color.RGBA{0xff, 0xff, 0xff, 0xff} },
+	"gray": func(t float64) color.RGBA {
+		g := uint8(0x40 + t*0xbf)
+		return color.RGBA{g, g, g, 0xff}
+	},
+	// heat runs from blue in the valleys to red on the peaks.
+	"heat": gradient(color.RGBA{0x00, 0x00, 0xff, 0xff}, color.RGBA{0xff, 0x00, 0x00, 0xff}),
+	"viridis": gradient(
+		color.RGBA{0x44, 0x01, 0x54, 0xff},
+		color.RGBA{0x3b, 0x52, 0x8b, 0xff},
+		color.RGBA{0x21, 0x90, 0x8d, 0xff},
+		color.RGBA{0x5d, 0xc9, 0x63, 0xff},
+		color.RGBA{0xfd, 0xe7, 0x25, 0xff},
+	),
+}
+
+// gradient interpolates linearly between equally spaced stops.
+func gradient(stops ...color.RGBA) colormap {
+	return func(t float64) color.RGBA {
+		t = math.Max(0, math.Min(1, t)) * float64(len(stops)-1)
+		k := int(t)
+		if k == len(stops)-1 {
+			return stops[k]
+		}
+		a, b, f := stops[k], stops[k+1], t-float64(k)
+		mix := func(x, y uint8) uint8 { return uint8(float64(x) + (float64(y)-float64(x))*f + 0.5) }
+		return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 0xff}
+	}
+}
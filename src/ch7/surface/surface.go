@@ -3,15 +3,24 @@
 
 // See page 203.
 
-// The surface program plots the 3-D surface of a user-provided function.
+// The surface program serves plots of the 3-D surface of a user-provided
+// function. For example,
+//
+//	http://localhost:8000/plot?expr=sin(-x)*pow(1.5,-r)&colormap=heat&format=png
+//
+// See parseParams for the query parameters. Recently rendered plots
+// are cached.
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"html/template"
 	"log"
-	"math"
 	"net/http"
+	"net/url"
 )
 
 //!+parseAndCheck
@@ -19,48 +28,6 @@ import "gopl.io/ch7/eval"
 
 //!-parseAndCheck
 
-// -- copied from gopl.io/ch3/surface --
-
-const (
-	width, height = 600, 320            // canvas size in pixels
-	cells         = 100                 // number of grid cells
-	xyrange       = 30.0                // x, y axis range (-xyrange..+xyrange)
-	xyscale       = width / 2 / xyrange // pixels per x or y unit
-	zscale        = height * 0.4        // pixels per z unit
-)
-
-var sin30, cos30 = 0.5, math.Sqrt(3.0 / 4.0) // sin(30°), cos(30°)
-
-func corner(f func(x, y float64) float64, i, j int) (float64, float64) {
-	// find point (x,y) at corner of cell (i,j)
-	x := xyrange * (float64(i)/cells - 0.5)
-	y := xyrange * (float64(j)/cells - 0.5)
-
-	z := f(x, y) // compute surface height z
-
-	// project (x,y,z) isometrically onto 2-D SVG canvas (sx,sy)
-	sx := width/2 + (x-y)*cos30*xyscale
-	sy := height/2 + (x+y)*sin30*xyscale - z*zscale
-	return sx, sy
-}
-
-func surface(w io.Writer, f func(x, y float64) float64) {
-	fmt.Fprintf(w, "<svg xmlns='http://www.w3.org/2000/svg' "+
-		"style='stroke: grey; fill: white; stroke-width: 0.7' "+
-		"width='%d' height='%d'>", width, height)
-	for i := 0; i < cells; i++ {
-		for j := 0; j < cells; j++ {
-			ax, ay := corner(f, i+1, j)
-			bx, by := corner(f, i, j)
-			cx, cy := corner(f, i, j+1)
-			dx, dy := corner(f, i+1, j+1)
-			fmt.Fprintf(w, "<polygon points='%g,%g %g,%g %g,%g %g,%g'/>\n",
-				ax, ay, bx, by, cx, cy, dx, dy)
-		}
-	}
-	fmt.Fprintln(w, "</svg>")
-}
-
 // -- main code for gopl.io/ch7/surface --
 
 //!+parseAndCheck
@@ -72,12 +39,14 @@ func parseAndCheck(s string) (eval.Expr, error) {
 	if err != nil {
 		return nil, err
 	}
+	// r is shorthand for the distance from (0,0).
+	expr = eval.Substitute(expr, "r", hypot)
 	vars := make(map[eval.Var]bool)
 	if err := expr.Check(vars); err != nil {
 		return nil, err
 	}
 	for v := range vars {
-		if v != "x" && v != "y" && v != "r" {
+		if v != "x" && v != "y" {
 			return nil, fmt.Errorf("undefined variable: %s", v)
 		}
 	}
@@ -86,26 +55,113 @@ func parseAndCheck(s string) (eval.Expr, error) {
 
 //!-parseAndCheck
 
+var hypot, _ = eval.Parse("hypot(x, y)")
+
+// A plot is a rendered image.
+type plot struct {
+	contentType string
+	body        []byte
+}
+
+// render is the function that the cache memoizes. Its key is the
+// canonical query of plotParams.key.
+func render(key string) (*plot, error) {
+	q, err := url.ParseQuery(key)
+	if err != nil {
+		return nil, err
+	}
+	p, err := parseParams(q)
+	if err != nil {
+		return nil, err
+	}
+	ps, err := panels(p)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if p.format == "png" {
+		err = writePNG(&buf, p, ps)
+		return &plot{"image/png", buf.Bytes()}, err
+	}
+	err = writeSVG(&buf, p, ps)
+	return &plot{"image/svg+xml", buf.Bytes()}, err
+}
+
+// A server serves plots from its cache.
+type server struct {
+	cache *cache
+}
+
 //!+plot
-func plot(w http.ResponseWriter, r *http.Request) {
+func (s *server) plot(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
-	expr, err := parseAndCheck(r.Form.Get("expr"))
+	p, err := parseParams(r.Form)
 	if err != nil {
-		http.Error(w, "bad expr: "+err.Error(), http.StatusBadRequest)
+		badRequest(w, err)
+		return
+	}
+	pl, err := s.cache.get(p.key())
+	if errors.Is(err, errTooDetailed) {
+		badRequest(w, err) // the request asks for too much work
+		return
+	} else if err != nil {
+		log.Printf("rendering %s: %v", p.key(), err)
+		replyError(w, http.StatusInternalServerError, err)
 		return
 	}
-	w.Header().Set("Content-Type", "image/svg+xml")
-	surface(w, func(x, y float64) float64 {
-		r := math.Hypot(x, y) // distance from (0,0)
-		return expr.Eval(eval.Env{"x": x, "y": y, "r": r})
-	})
+	w.Header().Set("Content-Type", pl.contentType)
+	w.Write(pl.body)
 }
 
 //!-plot
 
+// badRequest replies with a 400 status and err as JSON.
+func badRequest(w http.ResponseWriter, err error) {
+	replyError(w, http.StatusBadRequest, err)
+}
+
+// replyError replies with status and err as JSON.
+func replyError(w http.ResponseWriter, status int, err error) {
+	var perr *paramError
+	if !errors.As(err, &perr) {
+		perr = &paramError{Msg: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(perr)
+}
+
+var index = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<title>surface</title>
+<form action="/">
+<input name="expr" size="60" value="{{.Get "expr"}}">
+<select name="colormap">
+{{range .Maps}}<option{{if eq . ($.Get "colormap")}} selected{{end}}>{{.}}</option>{{end}}
+</select>
+<label><input type="checkbox" name="partials" value="true"
+{{- if .Get "partials"}} checked{{end}}> partial derivatives</label>
+<input type="submit" value="Plot">
+</form>
+{{if .Get "expr"}}<p><img src="/plot?{{.Query}}">{{end}}
+`))
+
+// home serves a form for trying out expressions.
+func home(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	if err := index.Execute(w, struct {
+		url.Values
+		Maps  []string
+		Query template.URL
+	}{r.Form, []string{"none", "gray", "heat", "viridis"}, template.URL(r.Form.Encode())}); err != nil {
+		log.Print(err)
+	}
+}
+
 //!+main
 func main() {
-	http.HandleFunc("/plot", plot)
+	s := &server{newCache(render)}
+	http.HandleFunc("/", home)
+	http.HandleFunc("/plot", s.plot)
 	log.Fatal(http.ListenAndServe("localhost:8000", nil))
 }
 
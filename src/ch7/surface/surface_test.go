@@ -0,0 +1,148 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func get(t *testing.T, s *server, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.plot(w, httptest.NewRequest("GET", "/plot?"+query, nil))
+	return w
+}
+
+func newServer() (*server, *int32) {
+	var renders int32
+	s := &server{newCache(func(key string) (*plot, error) {
+		atomic.AddInt32(&renders, 1)
+		return render(key)
+	})}
+	return s, &renders
+}
+
+func TestPlotSVG(t *testing.T) {
+	s, _ := newServer()
+	w := get(t, s, "expr="+url.QueryEscape("sin(r)/r")+"&cells=20&colormap=heat")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+		t.Errorf("Content-Type = %s", ct)
+	}
+	body := w.Body.String()
+	// r is 0 at the central corner, where sin(r)/r is NaN,
+	// so the four cells around it are left out.
+	if n := strings.Count(body, "<polygon"); n != 20*20-4 {
+		t.Errorf("got %d polygons, want %d", n, 20*20-4)
+	}
+	if !strings.Contains(body, "fill='#") {
+		t.Errorf("heat colormap not applied")
+	}
+	if strings.Contains(body, "NaN") || strings.Contains(body, "Inf") {
+		t.Errorf("SVG contains non-finite coordinates")
+	}
+}
+
+func TestPlotPNG(t *testing.T) {
+	s, _ := newServer()
+	w := get(t, s, "expr=x*y&cells=10&width=100&height=80&partials=true&format=png&colormap=viridis&zscale=fit")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	img, err := png.Decode(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 300 || b.Dy() != 80 {
+		t.Errorf("bounds %v, want 3 panels of 100x80", b)
+	}
+}
+
+func TestPlotCache(t *testing.T) {
+	s, renders := newServer()
+	a := get(t, s, "expr=x%2By&cells=5")
+	b := get(t, s, "expr="+url.QueryEscape("(x + y)")+"&cells=5&xmin=-15&format=svg")
+	if a.Code != http.StatusOK || !bytes.Equal(a.Body.Bytes(), b.Body.Bytes()) {
+		t.Errorf("equivalent queries gave different plots")
+	}
+	if n := atomic.LoadInt32(renders); n != 1 {
+		t.Errorf("rendered %d times, want 1", n)
+	}
+}
+
+func TestPlotErrors(t *testing.T) {
+	s, _ := newServer()
+	for _, test := range []struct {
+		query string
+		want  paramError
+	}{
+		{"", paramError{Param: "expr", Msg: "empty expression"}},
+		{"expr=" + url.QueryEscape("x +* y"),
+			paramError{"expr", "x +* y", "unexpected '*'", 1, 4}},
+		{"expr=" + url.QueryEscape("sqrt(x, y)"),
+			paramError{"expr", "sqrt(x, y)", "call to sqrt has 2 args, want 1", 1, 1}},
+		{"expr=z", paramError{Param: "expr", Value: "z", Msg: "undefined variable: z"}},
+		{"expr=x&cells=0", paramError{Param: "cells", Value: "0", Msg: "want an integer between 1 and 400"}},
+		{"expr=x&xmin=2&xmax=1", paramError{Param: "xmax", Value: "1", Msg: "must be greater than xmin"}},
+		{"expr=x&colormap=jet", paramError{Param: "colormap", Value: "jet", Msg: "want none, gray, heat or viridis"}},
+		{"expr=x&format=gif", paramError{Param: "format", Value: "gif", Msg: "want svg or png"}},
+		{"expr=x&zscale=-1", paramError{Param: "zscale", Value: "-1", Msg: `want a positive number or "fit"`}},
+		{"expr=" + url.QueryEscape("sin(x*50)*4") + "&cells=400&format=png",
+			paramError{Msg: errTooDetailed.Error()}},
+	} {
+		w := get(t, s, test.query)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", test.query, w.Code)
+			continue
+		}
+		var got paramError
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Errorf("%s: %v", test.query, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got %+v, want %+v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestCacheBound(t *testing.T) {
+	var renders int
+	c := newCache(func(key string) (*plot, error) {
+		renders++
+		return &plot{"text/plain", make([]byte, 1<<20)}, nil
+	})
+	for i := 0; i < 100; i++ {
+		c.get(strconv.Itoa(i))
+	}
+	if len(c.keys) > maxCached || c.bytes > maxCachedBytes {
+		t.Errorf("cache holds %d plots, %d bytes", len(c.keys), c.bytes)
+	}
+	c.get("99") // in the current memo
+	c.get("0")  // dropped with the first
+	if renders != 101 {
+		t.Errorf("rendered %d times, want 101", renders)
+	}
+}
+
+func TestRenderFailure(t *testing.T) {
+	s := &server{newCache(func(string) (*plot, error) {
+		return nil, errors.New("disk full")
+	})}
+	if w := get(t, s, "expr=x"); w.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500", w.Code)
+	}
+}
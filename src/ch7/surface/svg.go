@@ -0,0 +1,49 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+)
+
+// writeSVG draws the panels side by side as an SVG image.
+func writeSVG(w io.Writer, p *plotParams, ps []panel) error {
+	bw := &errWriter{w: w}
+	bw.printf("<svg xmlns='http://www.w3.org/2000/svg' "+
+		"style='stroke: grey; fill: white; stroke-width: 0.7' "+
+		"width='%d' height='%d'>\n", p.width*len(ps), p.height)
+	for k, pan := range ps {
+		bw.printf("<g transform='translate(%d,0)'>\n", k*p.width)
+		if len(ps) > 1 {
+			bw.printf("<text x='10' y='20' style='stroke: none; fill: black'>%s</text>\n",
+				html.EscapeString(pan.label))
+		}
+		for _, q := range pan.quads {
+			bw.printf("<polygon points='%g,%g %g,%g %g,%g %g,%g'",
+				q.pts[0][0], q.pts[0][1], q.pts[1][0], q.pts[1][1],
+				q.pts[2][0], q.pts[2][1], q.pts[3][0], q.pts[3][1])
+			if p.colormap != "none" {
+				bw.printf(" fill='#%02x%02x%02x'", q.fill.R, q.fill.G, q.fill.B)
+			}
+			bw.printf("/>\n")
+		}
+		bw.printf("</g>\n")
+	}
+	bw.printf("</svg>\n")
+	return bw.err
+}
+
+// An errWriter remembers the first error from w and ignores later writes.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}